@@ -0,0 +1,100 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchmark
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// Load generators. They issue system calls against a mounted file
+// system, and return the first error. File data is accessed with
+// O_DIRECT, so each read and write reaches the file system rather
+// than the page cache.
+
+// LookupLoad does n lstat calls in dir, cycling through names. If
+// the mount has a zero entry timeout, each of them results in a
+// LOOKUP.
+func LookupLoad(dir string, names []string, n int) error {
+	var st syscall.Stat_t
+	for i := 0; i < n; i++ {
+		if err := syscall.Lstat(filepath.Join(dir, names[i%len(names)]), &st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StatLoad does n fstat calls on the file name. If the mount has a
+// zero attribute timeout, each of them results in a GETATTR.
+func StatLoad(name string, n int) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var st syscall.Stat_t
+	for i := 0; i < n; i++ {
+		if err := syscall.Fstat(int(f.Fd()), &st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadLoad reads n blocks of blockSize bytes from the first size
+// bytes of the file name, either sequentially, wrapping around at
+// size, or at random block offsets.
+func ReadLoad(name string, size int64, blockSize, n int, random bool) error {
+	return blockLoad(name, os.O_RDONLY, size, blockSize, n, random)
+}
+
+// WriteLoad is like ReadLoad, but writes the blocks. The file must
+// exist.
+func WriteLoad(name string, size int64, blockSize, n int, random bool) error {
+	return blockLoad(name, os.O_WRONLY, size, blockSize, n, random)
+}
+
+// directIOAlign is the alignment needed for O_DIRECT buffers.
+const directIOAlign = 4096
+
+func blockLoad(name string, flag int, size int64, blockSize, n int, random bool) error {
+	f, err := os.OpenFile(name, flag|syscall.O_DIRECT, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, blockSize+directIOAlign)
+	if rem := uintptr(unsafe.Pointer(&buf[0])) % directIOAlign; rem != 0 {
+		buf = buf[directIOAlign-rem:]
+	}
+	buf = buf[:blockSize]
+
+	blocks := size / int64(blockSize)
+	if blocks == 0 {
+		blocks = 1
+	}
+	for i := 0; i < n; i++ {
+		b := int64(i) % blocks
+		if random {
+			b = rand.Int63n(blocks)
+		}
+		off := b * int64(blockSize)
+		if flag == os.O_RDONLY {
+			_, err = f.ReadAt(buf, off)
+		} else {
+			_, err = f.WriteAt(buf, off)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchmark
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+const (
+	// loadFileSize is the part of the data file that is read and
+	// written.
+	loadFileSize  = 16 << 20
+	loadBlockSize = 64 << 10
+	loadNames     = 100
+)
+
+var loadFileSystems = []struct {
+	name string
+	root func(dir string) (fs.InodeEmbedder, error)
+}{
+	{"null", func(string) (fs.InodeEmbedder, error) { return NewNullFS(), nil }},
+	{"mem", func(string) (fs.InodeEmbedder, error) { return NewMemFS(), nil }},
+	{"loopback", fs.NewLoopbackRoot},
+}
+
+// mountLoad mounts a reference file system with zero timeouts, so
+// every lstat and fstat reaches it, and populates it with files for
+// the load generators. The returned function unmounts it.
+func mountLoad(tb testing.TB, name string, root func(string) (fs.InodeEmbedder, error)) (string, []string, func()) {
+	backing := testutil.TempDir()
+	node, err := root(backing)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var zero time.Duration
+	opts := &fs.Options{
+		EntryTimeout:    &zero,
+		AttrTimeout:     &zero,
+		NegativeTimeout: &zero,
+	}
+	opts.Debug = testutil.VerboseTest()
+	mnt := testutil.TempDir()
+	server, err := fs.Mount(mnt, node, opts)
+	if err != nil {
+		tb.Fatalf("Mount %s: %v", name, err)
+	}
+	clean := func() {
+		server.Unmount()
+		os.RemoveAll(mnt)
+		os.RemoveAll(backing)
+	}
+
+	names := make([]string, loadNames)
+	for i := range names {
+		names[i] = fmt.Sprintf("file%d", i)
+	}
+	if name == "null" {
+		return mnt, names, clean
+	}
+	for _, n := range names {
+		if err := ioutil.WriteFile(mnt+"/"+n, nil, 0644); err != nil {
+			clean()
+			tb.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(mnt+"/data", make([]byte, loadFileSize), 0644); err != nil {
+		clean()
+		tb.Fatal(err)
+	}
+	return mnt, names, clean
+}
+
+func TestLoadGenerators(t *testing.T) {
+	for _, lfs := range loadFileSystems {
+		t.Run(lfs.name, func(t *testing.T) {
+			mnt, names, clean := mountLoad(t, lfs.name, lfs.root)
+			defer clean()
+			data := mnt + "/data"
+			for _, load := range []struct {
+				name string
+				f    func() error
+			}{
+				{"lookup", func() error { return LookupLoad(mnt, names, 10) }},
+				{"stat", func() error { return StatLoad(data, 10) }},
+				{"read", func() error { return ReadLoad(data, loadFileSize, loadBlockSize, 10, false) }},
+				{"randread", func() error { return ReadLoad(data, loadFileSize, loadBlockSize, 10, true) }},
+				{"write", func() error { return WriteLoad(data, loadFileSize, loadBlockSize, 10, false) }},
+				{"randwrite", func() error { return WriteLoad(data, loadFileSize, loadBlockSize, 10, true) }},
+			} {
+				if err := load.f(); err != nil {
+					t.Errorf("%s: %v", load.name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNullFSReadsZeros(t *testing.T) {
+	// Read buffers are reused, so they may hold data of earlier
+	// requests.
+	buf := bytes.Repeat([]byte("x"), 4096)
+	res, errno := (&nullFile{}).Read(context.Background(), nil, buf, 0)
+	if errno != 0 {
+		t.Fatalf("Read: %v", errno)
+	}
+	data, _ := res.Bytes(buf)
+	if !bytes.Equal(data, make([]byte, len(buf))) {
+		t.Errorf("read non-zero data")
+	}
+}
+
+// benchmarkLoad runs load against each reference file system. If
+// bytes is set, the benchmark reports bandwidth for a block size
+// of bytes.
+func benchmarkLoad(b *testing.B, bytes int64, load func(mnt string, names []string, n int) error) {
+	for _, lfs := range loadFileSystems {
+		b.Run(lfs.name, func(b *testing.B) {
+			mnt, names, clean := mountLoad(b, lfs.name, lfs.root)
+			defer clean()
+			b.SetBytes(bytes)
+			b.ResetTimer()
+			if err := load(mnt, names, b.N); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	benchmarkLoad(b, 0, LookupLoad)
+}
+
+func BenchmarkStat(b *testing.B) {
+	benchmarkLoad(b, 0, func(mnt string, names []string, n int) error {
+		return StatLoad(mnt+"/data", n)
+	})
+}
+
+func BenchmarkSequentialRead(b *testing.B) {
+	benchmarkLoad(b, loadBlockSize, func(mnt string, names []string, n int) error {
+		return ReadLoad(mnt+"/data", loadFileSize, loadBlockSize, n, false)
+	})
+}
+
+func BenchmarkRandomRead(b *testing.B) {
+	benchmarkLoad(b, loadBlockSize, func(mnt string, names []string, n int) error {
+		return ReadLoad(mnt+"/data", loadFileSize, loadBlockSize, n, true)
+	})
+}
+
+func BenchmarkSequentialWrite(b *testing.B) {
+	benchmarkLoad(b, loadBlockSize, func(mnt string, names []string, n int) error {
+		return WriteLoad(mnt+"/data", loadFileSize, loadBlockSize, n, false)
+	})
+}
+
+func BenchmarkRandomWrite(b *testing.B) {
+	benchmarkLoad(b, loadBlockSize, func(mnt string, names []string, n int) error {
+		return WriteLoad(mnt+"/data", loadFileSize, loadBlockSize, n, true)
+	})
+}
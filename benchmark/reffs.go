@@ -0,0 +1,104 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchmark
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// Reference file systems for benchmarks. Comparing the null file
+// system with the others separates the cost of go-fuse from the
+// cost of the storage.
+
+// NewNullFS returns a file system where every name is a file that
+// reads as zeros and discards writes, so the time spent serving
+// requests is all overhead of the kernel and go-fuse.
+func NewNullFS() fs.InodeEmbedder {
+	return &nullDir{}
+}
+
+type nullDir struct {
+	fs.Inode
+}
+
+var _ = (fs.NodeLookuper)((*nullDir)(nil))
+
+func (n *nullDir) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	out.Attr.Mode = fuse.S_IFREG | 0644
+	out.Attr.Size = fileSize
+	return n.NewInode(ctx, &nullFile{}, fs.StableAttr{Mode: fuse.S_IFREG}), fs.OK
+}
+
+type nullFile struct {
+	fs.Inode
+}
+
+var _ = (fs.NodeGetattrer)((*nullFile)(nil))
+
+func (n *nullFile) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0644
+	out.Size = fileSize
+	return fs.OK
+}
+
+var _ = (fs.NodeSetattrer)((*nullFile)(nil))
+
+func (n *nullFile) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	return n.Getattr(ctx, f, out)
+}
+
+var _ = (fs.NodeOpener)((*nullFile)(nil))
+
+func (n *nullFile) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	return nil, fuse.FOPEN_DIRECT_IO, fs.OK
+}
+
+var _ = (fs.NodeReader)((*nullFile)(nil))
+
+func (n *nullFile) Read(ctx context.Context, f fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	if off >= fileSize {
+		return fuse.ReadResultData(nil), fs.OK
+	}
+	if end := off + int64(len(dest)); end > fileSize {
+		dest = dest[:fileSize-off]
+	}
+	// dest is a pooled buffer that may hold data of other requests.
+	for i := range dest {
+		dest[i] = 0
+	}
+	return fuse.ReadResultData(dest), fs.OK
+}
+
+var _ = (fs.NodeWriter)((*nullFile)(nil))
+
+func (n *nullFile) Write(ctx context.Context, f fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	return uint32(len(data)), fs.OK
+}
+
+// NewMemFS returns an empty file system that keeps its files in
+// memory. Files can be created in the root directory.
+func NewMemFS() fs.InodeEmbedder {
+	return &memDir{}
+}
+
+type memDir struct {
+	fs.Inode
+}
+
+var _ = (fs.NodeCreater)((*memDir)(nil))
+
+func (n *memDir) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	f := &fs.MemRegularFile{
+		Attr: fuse.Attr{Mode: mode},
+	}
+	ch := n.NewPersistentInode(ctx, f, fs.StableAttr{Mode: fuse.S_IFREG})
+	n.AddChild(name, ch, true)
+	out.Attr.Mode = fuse.S_IFREG | mode
+	return ch, nil, 0, fs.OK
+}
@@ -0,0 +1,61 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This is main program driver for the encrypted filesystem from
+// github.com/hanwen/go-fuse/fuse/pathfs, which stores file contents
+// and names encrypted in an underlying directory.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/pathfs"
+)
+
+func main() {
+	debug := flag.Bool("debug", false, "print debugging messages.")
+	keyFile := flag.String("keyfile", "", "file holding the hex-encoded 32-byte key.")
+	flag.Parse()
+	if flag.NArg() < 2 || *keyFile == "" {
+		fmt.Printf("usage: %s -keyfile KEYFILE MOUNTPOINT ORIGINAL\n", path.Base(os.Args[0]))
+		fmt.Printf("\nGenerate a key with: head -c 32 /dev/urandom | xxd -p -c 32 > KEYFILE\n")
+		fmt.Printf("\noptions:\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	content, err := ioutil.ReadFile(*keyFile)
+	if err != nil {
+		fmt.Printf("ReadFile: %v\n", err)
+		os.Exit(1)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		fmt.Printf("key: %v\n", err)
+		os.Exit(1)
+	}
+
+	fs, err := pathfs.NewEncryptedFileSystem(pathfs.NewLoopbackFileSystem(flag.Arg(1)), key)
+	if err != nil {
+		fmt.Printf("NewEncryptedFileSystem: %v\n", err)
+		os.Exit(1)
+	}
+	server, err := pathfs.Mount(flag.Arg(0), fs, &fuse.MountOptions{
+		Name:  "encfs",
+		Debug: *debug,
+	}, nil)
+	if err != nil {
+		fmt.Printf("Mount fail: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Mounted!")
+	server.Serve()
+}
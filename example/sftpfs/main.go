@@ -0,0 +1,59 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This is main program driver for the SFTP filesystem from
+// github.com/hanwen/go-fuse/fuse/pathfs, which mounts a directory of
+// a remote host through ssh, like sshfs.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/pathfs"
+)
+
+func main() {
+	debug := flag.Bool("debug", false, "print debugging messages.")
+	conns := flag.Int("connections", 2, "number of ssh connections to use.")
+	timeout := flag.Duration("timeout", time.Minute, "fail requests that take longer than this.")
+	flag.Parse()
+	if flag.NArg() < 2 {
+		fmt.Printf("usage: %s MOUNTPOINT [USER@]HOST:[DIR]\n", path.Base(os.Args[0]))
+		fmt.Printf("\noptions:\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	host, dir := flag.Arg(1), ""
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host, dir = host[:i], host[i+1:]
+	}
+	fs, err := pathfs.NewSFTPFileSystem(pathfs.SFTPOptions{
+		Dial:        pathfs.SSHDialer(host),
+		Root:        dir,
+		Connections: *conns,
+		Timeout:     *timeout,
+	})
+	if err != nil {
+		fmt.Printf("NewSFTPFileSystem: %v\n", err)
+		os.Exit(1)
+	}
+	server, err := pathfs.Mount(flag.Arg(0), fs, &fuse.MountOptions{
+		Name:   "sftpfs",
+		FsName: flag.Arg(1),
+		Debug:  *debug,
+	}, nil)
+	if err != nil {
+		fmt.Printf("Mount fail: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Mounted!")
+	server.Serve()
+}
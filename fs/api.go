@@ -229,6 +229,18 @@ type NodeGetattrer interface {
 	Getattr(ctx context.Context, f FileHandle, out *fuse.AttrOut) syscall.Errno
 }
 
+// Statx reads attributes for statx(2), which include the birth time
+// (Btime). Flags and mask are the AT_STATX_* flags and the STATX_*
+// mask passed to statx(2); out.Mask says which fields are set. The
+// library will ensure that Mode and Ino are set correctly, as for
+// Getattr. Without NodeStatxer or FileStatxer, the attributes are
+// taken from Getattr, and carry no birth time. As of Linux 6.18, the
+// kernel uses the birth time and the basic stats, and fills in other
+// fields, such as the mount ID, itself.
+type NodeStatxer interface {
+	Statx(ctx context.Context, f FileHandle, flags uint32, mask uint32, out *fuse.StatxOut) syscall.Errno
+}
+
 // SetAttr sets attributes for an Inode.
 type NodeSetattrer interface {
 	Setattr(ctx context.Context, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno
@@ -239,6 +251,14 @@ type NodeOnAdder interface {
 	OnAdd(ctx context.Context)
 }
 
+// OnForget is called when the kernel drops its last reference to
+// the node. It can be used to release resources, such as file
+// descriptors, tied to the node. A node that is still part of the
+// tree may be handed to the kernel again.
+type NodeOnForgetter interface {
+	OnForget()
+}
+
 // Getxattr should read data for the given attribute into
 // `dest` and return the number of bytes. If `dest` is too
 // small, it should return ERANGE and the size of the attribute.
@@ -512,6 +532,11 @@ type FileGetattrer interface {
 	Getattr(ctx context.Context, out *fuse.AttrOut) syscall.Errno
 }
 
+// See NodeStatxer.
+type FileStatxer interface {
+	Statx(ctx context.Context, flags uint32, mask uint32, out *fuse.StatxOut) syscall.Errno
+}
+
 // See NodeReader.
 type FileReader interface {
 	Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno)
@@ -601,6 +626,14 @@ type Options struct {
 	// If nonzero, replace default (zero) GID with the given GID
 	GID uint32
 
+	// If set, report every file as owned by this UID and GID,
+	// whatever the file system returns, like the "squash" options
+	// of network file systems. This makes permission checks
+	// predictable for single-user mounts; use fuse.CurrentOwner
+	// for the user running the file system. It takes precedence
+	// over UID and GID.
+	Owner *fuse.Owner
+
 	// ServerCallbacks can be provided to stub out notification
 	// functions for testing a filesystem without mounting it.
 	ServerCallbacks ServerCallbacks
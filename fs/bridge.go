@@ -14,6 +14,7 @@ import (
 
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"github.com/hanwen/go-fuse/v2/internal"
+	"golang.org/x/sys/unix"
 )
 
 func errnoToStatus(errno syscall.Errno) fuse.Status {
@@ -54,6 +55,20 @@ type ServerCallbacks interface {
 	InodeNotifyStoreCache(node uint64, offset int64, data []byte) fuse.Status
 }
 
+// nodeDiscarder is implemented by nodes of this package that hold
+// resources from the moment they are created. discard is called if
+// the node is dropped without being handed to the kernel, because a
+// node with the same StableAttr was added first.
+type nodeDiscarder interface {
+	discard()
+}
+
+// rootDestroyer is implemented by roots of this package that hold
+// resources until the server shuts down.
+type rootDestroyer interface {
+	destroy()
+}
+
 type rawBridge struct {
 	options Options
 	root    *Inode
@@ -226,6 +241,14 @@ func (b *rawBridge) addNewChild(parent *Inode, name string, child *Inode, file F
 	b.mu.Unlock()
 	unlockNodes(parent, child)
 
+	if child != orig {
+		orig.mu.Lock()
+		unused := orig.lookupCount == 0 && !orig.persistent && orig.parents.count() == 0
+		orig.mu.Unlock()
+		if d, ok := orig.ops.(nodeDiscarder); ok && unused {
+			d.discard()
+		}
+	}
 	return child, fh
 }
 
@@ -242,7 +265,7 @@ func (b *rawBridge) setEntryOutTimeout(out *fuse.EntryOut) {
 func (b *rawBridge) setAttr(out *fuse.Attr) {
 	if !b.options.NullPermissions && out.Mode&07777 == 0 {
 		out.Mode |= 0644
-		if out.Mode&syscall.S_IFDIR != 0 {
+		if out.IsDir() {
 			out.Mode |= 0111
 		}
 	}
@@ -252,6 +275,9 @@ func (b *rawBridge) setAttr(out *fuse.Attr) {
 	if b.options.GID != 0 && out.Gid == 0 {
 		out.Gid = b.options.GID
 	}
+	if b.options.Owner != nil {
+		out.Owner = *b.options.Owner
+	}
 	setBlocks(out)
 }
 
@@ -324,6 +350,17 @@ func (b *rawBridge) inode(id uint64, fh uint64) (*Inode, *fileEntry) {
 	return n, f
 }
 
+// NodePath implements fuse.NodePather, for the debug trace.
+func (b *rawBridge) NodePath(nodeID uint64) string {
+	b.mu.Lock()
+	n := b.kernelNodeIds[nodeID]
+	b.mu.Unlock()
+	if n == nil {
+		return ""
+	}
+	return "/" + n.Path(nil)
+}
+
 func (b *rawBridge) Lookup(cancel <-chan struct{}, header *fuse.InHeader, name string, out *fuse.EntryOut) fuse.Status {
 	parent, _ := b.inode(header.NodeId, 0)
 	ctx := &fuse.Context{Caller: header.Caller, Cancel: cancel}
@@ -475,6 +512,9 @@ func (b *rawBridge) Forget(nodeid, nlookup uint64) {
 	forgotten, _ := n.removeRef(nlookup, false)
 
 	if forgotten {
+		if of, ok := n.ops.(NodeOnForgetter); ok {
+			of.OnForget()
+		}
 		b.compactMemory()
 	}
 }
@@ -581,6 +621,9 @@ func (b *rawBridge) SetAttr(cancel <-chan struct{}, in *fuse.SetAttrIn, out *fus
 	}
 
 	out.Mode = n.stableAttr.Mode | (out.Mode & 07777)
+	if errno == 0 {
+		b.setAttr(&out.Attr)
+	}
 	return errnoToStatus(errno)
 }
 
@@ -872,6 +915,33 @@ func (b *rawBridge) Write(cancel <-chan struct{}, input *fuse.WriteIn, data []by
 	return 0, fuse.ENOTSUP
 }
 
+// WriteFd implements fuse.SpliceWriter. Writes to loopback files
+// are spliced directly into the backing file descriptor. Like
+// loopbackFile.Write, the splice holds the lock of the file, so
+// Release waits for it. Writes that go through a NodeWriter, or a
+// file other than a plain loopbackFile, and writes to files opened
+// with O_APPEND take the regular Write path.
+func (b *rawBridge) WriteFd(cancel <-chan struct{}, input *fuse.WriteIn) (uintptr, func(), bool) {
+	n, f := b.inode(input.NodeId, input.Fh)
+	if _, ok := n.ops.(NodeWriter); ok {
+		return 0, nil, false
+	}
+	lf, ok := f.file.(*loopbackFile)
+	if !ok {
+		return 0, nil, false
+	}
+	lf.mu.Lock()
+	if lf.fd == -1 {
+		lf.mu.Unlock()
+		return 0, nil, false
+	}
+	if flags, err := unix.FcntlInt(uintptr(lf.fd), unix.F_GETFL, 0); err != nil || flags&syscall.O_APPEND != 0 {
+		lf.mu.Unlock()
+		return 0, nil, false
+	}
+	return uintptr(lf.fd), lf.mu.Unlock, true
+}
+
 func (b *rawBridge) Flush(cancel <-chan struct{}, input *fuse.FlushIn) fuse.Status {
 	n, f := b.inode(input.NodeId, input.Fh)
 	if fl, ok := n.ops.(NodeFlusher); ok {
@@ -1104,6 +1174,14 @@ func (b *rawBridge) Init(s *fuse.Server) {
 	b.server = s
 }
 
+// Destroy lets the root release resources that it holds until the
+// server shuts down.
+func (b *rawBridge) Destroy() {
+	if d, ok := b.root.ops.(rootDestroyer); ok {
+		d.destroy()
+	}
+}
+
 func (b *rawBridge) CopyFileRange(cancel <-chan struct{}, in *fuse.CopyFileRangeIn) (size uint32, status fuse.Status) {
 	n1, f1 := b.inode(in.NodeId, in.FhIn)
 	cfr, ok := n1.ops.(NodeCopyFileRanger)
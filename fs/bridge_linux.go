@@ -0,0 +1,66 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+func (b *rawBridge) Statx(cancel <-chan struct{}, input *fuse.StatxIn, out *fuse.StatxOut) fuse.Status {
+	var fh uint64
+	if input.GetattrFlags&fuse.FUSE_GETATTR_FH != 0 {
+		fh = input.Fh
+	}
+	n, fEntry := b.inode(input.NodeId, fh)
+	f := fEntry.file
+	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	return errnoToStatus(b.statx(ctx, n, f, input.SxFlags, input.SxMask, out))
+}
+
+func (b *rawBridge) statx(ctx context.Context, n *Inode, f FileHandle, flags uint32, mask uint32, out *fuse.StatxOut) syscall.Errno {
+	var fs FileStatxer
+	if f != nil {
+		fs, _ = f.(FileStatxer)
+	}
+
+	var errno syscall.Errno
+	if sx, ok := n.ops.(NodeStatxer); ok {
+		errno = sx.Statx(ctx, f, flags, mask, out)
+	} else if fs != nil {
+		errno = fs.Statx(ctx, flags, mask, out)
+	} else {
+		var attrOut fuse.AttrOut
+		if errno = b.getattr(ctx, n, f, &attrOut); errno == 0 {
+			out.Statx.FromAttr(&attrOut.Attr)
+			out.AttrValid = attrOut.AttrValid
+			out.AttrValidNsec = attrOut.AttrValidNsec
+		}
+		return errno
+	}
+	if errno != 0 {
+		return errno
+	}
+
+	// Apply the same fixups as getattr.
+	attr := fuse.Attr{
+		Size:    out.Size,
+		Blocks:  out.Blocks,
+		Mode:    uint32(out.Mode&07777) | n.stableAttr.Mode,
+		Owner:   fuse.Owner{Uid: out.Uid, Gid: out.Gid},
+		Blksize: out.Blksize,
+	}
+	b.setAttr(&attr)
+	out.Ino = n.stableAttr.Ino
+	out.Mode = uint16(attr.Mode)
+	out.Uid, out.Gid = attr.Uid, attr.Gid
+	out.Blocks, out.Blksize = attr.Blocks, attr.Blksize
+	if b.options.AttrTimeout != nil && out.Timeout() == 0 {
+		out.SetTimeout(*b.options.AttrTimeout)
+	}
+	return OK
+}
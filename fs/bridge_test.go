@@ -6,12 +6,16 @@ package fs
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
 	"log"
 	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
@@ -238,3 +242,92 @@ func (fn *testIno1) Lookup(ctx context.Context, name string, out *fuse.EntryOut)
 	child := fn.NewInode(ctx, &testIno1{}, stable)
 	return child, 0
 }
+
+type failNode struct {
+	Inode
+	err error
+}
+
+var _ = (NodeGetattrer)((*failNode)(nil))
+
+func (n *failNode) Getattr(ctx context.Context, f FileHandle, out *fuse.AttrOut) syscall.Errno {
+	return Fail(ctx, n.err)
+}
+
+func TestFailCause(t *testing.T) {
+	cause := fuse.NewError(fuse.EIO, errors.New("backend down"))
+	var mu sync.Mutex
+	var got []error
+	root := &Inode{}
+	opts := &Options{
+		OnAdd: func(ctx context.Context) {
+			ch := root.NewPersistentInode(ctx, &failNode{err: cause}, StableAttr{})
+			root.AddChild("file", ch, false)
+		},
+	}
+	opts.OpHook = func(rec *fuse.OpRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		if rec.Err != nil {
+			got = append(got, rec.Err)
+		}
+	}
+	mntDir, _, clean := testMount(t, root, opts)
+	defer clean()
+
+	if _, err := os.Lstat(mntDir + "/file"); err == nil {
+		t.Fatalf("Lstat succeeded, want EIO")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) == 0 || got[0] != cause {
+		t.Errorf("got causes %v, want %v", got, cause)
+	}
+}
+
+func TestWriteFd(t *testing.T) {
+	tc := newTestCase(t, &testOptions{suppressDebug: true})
+	defer tc.Clean()
+	if err := ioutil.WriteFile(tc.origDir+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rb := tc.rawFS.(*rawBridge)
+	var entry fuse.EntryOut
+	if code := rb.Lookup(nil, &fuse.InHeader{NodeId: 1}, "file", &entry); !code.Ok() {
+		t.Fatalf("Lookup: %v", code)
+	}
+	open := func(flags uint32) *fuse.ReleaseIn {
+		in := fuse.OpenIn{InHeader: fuse.InHeader{NodeId: entry.NodeId}, Flags: flags}
+		var out fuse.OpenOut
+		if code := rb.Open(nil, &in, &out); !code.Ok() {
+			t.Fatalf("Open: %v", code)
+		}
+		return &fuse.ReleaseIn{InHeader: in.InHeader, Fh: out.Fh}
+	}
+
+	rel := open(syscall.O_WRONLY | syscall.O_APPEND)
+	if _, _, ok := rb.WriteFd(nil, &fuse.WriteIn{InHeader: rel.InHeader, Fh: rel.Fh}); ok {
+		t.Errorf("WriteFd succeeded on O_APPEND file")
+	}
+	rb.Release(nil, rel)
+
+	rel = open(syscall.O_WRONLY)
+	_, done, ok := rb.WriteFd(nil, &fuse.WriteIn{InHeader: rel.InHeader, Fh: rel.Fh})
+	if !ok {
+		t.Fatalf("WriteFd failed")
+	}
+	released := make(chan struct{})
+	go func() {
+		rb.Release(nil, rel)
+		close(released)
+	}()
+	select {
+	case <-released:
+		t.Errorf("Release did not wait for the splice to finish")
+	case <-time.After(50 * time.Millisecond):
+	}
+	done()
+	<-released
+}
@@ -5,6 +5,7 @@
 package fs
 
 import (
+	"context"
 	"syscall"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
@@ -19,6 +20,13 @@ func ToErrno(err error) syscall.Errno {
 	return syscall.Errno(s)
 }
 
+// Fail is like ToErrno, but also records err as the cause of the
+// failure of the request that ctx belongs to, for debug logs and
+// fuse.MountOptions.OpHook.
+func Fail(ctx context.Context, err error) syscall.Errno {
+	return syscall.Errno(fuse.FailContext(ctx, err))
+}
+
 // RENAME_EXCHANGE is a flag argument for renameat2()
 const RENAME_EXCHANGE = 0x2
 
@@ -0,0 +1,400 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"golang.org/x/sys/unix"
+)
+
+// fdLoopbackNode is a loopback node that holds an O_PATH file
+// descriptor for its backing file. Operations use *at() system
+// calls relative to that descriptor, or its /proc/self/fd entry,
+// rather than a path, so they do not walk the tree from the root,
+// and they keep working on the right file if it is renamed on the
+// backing file system.
+type fdLoopbackNode struct {
+	Inode
+
+	rootData *LoopbackRoot
+
+	// fd is an O_PATH descriptor. It is closed when the kernel
+	// forgets the node, or when the server shuts down.
+	fd int
+
+	// fds holds the descriptors of all nodes of the file system.
+	fds *fdSet
+}
+
+// fdSet tracks the descriptors of the nodes of an fd loopback file
+// system, so the remaining ones can be closed on shutdown.
+type fdSet struct {
+	mu  sync.Mutex
+	fds map[int]struct{}
+}
+
+func (s *fdSet) add(fd int) {
+	s.mu.Lock()
+	s.fds[fd] = struct{}{}
+	s.mu.Unlock()
+}
+
+// close closes fd, unless closeAll already did.
+func (s *fdSet) close(fd int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.fds[fd]; ok {
+		delete(s.fds, fd)
+		syscall.Close(fd)
+	}
+}
+
+func (s *fdSet) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for fd := range s.fds {
+		syscall.Close(fd)
+	}
+	s.fds = map[int]struct{}{}
+}
+
+var _ = (NodeOnForgetter)((*fdLoopbackNode)(nil))
+var _ = (nodeDiscarder)((*fdLoopbackNode)(nil))
+var _ = (rootDestroyer)((*fdLoopbackNode)(nil))
+var _ = (NodeStatfser)((*fdLoopbackNode)(nil))
+var _ = (NodeGetattrer)((*fdLoopbackNode)(nil))
+var _ = (NodeSetattrer)((*fdLoopbackNode)(nil))
+var _ = (NodeGetxattrer)((*fdLoopbackNode)(nil))
+var _ = (NodeSetxattrer)((*fdLoopbackNode)(nil))
+var _ = (NodeRemovexattrer)((*fdLoopbackNode)(nil))
+var _ = (NodeListxattrer)((*fdLoopbackNode)(nil))
+var _ = (NodeReadlinker)((*fdLoopbackNode)(nil))
+var _ = (NodeOpener)((*fdLoopbackNode)(nil))
+var _ = (NodeCopyFileRanger)((*fdLoopbackNode)(nil))
+var _ = (NodeLookuper)((*fdLoopbackNode)(nil))
+var _ = (NodeOpendirer)((*fdLoopbackNode)(nil))
+var _ = (NodeReaddirer)((*fdLoopbackNode)(nil))
+var _ = (NodeMkdirer)((*fdLoopbackNode)(nil))
+var _ = (NodeMknoder)((*fdLoopbackNode)(nil))
+var _ = (NodeLinker)((*fdLoopbackNode)(nil))
+var _ = (NodeSymlinker)((*fdLoopbackNode)(nil))
+var _ = (NodeUnlinker)((*fdLoopbackNode)(nil))
+var _ = (NodeRmdirer)((*fdLoopbackNode)(nil))
+var _ = (NodeRenamer)((*fdLoopbackNode)(nil))
+var _ = (NodeCreater)((*fdLoopbackNode)(nil))
+
+// NewFdLoopbackRoot returns the root of a loopback file system that
+// keeps a file descriptor for each node the kernel knows, and for
+// each open file and directory. Unlike NewLoopbackRoot, it does not
+// construct full paths for each operation, which reduces path
+// resolution overhead and closes races against concurrent renames in
+// the backing file system. Reads are returned as file descriptor
+// ranges, so they are spliced when the server supports it.
+//
+// This uses one file descriptor per node in the kernel's inode
+// cache, so the process file limit may need to be raised.
+func NewFdLoopbackRoot(rootPath string) (InodeEmbedder, error) {
+	fd, err := unix.Open(rootPath, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	fds := &fdSet{fds: map[int]struct{}{}}
+	fds.add(fd)
+	return &fdLoopbackNode{
+		rootData: &LoopbackRoot{
+			Path: rootPath,
+			Dev:  uint64(st.Dev),
+		},
+		fd:  fd,
+		fds: fds,
+	}, nil
+}
+
+// procPath returns a path that refers to the file opened as fd.
+func procPath(fd int) string {
+	return fmt.Sprintf("/proc/self/fd/%d", fd)
+}
+
+func (n *fdLoopbackNode) OnForget() {
+	n.fds.close(n.fd)
+}
+
+func (n *fdLoopbackNode) discard() {
+	n.fds.close(n.fd)
+}
+
+// destroy closes the descriptors of the nodes that the kernel still
+// knows when the server shuts down.
+func (n *fdLoopbackNode) destroy() {
+	n.fds.closeAll()
+}
+
+// child returns a node for the entry name, which must exist.
+func (n *fdLoopbackNode) child(ctx context.Context, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	fd, err := unix.Openat(n.fd, name, unix.O_PATH|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, ToErrno(err)
+	}
+	st := syscall.Stat_t{}
+	if err := syscall.Fstat(fd, &st); err != nil {
+		syscall.Close(fd)
+		return nil, ToErrno(err)
+	}
+	out.Attr.FromStat(&st)
+	n.fds.add(fd)
+	node := &fdLoopbackNode{rootData: n.rootData, fd: fd, fds: n.fds}
+	return n.NewInode(ctx, node, n.rootData.idFromStat(&st)), OK
+}
+
+// preserveOwner sets uid and gid of the entry name according to the
+// caller information in ctx.
+func (n *fdLoopbackNode) preserveOwner(ctx context.Context, name string) error {
+	if os.Getuid() != 0 {
+		return nil
+	}
+	caller, ok := fuse.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return unix.Fchownat(n.fd, name, int(caller.Uid), int(caller.Gid), unix.AT_SYMLINK_NOFOLLOW)
+}
+
+func (n *fdLoopbackNode) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	s := syscall.Statfs_t{}
+	if err := syscall.Fstatfs(n.fd, &s); err != nil {
+		return ToErrno(err)
+	}
+	out.FromStatfsT(&s)
+	return OK
+}
+
+func (n *fdLoopbackNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	return n.child(ctx, name, out)
+}
+
+func (n *fdLoopbackNode) Mknod(ctx context.Context, name string, mode, rdev uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if err := unix.Mknodat(n.fd, name, mode, int(rdev)); err != nil {
+		return nil, ToErrno(err)
+	}
+	n.preserveOwner(ctx, name)
+	ch, errno := n.child(ctx, name, out)
+	if errno != 0 {
+		unix.Unlinkat(n.fd, name, 0)
+	}
+	return ch, errno
+}
+
+func (n *fdLoopbackNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if err := unix.Mkdirat(n.fd, name, mode); err != nil {
+		return nil, ToErrno(err)
+	}
+	n.preserveOwner(ctx, name)
+	ch, errno := n.child(ctx, name, out)
+	if errno != 0 {
+		unix.Unlinkat(n.fd, name, unix.AT_REMOVEDIR)
+	}
+	return ch, errno
+}
+
+func (n *fdLoopbackNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if err := unix.Symlinkat(target, n.fd, name); err != nil {
+		return nil, ToErrno(err)
+	}
+	n.preserveOwner(ctx, name)
+	ch, errno := n.child(ctx, name, out)
+	if errno != 0 {
+		unix.Unlinkat(n.fd, name, 0)
+	}
+	return ch, errno
+}
+
+func (n *fdLoopbackNode) Link(ctx context.Context, target InodeEmbedder, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	t, ok := target.(*fdLoopbackNode)
+	if !ok {
+		return nil, syscall.EXDEV
+	}
+	// Linking an O_PATH descriptor with AT_EMPTY_PATH needs
+	// CAP_DAC_READ_SEARCH, so go through /proc instead.
+	if err := unix.Linkat(unix.AT_FDCWD, procPath(t.fd), n.fd, name, unix.AT_SYMLINK_FOLLOW); err != nil {
+		return nil, ToErrno(err)
+	}
+	ch, errno := n.child(ctx, name, out)
+	if errno != 0 {
+		unix.Unlinkat(n.fd, name, 0)
+	}
+	return ch, errno
+}
+
+func (n *fdLoopbackNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*Inode, FileHandle, uint32, syscall.Errno) {
+	fd, err := unix.Openat(n.fd, name, int(flags)|os.O_CREATE, mode)
+	if err != nil {
+		return nil, nil, 0, ToErrno(err)
+	}
+	n.preserveOwner(ctx, name)
+	ch, errno := n.child(ctx, name, out)
+	if errno != 0 {
+		syscall.Close(fd)
+		return nil, nil, 0, errno
+	}
+	return ch, NewLoopbackFile(fd), 0, OK
+}
+
+func (n *fdLoopbackNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	return ToErrno(unix.Unlinkat(n.fd, name, 0))
+}
+
+func (n *fdLoopbackNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	return ToErrno(unix.Unlinkat(n.fd, name, unix.AT_REMOVEDIR))
+}
+
+func (n *fdLoopbackNode) Rename(ctx context.Context, name string, newParent InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	p, ok := newParent.(*fdLoopbackNode)
+	if !ok {
+		return syscall.EXDEV
+	}
+	if flags == 0 {
+		return ToErrno(unix.Renameat(n.fd, name, p.fd, newName))
+	}
+	return ToErrno(unix.Renameat2(n.fd, name, p.fd, newName, uint(flags)))
+}
+
+func (n *fdLoopbackNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	for l := 256; ; l *= 2 {
+		buf := make([]byte, l)
+		sz, err := unix.Readlinkat(n.fd, "", buf)
+		if err != nil {
+			return nil, ToErrno(err)
+		}
+		if sz < len(buf) {
+			return buf[:sz], 0
+		}
+	}
+}
+
+func (n *fdLoopbackNode) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	// An O_PATH descriptor cannot be read, so reopen the file
+	// through /proc. This does not follow symlinks: the magic
+	// link resolves to the file itself.
+	fd, err := syscall.Open(procPath(n.fd), int(flags)&^syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, 0, ToErrno(err)
+	}
+	return NewLoopbackFile(fd), 0, OK
+}
+
+func (n *fdLoopbackNode) Opendir(ctx context.Context) syscall.Errno {
+	fd, err := syscall.Open(procPath(n.fd), syscall.O_DIRECTORY, 0)
+	if err != nil {
+		return ToErrno(err)
+	}
+	syscall.Close(fd)
+	return OK
+}
+
+func (n *fdLoopbackNode) Readdir(ctx context.Context) (DirStream, syscall.Errno) {
+	return NewLoopbackDirStream(procPath(n.fd))
+}
+
+func (n *fdLoopbackNode) Getattr(ctx context.Context, f FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if f != nil {
+		return f.(FileGetattrer).Getattr(ctx, out)
+	}
+	st := syscall.Stat_t{}
+	if err := syscall.Fstat(n.fd, &st); err != nil {
+		return ToErrno(err)
+	}
+	out.FromStat(&st)
+	return OK
+}
+
+var _ = (NodeStatxer)((*fdLoopbackNode)(nil))
+
+func (n *fdLoopbackNode) Statx(ctx context.Context, f FileHandle, flags uint32, mask uint32, out *fuse.StatxOut) syscall.Errno {
+	if fs, ok := f.(FileStatxer); ok {
+		return fs.Statx(ctx, flags, mask, out)
+	}
+	fl := int(flags&_AT_STATX_SYNC_TYPE) | unix.AT_EMPTY_PATH | unix.AT_SYMLINK_NOFOLLOW
+	return ToErrno(statx(n.fd, "", fl, mask, &out.Statx))
+}
+
+func (n *fdLoopbackNode) Setattr(ctx context.Context, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if fsa, ok := f.(FileSetattrer); ok && fsa != nil {
+		return fsa.Setattr(ctx, in, out)
+	}
+
+	p := procPath(n.fd)
+	if m, ok := in.GetMode(); ok {
+		if err := syscall.Chmod(p, m); err != nil {
+			return ToErrno(err)
+		}
+	}
+
+	uid, uok := in.GetUID()
+	gid, gok := in.GetGID()
+	if uok || gok {
+		suid := -1
+		sgid := -1
+		if uok {
+			suid = int(uid)
+		}
+		if gok {
+			sgid = int(gid)
+		}
+		if err := unix.Fchownat(n.fd, "", suid, sgid, unix.AT_EMPTY_PATH|unix.AT_SYMLINK_NOFOLLOW); err != nil {
+			return ToErrno(err)
+		}
+	}
+
+	if ap, mp := in.GetTimes(); ap != nil || mp != nil {
+		var ts [2]syscall.Timespec
+		ts[0] = fuse.UtimeToTimespec(ap)
+		ts[1] = fuse.UtimeToTimespec(mp)
+		if err := syscall.UtimesNano(p, ts[:]); err != nil {
+			return ToErrno(err)
+		}
+	}
+
+	if sz, ok := in.GetSize(); ok {
+		if err := syscall.Truncate(p, int64(sz)); err != nil {
+			return ToErrno(err)
+		}
+	}
+	return n.Getattr(ctx, nil, out)
+}
+
+func (n *fdLoopbackNode) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
+	sz, err := unix.Getxattr(procPath(n.fd), attr, dest)
+	return uint32(sz), ToErrno(err)
+}
+
+func (n *fdLoopbackNode) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) syscall.Errno {
+	return ToErrno(unix.Setxattr(procPath(n.fd), attr, data, int(flags)))
+}
+
+func (n *fdLoopbackNode) Removexattr(ctx context.Context, attr string) syscall.Errno {
+	return ToErrno(unix.Removexattr(procPath(n.fd), attr))
+}
+
+func (n *fdLoopbackNode) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
+	sz, err := unix.Listxattr(procPath(n.fd), dest)
+	return uint32(sz), ToErrno(err)
+}
+
+func (n *fdLoopbackNode) CopyFileRange(ctx context.Context, fhIn FileHandle,
+	offIn uint64, out *Inode, fhOut FileHandle, offOut uint64,
+	len uint64, flags uint64) (uint32, syscall.Errno) {
+	return copyFileRange(fhIn, offIn, fhOut, offOut, len, flags)
+}
@@ -0,0 +1,135 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFdLoopback(t *testing.T) {
+	tc := newTestCase(t, &testOptions{
+		attrCache:  true,
+		entryCache: true,
+		newRoot:    NewFdLoopbackRoot,
+	})
+	defer tc.Clean()
+
+	if err := os.Mkdir(tc.mntDir+"/dir", 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	fn := tc.mntDir + "/dir/file"
+	if err := ioutil.WriteFile(fn, []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Symlink("file", tc.mntDir+"/dir/link"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if err := os.Link(fn, tc.mntDir+"/dir/hard"); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if err := os.Chmod(fn, 0600); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+
+	if target, err := os.Readlink(tc.mntDir + "/dir/link"); err != nil || target != "file" {
+		t.Errorf("Readlink: %q, %v", target, err)
+	}
+	if content, err := ioutil.ReadFile(tc.mntDir + "/dir/hard"); err != nil || string(content) != "hello" {
+		t.Errorf("ReadFile: %q, %v", content, err)
+	}
+	var st syscall.Stat_t
+	if err := syscall.Lstat(tc.origDir+"/dir/file", &st); err != nil {
+		t.Fatalf("Lstat: %v", err)
+	} else if st.Mode&07777 != 0600 || st.Nlink != 2 {
+		t.Errorf("got mode %o, nlink %d, want 0600, 2", st.Mode&07777, st.Nlink)
+	}
+
+	f, err := os.Open(tc.mntDir + "/dir")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		t.Fatalf("Readdirnames: %v", err)
+	}
+	sort.Strings(names)
+	if want := []string{"file", "hard", "link"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	// The kernel caches the entry for "dir". Renaming it in the
+	// backing file system does not affect operations on it,
+	// because they go through its file descriptor.
+	if err := os.Rename(tc.origDir+"/dir", tc.origDir+"/moved"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if err := ioutil.WriteFile(tc.mntDir+"/dir/new", []byte("x"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := os.Lstat(tc.origDir + "/moved/new"); err != nil {
+		t.Errorf("new file was not created in the renamed directory: %v", err)
+	}
+
+	if err := os.Rename(tc.mntDir+"/dir/new", tc.mntDir+"/new"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if err := os.Remove(tc.mntDir + "/dir/link"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := os.Lstat(tc.origDir + "/moved/link"); !os.IsNotExist(err) {
+		t.Errorf("link was not removed: %v", err)
+	}
+	if _, err := os.Lstat(tc.origDir + "/new"); err != nil {
+		t.Errorf("rename target missing: %v", err)
+	}
+}
+
+func countFds(t *testing.T) int {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	return len(fds)
+}
+
+func TestFdLoopbackClosesFds(t *testing.T) {
+	before := countFds(t)
+
+	tc := newTestCase(t, &testOptions{
+		attrCache:  true,
+		entryCache: true,
+		newRoot:    NewFdLoopbackRoot,
+	})
+	if err := os.MkdirAll(tc.mntDir+"/dir/sub", 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := ioutil.WriteFile(tc.mntDir+"/dir/sub/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := os.Lstat(tc.mntDir + "/dir/sub/file"); err != nil {
+		t.Fatalf("Lstat: %v", err)
+	}
+	tc.Clean()
+
+	// Serve closes the device after the request loops exit, which
+	// may be after Unmount returns.
+	var after int
+	for i := 0; i < 100; i++ {
+		if after = countFds(t); after <= before {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after != before {
+		t.Errorf("got %d open fds after unmounting, want %d", after, before)
+	}
+}
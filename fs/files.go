@@ -184,18 +184,7 @@ func (f *loopbackFile) setAttr(ctx context.Context, in *fuse.SetAttrIn) syscall.
 		}
 	}
 
-	mtime, mok := in.GetMTime()
-	atime, aok := in.GetATime()
-
-	if mok || aok {
-		ap := &atime
-		mp := &mtime
-		if !aok {
-			ap = nil
-		}
-		if !mok {
-			mp = nil
-		}
+	if ap, mp := in.GetTimes(); ap != nil || mp != nil {
 		errno = f.utimens(ap, mp)
 		if errno != 0 {
 			return errno
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
+	"golang.org/x/sys/unix"
 )
 
 func (f *loopbackFile) Allocate(ctx context.Context, off uint64, sz uint64, mode uint32) syscall.Errno {
@@ -22,6 +23,15 @@ func (f *loopbackFile) Allocate(ctx context.Context, off uint64, sz uint64, mode
 	return OK
 }
 
+var _ = (FileStatxer)((*loopbackFile)(nil))
+
+func (f *loopbackFile) Statx(ctx context.Context, flags uint32, mask uint32, out *fuse.StatxOut) syscall.Errno {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fl := int(flags&_AT_STATX_SYNC_TYPE) | unix.AT_EMPTY_PATH
+	return ToErrno(statx(f.fd, "", fl, mask, &out.Statx))
+}
+
 // Utimens - file handle based version of loopbackFileSystem.Utimens()
 func (f *loopbackFile) utimens(a *time.Time, m *time.Time) syscall.Errno {
 	var ts [2]syscall.Timespec
@@ -205,7 +205,6 @@ var _ = (NodeCreater)((*LoopbackNode)(nil))
 
 func (n *LoopbackNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (inode *Inode, fh FileHandle, fuseFlags uint32, errno syscall.Errno) {
 	p := filepath.Join(n.path(), name)
-	flags = flags &^ syscall.O_APPEND
 	fd, err := syscall.Open(p, int(flags)|os.O_CREATE, mode)
 	if err != nil {
 		return nil, nil, 0, ToErrno(err)
@@ -280,7 +279,9 @@ func (n *LoopbackNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
 }
 
 func (n *LoopbackNode) Open(ctx context.Context, flags uint32) (fh FileHandle, fuseFlags uint32, errno syscall.Errno) {
-	flags = flags &^ syscall.O_APPEND
+	// O_APPEND is kept, so writes from concurrent appenders
+	// land at the end of the file. In writeback cache mode, the
+	// fuse.Server removes it.
 	p := n.path()
 	f, err := syscall.Open(p, int(flags), 0)
 	if err != nil {
@@ -331,7 +332,9 @@ func (n *LoopbackNode) Setattr(ctx context.Context, f FileHandle, in *fuse.SetAt
 	p := n.path()
 	fsa, ok := f.(FileSetattrer)
 	if ok && fsa != nil {
-		fsa.Setattr(ctx, in, out)
+		if errno := fsa.Setattr(ctx, in, out); errno != 0 {
+			return errno
+		}
 	} else {
 		if m, ok := in.GetMode(); ok {
 			if err := syscall.Chmod(p, m); err != nil {
@@ -355,19 +358,7 @@ func (n *LoopbackNode) Setattr(ctx context.Context, f FileHandle, in *fuse.SetAt
 			}
 		}
 
-		mtime, mok := in.GetMTime()
-		atime, aok := in.GetATime()
-
-		if mok || aok {
-
-			ap := &atime
-			mp := &mtime
-			if !aok {
-				ap = nil
-			}
-			if !mok {
-				mp = nil
-			}
+		if ap, mp := in.GetTimes(); ap != nil || mp != nil {
 			var ts [2]syscall.Timespec
 			ts[0] = fuse.UtimeToTimespec(ap)
 			ts[1] = fuse.UtimeToTimespec(mp)
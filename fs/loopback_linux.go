@@ -11,9 +11,24 @@ import (
 	"path/filepath"
 	"syscall"
 
+	"github.com/hanwen/go-fuse/v2/fuse"
 	"golang.org/x/sys/unix"
 )
 
+var _ = (NodeStatxer)((*LoopbackNode)(nil))
+
+func (n *LoopbackNode) Statx(ctx context.Context, f FileHandle, flags uint32, mask uint32, out *fuse.StatxOut) syscall.Errno {
+	if fs, ok := f.(FileStatxer); ok {
+		return fs.Statx(ctx, flags, mask, out)
+	}
+
+	fl := int(flags & _AT_STATX_SYNC_TYPE)
+	if &n.Inode != n.Root() {
+		fl |= unix.AT_SYMLINK_NOFOLLOW
+	}
+	return ToErrno(statx(unix.AT_FDCWD, n.path(), fl, mask, &out.Statx))
+}
+
 func (n *LoopbackNode) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
 	sz, err := unix.Lgetxattr(n.path(), attr, dest)
 	return uint32(sz), ToErrno(err)
@@ -71,6 +86,12 @@ func (n *LoopbackNode) renameExchange(name string, newparent InodeEmbedder, newN
 
 func (n *LoopbackNode) CopyFileRange(ctx context.Context, fhIn FileHandle,
 	offIn uint64, out *Inode, fhOut FileHandle, offOut uint64,
+	len uint64, flags uint64) (uint32, syscall.Errno) {
+	return copyFileRange(fhIn, offIn, fhOut, offOut, len, flags)
+}
+
+// copyFileRange copies data between two loopback files.
+func copyFileRange(fhIn FileHandle, offIn uint64, fhOut FileHandle, offOut uint64,
 	len uint64, flags uint64) (uint32, syscall.Errno) {
 	lfIn, ok := fhIn.(*loopbackFile)
 	if !ok {
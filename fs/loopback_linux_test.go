@@ -6,6 +6,7 @@ package fs
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"os"
 	"reflect"
@@ -401,3 +402,59 @@ func TestRoMount(t *testing.T) {
 	tc := newTestCase(t, &testOptions{ro: true})
 	defer tc.Clean()
 }
+
+func TestStatx(t *testing.T) {
+	tc := newTestCase(t, &testOptions{attrCache: true, entryCache: true})
+	defer tc.Clean()
+
+	tc.writeOrig("file", "hello", 0644)
+
+	mask := uint32(unix.STATX_BASIC_STATS | unix.STATX_BTIME)
+	var orig fuse.Statx
+	if err := statx(unix.AT_FDCWD, tc.origDir+"/file", 0, mask, &orig); err != nil {
+		t.Fatalf("statx: %v", err)
+	}
+	if orig.Mask&unix.STATX_BTIME == 0 {
+		t.Skip("backing file system has no birth times")
+	}
+
+	var st fuse.Statx
+	if err := statx(unix.AT_FDCWD, tc.mntDir+"/file", 0, mask, &st); err != nil {
+		t.Fatalf("statx: %v", err)
+	}
+	if st.Mask&unix.STATX_BTIME == 0 {
+		t.Skip("kernel does not send STATX")
+	}
+	if st.Btime != orig.Btime {
+		t.Errorf("got btime %v, want %v", st.Btime, orig.Btime)
+	}
+	if st.Size != 5 {
+		t.Errorf("got size %d, want 5", st.Size)
+	}
+}
+
+func TestStatxGetattr(t *testing.T) {
+	root := &Inode{}
+	mntDir, _, clean := testMount(t, root, &Options{
+		FirstAutomaticIno: 1,
+		OnAdd: func(ctx context.Context) {
+			n := root.EmbeddedInode()
+			ch := n.NewPersistentInode(ctx, &MemRegularFile{Data: []byte("hello")}, StableAttr{})
+			n.AddChild("file", ch, false)
+		},
+		UID: 42,
+	})
+	defer clean()
+
+	// Without NodeStatxer, statx returns the Getattr attributes.
+	var st fuse.Statx
+	if err := statx(unix.AT_FDCWD, mntDir+"/file", 0, unix.STATX_BASIC_STATS|unix.STATX_BTIME, &st); err != nil {
+		t.Fatalf("statx: %v", err)
+	}
+	if st.Mask&unix.STATX_BTIME != 0 {
+		t.Errorf("got btime %v, want none", st.Btime)
+	}
+	if st.Size != 5 || st.Uid != 42 || st.Mode != syscall.S_IFREG|0644 {
+		t.Errorf("got size %d uid %d mode %o, want 5, 42, %o", st.Size, st.Uid, st.Mode, syscall.S_IFREG|0644)
+	}
+}
@@ -70,6 +70,47 @@ func TestDefaultOwner(t *testing.T) {
 	}
 }
 
+func TestSquashOwner(t *testing.T) {
+	root := &Inode{}
+	mntDir, _, clean := testMount(t, root, &Options{
+		FirstAutomaticIno: 1,
+		OnAdd: func(ctx context.Context) {
+			n := root.EmbeddedInode()
+			ch := n.NewPersistentInode(
+				ctx,
+				&MemRegularFile{
+					Attr: fuse.Attr{
+						Mode:  0644,
+						Owner: fuse.Owner{Uid: 1, Gid: 2},
+					},
+				},
+				StableAttr{})
+			n.AddChild("file", ch, false)
+		},
+		UID:   42,
+		Owner: &fuse.Owner{Uid: 44, Gid: 45},
+	})
+	defer clean()
+
+	for _, name := range []string{"", "/file"} {
+		var st syscall.Stat_t
+		if err := syscall.Lstat(mntDir+name, &st); err != nil {
+			t.Fatalf("Lstat: %v", err)
+		} else if st.Uid != 44 || st.Gid != 45 {
+			t.Errorf("%q: got owner %d, %d want 44,45", name, st.Uid, st.Gid)
+		}
+	}
+	if err := os.Chmod(mntDir+"/file", 0600); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+	var st syscall.Stat_t
+	if err := syscall.Lstat(mntDir+"/file", &st); err != nil {
+		t.Fatalf("Lstat: %v", err)
+	} else if st.Uid != 44 || st.Gid != 45 {
+		t.Errorf("after chmod: got owner %d, %d want 44,45", st.Uid, st.Gid)
+	}
+}
+
 func TestDataFile(t *testing.T) {
 	want := "hello"
 	root := &Inode{}
@@ -61,6 +61,11 @@ type testOptions struct {
 	suppressDebug bool
 	testDir       string
 	ro            bool
+	writeback     bool
+
+	// newRoot creates the root node for the backing directory.
+	// If unset, NewLoopbackRoot is used.
+	newRoot func(rootPath string) (InodeEmbedder, error)
 }
 
 // newTestCase creates the directories `orig` and `mnt` inside a temporary
@@ -85,8 +90,12 @@ func newTestCase(t *testing.T, opts *testOptions) *testCase {
 		t.Fatal(err)
 	}
 
+	newRoot := opts.newRoot
+	if newRoot == nil {
+		newRoot = NewLoopbackRoot
+	}
 	var err error
-	tc.loopback, err = NewLoopbackRoot(tc.origDir)
+	tc.loopback, err = newRoot(tc.origDir)
 	if err != nil {
 		t.Fatalf("NewLoopback: %v", err)
 	}
@@ -114,6 +123,7 @@ func newTestCase(t *testing.T, opts *testOptions) *testCase {
 	if opts.ro {
 		mOpts.Options = append(mOpts.Options, "ro")
 	}
+	mOpts.EnableWritebackCache = opts.writeback
 	tc.server, err = fuse.NewServer(tc.rawFS, tc.mntDir, mOpts)
 	if err != nil {
 		t.Fatal(err)
@@ -393,6 +403,73 @@ func TestPosix(t *testing.T) {
 	}
 }
 
+func TestAppendConcurrentWriter(t *testing.T) {
+	tc := newTestCase(t, &testOptions{attrCache: true, entryCache: true})
+	defer tc.Clean()
+
+	tc.writeOrig("file", "", 0644)
+	f, err := os.OpenFile(tc.mntDir+"/file", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// Append behind the kernel's back, while it has the old size
+	// cached.
+	g, err := os.OpenFile(tc.origDir+"/file", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	if _, err := g.Write([]byte("XYZ")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	g.Close()
+
+	if _, err := f.Write([]byte("world")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := ioutil.ReadFile(tc.origDir + "/file")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if want := "helloXYZworld"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAppendWriteback(t *testing.T) {
+	tc := newTestCase(t, &testOptions{attrCache: true, entryCache: true, writeback: true})
+	defer tc.Clean()
+
+	if tc.server.KernelSettings().Flags&fuse.CAP_WRITEBACK_CACHE == 0 {
+		t.Skip("kernel does not support writeback caching")
+	}
+	posixtest.AppendWrite(t, tc.mntDir)
+
+	// The kernel places appended data; the file system must not
+	// append again.
+	f, err := os.OpenFile(tc.mntDir+"/file", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	if _, err := f.Write([]byte("!")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	got, err := ioutil.ReadFile(tc.origDir + "/file")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if want := "helloworld!"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 func TestOpenDirectIO(t *testing.T) {
 	// Apparently, tmpfs does not allow O_DIRECT, so try to create
 	// a test temp directory in /var/tmp.
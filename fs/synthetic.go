@@ -0,0 +1,189 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// SyntheticFile is a file whose contents are generated by a
+// function, like the files in /proc. It is useful for exposing
+// runtime values of a program as files.
+//
+// The contents are generated when the file is opened, and all reads
+// through that file handle see the same snapshot. The file is
+// opened non-seekable and with direct I/O, so the kernel neither
+// caches the contents nor relies on the reported size. Getattr
+// generates the contents to report their size.
+type SyntheticFile struct {
+	Inode
+
+	// Content returns the contents of the file.
+	Content func(ctx context.Context) ([]byte, syscall.Errno)
+
+	// Store, if set, makes the file writable. The data written
+	// through a file handle is passed to Store when the handle is
+	// flushed, which happens on close(2). Its return value is
+	// returned from close.
+	Store func(ctx context.Context, data []byte) syscall.Errno
+
+	// Mode holds the permission bits. If zero, it is 0444, or
+	// 0644 if Store is set.
+	Mode uint32
+}
+
+var _ = (NodeOpener)((*SyntheticFile)(nil))
+var _ = (NodeReader)((*SyntheticFile)(nil))
+var _ = (NodeWriter)((*SyntheticFile)(nil))
+var _ = (NodeFlusher)((*SyntheticFile)(nil))
+var _ = (NodeGetattrer)((*SyntheticFile)(nil))
+var _ = (NodeSetattrer)((*SyntheticFile)(nil))
+
+// syntheticHandle is an open SyntheticFile.
+type syntheticHandle struct {
+	mu      sync.Mutex
+	content []byte
+	written []byte
+	dirty   bool
+}
+
+func (f *SyntheticFile) mode() uint32 {
+	if f.Mode != 0 {
+		return f.Mode
+	}
+	if f.Store != nil {
+		return 0644
+	}
+	return 0444
+}
+
+func (f *SyntheticFile) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	h := &syntheticHandle{}
+	if flags&syscall.O_ACCMODE != syscall.O_RDONLY && f.Store == nil {
+		return nil, 0, syscall.EACCES
+	}
+	if flags&syscall.O_ACCMODE != syscall.O_WRONLY && f.Content != nil {
+		content, errno := f.Content(ctx)
+		if errno != 0 {
+			return nil, 0, errno
+		}
+		h.content = content
+	}
+	return h, fuse.FOPEN_DIRECT_IO | fuse.FOPEN_NONSEEKABLE, OK
+}
+
+func (f *SyntheticFile) Read(ctx context.Context, fh FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h, ok := fh.(*syntheticHandle)
+	if !ok {
+		return nil, syscall.EBADF
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if off >= int64(len(h.content)) {
+		return fuse.ReadResultData(nil), OK
+	}
+	end := off + int64(len(dest))
+	if end > int64(len(h.content)) {
+		end = int64(len(h.content))
+	}
+	return fuse.ReadResultData(h.content[off:end]), OK
+}
+
+func (f *SyntheticFile) Write(ctx context.Context, fh FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	h, ok := fh.(*syntheticHandle)
+	if !ok || f.Store == nil {
+		return 0, syscall.EBADF
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if end := off + int64(len(data)); end > int64(len(h.written)) {
+		n := make([]byte, end)
+		copy(n, h.written)
+		h.written = n
+	}
+	copy(h.written[off:], data)
+	h.dirty = true
+	return uint32(len(data)), OK
+}
+
+func (f *SyntheticFile) Flush(ctx context.Context, fh FileHandle) syscall.Errno {
+	h, ok := fh.(*syntheticHandle)
+	if !ok {
+		return OK
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.dirty {
+		return OK
+	}
+	h.dirty = false
+	data := h.written
+	h.written = nil
+	return f.Store(ctx, data)
+}
+
+func (f *SyntheticFile) Getattr(ctx context.Context, fh FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | f.mode()
+	out.Nlink = 1
+	now := time.Now()
+	out.SetTimes(nil, &now, &now)
+	if h, ok := fh.(*syntheticHandle); ok {
+		h.mu.Lock()
+		out.Size = uint64(len(h.content))
+		h.mu.Unlock()
+	} else if f.Content != nil {
+		content, errno := f.Content(ctx)
+		if errno != 0 {
+			return errno
+		}
+		out.Size = uint64(len(content))
+	}
+	return OK
+}
+
+// Setattr accepts truncation to zero, which shells do before
+// writing, and ignores it.
+func (f *SyntheticFile) Setattr(ctx context.Context, fh FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if sz, ok := in.GetSize(); ok && (sz != 0 || f.Store == nil) {
+		return syscall.EACCES
+	}
+	return f.Getattr(ctx, fh, out)
+}
+
+type syntheticRoot struct {
+	Inode
+	files map[string]*SyntheticFile
+}
+
+var _ = (NodeOnAdder)((*syntheticRoot)(nil))
+
+// NewSyntheticRoot returns a root holding the given files. The keys
+// are slash-separated paths; the directories leading to them are
+// created as needed.
+func NewSyntheticRoot(files map[string]*SyntheticFile) InodeEmbedder {
+	return &syntheticRoot{files: files}
+}
+
+func (r *syntheticRoot) OnAdd(ctx context.Context) {
+	for p, f := range r.files {
+		dir := &r.Inode
+		comps := strings.Split(strings.Trim(p, "/"), "/")
+		for _, c := range comps[:len(comps)-1] {
+			ch := dir.GetChild(c)
+			if ch == nil {
+				ch = dir.NewPersistentInode(ctx, &Inode{}, StableAttr{Mode: fuse.S_IFDIR})
+				dir.AddChild(c, ch, true)
+			}
+			dir = ch
+		}
+		dir.AddChild(comps[len(comps)-1], dir.NewPersistentInode(ctx, f, StableAttr{}), true)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestSyntheticFile(t *testing.T) {
+	var mu sync.Mutex
+	value := "hello\n"
+	files := map[string]*SyntheticFile{
+		"sys/value": {
+			Content: func(ctx context.Context) ([]byte, syscall.Errno) {
+				mu.Lock()
+				defer mu.Unlock()
+				return []byte(value), OK
+			},
+			Store: func(ctx context.Context, data []byte) syscall.Errno {
+				if len(data) == 0 {
+					return syscall.EINVAL
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				value = string(data)
+				return OK
+			},
+		},
+		"version": {
+			Content: func(ctx context.Context) ([]byte, syscall.Errno) {
+				return []byte("1.0\n"), OK
+			},
+		},
+	}
+	mntDir, _, clean := testMount(t, NewSyntheticRoot(files), nil)
+	defer clean()
+
+	valueName := filepath.Join(mntDir, "sys", "value")
+	if c, err := ioutil.ReadFile(valueName); err != nil || string(c) != "hello\n" {
+		t.Fatalf("ReadFile: %q, %v", c, err)
+	}
+	if fi, err := os.Stat(valueName); err != nil {
+		t.Fatalf("Stat: %v", err)
+	} else if fi.Size() != 6 || fi.Mode().Perm() != 0644 {
+		t.Errorf("got size %d mode %o, want 6, 0644", fi.Size(), fi.Mode().Perm())
+	}
+
+	if err := ioutil.WriteFile(valueName, []byte("bye\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if c, err := ioutil.ReadFile(valueName); err != nil || string(c) != "bye\n" {
+		t.Fatalf("ReadFile after write: %q, %v", c, err)
+	}
+
+	f, err := os.Open(valueName)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.ReadAt(make([]byte, 2), 1); err == nil {
+		t.Errorf("ReadAt succeeded on nonseekable file")
+	}
+
+	versionName := filepath.Join(mntDir, "version")
+	if fi, err := os.Stat(versionName); err != nil || fi.Mode().Perm() != 0444 {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+	if err := ioutil.WriteFile(versionName, []byte("2.0"), 0644); err == nil {
+		t.Errorf("WriteFile succeeded on read-only file")
+	}
+}
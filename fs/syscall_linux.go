@@ -7,8 +7,15 @@ package fs
 import (
 	"syscall"
 	"unsafe"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"golang.org/x/sys/unix"
 )
 
+// _AT_STATX_SYNC_TYPE masks the statx(2) flags that say whether
+// network file systems should sync the attributes.
+const _AT_STATX_SYNC_TYPE = 0x6000
+
 // futimens - futimens(3) calls utimensat(2) with "pathname" set to null and
 // "flags" set to zero
 func futimens(fd int, times *[2]syscall.Timespec) (err error) {
@@ -18,3 +25,13 @@ func futimens(fd int, times *[2]syscall.Timespec) (err error) {
 	}
 	return
 }
+
+// statx calls statx(2) and stores the result in out.
+func statx(dirfd int, path string, flags int, mask uint32, out *fuse.Statx) error {
+	var st unix.Statx_t
+	if err := unix.Statx(dirfd, path, flags, int(mask), &st); err != nil {
+		return err
+	}
+	out.FromStatx(&st)
+	return nil
+}
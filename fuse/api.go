@@ -122,6 +122,12 @@
 // [2] https://sylabs.io/guides/3.7/user-guide/bind_paths_and_mounts.html#fuse-mounts
 package fuse
 
+import (
+	"io"
+	"log"
+	"time"
+)
+
 // Types for users to implement.
 
 // The result of Read is an array of bytes, but for performance
@@ -145,6 +151,25 @@ type ReadResult interface {
 type MountOptions struct {
 	AllowOther bool
 
+	// AllowRoot is like AllowOther, but only grants access to
+	// the root user, in addition to the user that mounted the file
+	// system. The kernel has no such option, so the file system
+	// is mounted with allow_other, and requests from other users
+	// are rejected with EACCES by the Server. AllowRoot and
+	// AllowOther are mutually exclusive.
+	AllowRoot bool
+
+	// If set, mount the file system read-only.
+	ReadOnly bool
+
+	// If set, the kernel checks access based on the file mode,
+	// instead of leaving permission checks to the file system.
+	DefaultPermissions bool
+
+	// MaxRead limits the size of read requests. If 0, the kernel
+	// default is used.
+	MaxRead int
+
 	// Options are passed as -o string to fusermount.
 	Options []string
 
@@ -158,7 +183,10 @@ type MountOptions struct {
 	MaxWrite int
 
 	// Max read ahead to use.  If 0, use default. This number is
-	// capped at the kernel maximum.
+	// capped at the kernel maximum. If negative, the kernel does
+	// not read ahead at all, which suits file systems where reads
+	// are expensive and mostly small. The value that the kernel
+	// accepted is returned by Server.MaxReadAhead.
 	MaxReadAhead int
 
 	// If IgnoreSecurityLabels is set, all security related xattr
@@ -182,13 +210,83 @@ type MountOptions struct {
 	// If set, wrap the file system in a single-threaded locking wrapper.
 	SingleThreaded bool
 
+	// MaxReaders is the maximum number of goroutines that wait for
+	// requests on the FUSE device at the same time. Each request
+	// is dispatched on the goroutine that read it, and a new
+	// reader is started once all readers are busy, so a slow
+	// operation never blocks other requests. If 0, the default is
+	// GOMAXPROCS, clamped to the range [2, 16]. The limit only
+	// applies to the primary device descriptor: descriptors opened
+	// for CloneDevice and the io_uring ring have readers of their
+	// own.
+	MaxReaders int
+
 	// If set, return ENOSYS for Getxattr calls, so the kernel does not issue any
 	// Xattr operations at all.
 	DisableXAttrs bool
 
-	// If set, print debugging information.
+	// If set, print debugging information: a trace of each
+	// request and reply, with decoded arguments and the time
+	// taken to handle the request.
 	Debug bool
 
+	// If set, collect per-operation counts and latencies, which
+	// can be retrieved with Server.Stats.
+	EnableStats bool
+
+	// If PriorityWorkers is positive, requests are not handled by
+	// the goroutine that read them, but queued and served by this
+	// many worker goroutines in order of priority.
+	PriorityWorkers int
+
+	// RequestPriority classifies requests for PriorityWorkers;
+	// requests with higher values are served first, and requests
+	// of equal priority in order of arrival. It is passed the
+	// operation name (eg. "GETATTR") and the request header, which
+	// includes the calling process. It defaults to
+	// DefaultRequestPriority.
+	RequestPriority func(op string, header *InHeader) int
+
+	// MaxQueuedRequests bounds the queue of requests waiting for
+	// one of the PriorityWorkers. Once it is full, up to as many
+	// requests again are held in order of arrival, and enter the
+	// queue as workers take requests from it. Beyond that, the
+	// server stops reading from the device until a worker is
+	// done. If 0, the queue is unbounded.
+	MaxQueuedRequests int
+
+	// RequestTimeout returns how long requests for an operation
+	// (eg. "READ") may take, or 0 for no limit. Late requests fail
+	// with EIO and are canceled; nodes and handles from their late
+	// successes are released. Not used with SingleThreaded.
+	RequestTimeout func(op string) time.Duration
+
+	// If RethrowPanics is set, a panic in the file system
+	// crashes the program. By default, the panic is logged with
+	// its stack trace, the request fails with EIO, and the
+	// server keeps serving.
+	RethrowPanics bool
+
+	// Logger is the destination of the Debug trace. Use
+	// log.New to direct it to an io.Writer. If unset, the
+	// standard logger is used.
+	Logger *log.Logger
+
+	// If set, OpHook is called for every request after its reply
+	// was sent, with the operation, the node and file names it
+	// applies to, the calling process, the latency and the
+	// result. It runs on the goroutine that served the request,
+	// so it should be fast. NewJSONOpHook returns a hook that
+	// writes access logs as JSON.
+	OpHook func(*OpRecord)
+
+	// Middleware is wrapped around the calls into the
+	// RawFileSystem, the first element outermost, so cross-cutting
+	// concerns such as access control, metrics or tracing can be
+	// added without wrapping the RawFileSystem. INIT, INTERRUPT
+	// and notification replies bypass the middleware.
+	Middleware []Middleware
+
 	// If set, ask kernel to forward file locks to FUSE. If using,
 	// you must implement the GetLk/SetLk/SetLkw methods.
 	EnableLocks bool
@@ -197,6 +295,12 @@ type MountOptions struct {
 	// The filesystem is fully responsible for invalidating data cache.
 	ExplicitDataCacheControl bool
 
+	// If set, ask the kernel to cache writes (CAP_WRITEBACK_CACHE).
+	// The kernel then owns the file size, so O_APPEND is removed,
+	// and O_WRONLY changed to O_RDWR, in Open and Create calls.
+	// Without it, file systems should honor O_APPEND.
+	EnableWritebackCache bool
+
 	// SyncRead is off by default, which means that go-fuse enable the
 	// FUSE_CAP_ASYNC_READ capability.
 	// The kernel then submits multiple concurrent reads to service
@@ -219,23 +323,185 @@ type MountOptions struct {
 	// but might be needed if fusermount is not available.
 	DirectMount bool
 
+	// If set, the kernel unmounts the file system lazily when the
+	// process exits, even if it crashes, rather than leaving a
+	// mount point that fails with ENOTCONN until someone runs
+	// `fusermount -u`. This is the auto_unmount option of libfuse.
+	// It requires fusermount, so DirectMount is ignored. Linux
+	// only.
+	AutoUnmount bool
+
+	// If set, and the RawFileSystem implements SpliceWriter,
+	// requests are read from the FUSE device through a pipe, so
+	// the payload of WRITE requests can be spliced into a file
+	// descriptor without being copied into Go memory. This adds
+	// some overhead to every other request, so it only pays off
+	// for write-heavy workloads. Linux only.
+	SpliceWrite bool
+
 	// Options passed to syscall.Mount, the default value used by fusermount
 	// is syscall.MS_NOSUID|syscall.MS_NODEV
 	DirectMountFlags uintptr
 
-	// EnableAcls enables kernel ACL support.
+	// EnableAcls enables kernel ACL support. The kernel then
+	// reads and writes POSIX ACLs through the
+	// system.posix_acl_access and system.posix_acl_default
+	// extended attributes, so the file system must implement
+	// GetXAttr, SetXAttr and RemoveXAttr, and store these
+	// attributes as is. The kernel evaluates the ACLs itself,
+	// which implies DefaultPermissions. IgnoreSecurityLabels
+	// does not hide ACLs if EnableAcl is set.
 	//
 	// See the comments to FUSE_CAP_POSIX_ACL
 	// in https://github.com/libfuse/libfuse/blob/master/include/fuse_common.h
 	// for details.
 	EnableAcl bool
 
+	// If set, the kernel sends the security label, such as the
+	// SELinux context, of each node that is created with CREATE,
+	// MKDIR, MKNOD or SYMLINK. After the file system created the
+	// node, the Server stores the label by calling SetXAttr on
+	// it. Without this, nodes created on hosts that enforce
+	// SELinux may get the wrong label. This needs kernel
+	// protocol 7.38 (Linux 6.2) or later.
+	EnableSecurityContext bool
+
+	// EnablePassthrough negotiates passthrough of file data: an
+	// OPEN or CREATE reply with FOPEN_PASSTHROUGH names a backing
+	// file, registered with Server.RegisterBackingFd, and the
+	// kernel then reads and writes that file directly, without
+	// calling the file system. Metadata operations are still
+	// served by the file system. This needs Linux 6.9 or later,
+	// and CAP_SYS_ADMIN to register backing files. It is not
+	// available together with EnableWritebackCache.
+	EnablePassthrough bool
+
+	// EnableIoUring receives requests through an io_uring
+	// instead of by reading /dev/fuse, which saves a system call
+	// and a wakeup per request: the reply to one request and the
+	// wait for the next are a single submission. This needs Linux
+	// 6.14 or later, with the fuse module parameter enable_uring
+	// set. If the kernel does not offer it, or setting up the
+	// ring fails, requests are read from /dev/fuse as usual.
+	// INIT, FORGET and INTERRUPT always use /dev/fuse.
+	// Server.InodeRetrieveCache is not available with the ring.
+	EnableIoUring bool
+
+	// CloneDevice opens a /dev/fuse descriptor for each CPU
+	// (GOMAXPROCS), attached to the mount with FUSE_DEV_IOC_CLONE,
+	// and runs a reader on each. The kernel keeps a list of
+	// requests awaiting a reply per descriptor, so this spreads
+	// contention when many requests are read and answered in
+	// parallel. Linux only.
+	CloneDevice bool
+
+	// ExportSupport lets the kernel export the mount over NFS.
+	// The kernel encodes (NodeId, Generation) in NFS file
+	// handles. To decode a handle for a node it has forgotten, it
+	// sends LOOKUP with name "." on the node ID, and to find the
+	// parent of a directory, LOOKUP with name "..". The file
+	// system must answer these; nodefs does. A LOOKUP for a node
+	// ID that was reused must return a new generation, or
+	// ESTALE.
+	ExportSupport bool
+
+	// If set, all requests and replies are written to Record, for
+	// Replay. Writes happen on the request path, so Record should
+	// be buffered. SpliceWrite is ignored while recording.
+	Record io.Writer
+
 	// Disable ReadDirPlus capability so ReadDir is used instead. Simple 
 	// directory queries (i.e. 'ls' without '-l') can be faster with 
 	// ReadDir, as no per-file stat calls are needed
 	DisableReadDirPlus bool
 }
 
+// SpliceWriter is an optional interface for RawFileSystem. If
+// MountOptions.SpliceWrite is set, the payload of large WRITE
+// requests is left in a pipe, and WriteFd is called to find the
+// destination of the write. If it returns ok, the payload is
+// spliced into fd at the offset of the write, and done is called
+// afterwards. Otherwise, the payload is read into memory, and Write
+// is called as usual.
+//
+// The file descriptor must remain open until done is called. It
+// must not be opened with O_APPEND, as splicing at an offset fails
+// on such descriptors.
+type SpliceWriter interface {
+	WriteFd(cancel <-chan struct{}, input *WriteIn) (fd uintptr, done func(), ok bool)
+}
+
+// NodePather is an optional interface for RawFileSystem. If
+// implemented, the Debug trace includes the path of the node that a
+// request operates on.
+type NodePather interface {
+	NodePath(nodeID uint64) string
+}
+
+// Destroyer is an optional interface for RawFileSystem. Destroy is
+// called once when the file system is shut down: either when the
+// kernel sends a DESTROY request, or after unmounting, once the
+// request loops have exited.
+type Destroyer interface {
+	Destroy()
+}
+
+// Ioctler is an optional interface for RawFileSystem, to handle
+// ioctl(2) on open files and CUSE devices. Only restricted ioctls
+// are supported: the kernel derives the size of the input and
+// output from the command number, and input holds the argument
+// data. The returned data is truncated to input.OutSize bytes.
+// Without Ioctler, ioctl fails with ENOTTY.
+type Ioctler interface {
+	Ioctl(cancel <-chan struct{}, input *IoctlIn, inData []byte) (result int32, outData []byte, code Status)
+}
+
+// TmpFiler is an optional interface for RawFileSystem, to handle
+// open(2) with O_TMPFILE: TmpFile creates a file without a name in
+// the directory input.NodeId, and opens it, like Create. The file
+// may get a name later through Link, unless it was opened with
+// O_EXCL. Without TmpFiler, the kernel fails O_TMPFILE opens with
+// EOPNOTSUPP, and programs fall back to named temporary files. The
+// kernel sends TMPFILE since Linux 6.1.
+type TmpFiler interface {
+	TmpFile(cancel <-chan struct{}, input *CreateIn, out *CreateOut) (code Status)
+}
+
+// Statxer is an optional interface for RawFileSystem, to answer
+// statx(2) calls that ask for more than GetAttr returns, such as the
+// birth time. Without Statxer, or if Statx returns ENOSYS, the kernel
+// uses GetAttr for the rest of the mount, and reports no birth
+// times. The kernel sends STATX since Linux 6.6.
+type Statxer interface {
+	Statx(cancel <-chan struct{}, input *StatxIn, out *StatxOut) (code Status)
+}
+
+// Poller is an optional interface for RawFileSystem, to handle
+// poll(2) on CUSE devices. Poll should set out.Revents to the events
+// from input.Events that are ready. If none are ready and
+// input.Flags has FUSE_POLL_SCHEDULE_NOTIFY, the file system should
+// call Server.PollWakeupNotify(input.Kh) once they are.
+//
+// Regular FUSE mounts disable poll on startup (see the poll hack
+// in poll.go), so Poll is only called for CUSE devices.
+type Poller interface {
+	Poll(cancel <-chan struct{}, input *PollIn, out *PollOut) Status
+}
+
+// CuseOptions configures a character device served through CUSE.
+type CuseOptions struct {
+	MountOptions
+
+	// DevName is the name of the device node. The kernel (or udev)
+	// creates it as /dev/DevName.
+	DevName string
+
+	// DevMajor and DevMinor are the device number. If zero, the
+	// kernel picks a free one.
+	DevMajor uint32
+	DevMinor uint32
+}
+
 // RawFileSystem is an interface close to the FUSE wire protocol.
 //
 // Unless you really know what you are doing, you should not implement
@@ -30,6 +30,8 @@ func (a *Attr) IsSymlink() bool { return (uint32(a.Mode) & syscall.S_IFMT) == sy
 // IsSocket reports whether the FileInfo describes a socket.
 func (a *Attr) IsSocket() bool { return (uint32(a.Mode) & syscall.S_IFMT) == syscall.S_IFSOCK }
 
+// SetTimes sets the access, modification and change times to the
+// nanosecond. Nil times are left unchanged.
 func (a *Attr) SetTimes(access *time.Time, mod *time.Time, chstatus *time.Time) {
 	if access != nil {
 		a.Atime = uint64(access.Unix())
@@ -45,14 +47,17 @@ func (a *Attr) SetTimes(access *time.Time, mod *time.Time, chstatus *time.Time)
 	}
 }
 
+// ChangeTime returns the ctime.
 func (a *Attr) ChangeTime() time.Time {
 	return time.Unix(int64(a.Ctime), int64(a.Ctimensec))
 }
 
+// AccessTime returns the atime.
 func (a *Attr) AccessTime() time.Time {
 	return time.Unix(int64(a.Atime), int64(a.Atimensec))
 }
 
+// ModTime returns the mtime.
 func (a *Attr) ModTime() time.Time {
 	return time.Unix(int64(a.Mtime), int64(a.Mtimensec))
 }
@@ -65,15 +70,111 @@ func ToStatT(f os.FileInfo) *syscall.Stat_t {
 	return nil
 }
 
+// ToAttr converts an os.FileInfo to an Attr. If f comes from
+// os.Stat, all fields are filled from the underlying Stat_t.
+// Otherwise, as for FileInfos from archive readers, only type,
+// permissions, size and times are set, and the link count is 1.
 func ToAttr(f os.FileInfo) *Attr {
 	if f == nil {
 		return nil
 	}
+	if a, ok := f.Sys().(*Attr); ok {
+		c := *a
+		return &c
+	}
 	s := ToStatT(f)
 	if s != nil {
 		a := &Attr{}
 		a.FromStat(s)
 		return a
 	}
-	return nil
+
+	a := &Attr{
+		Size:  uint64(f.Size()),
+		Mode:  FromFileMode(f.Mode()),
+		Nlink: 1,
+	}
+	a.Blocks = (a.Size + 511) / 512
+	t := f.ModTime()
+	a.SetTimes(&t, &t, &t)
+	return a
+}
+
+// FromFileMode converts an os.FileMode to the mode bits used in
+// Attr.Mode.
+func FromFileMode(m os.FileMode) uint32 {
+	mode := uint32(m.Perm())
+	switch {
+	case m&os.ModeDir != 0:
+		mode |= syscall.S_IFDIR
+	case m&os.ModeSymlink != 0:
+		mode |= syscall.S_IFLNK
+	case m&os.ModeNamedPipe != 0:
+		mode |= syscall.S_IFIFO
+	case m&os.ModeSocket != 0:
+		mode |= syscall.S_IFSOCK
+	case m&os.ModeCharDevice != 0:
+		mode |= syscall.S_IFCHR
+	case m&os.ModeDevice != 0:
+		mode |= syscall.S_IFBLK
+	default:
+		mode |= syscall.S_IFREG
+	}
+	if m&os.ModeSetuid != 0 {
+		mode |= syscall.S_ISUID
+	}
+	if m&os.ModeSetgid != 0 {
+		mode |= syscall.S_ISGID
+	}
+	if m&os.ModeSticky != 0 {
+		mode |= syscall.S_ISVTX
+	}
+	return mode
+}
+
+// ToFileMode converts the mode bits of Attr.Mode to an os.FileMode.
+func ToFileMode(mode uint32) os.FileMode {
+	m := os.FileMode(mode & 0777)
+	switch mode & syscall.S_IFMT {
+	case syscall.S_IFDIR:
+		m |= os.ModeDir
+	case syscall.S_IFLNK:
+		m |= os.ModeSymlink
+	case syscall.S_IFIFO:
+		m |= os.ModeNamedPipe
+	case syscall.S_IFSOCK:
+		m |= os.ModeSocket
+	case syscall.S_IFCHR:
+		m |= os.ModeDevice | os.ModeCharDevice
+	case syscall.S_IFBLK:
+		m |= os.ModeDevice
+	}
+	if mode&syscall.S_ISUID != 0 {
+		m |= os.ModeSetuid
+	}
+	if mode&syscall.S_ISGID != 0 {
+		m |= os.ModeSetgid
+	}
+	if mode&syscall.S_ISVTX != 0 {
+		m |= os.ModeSticky
+	}
+	return m
+}
+
+type attrFileInfo struct {
+	name string
+	attr Attr
+}
+
+func (fi *attrFileInfo) Name() string       { return fi.name }
+func (fi *attrFileInfo) Size() int64        { return int64(fi.attr.Size) }
+func (fi *attrFileInfo) Mode() os.FileMode  { return ToFileMode(fi.attr.Mode) }
+func (fi *attrFileInfo) ModTime() time.Time { return fi.attr.ModTime() }
+func (fi *attrFileInfo) IsDir() bool        { return fi.attr.IsDir() }
+func (fi *attrFileInfo) Sys() interface{}   { return &fi.attr }
+
+// FileInfo returns an os.FileInfo for the attributes, for an entry
+// with the given base name. Its Sys method returns a copy of a.
+func (a *Attr) FileInfo(name string) os.FileInfo {
+	return &attrFileInfo{name: name, attr: *a}
 }
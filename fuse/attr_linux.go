@@ -6,8 +6,12 @@ package fuse
 
 import (
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
+// FromStat fills all fields of a from the result of stat(2), such as
+// syscall.Stat or syscall.Fstat.
 func (a *Attr) FromStat(s *syscall.Stat_t) {
 	a.Ino = uint64(s.Ino)
 	a.Size = uint64(s.Size)
@@ -25,3 +29,46 @@ func (a *Attr) FromStat(s *syscall.Stat_t) {
 	a.Rdev = uint32(s.Rdev)
 	a.Blksize = uint32(s.Blksize)
 }
+
+// FromAttr fills the fields of s that Attr has, and sets Mask to
+// STATX_BASIC_STATS. Add STATX_BTIME to Mask when setting Btime.
+func (s *Statx) FromAttr(a *Attr) {
+	s.Mask = unix.STATX_BASIC_STATS
+	s.Blksize = a.Blksize
+	s.Nlink = a.Nlink
+	s.Uid = a.Uid
+	s.Gid = a.Gid
+	s.Mode = uint16(a.Mode)
+	s.Ino = a.Ino
+	s.Size = a.Size
+	s.Blocks = a.Blocks
+	s.Atime = SxTime{Sec: int64(a.Atime), Nsec: a.Atimensec}
+	s.Ctime = SxTime{Sec: int64(a.Ctime), Nsec: a.Ctimensec}
+	s.Mtime = SxTime{Sec: int64(a.Mtime), Nsec: a.Mtimensec}
+	s.RdevMajor = unix.Major(uint64(a.Rdev))
+	s.RdevMinor = unix.Minor(uint64(a.Rdev))
+}
+
+// FromStatx fills the fields of s from the result of statx(2), such
+// as unix.Statx.
+func (s *Statx) FromStatx(st *unix.Statx_t) {
+	s.Mask = st.Mask
+	s.Blksize = st.Blksize
+	s.Attributes = st.Attributes
+	s.Nlink = st.Nlink
+	s.Uid = st.Uid
+	s.Gid = st.Gid
+	s.Mode = st.Mode
+	s.Ino = st.Ino
+	s.Size = st.Size
+	s.Blocks = st.Blocks
+	s.AttributesMask = st.Attributes_mask
+	s.Atime = SxTime{Sec: st.Atime.Sec, Nsec: st.Atime.Nsec}
+	s.Btime = SxTime{Sec: st.Btime.Sec, Nsec: st.Btime.Nsec}
+	s.Ctime = SxTime{Sec: st.Ctime.Sec, Nsec: st.Ctime.Nsec}
+	s.Mtime = SxTime{Sec: st.Mtime.Sec, Nsec: st.Mtime.Nsec}
+	s.RdevMajor = st.Rdev_major
+	s.RdevMinor = st.Rdev_minor
+	s.DevMajor = st.Dev_major
+	s.DevMinor = st.Dev_minor
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFileModeRoundTrip(t *testing.T) {
+	for _, m := range []os.FileMode{
+		0644,
+		os.ModeDir | 0755,
+		os.ModeSymlink | 0777,
+		os.ModeNamedPipe | 0600,
+		os.ModeSocket | 0700,
+		os.ModeDevice | os.ModeCharDevice | 0660,
+		os.ModeDevice | 0660,
+		os.ModeSetuid | os.ModeSetgid | os.ModeSticky | 0755,
+	} {
+		if got := ToFileMode(FromFileMode(m)); got != m {
+			t.Errorf("round trip of %v: got %v", m, got)
+		}
+	}
+	if got, want := FromFileMode(os.ModeDir|0755), uint32(syscall.S_IFDIR|0755); got != want {
+		t.Errorf("got %o, want %o", got, want)
+	}
+}
+
+func TestAttrFileInfo(t *testing.T) {
+	mtime := time.Unix(1234567890, 5)
+	a := &Attr{
+		Size:  42,
+		Mode:  syscall.S_IFREG | 0640,
+		Nlink: 3,
+	}
+	a.SetTimes(nil, &mtime, nil)
+
+	fi := a.FileInfo("name")
+	if fi.Name() != "name" || fi.Size() != 42 || fi.Mode() != 0640 || fi.IsDir() || !fi.ModTime().Equal(mtime) {
+		t.Errorf("got FileInfo %q %d %v %v %v", fi.Name(), fi.Size(), fi.Mode(), fi.IsDir(), fi.ModTime())
+	}
+	if back := ToAttr(fi); *back != *a {
+		t.Errorf("ToAttr: got %v, want %v", back, a)
+	}
+	if s := a.String(); !strings.Contains(s, "M 1234567890.000000005") {
+		t.Errorf("String: got %s, want mtime to the nanosecond", s)
+	}
+}
+
+func TestToAttrStat(t *testing.T) {
+	f, err := ioutil.TempFile("", "TestToAttrStat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.Write([]byte("hello"))
+
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := ToAttr(fi)
+	if a.Size != 5 || a.Nlink != 1 || !a.IsRegular() || a.Ino == 0 {
+		t.Errorf("got %v", a)
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(f.Fd()), &st); err != nil {
+		t.Fatal(err)
+	}
+	a = &Attr{}
+	a.FromStat(&st)
+	if a.Blocks != uint64(st.Blocks) || a.Blksize != uint32(st.Blksize) || a.Ino != uint64(st.Ino) ||
+		a.Rdev != uint32(st.Rdev) || !a.ModTime().Equal(fi.ModTime()) {
+		t.Errorf("FromStat: got %v for %v", a, st)
+	}
+}
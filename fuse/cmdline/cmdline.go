@@ -0,0 +1,259 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cmdline provides a main() skeleton for file system
+// binaries. It understands the same flags as file systems built on
+// libfuse:
+//
+//	myfs [-d] [-f] [-s] [-o opt,opt...] MOUNTPOINT [ARGS...]
+//
+// A typical main function is
+//
+//	func main() {
+//	  err := cmdline.Run(os.Args[1:], func(a *cmdline.Args) (fuse.RawFileSystem, error) {
+//	    root, err := fs.NewLoopbackRoot(a.Positional[0])
+//	    if err != nil {
+//	      return nil, err
+//	    }
+//	    return fs.NewNodeFS(root, &fs.Options{}), nil
+//	  })
+//	  if err != nil {
+//	    log.Fatal(err)
+//	  }
+//	}
+package cmdline
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// Args holds the parsed command line.
+type Args struct {
+	// MountPoint is the first non-flag argument.
+	MountPoint string
+
+	// Positional holds the non-flag arguments after the mount point.
+	Positional []string
+
+	// Debug is set by -d. It implies Foreground.
+	Debug bool
+
+	// Foreground is set by -f. Without it, Run continues in a
+	// background process once the file system is mounted.
+	Foreground bool
+
+	// SingleThreaded is set by -s.
+	SingleThreaded bool
+
+	// MountOptions holds the -o options.
+	MountOptions fuse.MountOptions
+}
+
+// UnmountTimeout is how long Run waits for in-flight requests when
+// it unmounts on SIGINT or SIGTERM.
+var UnmountTimeout = 10 * time.Second
+
+// daemonEnv marks the background process started by Run. Its value
+// is the file descriptor for reporting the mount result.
+const daemonEnv = "_GOFUSE_CMDLINE_READY_FD"
+
+type optionsFlag struct {
+	opts *fuse.MountOptions
+}
+
+func (f *optionsFlag) String() string { return "" }
+func (f *optionsFlag) Set(s string) error {
+	return ParseOptions(f.opts, s)
+}
+
+// Parse parses a command line, without the program name.
+func Parse(args []string) (*Args, error) {
+	a := &Args{}
+	fs := flag.NewFlagSet("fuse", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.BoolVar(&a.Debug, "d", false, "print debugging messages; implies -f")
+	fs.BoolVar(&a.Foreground, "f", false, "stay in the foreground")
+	fs.BoolVar(&a.SingleThreaded, "s", false, "handle one request at a time")
+	fs.Var(&optionsFlag{&a.MountOptions}, "o", "comma-separated mount options")
+
+	// Like libfuse, accept flags after the mount point.
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			if a.MountPoint == "" {
+				a.MountPoint = args[0]
+			} else {
+				a.Positional = append(a.Positional, args[0])
+			}
+			args = args[1:]
+		}
+	}
+	if a.MountPoint == "" {
+		return nil, fmt.Errorf("missing mount point")
+	}
+	if a.Debug {
+		a.Foreground = true
+		a.MountOptions.Debug = true
+	}
+	a.MountOptions.SingleThreaded = a.SingleThreaded
+	return a, nil
+}
+
+// ParseOptions applies a comma-separated list of mount options, as
+// given to -o, to opts. Options that have a field in
+// fuse.MountOptions set that field; others are passed to the kernel
+// as is.
+func ParseOptions(opts *fuse.MountOptions, s string) error {
+	for _, o := range strings.Split(s, ",") {
+		if o == "" {
+			continue
+		}
+		key, value := o, ""
+		if i := strings.Index(o, "="); i >= 0 {
+			key, value = o[:i], o[i+1:]
+		}
+
+		var err error
+		switch key {
+		case "ro":
+			opts.ReadOnly = true
+		case "rw":
+			opts.ReadOnly = false
+		case "allow_other":
+			opts.AllowOther = true
+		case "allow_root":
+			opts.AllowRoot = true
+		case "default_permissions":
+			opts.DefaultPermissions = true
+		case "fsname":
+			opts.FsName = value
+		case "subtype":
+			opts.Name = value
+		case "max_read":
+			opts.MaxRead, err = strconv.Atoi(value)
+		case "max_write":
+			opts.MaxWrite, err = strconv.Atoi(value)
+		case "max_readahead":
+			opts.MaxReadAhead, err = strconv.Atoi(value)
+		case "direct_mount":
+			opts.DirectMount = true
+		case "auto_unmount":
+			opts.AutoUnmount = true
+		default:
+			opts.Options = append(opts.Options, o)
+		}
+		if err != nil {
+			return fmt.Errorf("option %q: %v", o, err)
+		}
+	}
+	return nil
+}
+
+// Run parses args (the command line without the program name),
+// calls newFS to create the file system, mounts it, and serves it
+// until it is unmounted, either externally or because the process
+// receives SIGINT or SIGTERM.
+//
+// Unless -f or -d is given, Run starts a copy of the program in the
+// background, and returns once that copy has mounted the file
+// system. The background process must be started with the same
+// command line, so args should be os.Args[1:].
+func Run(args []string, newFS func(a *Args) (fuse.RawFileSystem, error)) error {
+	a, err := Parse(args)
+	if err != nil {
+		return err
+	}
+
+	readyFd := -1
+	if v := os.Getenv(daemonEnv); v != "" {
+		readyFd, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s: %v", daemonEnv, err)
+		}
+		os.Unsetenv(daemonEnv)
+	} else if !a.Foreground {
+		return daemonize()
+	}
+
+	server, err := mount(a, newFS)
+	if readyFd >= 0 {
+		ready := os.NewFile(uintptr(readyFd), "ready")
+		if err != nil {
+			fmt.Fprint(ready, err)
+		} else {
+			fmt.Fprint(ready, "ok")
+		}
+		ready.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	stop := server.UnmountOnSignal(UnmountTimeout)
+	defer stop()
+	server.Wait()
+	return nil
+}
+
+func mount(a *Args, newFS func(a *Args) (fuse.RawFileSystem, error)) (*fuse.Server, error) {
+	fs, err := newFS(a)
+	if err != nil {
+		return nil, err
+	}
+	server, err := fuse.NewServer(fs, a.MountPoint, &a.MountOptions)
+	if err != nil {
+		return nil, err
+	}
+	go server.Serve()
+	if err := server.WaitMount(); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// daemonize starts the program again in a new session, and waits
+// for it to report whether the mount succeeded.
+func daemonize() error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=3")
+	cmd.ExtraFiles = []*os.File{w} // fd would be (index + 3)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return err
+	}
+	cmd.Process.Release()
+
+	result, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	switch string(result) {
+	case "ok":
+		return nil
+	case "":
+		return fmt.Errorf("background process exited before mounting")
+	}
+	return fmt.Errorf("%s", result)
+}
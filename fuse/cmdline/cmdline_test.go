@@ -0,0 +1,43 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmdline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	a, err := Parse([]string{"-s", "/mnt", "-o", "ro,fsname=src,max_read=4096,auto_unmount,noatime", "orig", "-d"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if a.MountPoint != "/mnt" || !reflect.DeepEqual(a.Positional, []string{"orig"}) {
+		t.Errorf("got mount point %q, args %v", a.MountPoint, a.Positional)
+	}
+	if !a.Debug || !a.Foreground || !a.SingleThreaded {
+		t.Errorf("got flags %v", a)
+	}
+	o := a.MountOptions
+	if !o.ReadOnly || o.FsName != "src" || o.MaxRead != 4096 || !o.AutoUnmount || !o.Debug || !o.SingleThreaded {
+		t.Errorf("got options %+v", o)
+	}
+	if !reflect.DeepEqual(o.Options, []string{"noatime"}) {
+		t.Errorf("got kernel options %v", o.Options)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-f"},
+		{"-x", "/mnt"},
+		{"-o", "max_read=abc", "/mnt"},
+	} {
+		if _, err := Parse(args); err == nil {
+			t.Errorf("Parse(%q) succeeded", args)
+		}
+	}
+}
@@ -14,8 +14,10 @@ import (
 // package from Go, but it does implement the context.Context
 // interface.
 //
-// When a FUSE request is canceled, the API routine should respond by
-// returning the EINTR status code.
+// Cancel is closed when the kernel interrupts the request, when the
+// request exceeds MountOptions.RequestTimeout, or when the file
+// system is unmounted. When a FUSE request is canceled, the API
+// routine should respond by returning the EINTR status code.
 type Context struct {
 	Caller
 	Cancel <-chan struct{}
@@ -42,6 +44,10 @@ type callerKeyType struct{}
 
 var callerKey callerKeyType
 
+type cancelKeyType struct{}
+
+var cancelKey cancelKeyType
+
 func FromContext(ctx context.Context) (*Caller, bool) {
 	v, ok := ctx.Value(callerKey).(*Caller)
 	return v, ok
@@ -55,6 +61,9 @@ func (c *Context) Value(key interface{}) interface{} {
 	if key == callerKey {
 		return &c.Caller
 	}
+	if key == cancelKey {
+		return c.Cancel
+	}
 	return nil
 }
 
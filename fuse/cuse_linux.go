@@ -0,0 +1,69 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+const cuseDevice = "/dev/cuse"
+
+// NewCuseServer creates a character device through CUSE (character
+// devices in userspace), and returns a Server that serves requests
+// for it. Call Serve to start processing requests.
+//
+// Only the Open, Read, Write, Flush, Fsync and Release methods of fs
+// are called, along with the optional Ioctler and Poller
+// interfaces. Requests carry no node ID; open files are told apart
+// by their file handle.
+//
+// The device is removed when the Server's file descriptor is
+// closed, ie. when the process exits. Unmount has no effect.
+func NewCuseServer(fs RawFileSystem, opts *CuseOptions) (*Server, error) {
+	fd, err := syscall.Open(cuseDevice, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	ms, err := newCuseServer(fs, fd, opts)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return ms, nil
+}
+
+// newCuseServer performs the CUSE_INIT handshake on an opened
+// /dev/cuse descriptor.
+func newCuseServer(fs RawFileSystem, fd int, opts *CuseOptions) (*Server, error) {
+	if opts == nil || opts.DevName == "" {
+		return nil, fmt.Errorf("CUSE device needs a name")
+	}
+	if strings.ContainsAny(opts.DevName, "\x00/") {
+		return nil, fmt.Errorf("invalid CUSE device name %q", opts.DevName)
+	}
+	if len(opts.DevName)+len("DEVNAME=")+1 > CUSE_INIT_INFO_MAX {
+		return nil, fmt.Errorf("CUSE device name %q too long", opts.DevName)
+	}
+
+	mountOpts := opts.MountOptions
+	if mountOpts.MaxBackground == 0 {
+		mountOpts.MaxBackground = _DEFAULT_BACKGROUND_TASKS
+	}
+	ms, err := newServer(fs, &mountOpts)
+	if err != nil {
+		return nil, err
+	}
+	o := *opts
+	ms.cuse = &o
+	ms.mountFd = fd
+
+	if code := ms.handleInit(); !code.Ok() {
+		return nil, fmt.Errorf("cuse init: %s", code)
+	}
+	ms.loops.Add(1)
+	return ms, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+type echoIoctlFS struct {
+	RawFileSystem
+}
+
+func (fs *echoIoctlFS) Ioctl(cancel <-chan struct{}, input *IoctlIn, inData []byte) (int32, []byte, Status) {
+	return int32(input.Cmd), bytes.ToUpper(inData), OK
+}
+
+func structBytes(p unsafe.Pointer, sz uintptr) []byte {
+	return append([]byte{}, (*[1 << 16]byte)(p)[:sz:sz]...)
+}
+
+// TestCuseServer runs the CUSE protocol over a socketpair, playing
+// the part of the kernel.
+func TestCuseServer(t *testing.T) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_SEQPACKET, 0)
+	if err != nil {
+		t.Fatalf("Socketpair: %v", err)
+	}
+	kernel := fds[1]
+	defer syscall.Close(kernel)
+
+	initIn := CuseInitIn{
+		InHeader: InHeader{
+			Length: uint32(unsafe.Sizeof(CuseInitIn{})),
+			Opcode: _OP_CUSE_INIT,
+			Unique: 1,
+		},
+		Major: _FUSE_KERNEL_VERSION,
+		Minor: 26,
+	}
+	if _, err := syscall.Write(kernel, structBytes(unsafe.Pointer(&initIn), unsafe.Sizeof(initIn))); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	ms, err := newCuseServer(&echoIoctlFS{NewDefaultRawFileSystem()}, fds[0], &CuseOptions{
+		DevName:  "gofuse-test",
+		DevMajor: 42,
+	})
+	if err != nil {
+		t.Fatalf("newCuseServer: %v", err)
+	}
+	if major, minor := ms.ProtocolVersion(); major != 7 || minor != 26 {
+		t.Errorf("got protocol %d.%d, want 7.26", major, minor)
+	}
+
+	buf := make([]byte, 4096)
+	n, err := syscall.Read(kernel, buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	hdrSize := int(unsafe.Sizeof(OutHeader{}))
+	outSize := int(unsafe.Sizeof(CuseInitOut{}))
+	if n < hdrSize+outSize {
+		t.Fatalf("short CUSE_INIT reply: %d bytes", n)
+	}
+	hdr := (*OutHeader)(unsafe.Pointer(&buf[0]))
+	if hdr.Status != 0 || hdr.Unique != 1 || int(hdr.Length) != n {
+		t.Fatalf("got header %+v for %d bytes", *hdr, n)
+	}
+	initOut := (*CuseInitOut)(unsafe.Pointer(&buf[hdrSize]))
+	if initOut.DevMajor != 42 || initOut.Minor != 26 {
+		t.Errorf("got %+v", *initOut)
+	}
+	if info := string(buf[hdrSize+outSize : n]); info != "DEVNAME=gofuse-test\x00" {
+		t.Errorf("got info %q", info)
+	}
+
+	go ms.Serve()
+
+	arg := []byte("hello")
+	ioctlIn := IoctlIn{
+		InHeader: InHeader{
+			Length: uint32(unsafe.Sizeof(IoctlIn{})) + uint32(len(arg)),
+			Opcode: _OP_IOCTL,
+			Unique: 2,
+		},
+		Cmd:     17,
+		InSize:  uint32(len(arg)),
+		OutSize: 3,
+	}
+	msg := append(structBytes(unsafe.Pointer(&ioctlIn), unsafe.Sizeof(ioctlIn)), arg...)
+	if _, err := syscall.Write(kernel, msg); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	n, err = syscall.Read(kernel, buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	hdr = (*OutHeader)(unsafe.Pointer(&buf[0]))
+	if hdr.Status != 0 || hdr.Unique != 2 {
+		t.Fatalf("got header %+v", *hdr)
+	}
+	ioctlOut := (*IoctlOut)(unsafe.Pointer(&buf[hdrSize]))
+	if ioctlOut.Result != 17 {
+		t.Errorf("got result %d, want 17", ioctlOut.Result)
+	}
+	if got := string(buf[hdrSize+int(unsafe.Sizeof(IoctlOut{})) : n]); got != "HEL" {
+		t.Errorf("got ioctl data %q, want %q", got, "HEL")
+	}
+}
+
+func TestCuseServerBadName(t *testing.T) {
+	for _, name := range []string{"", "a/b"} {
+		if _, err := newCuseServer(NewDefaultRawFileSystem(), -1, &CuseOptions{DevName: name}); err == nil {
+			t.Errorf("newCuseServer(%q) succeeded", name)
+		}
+	}
+}
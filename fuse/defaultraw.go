@@ -9,7 +9,16 @@ import (
 )
 
 // NewDefaultRawFileSystem returns ENOSYS (not implemented) for all
-// operations.
+// operations, except Open and Flush, which succeed so files can be
+// opened and closed without implementing them. File systems that
+// speak the protocol directly should embed it, so they keep compiling
+// when methods are added to RawFileSystem:
+//
+//	type rawFS struct {
+//		fuse.RawFileSystem
+//	}
+//
+//	fs := &rawFS{RawFileSystem: fuse.NewDefaultRawFileSystem()}
 func NewDefaultRawFileSystem() RawFileSystem {
 	return (*defaultRawFileSystem)(nil)
 }
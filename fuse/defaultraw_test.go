@@ -0,0 +1,35 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefaultRawFileSystem(t *testing.T) {
+	fs := reflect.ValueOf(NewDefaultRawFileSystem())
+	statusType := reflect.TypeOf(OK)
+	for i := 0; i < fs.NumMethod(); i++ {
+		name := fs.Type().Method(i).Name
+		m := fs.Method(i)
+		args := make([]reflect.Value, m.Type().NumIn())
+		for j := range args {
+			args[j] = reflect.Zero(m.Type().In(j))
+		}
+		outs := m.Call(args)
+		if len(outs) == 0 || outs[len(outs)-1].Type() != statusType {
+			continue
+		}
+
+		want := ENOSYS
+		if name == "Open" || name == "Flush" {
+			want = OK
+		}
+		if got := outs[len(outs)-1].Interface().(Status); got != want {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Error is an error together with the Status that is sent to the
+// kernel for it. ToStatus returns that Status, also if the Error is
+// wrapped, eg. with fmt.Errorf("...: %w", err).
+type Error struct {
+	Status Status
+	Err    error
+}
+
+// NewError returns an Error that reports err to the kernel as code.
+func NewError(code Status, err error) *Error {
+	return &Error{Status: code, Err: err}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v", e.Status, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// failing maps the cancel channels of requests that are being served
+// to their request, so Fail can find where to record a cause. Requests
+// are only added while a cause can be reported, ie. with
+// MountOptions.Debug or MountOptions.OpHook.
+var failing sync.Map
+
+// startFailures makes Fail record causes for r.
+func (r *request) startFailures() {
+	r.failMu.Lock()
+	r.serving = true
+	r.failMu.Unlock()
+	failing.Store((<-chan struct{})(r.cancel), r)
+}
+
+// endFailures stops recording causes for r, and copies the recorded
+// cause, if any, to r.cause.
+func (r *request) endFailures() {
+	failing.Delete((<-chan struct{})(r.cancel))
+	r.failMu.Lock()
+	r.serving = false
+	if r.failure != nil {
+		r.cause = r.failure
+	}
+	r.failMu.Unlock()
+}
+
+// Fail returns the Status for err, and records err as the cause of
+// the failure of the request with the given cancel channel, ie. the
+// cancel argument of RawFileSystem methods or Context.Cancel. The
+// cause is printed in debug logs and passed to MountOptions.OpHook.
+// It must be called before the method serving the request returns;
+// later calls are not recorded.
+func Fail(cancel <-chan struct{}, err error) Status {
+	code := ToStatus(err)
+	if cancel == nil || code.Ok() {
+		return code
+	}
+	v, ok := failing.Load(cancel)
+	if !ok {
+		return code
+	}
+	r := v.(*request)
+	r.failMu.Lock()
+	if r.serving {
+		r.failure = err
+	}
+	r.failMu.Unlock()
+	return code
+}
+
+// FailContext is like Fail, for the request that ctx was derived
+// from.
+func FailContext(ctx context.Context, err error) Status {
+	var cancel <-chan struct{}
+	if c, ok := ctx.(*Context); ok {
+		cancel = c.Cancel
+	} else if c, ok := ctx.Value(cancelKey).(<-chan struct{}); ok {
+		cancel = c
+	}
+	return Fail(cancel, err)
+}
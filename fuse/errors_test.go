@@ -0,0 +1,58 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestErrorToStatus(t *testing.T) {
+	rpc := errors.New("rpc timeout")
+	e := NewError(EIO, rpc)
+	for _, err := range []error{
+		e,
+		fmt.Errorf("read block: %w", e),
+		&os.PathError{Op: "open", Path: "/x", Err: e},
+	} {
+		if got := ToStatus(err); got != EIO {
+			t.Errorf("ToStatus(%v): got %v, want EIO", err, got)
+		}
+	}
+	if !errors.Is(fmt.Errorf("wrapped: %w", e), rpc) {
+		t.Errorf("errors.Is does not find the cause")
+	}
+}
+
+func TestFailCause(t *testing.T) {
+	req := &request{cancel: make(chan struct{})}
+	req.startFailures()
+	defer req.endFailures()
+
+	cause := NewError(EIO, errors.New("backend down"))
+	if got := Fail(req.cancel, cause); got != EIO {
+		t.Fatalf("Fail: got %v, want EIO", got)
+	}
+	if req.failure != cause {
+		t.Errorf("got cause %v, want %v", req.failure, cause)
+	}
+
+	// Channels of requests that are not being served are ignored.
+	if got := Fail(make(chan struct{}), cause); got != EIO {
+		t.Fatalf("Fail: got %v, want EIO", got)
+	}
+
+	// The cancel channel is found through derived contexts.
+	req.failure = nil
+	ctx, stop := context.WithCancel(&Context{Cancel: req.cancel})
+	defer stop()
+	FailContext(ctx, cause)
+	if req.failure != cause {
+		t.Errorf("cause from context: got %v, want %v", req.failure, cause)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package locks implements POSIX byte-range locks for file systems
+// that answer GetLk, SetLk and SetLkw themselves, eg. because their
+// backing store has no locks of its own.
+//
+// A Manager keeps the locks of any number of files, identified by a
+// key of the file system's choosing, such as the inode number. Locks
+// are held by lock owners, as passed by the kernel. Like fcntl(2)
+// locks, the locks of an owner on a file never conflict with each
+// other: setting a lock replaces the owner's locks on the range, and
+// adjacent locks of the same type are merged.
+package locks
+
+import (
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+type lock struct {
+	owner uint64
+	typ   uint32
+	pid   uint32
+
+	// start and end are inclusive, like in fuse.FileLock.
+	start, end uint64
+}
+
+func (l *lock) overlaps(start, end uint64) bool {
+	return l.start <= end && start <= l.end
+}
+
+// touches returns whether l overlaps or adjoins [start, end].
+func (l *lock) touches(start, end uint64) bool {
+	return l.overlaps(start, end) ||
+		(l.end != ^uint64(0) && l.end+1 == start) ||
+		(end != ^uint64(0) && end+1 == l.start)
+}
+
+// waiter is a SetLkw call that is blocked.
+type waiter struct {
+	file uint64
+	lk   fuse.FileLock
+}
+
+// Manager keeps byte-range locks. The zero value is not usable; use
+// NewManager.
+type Manager struct {
+	mu    sync.Mutex
+	files map[uint64][]lock
+
+	// waiting holds what blocked owners wait for, for deadlock
+	// detection.
+	waiting map[uint64]waiter
+
+	// changed is closed and replaced whenever the locks change,
+	// to wake up waiters.
+	changed chan struct{}
+}
+
+// NewManager returns a Manager without locks.
+func NewManager() *Manager {
+	return &Manager{
+		files:   map[uint64][]lock{},
+		waiting: map[uint64]waiter{},
+		changed: make(chan struct{}),
+	}
+}
+
+// conflicts returns the locks held by other owners that keep owner
+// from taking lk.
+func (m *Manager) conflicts(file uint64, owner uint64, lk *fuse.FileLock) []lock {
+	if lk.Typ == syscall.F_UNLCK {
+		return nil
+	}
+	var out []lock
+	for _, l := range m.files[file] {
+		if l.owner == owner || !l.overlaps(lk.Start, lk.End) {
+			continue
+		}
+		if l.typ == syscall.F_WRLCK || lk.Typ == syscall.F_WRLCK {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// set applies lk for owner, which must not conflict with other
+// owners.
+func (m *Manager) set(file uint64, owner uint64, lk *fuse.FileLock) {
+	nl := lock{owner: owner, typ: lk.Typ, pid: lk.Pid, start: lk.Start, end: lk.End}
+	var out []lock
+	for _, l := range m.files[file] {
+		if l.owner != owner || !l.touches(lk.Start, lk.End) {
+			out = append(out, l)
+			continue
+		}
+		if l.typ == nl.typ {
+			if l.start < nl.start {
+				nl.start = l.start
+			}
+			if l.end > nl.end {
+				nl.end = l.end
+			}
+			continue
+		}
+		if !l.overlaps(lk.Start, lk.End) {
+			out = append(out, l)
+			continue
+		}
+		// Keep the parts outside the new range.
+		if l.start < lk.Start {
+			head := l
+			head.end = lk.Start - 1
+			out = append(out, head)
+		}
+		if l.end > lk.End {
+			tail := l
+			tail.start = lk.End + 1
+			out = append(out, tail)
+		}
+	}
+	if nl.typ != syscall.F_UNLCK {
+		out = append(out, nl)
+	}
+
+	if len(out) == 0 {
+		delete(m.files, file)
+	} else {
+		m.files[file] = out
+	}
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// deadlock returns whether owner waiting for the blockers would
+// wait for itself.
+func (m *Manager) deadlock(owner uint64, blockers []lock) bool {
+	seen := map[uint64]bool{}
+	var visit func(bs []lock) bool
+	visit = func(bs []lock) bool {
+		for _, b := range bs {
+			if b.owner == owner {
+				return true
+			}
+			if seen[b.owner] {
+				continue
+			}
+			seen[b.owner] = true
+			if w, ok := m.waiting[b.owner]; ok && visit(m.conflicts(w.file, b.owner, &w.lk)) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(blockers)
+}
+
+// GetLk fills out with a lock that conflicts with lk, or sets
+// out.Typ to F_UNLCK if owner could take lk.
+func (m *Manager) GetLk(file uint64, owner uint64, lk *fuse.FileLock, out *fuse.FileLock) fuse.Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c := m.conflicts(file, owner, lk); len(c) > 0 {
+		*out = fuse.FileLock{Start: c[0].start, End: c[0].end, Typ: c[0].typ, Pid: c[0].pid}
+	} else {
+		*out = *lk
+		out.Typ = syscall.F_UNLCK
+	}
+	return fuse.OK
+}
+
+// SetLk takes or releases lk for owner. It returns EAGAIN if the lock
+// is held by another owner.
+func (m *Manager) SetLk(file uint64, owner uint64, lk *fuse.FileLock) fuse.Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.conflicts(file, owner, lk)) > 0 {
+		return fuse.EAGAIN
+	}
+	m.set(file, owner, lk)
+	return fuse.OK
+}
+
+// SetLkw is like SetLk, but waits for conflicting locks to be
+// released. It returns EDEADLK if waiting would deadlock, and EINTR
+// if cancel is closed first. An owner can wait for one lock at a
+// time.
+func (m *Manager) SetLkw(cancel <-chan struct{}, file uint64, owner uint64, lk *fuse.FileLock) fuse.Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for {
+		blockers := m.conflicts(file, owner, lk)
+		if len(blockers) == 0 {
+			delete(m.waiting, owner)
+			m.set(file, owner, lk)
+			return fuse.OK
+		}
+		if m.deadlock(owner, blockers) {
+			delete(m.waiting, owner)
+			return fuse.Status(syscall.EDEADLK)
+		}
+		m.waiting[owner] = waiter{file, *lk}
+		changed := m.changed
+
+		m.mu.Unlock()
+		select {
+		case <-changed:
+		case <-cancel:
+			m.mu.Lock()
+			delete(m.waiting, owner)
+			return fuse.EINTR
+		}
+		m.mu.Lock()
+	}
+}
+
+// Release drops all locks of owner on file, eg. when the file is
+// released.
+func (m *Manager) Release(file uint64, owner uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(file, owner, &fuse.FileLock{Start: 0, End: ^uint64(0), Typ: syscall.F_UNLCK})
+}
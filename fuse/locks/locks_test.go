@@ -0,0 +1,147 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package locks
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+func lk(typ uint32, start, end uint64) *fuse.FileLock {
+	return &fuse.FileLock{Start: start, End: end, Typ: typ}
+}
+
+func TestSetLkConflicts(t *testing.T) {
+	m := NewManager()
+	if code := m.SetLk(1, 10, lk(syscall.F_RDLCK, 0, 99)); !code.Ok() {
+		t.Fatalf("SetLk: %v", code)
+	}
+	// Read locks are shared.
+	if code := m.SetLk(1, 20, lk(syscall.F_RDLCK, 50, 149)); !code.Ok() {
+		t.Fatalf("shared SetLk: %v", code)
+	}
+	if code := m.SetLk(1, 20, lk(syscall.F_WRLCK, 90, 99)); code != fuse.EAGAIN {
+		t.Fatalf("conflicting SetLk: got %v, want EAGAIN", code)
+	}
+	// Other files are independent.
+	if code := m.SetLk(2, 20, lk(syscall.F_WRLCK, 0, 99)); !code.Ok() {
+		t.Fatalf("SetLk on other file: %v", code)
+	}
+
+	var out fuse.FileLock
+	m.GetLk(1, 20, lk(syscall.F_WRLCK, 90, 99), &out)
+	if out.Typ != syscall.F_RDLCK || out.Start != 0 || out.End != 99 {
+		t.Errorf("GetLk: got %+v", out)
+	}
+	m.GetLk(1, 20, lk(syscall.F_WRLCK, 100, 149), &out)
+	if out.Typ != syscall.F_UNLCK {
+		t.Errorf("GetLk on own lock: got %+v", out)
+	}
+}
+
+func TestSetLkSplitMerge(t *testing.T) {
+	m := NewManager()
+	m.SetLk(1, 10, lk(syscall.F_WRLCK, 0, 99))
+	// Unlocking the middle splits the lock.
+	m.SetLk(1, 10, lk(syscall.F_UNLCK, 40, 59))
+	want := []lock{
+		{owner: 10, typ: syscall.F_WRLCK, start: 0, end: 39},
+		{owner: 10, typ: syscall.F_WRLCK, start: 60, end: 99},
+	}
+	if got := m.files[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("after unlock: got %v, want %v", got, want)
+	}
+
+	// Filling the hole merges them again.
+	m.SetLk(1, 10, lk(syscall.F_WRLCK, 40, 59))
+	want = []lock{{owner: 10, typ: syscall.F_WRLCK, start: 0, end: 99}}
+	if got := m.files[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("after relock: got %v, want %v", got, want)
+	}
+
+	// Downgrading part of the lock.
+	m.SetLk(1, 10, lk(syscall.F_RDLCK, 90, 199))
+	want = []lock{
+		{owner: 10, typ: syscall.F_WRLCK, start: 0, end: 89},
+		{owner: 10, typ: syscall.F_RDLCK, start: 90, end: 199},
+	}
+	if got := m.files[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("after downgrade: got %v, want %v", got, want)
+	}
+
+	m.Release(1, 10)
+	if len(m.files) != 0 {
+		t.Errorf("after Release: got %v", m.files)
+	}
+}
+
+func TestSetLkwWaits(t *testing.T) {
+	m := NewManager()
+	m.SetLk(1, 10, lk(syscall.F_WRLCK, 0, 99))
+
+	done := make(chan fuse.Status, 1)
+	go func() {
+		done <- m.SetLkw(nil, 1, 20, lk(syscall.F_WRLCK, 50, 59))
+	}()
+	select {
+	case code := <-done:
+		t.Fatalf("SetLkw returned %v while the lock was held", code)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	m.SetLk(1, 10, lk(syscall.F_UNLCK, 0, 99))
+	select {
+	case code := <-done:
+		if !code.Ok() {
+			t.Fatalf("SetLkw: %v", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SetLkw still blocked after unlock")
+	}
+
+	cancel := make(chan struct{})
+	go func() {
+		done <- m.SetLkw(cancel, 1, 30, lk(syscall.F_RDLCK, 55, 55))
+	}()
+	close(cancel)
+	if code := <-done; code != fuse.EINTR {
+		t.Errorf("canceled SetLkw: got %v, want EINTR", code)
+	}
+}
+
+func TestSetLkwDeadlock(t *testing.T) {
+	m := NewManager()
+	m.SetLk(1, 10, lk(syscall.F_WRLCK, 0, 9))
+	m.SetLk(1, 20, lk(syscall.F_WRLCK, 10, 19))
+
+	// 10 waits for 20.
+	done := make(chan fuse.Status, 1)
+	go func() {
+		done <- m.SetLkw(nil, 1, 10, lk(syscall.F_WRLCK, 10, 19))
+	}()
+	for {
+		m.mu.Lock()
+		_, ok := m.waiting[10]
+		m.mu.Unlock()
+		if ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 20 waiting for 10 would deadlock.
+	if code := m.SetLkw(nil, 1, 20, lk(syscall.F_WRLCK, 0, 9)); code != fuse.Status(syscall.EDEADLK) {
+		t.Fatalf("got %v, want EDEADLK", code)
+	}
+
+	m.Release(1, 20)
+	if code := <-done; !code.Ok() {
+		t.Errorf("SetLkw after release: %v", code)
+	}
+}
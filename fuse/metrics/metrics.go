@@ -0,0 +1,169 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package metrics exports the per-operation statistics of FUSE
+// servers (see fuse.MountOptions.EnableStats) through expvar, and in
+// the Prometheus text exposition format.
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"golang.org/x/sys/unix"
+)
+
+// Exporter publishes the statistics of a set of FUSE servers.
+type Exporter struct {
+	mu      sync.Mutex
+	servers map[string]*fuse.Server
+}
+
+// NewExporter returns an Exporter without servers.
+func NewExporter() *Exporter {
+	return &Exporter{
+		servers: make(map[string]*fuse.Server),
+	}
+}
+
+// Add registers a server under the given name, typically its mount
+// point. The name is used as the "mount" label.
+func (e *Exporter) Add(name string, server *fuse.Server) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.servers[name] = server
+}
+
+// Remove unregisters the server with the given name.
+func (e *Exporter) Remove(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.servers, name)
+}
+
+// Snapshot returns the current statistics, keyed by server name
+// and operation name.
+func (e *Exporter) Snapshot() map[string]map[string]fuse.OpStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r := make(map[string]map[string]fuse.OpStats, len(e.servers))
+	for name, s := range e.servers {
+		r[name] = s.Stats()
+	}
+	return r
+}
+
+// Publish exports the statistics as an expvar variable with the
+// given name. Like expvar.Publish, it panics if the name is already
+// in use.
+func (e *Exporter) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return e.Snapshot()
+	}))
+}
+
+// ServeHTTP serves the statistics in the Prometheus text exposition
+// format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	e.WritePrometheus(w)
+}
+
+// errnoName returns the symbolic name of code, eg. "ENOENT".
+func errnoName(code fuse.Status) string {
+	if name := unix.ErrnoName(syscall.Errno(code)); name != "" {
+		return name
+	}
+	return strconv.Itoa(int(code))
+}
+
+// labelEscaper escapes label values as the Prometheus text format
+// requires. Other characters, including non-ASCII ones, are written
+// as is.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// label formats a label value, including the quotes.
+func label(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+type sample struct {
+	mount, op string
+	st        fuse.OpStats
+}
+
+// WritePrometheus writes the statistics in the Prometheus text
+// exposition format.
+func (e *Exporter) WritePrometheus(w io.Writer) error {
+	var samples []sample
+	for mount, ops := range e.Snapshot() {
+		for op, st := range ops {
+			samples = append(samples, sample{mount, op, st})
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].mount != samples[j].mount {
+			return samples[i].mount < samples[j].mount
+		}
+		return samples[i].op < samples[j].op
+	})
+
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("# HELP fuse_requests_total Number of FUSE requests handled.\n")
+	printf("# TYPE fuse_requests_total counter\n")
+	for _, s := range samples {
+		printf("fuse_requests_total{mount=%s,op=%s} %d\n", label(s.mount), label(s.op), s.st.Count)
+	}
+
+	printf("# HELP fuse_request_errors_total Number of FUSE requests that returned an error.\n")
+	printf("# TYPE fuse_request_errors_total counter\n")
+	for _, s := range samples {
+		printf("fuse_request_errors_total{mount=%s,op=%s} %d\n", label(s.mount), label(s.op), s.st.Errors)
+	}
+
+	printf("# HELP fuse_request_errno_total Number of FUSE requests that returned an error, by errno.\n")
+	printf("# TYPE fuse_request_errno_total counter\n")
+	for _, s := range samples {
+		var codes []fuse.Status
+		for code := range s.st.ErrorsByStatus {
+			codes = append(codes, code)
+		}
+		sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+		for _, code := range codes {
+			printf("fuse_request_errno_total{mount=%s,op=%s,errno=%s} %d\n", label(s.mount), label(s.op), label(errnoName(code)), s.st.ErrorsByStatus[code])
+		}
+	}
+
+	printf("# HELP fuse_request_duration_seconds Time spent handling FUSE requests.\n")
+	printf("# TYPE fuse_request_duration_seconds summary\n")
+	for _, s := range samples {
+		for _, q := range []struct {
+			q string
+			v float64
+		}{
+			{"0.5", s.st.P50.Seconds()},
+			{"0.9", s.st.P90.Seconds()},
+			{"0.99", s.st.P99.Seconds()},
+		} {
+			printf("fuse_request_duration_seconds{mount=%s,op=%s,quantile=%s} %g\n", label(s.mount), label(s.op), label(q.q), q.v)
+		}
+		printf("fuse_request_duration_seconds_sum{mount=%s,op=%s} %g\n", label(s.mount), label(s.op), s.st.Total.Seconds())
+		printf("fuse_request_duration_seconds_count{mount=%s,op=%s} %d\n", label(s.mount), label(s.op), s.st.Count)
+	}
+	return err
+}
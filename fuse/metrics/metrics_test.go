@@ -0,0 +1,82 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/fuse/pathfs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestExporter(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	mnt := testutil.TempDir()
+	defer os.RemoveAll(mnt)
+
+	fs := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(dir), nil)
+	s, _, err := nodefs.Mount(mnt, fs.Root(), &fuse.MountOptions{
+		EnableStats: true,
+		Debug:       testutil.VerboseTest(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	os.Lstat(mnt + "/nonexistent")
+
+	e := NewExporter()
+	e.Add("test", s)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE fuse_requests_total counter\n",
+		`fuse_request_errors_total{mount="test",op="LOOKUP"} `,
+		"# TYPE fuse_request_errno_total counter\n",
+		`,errno="E`,
+		`fuse_request_duration_seconds{mount="test",op="LOOKUP",quantile="0.99"} `,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	e.Publish("TestExporter")
+	var vars map[string]map[string]fuse.OpStats
+	if err := json.Unmarshal([]byte(expvar.Get("TestExporter").String()), &vars); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if vars["test"]["LOOKUP"].Count == 0 {
+		t.Errorf("expvar: got %v, want LOOKUP requests", vars)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	for in, want := range map[string]string{
+		"test":         `"test"`,
+		`a"b\c`:        `"a\"b\\c"`,
+		"line\nbreak":  `"line\nbreak"`,
+		"tab\tand ünï": "\"tab\tand ünï\"",
+	} {
+		if got := label(in); got != want {
+			t.Errorf("label(%q): got %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+// Op is a request on its way to the RawFileSystem, as seen by
+// Middleware.
+type Op struct {
+	// Name is the operation name, eg. "LOOKUP".
+	Name string
+
+	// Header holds the node the request applies to and the
+	// calling process.
+	Header *InHeader
+
+	// In is the decoded input struct, eg. *OpenIn for OPEN, or
+	// nil if the operation has none. Middleware may modify it.
+	In interface{}
+
+	// Names holds the file name arguments, eg. the name looked up
+	// by LOOKUP, or the old and new names of RENAME. Middleware
+	// may replace them, but not change their number.
+	Names []string
+
+	// Out is the decoded output struct, eg. *EntryOut for
+	// LOOKUP, or nil if the operation has none. It is filled in
+	// by the RawFileSystem, so Middleware may inspect or modify
+	// it after calling the next Handler.
+	Out interface{}
+
+	// Cancel is closed if the kernel interrupts the request.
+	Cancel <-chan struct{}
+
+	req *request
+}
+
+// Handler serves a request, and returns its status.
+type Handler func(op *Op) Status
+
+// Middleware wraps the Handler that passes requests to the
+// RawFileSystem, eg. to check permissions, collect metrics or
+// rewrite requests. A Middleware can refuse a request by returning an
+// error status without calling next.
+type Middleware func(next Handler) Handler
+
+// callFileSystem is the innermost Handler.
+func (ms *Server) callFileSystem(op *Op) Status {
+	req := op.req
+	if len(op.Names) != len(req.filenames) {
+		return EINVAL
+	}
+	req.filenames = op.Names
+	req.handler.Func(ms, req)
+	return req.status
+}
+
+// bypassMiddleware returns whether an opcode is handled by the
+// Server itself rather than the RawFileSystem.
+func bypassMiddleware(opcode uint32) bool {
+	switch opcode {
+	case _OP_INIT, _OP_CUSE_INIT, _OP_INTERRUPT, _OP_NOTIFY_REPLY:
+		return true
+	}
+	return false
+}
+
+// dispatch passes a request through the middleware chain.
+func (ms *Server) dispatch(req *request) {
+	op := &Op{
+		Name:   req.handler.Name,
+		Header: req.inHeader,
+		Names:  req.filenames,
+		Cancel: req.cancel,
+		req:    req,
+	}
+	if req.inData != nil && req.handler.DecodeIn != nil {
+		op.In = req.handler.DecodeIn(req.inData)
+	}
+	if req.handler.OutputSize > 0 && req.handler.DecodeOut != nil {
+		op.Out = req.handler.DecodeOut(req.outData())
+	}
+	req.status = ms.middleware(op)
+}
+
+// chainMiddleware returns a Handler that runs the middleware, the
+// first one outermost, around the file system.
+func (ms *Server) chainMiddleware(mw []Middleware) Handler {
+	h := Handler(ms.callFileSystem)
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
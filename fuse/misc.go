@@ -7,6 +7,7 @@
 package fuse
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -35,8 +36,9 @@ func (code Status) Ok() bool {
 	return code == OK
 }
 
-// ToStatus extracts an errno number from Go error objects.  If it
-// fails, it logs an error and returns ENOSYS.
+// ToStatus extracts an errno number from Go error objects, looking
+// through wrapped errors. If it fails, it logs an error and returns
+// ENOSYS.
 func ToStatus(err error) Status {
 	switch err {
 	case nil:
@@ -60,6 +62,11 @@ func ToStatus(err error) Status {
 		return ToStatus(t.Err)
 	case *os.LinkError:
 		return ToStatus(t.Err)
+	case *Error:
+		return t.Status
+	}
+	if u := errors.Unwrap(err); u != nil {
+		return ToStatus(u)
 	}
 	log.Println("can't convert error type:", err)
 	return ENOSYS
@@ -81,20 +88,44 @@ func CurrentOwner() *Owner {
 	}
 }
 
-const _UTIME_OMIT = ((1 << 30) - 2)
+const (
+	_UTIME_NOW  = ((1 << 30) - 1)
+	_UTIME_OMIT = ((1 << 30) - 2)
+)
+
+// utimeNowLocation marks times returned by UtimeNow.
+var utimeNowLocation = time.FixedZone("UTIME_NOW", 0)
+
+// UtimeNow returns the current time, marked so that IsUtimeNow
+// recognizes it. It stands for "set to the current time" in
+// Utimens, which the kernel signals with FATTR_ATIME_NOW and
+// FATTR_MTIME_NOW. File systems that ignore the mark still see the
+// current time.
+func UtimeNow() time.Time {
+	return time.Now().In(utimeNowLocation)
+}
+
+// IsUtimeNow returns whether t was returned by UtimeNow.
+func IsUtimeNow(t time.Time) bool {
+	return t.Location() == utimeNowLocation
+}
 
 // UtimeToTimespec converts a "Time" pointer as passed to Utimens to a
 // "Timespec" that can be passed to the utimensat syscall.
-// A nil pointer is converted to the special UTIME_OMIT value.
+// A nil pointer is converted to the special UTIME_OMIT value, and a
+// time from UtimeNow to UTIME_NOW. The latter lets the kernel
+// decide the time, and only requires write access to the file
+// rather than ownership.
 func UtimeToTimespec(t *time.Time) (ts syscall.Timespec) {
 	if t == nil {
 		ts.Nsec = _UTIME_OMIT
+	} else if IsUtimeNow(*t) {
+		ts.Nsec = _UTIME_NOW
 	} else {
-		ts = syscall.NsecToTimespec(t.UnixNano())
-		// Go bug https://github.com/golang/go/issues/12777
-		if ts.Nsec < 0 {
-			ts.Nsec = 0
-		}
+		// Not UnixNano, which overflows outside the years
+		// 1678-2262.
+		ts.Sec = t.Unix()
+		ts.Nsec = int64(t.Nanosecond())
 	}
 	return ts
 }
@@ -8,6 +8,7 @@ import (
 	"os"
 	"syscall"
 	"testing"
+	"time"
 )
 
 func TestToStatus(t *testing.T) {
@@ -28,3 +29,43 @@ func TestToStatus(t *testing.T) {
 		t.Errorf("Wrong conversion %v != %v", errNo, syscall.ENOENT)
 	}
 }
+
+func TestUtimeToTimespec(t *testing.T) {
+	if ts := UtimeToTimespec(nil); ts.Nsec != _UTIME_OMIT {
+		t.Errorf("nil: got %v, want UTIME_OMIT", ts)
+	}
+	now := UtimeNow()
+	if ts := UtimeToTimespec(&now); ts.Nsec != _UTIME_NOW {
+		t.Errorf("UtimeNow: got %v, want UTIME_NOW", ts)
+	}
+	explicit := time.Unix(42, 7)
+	if ts := UtimeToTimespec(&explicit); ts.Sec != 42 || ts.Nsec != 7 {
+		t.Errorf("explicit: got %v, want 42.000000007", ts)
+	}
+	for _, want := range []time.Time{
+		time.Unix(-1, 5),
+		time.Date(3000, 1, 1, 0, 0, 0, 123, time.UTC),
+	} {
+		ts := UtimeToTimespec(&want)
+		if got := time.Unix(ts.Sec, ts.Nsec); !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	in := SetAttrIn{SetAttrInCommon: SetAttrInCommon{
+		Valid: FATTR_ATIME | FATTR_ATIME_NOW | FATTR_MTIME,
+		Mtime: 43,
+	}}
+	if a, ok := in.GetATime(); !ok || !IsUtimeNow(a) {
+		t.Errorf("GetATime: got %v, %v, want UtimeNow", a, ok)
+	}
+	if m, ok := in.GetMTime(); !ok || IsUtimeNow(m) || m.Unix() != 43 {
+		t.Errorf("GetMTime: got %v, %v, want 43", m, ok)
+	}
+
+	// touch -m leaves the atime alone.
+	in.Valid = FATTR_MTIME
+	if a, m := in.GetTimes(); a != nil || m == nil || m.Unix() != 43 {
+		t.Errorf("GetTimes: got %v, %v, want nil, 43", a, m)
+	}
+}
@@ -25,9 +25,14 @@ func unixgramSocketpair() (l, r *os.File, err error) {
 	return
 }
 
+// autoUnmounter is not used on Darwin, which has no auto_unmount.
+type autoUnmounter struct{}
+
+func (au *autoUnmounter) release() {}
+
 // Create a FUSE FS on the specified mount point.  The returned
 // mount point is always absolute.
-func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, err error) {
+func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, au *autoUnmounter, err error) {
 	local, remote, err := unixgramSocketpair()
 	if err != nil {
 		return
@@ -38,7 +43,7 @@ func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, e
 
 	bin, err := fusermountBinary()
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
 	cmd := exec.Command(bin,
@@ -64,7 +69,7 @@ func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, e
 
 	fd, err = getConnection(local)
 	if err != nil {
-		return -1, err
+		return -1, nil, err
 	}
 
 	go func() {
@@ -83,7 +88,7 @@ func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, e
 	// Buf for fd, we have to set CLOEXEC manually
 	syscall.CloseOnExec(fd)
 
-	return fd, err
+	return fd, nil, err
 }
 
 func unmount(dir string, opts *MountOptions) error {
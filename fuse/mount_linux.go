@@ -12,6 +12,7 @@ import (
 	"os/exec"
 	"path"
 	"strings"
+	"sync"
 	"syscall"
 	"unsafe"
 )
@@ -28,9 +29,9 @@ func unixgramSocketpair() (l, r *os.File, err error) {
 }
 
 // Create a FUSE FS on the specified mount point without using
-// fusermount.
+// fusermount. This requires CAP_SYS_ADMIN.
 func mountDirect(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, err error) {
-	fd, err = syscall.Open("/dev/fuse", os.O_RDWR, 0) // use syscall.Open since we want an int fd
+	fd, err = syscall.Open("/dev/fuse", os.O_RDWR|syscall.O_CLOEXEC, 0) // use syscall.Open since we want an int fd
 	if err != nil {
 		return
 	}
@@ -41,23 +42,24 @@ func mountDirect(mountPoint string, opts *MountOptions, ready chan<- error) (fd
 		source = opts.Name
 	}
 
-	var flags uintptr
-	flags |= syscall.MS_NOSUID | syscall.MS_NODEV
+	flags := opts.DirectMountFlags
+	if flags == 0 {
+		flags = syscall.MS_NOSUID | syscall.MS_NODEV
+	}
+	if opts.ReadOnly {
+		flags |= syscall.MS_RDONLY
+	}
 
 	// some values we need to pass to mount, but override possible since opts.Options comes after
 	var r = []string{
 		fmt.Sprintf("fd=%d", fd),
 		"rootmode=40000",
-		"user_id=0",
-		"group_id=0",
-	}
-	r = append(r, opts.Options...)
-
-	if opts.AllowOther {
-		r = append(r, "allow_other")
+		fmt.Sprintf("user_id=%d", os.Geteuid()),
+		fmt.Sprintf("group_id=%d", os.Getegid()),
 	}
+	r = append(r, opts.kernelOptionsStrings()...)
 
-	err = syscall.Mount(opts.FsName, mountPoint, "fuse."+opts.Name, opts.DirectMountFlags, strings.Join(r, ","))
+	err = syscall.Mount(source, mountPoint, "fuse."+opts.Name, flags, strings.Join(r, ","))
 	if err != nil {
 		syscall.Close(fd)
 		return
@@ -73,8 +75,9 @@ func mountDirect(mountPoint string, opts *MountOptions, ready chan<- error) (fd
 // * opens `/dev/fuse`
 // * mount()s this file descriptor to `mountPoint`
 // * passes this file descriptor back to us via a unix domain socket
-// This file descriptor is returned as `fd`.
-func callFusermount(mountPoint string, opts *MountOptions) (fd int, err error) {
+// This file descriptor is returned as `fd`. With AutoUnmount, the
+// fusermount process that stays around is returned as `au`.
+func callFusermount(mountPoint string, opts *MountOptions) (fd int, au *autoUnmounter, err error) {
 	local, remote, err := unixgramSocketpair()
 	if err != nil {
 		return
@@ -85,11 +88,15 @@ func callFusermount(mountPoint string, opts *MountOptions) (fd int, err error) {
 
 	bin, err := fusermountBinary()
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
 	cmd := []string{bin, mountPoint}
-	if s := opts.optionsStrings(); len(s) > 0 {
+	s := opts.optionsStrings()
+	if opts.AutoUnmount {
+		s = append(s, "auto_unmount")
+	}
+	if len(s) > 0 {
 		cmd = append(cmd, "-o", strings.Join(s, ","))
 	}
 	if opts.Debug {
@@ -105,6 +112,10 @@ func callFusermount(mountPoint string, opts *MountOptions) (fd int, err error) {
 		return
 	}
 
+	if opts.AutoUnmount {
+		return autoUnmountConnection(proc, local, remote)
+	}
+
 	w, err := proc.Wait()
 	if err != nil {
 		return
@@ -116,19 +127,78 @@ func callFusermount(mountPoint string, opts *MountOptions) (fd int, err error) {
 
 	fd, err = getConnection(local)
 	if err != nil {
-		return -1, err
+		return -1, nil, err
 	}
 
 	return
 }
 
+// autoUnmounter is a fusermount process started with auto_unmount.
+// Such a fusermount does not exit after mounting: it stays around
+// until its end of the socket is closed, and then unmounts. We keep
+// our end open until the server is done, so the mount goes away when
+// we die.
+type autoUnmounter struct {
+	proc *os.Process
+	keep int
+
+	once sync.Once
+}
+
+// release closes our end of the socket, and reaps the fusermount
+// process, which unmounts if that has not happened yet.
+func (au *autoUnmounter) release() {
+	au.once.Do(func() {
+		syscall.Close(au.keep)
+		au.proc.Wait()
+	})
+}
+
+// autoUnmountConnection receives the FUSE file descriptor from a
+// fusermount started with auto_unmount.
+func autoUnmountConnection(proc *os.Process, local, remote *os.File) (int, *autoUnmounter, error) {
+	// Close our copy of the remote end, so we see EOF if fusermount
+	// exits without sending us a file descriptor.
+	remote.Close()
+
+	fd, err := getConnection(local)
+	if err != nil {
+		if w, werr := proc.Wait(); werr == nil && !w.Success() {
+			err = fmt.Errorf("fusermount exited with code %v\n", w.Sys())
+		}
+		return -1, nil, err
+	}
+
+	keep, err := syscall.Dup(int(local.Fd()))
+	if err != nil {
+		syscall.Close(fd)
+		proc.Kill()
+		proc.Wait()
+		return -1, nil, err
+	}
+	syscall.CloseOnExec(keep)
+	return fd, &autoUnmounter{proc: proc, keep: keep}, nil
+}
+
 // Create a FUSE FS on the specified mount point.  The returned
 // mount point is always absolute.
-func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, err error) {
-	if opts.DirectMount {
+//
+// If DirectMount is set, or if the fusermount binary is not
+// installed, mount(2) is tried first. AutoUnmount always needs
+// fusermount; the fusermount process that then stays around is
+// returned as au.
+func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, au *autoUnmounter, err error) {
+	_, noFusermount := fusermountBinary()
+	if opts.AutoUnmount && parseFuseFd(mountPoint) < 0 {
+		if noFusermount != nil {
+			return -1, nil, fmt.Errorf("auto_unmount needs fusermount: %v", noFusermount)
+		}
+	} else if opts.DirectMount || noFusermount != nil && parseFuseFd(mountPoint) < 0 {
 		fd, err := mountDirect(mountPoint, opts, ready)
 		if err == nil {
-			return fd, nil
+			return fd, nil, nil
+		} else if noFusermount != nil {
+			return -1, nil, fmt.Errorf("direct mount failed: %v; fallback: %v", err, noFusermount)
 		} else if opts.Debug {
 			log.Printf("mount: failed to do direct mount: %s", err)
 		}
@@ -143,7 +213,7 @@ func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, e
 		}
 	} else {
 		// Usual case: mount via the `fusermount` suid helper
-		fd, err = callFusermount(mountPoint, opts)
+		fd, au, err = callFusermount(mountPoint, opts)
 		if err != nil {
 			return
 		}
@@ -153,22 +223,22 @@ func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, e
 	// Buf for fd, we have to set CLOEXEC manually
 	syscall.CloseOnExec(fd)
 	close(ready)
-	return fd, err
+	return fd, au, err
 }
 
 func unmount(mountPoint string, opts *MountOptions) (err error) {
-	if opts.DirectMount {
+	bin, binErr := fusermountBinary()
+	if opts.DirectMount || binErr != nil {
 		// Attempt to directly unmount, if fails fallback to fusermount method
 		err := syscall.Unmount(mountPoint, 0)
 		if err == nil {
 			return nil
 		}
+		if binErr != nil {
+			return err
+		}
 	}
 
-	bin, err := fusermountBinary()
-	if err != nil {
-		return err
-	}
 	errBuf := bytes.Buffer{}
 	cmd := exec.Command(bin, "-u", mountPoint)
 	cmd.Stderr = &errBuf
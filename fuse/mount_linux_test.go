@@ -22,7 +22,7 @@ func TestMountDevFd(t *testing.T) {
 	// Call the fusermount suid helper to obtain the file descriptor in place
 	// of a privileged parent.
 	var fuOpts MountOptions
-	fd, err := callFusermount(realMountPoint, &fuOpts)
+	fd, _, err := callFusermount(realMountPoint, &fuOpts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -98,3 +98,19 @@ func TestMountMaxWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestAutoUnmountNeedsFusermount(t *testing.T) {
+	if _, err := fusermountBinary(); err == nil {
+		t.Skip("fusermount is installed")
+	}
+	dir, err := ioutil.TempDir("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Rmdir(dir)
+
+	_, err = NewServer(NewDefaultRawFileSystem(), dir, &MountOptions{AutoUnmount: true})
+	if err == nil {
+		t.Fatal("NewServer succeeded without fusermount")
+	}
+}
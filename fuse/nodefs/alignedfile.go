@@ -0,0 +1,150 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// NewAlignedFile wraps a File whose reads and writes must be aligned
+// to blockSize, such as a file on a network block store, or on the
+// host opened with O_DIRECT. The kernel does not align requests on
+// FOPEN_DIRECT_IO handles, and it only aligns reads to pages
+// otherwise.
+//
+// If rejectUnaligned is set, reads and writes whose offset or size
+// is not a multiple of blockSize fail with EINVAL, as with O_DIRECT
+// on a block device. Otherwise, reads are widened to whole blocks,
+// and unaligned writes read, modify and write back the blocks they
+// cover. Writes are then serialized, and the file size is assumed
+// to be a multiple of blockSize: a write at the end of the file
+// extends it to a block boundary.
+func NewAlignedFile(f File, blockSize int64, rejectUnaligned bool) File {
+	return &alignedFile{
+		forwardingFile:  forwardingFile{f},
+		blockSize:       blockSize,
+		rejectUnaligned: rejectUnaligned,
+	}
+}
+
+type alignedFile struct {
+	forwardingFile
+	blockSize       int64
+	rejectUnaligned bool
+
+	// mu serializes writes, so read-modify-write cycles do not
+	// lose concurrent writes to the same block.
+	mu sync.Mutex
+}
+
+func (f *alignedFile) InnerFile() File {
+	return f.File
+}
+
+func (f *alignedFile) String() string {
+	return fmt.Sprintf("alignedFile(%d, %s)", f.blockSize, f.File.String())
+}
+
+func (f *alignedFile) aligned(off int64, size int) bool {
+	return off%f.blockSize == 0 && int64(size)%f.blockSize == 0
+}
+
+// blocks returns the block aligned range covering size bytes at off.
+func (f *alignedFile) blocks(off int64, size int) (start int64, end int64) {
+	start = off - off%f.blockSize
+	end = off + int64(size)
+	if r := end % f.blockSize; r != 0 {
+		end += f.blockSize - r
+	}
+	return start, end
+}
+
+func (f *alignedFile) Read(buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	return f.ReadContext(&fuse.Context{}, buf, off)
+}
+
+func (f *alignedFile) ReadContext(ctx *fuse.Context, buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	inner := ToContextFile(f.File)
+	if f.aligned(off, len(buf)) {
+		return inner.ReadContext(ctx, buf, off)
+	}
+	if f.rejectUnaligned {
+		return nil, fuse.EINVAL
+	}
+
+	start, end := f.blocks(off, len(buf))
+	block := make([]byte, end-start)
+	res, code := inner.ReadContext(ctx, block, start)
+	if !code.Ok() {
+		return nil, code
+	}
+	data, code := res.Bytes(block)
+	res.Done()
+	if !code.Ok() {
+		return nil, code
+	}
+	skip := int(off - start)
+	if skip >= len(data) {
+		return fuse.ReadResultData(nil), fuse.OK
+	}
+	n := copy(buf, data[skip:])
+	return fuse.ReadResultData(buf[:n]), fuse.OK
+}
+
+func (f *alignedFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	return f.WriteContext(&fuse.Context{}, data, off)
+}
+
+func (f *alignedFile) WriteContext(ctx *fuse.Context, data []byte, off int64) (uint32, fuse.Status) {
+	inner := ToContextFile(f.File)
+	if f.rejectUnaligned {
+		if !f.aligned(off, len(data)) {
+			return 0, fuse.EINVAL
+		}
+		return inner.WriteContext(ctx, data, off)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.aligned(off, len(data)) {
+		return inner.WriteContext(ctx, data, off)
+	}
+
+	start, end := f.blocks(off, len(data))
+	block := make([]byte, end-start)
+	res, code := inner.ReadContext(ctx, block, start)
+	if !code.Ok() {
+		return 0, code
+	}
+	old, code := res.Bytes(block)
+	res.Done()
+	if !code.Ok() {
+		return 0, code
+	}
+	// Past the end of the file, the blocks are zero filled.
+	for i := copy(block, old); i < len(block); i++ {
+		block[i] = 0
+	}
+	copy(block[off-start:], data)
+
+	n, code := inner.WriteContext(ctx, block, start)
+	if !code.Ok() {
+		return 0, code
+	}
+	if int64(n) < end-start {
+		// Report the part of data that was written.
+		written := int64(n) - (off - start)
+		if written < 0 {
+			written = 0
+		} else if written > int64(len(data)) {
+			written = int64(len(data))
+		}
+		return uint32(written), fuse.OK
+	}
+	return uint32(len(data)), fuse.OK
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"bytes"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// blockFile is a fixed size File that only accepts aligned I/O.
+type blockFile struct {
+	File
+	blockSize int64
+	data      []byte
+}
+
+func (f *blockFile) Read(buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	if off%f.blockSize != 0 || int64(len(buf))%f.blockSize != 0 {
+		return nil, fuse.EINVAL
+	}
+	if off >= int64(len(f.data)) {
+		return fuse.ReadResultData(nil), fuse.OK
+	}
+	n := copy(buf, f.data[off:])
+	return fuse.ReadResultData(buf[:n]), fuse.OK
+}
+
+func (f *blockFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	if off%f.blockSize != 0 || int64(len(data))%f.blockSize != 0 {
+		return 0, fuse.EINVAL
+	}
+	if off >= int64(len(f.data)) {
+		return 0, fuse.Status(syscall.ENOSPC)
+	}
+	return uint32(copy(f.data[off:], data)), fuse.OK
+}
+
+func TestAlignedFile(t *testing.T) {
+	back := &blockFile{File: NewDefaultFile(), blockSize: 8, data: []byte("0123456789abcdefghijklmnopqrstuv")}
+	f := NewAlignedFile(back, 8, false)
+
+	buf := make([]byte, 10)
+	res, code := f.Read(buf, 5)
+	if !code.Ok() {
+		t.Fatalf("Read: %v", code)
+	}
+	if got, _ := res.Bytes(buf); string(got) != "56789abcde" {
+		t.Errorf("Read: got %q", got)
+	}
+	res, code = f.Read(buf, 28)
+	if !code.Ok() {
+		t.Fatalf("Read at end: %v", code)
+	}
+	if got, _ := res.Bytes(buf); string(got) != "stuv" {
+		t.Errorf("Read at end: got %q", got)
+	}
+
+	if n, code := f.Write([]byte("XYZ"), 6); !code.Ok() || n != 3 {
+		t.Fatalf("Write: %d, %v", n, code)
+	}
+	if n, code := f.Write([]byte("PQRSTUVW"), 13); !code.Ok() || n != 8 {
+		t.Fatalf("Write: %d, %v", n, code)
+	}
+	if want := []byte("012345XYZ9abcPQRSTUVWlmnopqrstuv"); !bytes.Equal(back.data, want) {
+		t.Errorf("got %q, want %q", back.data, want)
+	}
+
+	strict := NewAlignedFile(back, 8, true)
+	if _, code := strict.Read(buf, 5); code != fuse.EINVAL {
+		t.Errorf("unaligned Read: got %v, want EINVAL", code)
+	}
+	if _, code := strict.Write([]byte("XYZ"), 6); code != fuse.EINVAL {
+		t.Errorf("unaligned Write: got %v, want EINVAL", code)
+	}
+	if n, code := strict.Write([]byte("ABCDEFGH"), 8); !code.Ok() || n != 8 {
+		t.Errorf("aligned Write: %d, %v", n, code)
+	}
+}
@@ -37,7 +37,9 @@ type Node interface {
 
 	// OnUnmount is executed just before a submount is removed,
 	// and when the process receives a forget for the FUSE root
-	// node.
+	// node. When the file system is destroyed, it is executed on
+	// the roots of all mounts, submounts first. It is called at
+	// most once per mount.
 	OnUnmount()
 
 	// Lookup finds a child node to this node; it is only called
@@ -62,6 +64,10 @@ type Node interface {
 	OnForget()
 
 	// Misc.
+
+	// Access checks permissions. If it returns ENOSYS, it is not
+	// called again for any node of the mount, and access is
+	// granted from then on.
 	Access(mode uint32, context *fuse.Context) (code fuse.Status)
 	Readlink(c *fuse.Context) ([]byte, fuse.Status)
 
@@ -120,8 +126,13 @@ type Node interface {
 	// Attributes
 	GetAttr(out *fuse.Attr, file File, context *fuse.Context) (code fuse.Status)
 	Chmod(file File, perms uint32, context *fuse.Context) (code fuse.Status)
+	// Chown changes the owner and group. An ID of ^uint32(0)
+	// (-1) is left unchanged, eg. for chgrp(1).
 	Chown(file File, uid uint32, gid uint32, context *fuse.Context) (code fuse.Status)
 	Truncate(file File, size uint64, context *fuse.Context) (code fuse.Status)
+	// Utimens sets the access and modification times. A nil
+	// time is left unchanged; a time for which fuse.IsUtimeNow
+	// holds means "now", eg. from touch(1).
 	Utimens(file File, atime *time.Time, mtime *time.Time, context *fuse.Context) (code fuse.Status)
 	Fallocate(file File, off uint64, size uint64, mode uint32, context *fuse.Context) (code fuse.Status)
 
@@ -144,6 +155,11 @@ type File interface {
 	// the inner file here.
 	InnerFile() File
 
+	// Read reads data at offset off. The dest buffer is sized
+	// from the kernel request; implementations should either
+	// fill it and return fuse.ReadResultData(dest[:n]), or return
+	// fuse.ReadResultFd, so the data can be spliced into the
+	// kernel without copying it through Go memory.
 	Read(dest []byte, off int64) (fuse.ReadResult, fuse.Status)
 	Write(data []byte, off int64) (written uint32, code fuse.Status)
 
@@ -170,10 +186,148 @@ type File interface {
 	GetAttr(out *fuse.Attr) fuse.Status
 	Chown(uid uint32, gid uint32) fuse.Status
 	Chmod(perms uint32) fuse.Status
+	// Utimens is like Node.Utimens: a nil time is left unchanged.
 	Utimens(atime *time.Time, mtime *time.Time) fuse.Status
 	Allocate(off uint64, size uint64, mode uint32) (code fuse.Status)
 }
 
+// ContextFile is an optional interface for File. If implemented,
+// its methods are called instead of the File methods of the same
+// name. The context is canceled when the kernel interrupts the
+// request, when it times out, or when the file system is unmounted,
+// so files backed by a network service can abandon their RPCs. The
+// File wrappers in this package implement it, and pass the context
+// on to the File they wrap.
+type ContextFile interface {
+	ReadContext(ctx *fuse.Context, dest []byte, off int64) (fuse.ReadResult, fuse.Status)
+	WriteContext(ctx *fuse.Context, data []byte, off int64) (written uint32, code fuse.Status)
+	FlushContext(ctx *fuse.Context) fuse.Status
+	ReleaseContext(ctx *fuse.Context)
+	FsyncContext(ctx *fuse.Context, flags int) fuse.Status
+	TruncateContext(ctx *fuse.Context, size uint64) fuse.Status
+	GetAttrContext(ctx *fuse.Context, out *fuse.Attr) fuse.Status
+	ChownContext(ctx *fuse.Context, uid uint32, gid uint32) fuse.Status
+	ChmodContext(ctx *fuse.Context, perms uint32) fuse.Status
+	UtimensContext(ctx *fuse.Context, atime *time.Time, mtime *time.Time) fuse.Status
+	AllocateContext(ctx *fuse.Context, off uint64, size uint64, mode uint32) fuse.Status
+}
+
+// SetAttrer is an optional interface for Node. If implemented,
+// SetAttr is called with all attribute changes of a setattr request,
+// so they can be applied at once. If it returns ENOSYS, the changes
+// are applied one at a time with Chown, Chmod, Truncate, Utimens and
+// SetCtime, in that order.
+type SetAttrer interface {
+	SetAttr(input *fuse.SetAttrIn, file File, context *fuse.Context) fuse.Status
+}
+
+// CtimeSetter is an optional interface for Node. In writeback cache
+// mode, the kernel keeps the ctime of files itself, and sends it
+// along with other attribute changes (fuse.FATTR_CTIME). If
+// implemented, SetCtime is called with that ctime after the other
+// changes, so GetAttr keeps returning the ctime that the kernel
+// reported. Otherwise, or if it returns ENOSYS, the ctime is
+// ignored.
+type CtimeSetter interface {
+	SetCtime(file File, ctime time.Time, context *fuse.Context) fuse.Status
+}
+
+// BirthTimer is an optional interface for Node, to report when the
+// file was created. The birth time is returned by statx(2) along
+// with the attributes from GetAttr.
+type BirthTimer interface {
+	BirthTime(file File, context *fuse.Context) (time.Time, fuse.Status)
+}
+
+// ReleaseInfo describes the release of a file or directory handle.
+type ReleaseInfo struct {
+	// OpenFlags are the flags the handle was opened with.
+	OpenFlags uint32
+
+	// ReleaseFlags holds fuse.RELEASE_FLUSH if the kernel wants
+	// the file flushed as part of the release.
+	ReleaseFlags uint32
+
+	// Flushed is true if Flush was called on the handle.
+	Flushed bool
+}
+
+// FileReleaser is an optional interface for File. If implemented,
+// ReleaseWithInfo is called instead of Release.
+type FileReleaser interface {
+	ReleaseWithInfo(info *ReleaseInfo)
+}
+
+// PassthroughFile is an optional interface for File. If the mount
+// negotiated passthrough (see fuse.MountOptions.EnablePassthrough)
+// and PassthroughFd returns true, the kernel reads and writes fd
+// directly, and Read, Write, Fsync and related calls do not reach
+// the File. If registering fd fails, for example for lack of
+// privileges, I/O goes through the File as usual.
+type PassthroughFile interface {
+	PassthroughFd() (fd int, ok bool)
+}
+
+// UnnamedCreater is an optional interface for Node, to support
+// open(2) with O_TMPFILE. CreateUnnamed creates and opens a file
+// without a name in the directory; the returned Node is attached to
+// a new Inode that has no parent. It can be given a name through
+// Link, unless it was opened with O_EXCL; otherwise it is dropped
+// when the kernel forgets it.
+type UnnamedCreater interface {
+	CreateUnnamed(flags uint32, mode uint32, context *fuse.Context) (file File, child Node, code fuse.Status)
+}
+
+// DirReleaser is an optional interface for Node. If implemented,
+// ReleaseDir is called when a handle from OpenDir is released.
+type DirReleaser interface {
+	ReleaseDir(info *ReleaseInfo)
+}
+
+// DirStream lists directory entries one at a time.
+type DirStream interface {
+	// HasNext indicates if there are further entries.
+	HasNext() bool
+
+	// Next retrieves the next entry. It is only called if HasNext
+	// has previously returned true. The Status return may be used
+	// to indicate I/O errors.
+	Next() (fuse.DirEntry, fuse.Status)
+
+	// Close releases resources related to this directory stream.
+	Close()
+}
+
+// DirSeeker is an optional interface for DirStream. It is used when
+// the kernel reads a directory from an offset before the current
+// one, so streams that can resume from a saved position need not be
+// restarted from the beginning. SeekDir positions the stream at an
+// offset (the number of entries from the start) no larger than
+// offset, and returns the offset it was positioned at. The entries
+// up to offset are skipped by reading them.
+type DirSeeker interface {
+	SeekDir(offset uint64) (uint64, fuse.Status)
+}
+
+// ContextDirStream is an optional interface for DirStream. If
+// implemented, HasNextContext is called instead of HasNext, with the
+// context of the READDIR or READDIRPLUS request that needs the next
+// entry, so streams that fetch entries lazily can pass on its caller
+// and cancel channel. The context passed to OpenDirStream is only
+// valid for the request that opened the stream.
+type ContextDirStream interface {
+	HasNextContext(ctx *fuse.Context) bool
+}
+
+// DirStreamer is an optional interface for Node. If implemented,
+// OpenDirStream is called instead of OpenDir, so the entries of
+// large directories are produced as the kernel reads them, rather
+// than all before the first READDIR is answered. If it returns
+// ENOSYS, OpenDir is called instead.
+type DirStreamer interface {
+	OpenDirStream(context *fuse.Context) (DirStream, fuse.Status)
+}
+
 // Wrap a File return in this to set FUSE flags.  Also used internally
 // to store open file data.
 type WithFlags struct {
@@ -187,6 +341,10 @@ type WithFlags struct {
 
 	// O_RDWR, O_TRUNCATE, etc.
 	OpenFlags uint32
+
+	// If set, Write calls for this file handle are made one at
+	// a time. See Options.SerializeWrites.
+	SerializeWrites bool
 }
 
 // Options contains time out options for a node FileSystem.  The
@@ -202,6 +360,13 @@ type Options struct {
 	// uid/gid.
 	*fuse.Owner
 
+	// If set, a zero UID or GID returned by the file system is
+	// replaced with the one given here, so files of in-memory
+	// file systems that leave the owner unset are not owned by
+	// root. Use fuse.CurrentOwner for the user running the file
+	// system. Owner, if set, takes precedence.
+	DefaultOwner *fuse.Owner
+
 	// This option exists for compatibility and is ignored.
 	PortableInodes bool
 
@@ -212,4 +377,126 @@ type Options struct {
 	// children. This allows the filesystem to update its inode
 	// hierarchy in response to kernel calls.
 	LookupKnownChildren bool
+
+	// If set on both mounts, renaming a file or symlink from one
+	// mount to another copies it, and then removes the original,
+	// rather than failing with EXDEV. Applications such as mv(1)
+	// do the same on EXDEV, but then all data passes through the
+	// kernel twice. Directories still fail with EXDEV.
+	CopyOnCrossMountRename bool
+
+	// If set, operations that change the mount fail with EROFS
+	// before they reach the file system, including Open for
+	// writing. This can be used to expose a file system
+	// read-only on one mount point while it is writable on
+	// another. Unlike fuse.MountOptions.ReadOnly, it applies to
+	// a single mount within the connector.
+	ReadOnly bool
+
+	// MountPolicy says what FileSystemConnector.Mount does if
+	// the mount point is already in the inode tree. It is taken
+	// from the options of the new mount.
+	MountPolicy MountPolicy
+
+	// If set, the root directory has a read-only file of this
+	// name, eg. ".fuse-stats", that reports inode and open file
+	// counts, and the per-operation statistics of the server if
+	// fuse.MountOptions.EnableStats is set. The file is not
+	// added to directory listings, unless the root Node lists
+	// its Inode children, and it hides a file of the same name
+	// in the root file system. It is only honored in the
+	// options of the root file system.
+	StatsFile string
+
+	// MaxNameLength and MaxPathLength limit the length of names,
+	// and of paths below the mount point. Operations that create
+	// or look up a name beyond the limits fail with ENAMETOOLONG
+	// before they reach the file system. If zero, the limits are
+	// NAME_MAX (255) and PATH_MAX (4096); if negative, there is no
+	// limit.
+	MaxNameLength int
+	MaxPathLength int
+
+	// If set, the kernel caches directory listings
+	// (FOPEN_CACHE_DIR), and serves readdir from the cache across
+	// opendir calls until the directory changes. This needs
+	// protocol 7.28 (Linux 4.20); it is ignored on older kernels.
+	// Changes made through the mount invalidate the cache; use
+	// FileSystemConnector.DirNotify for changes made elsewhere.
+	CacheDirs bool
+
+	// StatFsPolicy says what StatFs reports for directories that
+	// have mounts below them. It is taken from the options of the
+	// mount containing the directory.
+	StatFsPolicy StatFsPolicy
+
+	// AtimePolicy says whether reading a file updates its
+	// access time. The kernel leaves atime to the file system,
+	// and by default reads do not change it.
+	AtimePolicy AtimePolicy
+
+	// If set, Write calls for a file handle are made one at a
+	// time, for backends that can not write to one file at
+	// several offsets concurrently. Writes to different handles
+	// still run in parallel. To serialize the writes of single
+	// handles only, return the File from Open or Create wrapped
+	// in a WithFlags with SerializeWrites set.
+	SerializeWrites bool
 }
+
+// StatFsPolicy determines what StatFs reports for a directory with
+// other file systems mounted below it.
+type StatFsPolicy int
+
+const (
+	// StatFsCovering reports the file system containing the
+	// directory. This is the default.
+	StatFsCovering StatFsPolicy = iota
+
+	// StatFsAggregate adds up the block and inode counts of the
+	// file system containing the directory and of all mounts
+	// below it, so df(1) on the root of a composed name space
+	// shows its total size. Block counts are converted to the
+	// block size of the containing file system.
+	StatFsAggregate
+)
+
+// AtimePolicy determines when reads update the access time of a
+// file, through Node.Utimens.
+type AtimePolicy int
+
+const (
+	// NoAtime leaves the access time alone, like the noatime
+	// mount option. This is the default, and it saves a write to
+	// the file system for every read.
+	NoAtime AtimePolicy = iota
+
+	// RelAtime updates the access time if it is not later than
+	// the modification or change time, or is more than a day
+	// old, like the relatime mount option. This needs a GetAttr
+	// call for every read.
+	RelAtime
+
+	// StrictAtime updates the access time on every read.
+	StrictAtime
+)
+
+// MountPolicy determines how a file system is mounted on a name that
+// already has an inode. There is no policy for merging the existing
+// directory with the new file system: the kernel would see inodes of
+// two file systems in a single directory, with no way to tell which
+// one handles an operation.
+type MountPolicy int
+
+const (
+	// MountFail makes Mount return EBUSY if the mount point
+	// exists. This is the default.
+	MountFail MountPolicy = iota
+
+	// MountShadow hides an existing directory below the new
+	// mount. The directory and its cached children are visible
+	// again after Unmount. Other kinds of files can not be
+	// shadowed, and the directory must not be a mount point
+	// itself.
+	MountShadow
+)
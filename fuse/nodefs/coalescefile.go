@@ -0,0 +1,193 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// NewCoalescingFile wraps a File so small sequential writes are
+// merged into writes of up to bufSize bytes. This is useful for
+// backends where each write is a round trip, such as an RPC.
+//
+// Buffered data is written out when a write does not continue the
+// buffer or does not fit in it, and before Read, GetAttr,
+// Truncate, Allocate, Flush, Fsync and Release. An error writing
+// out buffered data is returned from the call that triggered it, so
+// the failure of an earlier write may only show up in close(2) or
+// fsync(2).
+func NewCoalescingFile(f File, bufSize int) File {
+	return &coalescingFile{
+		forwardingFile: forwardingFile{f},
+		bufSize:        bufSize,
+	}
+}
+
+type coalescingFile struct {
+	forwardingFile
+	bufSize int
+
+	// mu protects buf and off, and serializes writes to File
+	// with writing out the buffer.
+	mu sync.Mutex
+
+	// buf holds data to be written at off.
+	buf []byte
+	off int64
+}
+
+func (f *coalescingFile) InnerFile() File {
+	return f.File
+}
+
+func (f *coalescingFile) String() string {
+	return fmt.Sprintf("coalescingFile(%d, %s)", f.bufSize, f.File.String())
+}
+
+// flushLocked writes out the buffer. The buffer is discarded even
+// if this fails, so a failing write is reported once.
+func (f *coalescingFile) flushLocked(ctx *fuse.Context) fuse.Status {
+	data, off := f.buf, f.off
+	f.buf = f.buf[:0]
+	for len(data) > 0 {
+		n, code := ToContextFile(f.File).WriteContext(ctx, data, off)
+		if !code.Ok() {
+			return code
+		}
+		if n == 0 {
+			return fuse.EIO
+		}
+		data = data[n:]
+		off += int64(n)
+	}
+	return fuse.OK
+}
+
+func (f *coalescingFile) flush(ctx *fuse.Context) fuse.Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flushLocked(ctx)
+}
+
+func (f *coalescingFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	return f.WriteContext(&fuse.Context{}, data, off)
+}
+
+func (f *coalescingFile) WriteContext(ctx *fuse.Context, data []byte, off int64) (uint32, fuse.Status) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Write out the buffer before taking any of data, so a failure
+	// rejects this write as a whole.
+	if len(f.buf) > 0 && (off != f.off+int64(len(f.buf)) || len(f.buf)+len(data) > f.bufSize) {
+		if code := f.flushLocked(ctx); !code.Ok() {
+			return 0, code
+		}
+	}
+	if len(f.buf) == 0 {
+		if len(data) >= f.bufSize {
+			return ToContextFile(f.File).WriteContext(ctx, data, off)
+		}
+		if f.buf == nil {
+			f.buf = make([]byte, 0, f.bufSize)
+		}
+		f.off = off
+	}
+	f.buf = append(f.buf, data...)
+	return uint32(len(data)), fuse.OK
+}
+
+func (f *coalescingFile) Read(buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	return f.ReadContext(&fuse.Context{}, buf, off)
+}
+
+func (f *coalescingFile) ReadContext(ctx *fuse.Context, buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	if code := f.flush(ctx); !code.Ok() {
+		return nil, code
+	}
+	return ToContextFile(f.File).ReadContext(ctx, buf, off)
+}
+
+func (f *coalescingFile) GetAttr(out *fuse.Attr) fuse.Status {
+	return f.GetAttrContext(&fuse.Context{}, out)
+}
+
+func (f *coalescingFile) GetAttrContext(ctx *fuse.Context, out *fuse.Attr) fuse.Status {
+	if code := f.flush(ctx); !code.Ok() {
+		return code
+	}
+	return ToContextFile(f.File).GetAttrContext(ctx, out)
+}
+
+func (f *coalescingFile) Truncate(size uint64) fuse.Status {
+	return f.TruncateContext(&fuse.Context{}, size)
+}
+
+func (f *coalescingFile) TruncateContext(ctx *fuse.Context, size uint64) fuse.Status {
+	if code := f.flush(ctx); !code.Ok() {
+		return code
+	}
+	return ToContextFile(f.File).TruncateContext(ctx, size)
+}
+
+func (f *coalescingFile) Allocate(off uint64, size uint64, mode uint32) fuse.Status {
+	return f.AllocateContext(&fuse.Context{}, off, size, mode)
+}
+
+func (f *coalescingFile) AllocateContext(ctx *fuse.Context, off uint64, size uint64, mode uint32) fuse.Status {
+	if code := f.flush(ctx); !code.Ok() {
+		return code
+	}
+	return ToContextFile(f.File).AllocateContext(ctx, off, size, mode)
+}
+
+func (f *coalescingFile) Utimens(atime *time.Time, mtime *time.Time) fuse.Status {
+	return f.UtimensContext(&fuse.Context{}, atime, mtime)
+}
+
+func (f *coalescingFile) UtimensContext(ctx *fuse.Context, atime *time.Time, mtime *time.Time) fuse.Status {
+	// Write out first, so the backend does not overwrite mtime
+	// when the data arrives.
+	if code := f.flush(ctx); !code.Ok() {
+		return code
+	}
+	return ToContextFile(f.File).UtimensContext(ctx, atime, mtime)
+}
+
+func (f *coalescingFile) Flush() fuse.Status {
+	return f.FlushContext(&fuse.Context{})
+}
+
+func (f *coalescingFile) FlushContext(ctx *fuse.Context) fuse.Status {
+	code := f.flush(ctx)
+	if c := ToContextFile(f.File).FlushContext(ctx); code.Ok() {
+		code = c
+	}
+	return code
+}
+
+func (f *coalescingFile) Fsync(flags int) fuse.Status {
+	return f.FsyncContext(&fuse.Context{}, flags)
+}
+
+func (f *coalescingFile) FsyncContext(ctx *fuse.Context, flags int) fuse.Status {
+	if code := f.flush(ctx); !code.Ok() {
+		return code
+	}
+	return ToContextFile(f.File).FsyncContext(ctx, flags)
+}
+
+func (f *coalescingFile) Release() {
+	f.ReleaseContext(&fuse.Context{})
+}
+
+func (f *coalescingFile) ReleaseContext(ctx *fuse.Context) {
+	f.flush(ctx)
+	ToContextFile(f.File).ReleaseContext(ctx)
+}
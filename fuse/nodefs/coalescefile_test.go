@@ -0,0 +1,114 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// countingFile is an in-memory File that counts backend writes.
+type countingFile struct {
+	File
+	data   []byte
+	writes int
+}
+
+func (f *countingFile) Read(buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	if off >= int64(len(f.data)) {
+		return fuse.ReadResultData(nil), fuse.OK
+	}
+	n := copy(buf, f.data[off:])
+	return fuse.ReadResultData(buf[:n]), fuse.OK
+}
+
+func (f *countingFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	f.writes++
+	if end := off + int64(len(data)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	return uint32(copy(f.data[off:], data)), fuse.OK
+}
+
+func TestCoalescingFile(t *testing.T) {
+	back := &countingFile{File: NewDefaultFile()}
+	f := NewCoalescingFile(back, 16)
+
+	var want []byte
+	for i := 0; i < 10; i++ {
+		chunk := []byte{'a' + byte(i), 'a' + byte(i), 'a' + byte(i)}
+		if n, code := f.Write(chunk, int64(len(want))); !code.Ok() || n != 3 {
+			t.Fatalf("Write: %d, %v", n, code)
+		}
+		want = append(want, chunk...)
+	}
+	if back.writes != 1 {
+		t.Errorf("got %d backend writes before Flush, want 1", back.writes)
+	}
+
+	buf := make([]byte, 64)
+	res, code := f.Read(buf, 0)
+	if !code.Ok() {
+		t.Fatalf("Read: %v", code)
+	}
+	if got, _ := res.Bytes(buf); !bytes.Equal(got, want) {
+		t.Errorf("Read: got %q, want %q", got, want)
+	}
+	if back.writes != 2 {
+		t.Errorf("got %d backend writes after Read, want 2", back.writes)
+	}
+
+	// A write elsewhere writes out the buffer first.
+	f.Write([]byte("xy"), 30)
+	f.Write([]byte("Z"), 0)
+	if back.writes != 3 {
+		t.Errorf("got %d backend writes after seek, want 3", back.writes)
+	}
+	if code := f.Flush(); !code.Ok() {
+		t.Fatalf("Flush: %v", code)
+	}
+	want = append(want, "xy"...)
+	want[0] = 'Z'
+	if !bytes.Equal(back.data, want) {
+		t.Errorf("got %q, want %q", back.data, want)
+	}
+
+	// Large writes bypass the buffer.
+	back.writes = 0
+	f.Write(make([]byte, 40), 0)
+	if back.writes != 1 {
+		t.Errorf("got %d backend writes for large write, want 1", back.writes)
+	}
+}
+
+// failingFile fails all writes.
+type failingFile struct {
+	File
+}
+
+func (f *failingFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	return 0, fuse.EIO
+}
+
+func TestCoalescingFileWriteError(t *testing.T) {
+	f := NewCoalescingFile(&failingFile{NewDefaultFile()}, 16)
+	if n, code := f.Write(make([]byte, 10), 0); !code.Ok() || n != 10 {
+		t.Fatalf("Write: %d, %v", n, code)
+	}
+
+	// The buffer is written out before any of the new data is
+	// taken, so the failure rejects the write as a whole.
+	if n, code := f.Write(make([]byte, 10), 10); code != fuse.EIO || n != 0 {
+		t.Fatalf("Write: got %d, %v, want 0, EIO", n, code)
+	}
+
+	// The failed buffer is reported once; the rejected data was
+	// not buffered.
+	if code := f.Flush(); !code.Ok() {
+		t.Errorf("Flush: %v", code)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// ToContextFile returns f as a ContextFile. If f does not implement
+// ContextFile, the result calls the File methods of f, dropping the
+// context.
+func ToContextFile(f File) ContextFile {
+	if cf, ok := f.(ContextFile); ok {
+		return cf
+	}
+	return forwardingFile{f}
+}
+
+// forwardingFile implements ContextFile on top of File. The wrappers
+// in this package embed it, so the context methods they do not
+// override reach the wrapped File.
+type forwardingFile struct {
+	File
+}
+
+func (f forwardingFile) ReadContext(ctx *fuse.Context, dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	if cf, ok := f.File.(ContextFile); ok {
+		return cf.ReadContext(ctx, dest, off)
+	}
+	return f.File.Read(dest, off)
+}
+
+func (f forwardingFile) WriteContext(ctx *fuse.Context, data []byte, off int64) (uint32, fuse.Status) {
+	if cf, ok := f.File.(ContextFile); ok {
+		return cf.WriteContext(ctx, data, off)
+	}
+	return f.File.Write(data, off)
+}
+
+func (f forwardingFile) FlushContext(ctx *fuse.Context) fuse.Status {
+	if cf, ok := f.File.(ContextFile); ok {
+		return cf.FlushContext(ctx)
+	}
+	return f.File.Flush()
+}
+
+func (f forwardingFile) ReleaseContext(ctx *fuse.Context) {
+	if cf, ok := f.File.(ContextFile); ok {
+		cf.ReleaseContext(ctx)
+		return
+	}
+	f.File.Release()
+}
+
+func (f forwardingFile) FsyncContext(ctx *fuse.Context, flags int) fuse.Status {
+	if cf, ok := f.File.(ContextFile); ok {
+		return cf.FsyncContext(ctx, flags)
+	}
+	return f.File.Fsync(flags)
+}
+
+func (f forwardingFile) TruncateContext(ctx *fuse.Context, size uint64) fuse.Status {
+	if cf, ok := f.File.(ContextFile); ok {
+		return cf.TruncateContext(ctx, size)
+	}
+	return f.File.Truncate(size)
+}
+
+func (f forwardingFile) GetAttrContext(ctx *fuse.Context, out *fuse.Attr) fuse.Status {
+	if cf, ok := f.File.(ContextFile); ok {
+		return cf.GetAttrContext(ctx, out)
+	}
+	return f.File.GetAttr(out)
+}
+
+func (f forwardingFile) ChownContext(ctx *fuse.Context, uid uint32, gid uint32) fuse.Status {
+	if cf, ok := f.File.(ContextFile); ok {
+		return cf.ChownContext(ctx, uid, gid)
+	}
+	return f.File.Chown(uid, gid)
+}
+
+func (f forwardingFile) ChmodContext(ctx *fuse.Context, perms uint32) fuse.Status {
+	if cf, ok := f.File.(ContextFile); ok {
+		return cf.ChmodContext(ctx, perms)
+	}
+	return f.File.Chmod(perms)
+}
+
+func (f forwardingFile) UtimensContext(ctx *fuse.Context, atime *time.Time, mtime *time.Time) fuse.Status {
+	if cf, ok := f.File.(ContextFile); ok {
+		return cf.UtimensContext(ctx, atime, mtime)
+	}
+	return f.File.Utimens(atime, mtime)
+}
+
+func (f forwardingFile) AllocateContext(ctx *fuse.Context, off uint64, size uint64, mode uint32) fuse.Status {
+	if cf, ok := f.File.(ContextFile); ok {
+		return cf.AllocateContext(ctx, off, size, mode)
+	}
+	return f.File.Allocate(off, size, mode)
+}
@@ -0,0 +1,165 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ServeControl accepts connections on l, typically a unix socket,
+// and executes the commands read from them. This lets an
+// administrator change a running mount without restarting the
+// daemon, eg.
+//
+//	echo "attr-timeout 10s" | socat - UNIX-CONNECT:/run/myfs.sock
+//
+// Each command is a line. The reply ends in a line "ok", preceded
+// by output if there is any, or in a line "error: " followed by a
+// message. The commands are
+//
+//	debug on|off            toggle debug output
+//	entry-timeout DURATION  set EntryTimeout of all mounts
+//	attr-timeout DURATION   set AttrTimeout of all mounts
+//	negative-timeout DURATION
+//	                        set NegativeTimeout of all mounts
+//	flush                   drop the kernel caches of all known inodes
+//	stats                   print the statistics of Options.StatsFile
+//	unmount                 unmount the file system
+//
+// Timeouts only apply to entries and attributes returned after the
+// change. Like Server.SetDebug, debug changes are not synchronized
+// with requests in flight, so they trigger the race detector.
+// ServeControl returns when l is closed. Access control is
+// up to the caller, eg. through the permissions of the socket.
+func (c *FileSystemConnector) ServeControl(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go c.serveControlConn(conn)
+	}
+}
+
+func (c *FileSystemConnector) serveControlConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		out, err := c.controlCommand(fields[0], fields[1:])
+		if err != nil {
+			fmt.Fprintf(conn, "error: %v\n", err)
+			continue
+		}
+		fmt.Fprintf(conn, "%sok\n", out)
+	}
+}
+
+func (c *FileSystemConnector) controlCommand(cmd string, args []string) (string, error) {
+	want := 0
+	switch cmd {
+	case "debug", "entry-timeout", "attr-timeout", "negative-timeout":
+		want = 1
+	}
+	if len(args) != want {
+		return "", fmt.Errorf("%s: want %d arguments, got %d", cmd, want, len(args))
+	}
+
+	switch cmd {
+	case "debug":
+		var on bool
+		switch args[0] {
+		case "on":
+			on = true
+		case "off":
+		default:
+			return "", fmt.Errorf("debug: want on or off, got %q", args[0])
+		}
+		c.SetDebug(on)
+		if c.server != nil {
+			c.server.SetDebug(on)
+		}
+	case "entry-timeout", "attr-timeout", "negative-timeout":
+		d, err := time.ParseDuration(args[0])
+		if err != nil {
+			return "", err
+		}
+		if d < 0 {
+			return "", fmt.Errorf("%s: negative duration %v", cmd, d)
+		}
+		c.setTimeout(cmd, d)
+	case "flush":
+		c.flushKernelCache()
+	case "stats":
+		return string(c.statsData()), nil
+	case "unmount":
+		if c.server == nil {
+			return "", fmt.Errorf("not mounted")
+		}
+		if err := c.server.Unmount(); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unknown command %q", cmd)
+	}
+	return "", nil
+}
+
+// setTimeout changes a timeout of all mounts. The Options structs
+// passed to Mount are not modified.
+func (c *FileSystemConnector) setTimeout(name string, d time.Duration) {
+	for _, info := range c.Mounts() {
+		m := info.mount
+		switch name {
+		case "entry-timeout":
+			atomic.StoreInt64(&m.entryTimeout, int64(d))
+		case "attr-timeout":
+			atomic.StoreInt64(&m.attrTimeout, int64(d))
+		case "negative-timeout":
+			atomic.StoreInt64(&m.negativeTimeout, int64(d))
+		}
+	}
+}
+
+// flushKernelCache invalidates the kernel's entries, attributes and
+// data for all inodes in the tree.
+func (c *FileSystemConnector) flushKernelCache() {
+	type entry struct {
+		parent *Inode
+		name   string
+	}
+	var entries []entry
+	nodes := []*Inode{c.rootNode}
+	seen := map[*Inode]bool{c.rootNode: true}
+	for i := 0; i < len(nodes); i++ {
+		for name, ch := range nodes[i].Children() {
+			entries = append(entries, entry{nodes[i], name})
+			if !seen[ch] {
+				seen[ch] = true
+				nodes = append(nodes, ch)
+			}
+		}
+	}
+	if c.server == nil {
+		return
+	}
+
+	// Notifications must be sent without holding tree locks.
+	for _, n := range nodes {
+		c.FileNotify(n, 0, 0)
+	}
+	for _, e := range entries {
+		c.EntryNotify(e.parent, e.name)
+	}
+}
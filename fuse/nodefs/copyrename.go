@@ -0,0 +1,157 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// copyRenameBufSize is the size of the chunks in which copyRename
+// copies file data.
+const copyRenameBufSize = 128 * 1024
+
+// copyRenameSeq makes the temporary names of concurrent copies
+// unique.
+var copyRenameSeq uint64
+
+// copyRename implements Rename between two mounts as a copy followed
+// by Unlink of the source. See Options.CopyOnCrossMountRename.
+//
+// The copy is made under a temporary name in the new directory. The
+// source is then moved aside under a temporary name, and only then is
+// the copy renamed over newName, so a failure in any step can be
+// undone, and leaves an existing target untouched.
+func (c *FileSystemConnector) copyRename(oldParent *Inode, oldName string, newParent *Inode, newName string, context *fuse.Context) fuse.Status {
+	src := oldParent.GetChild(oldName)
+	if src == nil {
+		return fuse.ENOENT
+	}
+	if len(src.Files(0)) > 0 {
+		// The open files belong to the old mount.
+		return fuse.EXDEV
+	}
+	var attr fuse.Attr
+	if code := src.fsInode.GetAttr(&attr, nil, context); !code.Ok() {
+		return code
+	}
+
+	seq := atomic.AddUint64(&copyRenameSeq, 1)
+	tmpName := fmt.Sprintf(".copyrename.%d.%d.tmp", os.Getpid(), seq)
+	srcTmpName := fmt.Sprintf(".copyrename.%d.%d.old", os.Getpid(), seq)
+
+	var dst *Inode
+	var code fuse.Status
+	switch attr.Mode & syscall.S_IFMT {
+	case syscall.S_IFREG:
+		dst, code = c.copyFile(src, &attr, newParent, tmpName, context)
+	case syscall.S_IFLNK:
+		var target []byte
+		target, code = src.fsInode.Readlink(context)
+		if code.Ok() {
+			dst, code = newParent.fsInode.Symlink(tmpName, string(target), context)
+		}
+	default:
+		return fuse.EXDEV
+	}
+	if !code.Ok() {
+		if dst != nil {
+			newParent.fsInode.Unlink(tmpName, context)
+		}
+		return code
+	}
+	if code = oldParent.fsInode.Rename(oldName, oldParent.fsInode, srcTmpName, context); !code.Ok() {
+		newParent.fsInode.Unlink(tmpName, context)
+		return code
+	}
+	if code = newParent.fsInode.Rename(tmpName, newParent.fsInode, newName, context); !code.Ok() {
+		oldParent.fsInode.Rename(srcTmpName, oldParent.fsInode, oldName, context)
+		newParent.fsInode.Unlink(tmpName, context)
+		return code
+	}
+	if code := oldParent.fsInode.Unlink(srcTmpName, context); !code.Ok() {
+		// The rename is complete; only the moved-aside source
+		// is left behind.
+		log.Printf("copyRename: removing %q: %v", srcTmpName, code)
+	}
+
+	// The kernel moves its entry for the source to the new name,
+	// so the node ID it has for the source must now refer to the
+	// copy.
+	c.lookupLock.Lock()
+	moved := c.inodeMap.Replace(&src.handled, &dst.handled)
+	c.lookupLock.Unlock()
+	if !moved {
+		// The notification blocks until the kernel has
+		// processed our reply, so it must be sent
+		// asynchronously.
+		go c.EntryNotify(newParent, newName)
+	}
+	return fuse.OK
+}
+
+// copyFile copies the regular file src to a new file newName in
+// newParent. On failure, it returns the new node, if one was created,
+// so the caller can remove it.
+func (c *FileSystemConnector) copyFile(src *Inode, attr *fuse.Attr, newParent *Inode, newName string, context *fuse.Context) (*Inode, fuse.Status) {
+	in, code := src.fsInode.Open(uint32(syscall.O_RDONLY), context)
+	if !code.Ok() {
+		return nil, code
+	}
+	if in != nil {
+		defer ToContextFile(in).ReleaseContext(context)
+	}
+
+	flags := uint32(syscall.O_WRONLY | syscall.O_CREAT | syscall.O_EXCL)
+	out, dst, code := newParent.fsInode.Create(newName, flags, attr.Mode&07777, context)
+	if !code.Ok() {
+		return nil, code
+	}
+	if out != nil {
+		defer ToContextFile(out).ReleaseContext(context)
+	}
+
+	buf := make([]byte, copyRenameBufSize)
+	for off := int64(0); ; {
+		res, code := src.fsInode.Read(in, buf, off, context)
+		if !code.Ok() {
+			return dst, code
+		}
+		data, code := res.Bytes(buf)
+		res.Done()
+		if !code.Ok() {
+			return dst, code
+		}
+		if len(data) == 0 {
+			break
+		}
+		for len(data) > 0 {
+			n, code := dst.fsInode.Write(out, data, off, context)
+			if !code.Ok() {
+				return dst, code
+			}
+			if n == 0 {
+				return dst, fuse.EIO
+			}
+			data = data[n:]
+			off += int64(n)
+		}
+	}
+	if out != nil {
+		if code := ToContextFile(out).FlushContext(context); !code.Ok() {
+			return dst, code
+		}
+	}
+
+	atime := attr.AccessTime()
+	mtime := attr.ModTime()
+	dst.fsInode.Utimens(out, &atime, &mtime, context)
+	return dst, fuse.OK
+}
@@ -11,53 +11,190 @@ import (
 	"github.com/hanwen/go-fuse/v2/fuse"
 )
 
+type listDirStream struct {
+	list []fuse.DirEntry
+}
+
+// NewListDirStream returns a DirStream that lists the given entries.
+func NewListDirStream(list []fuse.DirEntry) DirStream {
+	return &listDirStream{list}
+}
+
+func (s *listDirStream) HasNext() bool {
+	return len(s.list) > 0
+}
+
+func (s *listDirStream) Next() (fuse.DirEntry, fuse.Status) {
+	e := s.list[0]
+	s.list = s.list[1:]
+	return e, fuse.OK
+}
+
+func (s *listDirStream) Close() {
+	s.list = nil
+}
+
 type connectorDir struct {
 	node  Node
 	inode *Inode
 	rawFS fuse.RawFileSystem
 
-	// Protect stream and lastOffset.  These are written in case
-	// there is a seek on the directory.
-	mu     sync.Mutex
-	stream []fuse.DirEntry
+	// Protect the fields below. These are written in case there is
+	// a seek on the directory.
+	mu sync.Mutex
+
+	// stream produces the entries of the node. It is followed by
+	// extra, which holds the mount points, "." and "..".
+	stream DirStream
+	extra  []fuse.DirEntry
+
+	// offset is the directory offset of the entry returned by
+	// peek.
+	offset uint64
+
+	// lookahead is the entry at offset, if it was read from
+	// stream already.
+	lookahead *fuse.DirEntry
 }
 
-func (d *connectorDir) ReadDir(cancel <-chan struct{}, input *fuse.ReadIn, out *fuse.DirEntryList) (code fuse.Status) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+// open (re)starts the listing.
+func (d *connectorDir) open(context *fuse.Context) fuse.Status {
+	d.close()
+	var stream DirStream
+	code := fuse.ENOSYS
+	if s, ok := d.node.(DirStreamer); ok {
+		stream, code = s.OpenDirStream(context)
+	}
+	if code == fuse.ENOSYS {
+		var list []fuse.DirEntry
+		list, code = d.node.OpenDir(context)
+		stream = NewListDirStream(list)
+	}
+	if !code.Ok() {
+		return code
+	}
+	d.stream = stream
+	d.extra = d.extraEntries()
+	d.offset = 0
+	d.lookahead = nil
+	return fuse.OK
+}
+
+// extraEntries returns the entries listed after those of the node.
+func (d *connectorDir) extraEntries() []fuse.DirEntry {
+	return append(d.inode.getMountDirEntries(),
+		fuse.DirEntry{Mode: fuse.S_IFDIR, Name: "."},
+		fuse.DirEntry{Mode: fuse.S_IFDIR, Name: ".."})
+}
+
+func (d *connectorDir) close() {
+	if d.stream != nil {
+		d.stream.Close()
+		d.stream = nil
+	}
+}
+
+// hasNext calls HasNext on the stream, passing the context of the
+// current request if the stream takes it.
+func (d *connectorDir) hasNext(context *fuse.Context) bool {
+	if s, ok := d.stream.(ContextDirStream); ok {
+		return s.HasNextContext(context)
+	}
+	return d.stream.HasNext()
+}
+
+// peek returns the entry at d.offset, or nil at the end of the
+// directory.
+func (d *connectorDir) peek(context *fuse.Context) (*fuse.DirEntry, fuse.Status) {
+	if d.lookahead != nil {
+		return d.lookahead, fuse.OK
+	}
+	if d.hasNext(context) {
+		e, code := d.stream.Next()
+		if !code.Ok() {
+			return nil, code
+		}
+		d.lookahead = &e
+		return d.lookahead, fuse.OK
+	}
+	if len(d.extra) > 0 {
+		d.lookahead = &d.extra[0]
+		d.extra = d.extra[1:]
+		return d.lookahead, fuse.OK
+	}
+	return nil, fuse.OK
+}
+
+// advance moves past the entry returned by peek.
+func (d *connectorDir) advance() {
+	d.lookahead = nil
+	d.offset++
+}
 
+// seek positions the listing at the given offset, and returns
+// false if the offset is beyond the end of the directory.
+func (d *connectorDir) seek(context *fuse.Context, input *fuse.ReadIn) (bool, fuse.Status) {
 	// rewinddir() should be as if reopening directory.
-	// TODO - test this.
 	if d.stream == nil || input.Offset == 0 {
-		d.stream, code = d.node.OpenDir(&fuse.Context{Caller: input.Caller, Cancel: cancel})
+		if code := d.open(context); !code.Ok() {
+			return false, code
+		}
+	} else if input.Offset < d.offset {
+		if s, ok := d.stream.(DirSeeker); ok {
+			off, code := s.SeekDir(input.Offset)
+			if !code.Ok() {
+				return false, code
+			}
+			d.offset = off
+			d.extra = d.extraEntries()
+			d.lookahead = nil
+		} else if code := d.open(context); !code.Ok() {
+			return false, code
+		}
+	}
+	for d.offset < input.Offset {
+		e, code := d.peek(context)
 		if !code.Ok() {
-			return code
+			return false, code
 		}
-		d.stream = append(d.stream, d.inode.getMountDirEntries()...)
-		d.stream = append(d.stream,
-			fuse.DirEntry{Mode: fuse.S_IFDIR, Name: "."},
-			fuse.DirEntry{Mode: fuse.S_IFDIR, Name: ".."})
+		if e == nil {
+			// See https://github.com/hanwen/go-fuse/issues/297
+			// This can happen for FUSE exported over NFS.  This
+			// seems incorrect, (maybe the kernel is using offsets
+			// from other opendir/readdir calls), it is harmless to reinforce that
+			// we have reached EOF.
+			return false, fuse.OK
+		}
+		d.advance()
 	}
+	return true, fuse.OK
+}
 
-	if input.Offset > uint64(len(d.stream)) {
-		// See https://github.com/hanwen/go-fuse/issues/297
-		// This can happen for FUSE exported over NFS.  This
-		// seems incorrect, (maybe the kernel is using offsets
-		// from other opendir/readdir calls), it is harmless to reinforce that
-		// we have reached EOF.
-		return fuse.OK
-	}
+func (d *connectorDir) ReadDir(cancel <-chan struct{}, input *fuse.ReadIn, out *fuse.DirEntryList) (code fuse.Status) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
-	todo := d.stream[input.Offset:]
-	for _, e := range todo {
+	context := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	if ok, code := d.seek(context, input); !ok {
+		return code
+	}
+	for {
+		e, code := d.peek(context)
+		if !code.Ok() {
+			return code
+		}
+		if e == nil {
+			break
+		}
 		if e.Name == "" {
 			log.Printf("got empty directory entry, mode %o.", e.Mode)
+			d.advance()
 			continue
 		}
-		ok := out.AddDirEntry(e)
-		if !ok {
+		if !out.AddDirEntry(*e) {
 			break
 		}
+		d.advance()
 	}
 	return fuse.OK
 }
@@ -66,35 +203,31 @@ func (d *connectorDir) ReadDirPlus(cancel <-chan struct{}, input *fuse.ReadIn, o
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	// rewinddir() should be as if reopening directory.
-	if d.stream == nil || input.Offset == 0 {
-		d.stream, code = d.node.OpenDir(&fuse.Context{Caller: input.Caller, Cancel: cancel})
+	context := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	if ok, code := d.seek(context, input); !ok {
+		return code
+	}
+	for {
+		e, code := d.peek(context)
 		if !code.Ok() {
 			return code
 		}
-		d.stream = append(d.stream, d.inode.getMountDirEntries()...)
-		d.stream = append(d.stream,
-			fuse.DirEntry{Mode: fuse.S_IFDIR, Name: "."},
-			fuse.DirEntry{Mode: fuse.S_IFDIR, Name: ".."})
-	}
-
-	if input.Offset > uint64(len(d.stream)) {
-		// See comment in Readdir
-		return fuse.OK
-	}
-	todo := d.stream[input.Offset:]
-	for _, e := range todo {
+		if e == nil {
+			break
+		}
 		if e.Name == "" {
 			log.Printf("got empty directory entry, mode %o.", e.Mode)
+			d.advance()
 			continue
 		}
 
 		// we have to be sure entry will fit if we try to add
 		// it, or we'll mess up the lookup counts.
-		entryDest := out.AddDirLookupEntry(e)
+		entryDest := out.AddDirLookupEntry(*e)
 		if entryDest == nil {
 			break
 		}
+		d.advance()
 		entryDest.Ino = uint64(fuse.FUSE_UNKNOWN_INO)
 
 		// No need to fill attributes for . and ..
@@ -107,6 +240,13 @@ func (d *connectorDir) ReadDirPlus(cancel <-chan struct{}, input *fuse.ReadIn, o
 	return fuse.OK
 }
 
+// release closes the stream when the directory handle is released.
+func (d *connectorDir) release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.close()
+}
+
 type rawDir interface {
 	ReadDir(out *fuse.DirEntryList, input *fuse.ReadIn, c *fuse.Context) fuse.Status
 	ReadDirPlus(out *fuse.DirEntryList, input *fuse.ReadIn, c *fuse.Context) fuse.Status
@@ -6,6 +6,8 @@ package nodefs
 
 import (
 	"io/ioutil"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
@@ -128,3 +130,116 @@ func TestNodeRead(t *testing.T) {
 		t.Fatalf("got %q, want %q", content, want)
 	}
 }
+
+type contextFile struct {
+	forwardingFile
+	pid     uint32
+	flushed int32
+}
+
+func (f *contextFile) ReadContext(ctx *fuse.Context, dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	// Prefetches run without a caller.
+	if ctx.Pid != 0 {
+		atomic.StoreUint32(&f.pid, ctx.Pid)
+	}
+	data := []byte("ctx")
+	if off >= int64(len(data)) {
+		return fuse.ReadResultData(nil), fuse.OK
+	}
+	return fuse.ReadResultData(data[off:]), fuse.OK
+}
+
+func (f *contextFile) WriteContext(ctx *fuse.Context, data []byte, off int64) (uint32, fuse.Status) {
+	return 0, fuse.ENOSYS
+}
+
+func (f *contextFile) FlushContext(ctx *fuse.Context) fuse.Status {
+	if ctx.Pid != 0 {
+		atomic.StoreInt32(&f.flushed, 1)
+	}
+	return fuse.OK
+}
+
+func (f *contextFile) FsyncContext(ctx *fuse.Context, flags int) fuse.Status {
+	return fuse.ENOSYS
+}
+
+type contextFileNode struct {
+	Node
+	file *contextFile
+	wrap func(File) File
+}
+
+func (n *contextFileNode) Open(flags uint32, context *fuse.Context) (File, fuse.Status) {
+	return n.wrap(n.file), fuse.OK
+}
+
+func (n *contextFileNode) GetAttr(out *fuse.Attr, file File, context *fuse.Context) fuse.Status {
+	out.Mode = fuse.S_IFREG | 0644
+	out.Size = 3
+	return fuse.OK
+}
+
+func TestContextFile(t *testing.T) {
+	wrappers := map[string]func(File) File{
+		"plain":    func(f File) File { return f },
+		"locking":  func(f File) File { return NewLockingFile(&sync.Mutex{}, f) },
+		"readonly": NewReadOnlyFile,
+		"aligned": func(f File) File {
+			return NewAlignedFile(f, 1, false)
+		},
+		"coalescing": func(f File) File {
+			return NewCoalescingFile(f, 4096)
+		},
+		"readahead": func(f File) File {
+			return NewReadAheadFile(f, 4096)
+		},
+		"writebehind": func(f File) File {
+			return NewWriteBehindFile(f, 4096)
+		},
+	}
+	for name, wrap := range wrappers {
+		t.Run(name, func(t *testing.T) {
+			testContextFile(t, wrap)
+		})
+	}
+}
+
+func testContextFile(t *testing.T, wrap func(File) File) {
+	dir, err := ioutil.TempDir("", "nodefs")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+
+	root := NewDefaultNode()
+	s, _, err := MountRoot(dir, root, &Options{Debug: testutil.VerboseTest()})
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	defer s.Unmount()
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+
+	node := &contextFileNode{
+		Node: NewDefaultNode(),
+		file: &contextFile{forwardingFile: forwardingFile{NewDefaultFile()}},
+		wrap: wrap,
+	}
+	root.Inode().NewChild("file", false, node)
+
+	content, err := ioutil.ReadFile(dir + "/file")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(content) != "ctx" {
+		t.Fatalf("got %q, want %q", content, "ctx")
+	}
+	if atomic.LoadUint32(&node.file.pid) == 0 {
+		t.Errorf("ReadContext got no caller")
+	}
+	if atomic.LoadInt32(&node.file.flushed) == 0 {
+		t.Errorf("FlushContext was not called")
+	}
+}
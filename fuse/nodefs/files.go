@@ -121,6 +121,10 @@ func (f *loopbackFile) String() string {
 	return fmt.Sprintf("loopbackFile(%s)", f.File.Name())
 }
 
+func (f *loopbackFile) PassthroughFd() (int, bool) {
+	return int(f.File.Fd()), true
+}
+
 func (f *loopbackFile) Read(buf []byte, off int64) (res fuse.ReadResult, code fuse.Status) {
 	f.lock.Lock()
 	// This is not racy by virtue of the kernel properly
@@ -132,8 +136,13 @@ func (f *loopbackFile) Read(buf []byte, off int64) (res fuse.ReadResult, code fu
 
 func (f *loopbackFile) Write(data []byte, off int64) (uint32, fuse.Status) {
 	f.lock.Lock()
-	n, err := f.File.WriteAt(data, off)
+	// Unlike WriteAt, pwrite(2) works on files opened with
+	// O_APPEND, where it ignores off and appends.
+	n, err := syscall.Pwrite(int(f.File.Fd()), data, off)
 	f.lock.Unlock()
+	if n < 0 {
+		n = 0
+	}
 	return uint32(n), fuse.ToStatus(err)
 }
 
@@ -237,12 +246,21 @@ func (f *loopbackFile) Chmod(mode uint32) fuse.Status {
 
 func (f *loopbackFile) Chown(uid uint32, gid uint32) fuse.Status {
 	f.lock.Lock()
-	r := fuse.ToStatus(f.File.Chown(int(uid), int(gid)))
+	r := fuse.ToStatus(f.File.Chown(chownID(uid), chownID(gid)))
 	f.lock.Unlock()
 
 	return r
 }
 
+// chownID converts an ID passed to Chown for use with os.Chown,
+// which takes -1 for an ID that is left unchanged.
+func chownID(id uint32) int {
+	if id == ^uint32(0) {
+		return -1
+	}
+	return int(id)
+}
+
 func (f *loopbackFile) GetAttr(a *fuse.Attr) fuse.Status {
 	st := syscall.Stat_t{}
 	f.lock.Lock()
@@ -264,11 +282,11 @@ func (f *loopbackFile) GetAttr(a *fuse.Attr) fuse.Status {
 
 // NewReadOnlyFile wraps a File so all write operations are denied.
 func NewReadOnlyFile(f File) File {
-	return &readOnlyFile{File: f}
+	return &readOnlyFile{forwardingFile{f}}
 }
 
 type readOnlyFile struct {
-	File
+	forwardingFile
 }
 
 func (f *readOnlyFile) InnerFile() File {
@@ -283,22 +301,46 @@ func (f *readOnlyFile) Write(data []byte, off int64) (uint32, fuse.Status) {
 	return 0, fuse.EPERM
 }
 
+func (f *readOnlyFile) WriteContext(ctx *fuse.Context, data []byte, off int64) (uint32, fuse.Status) {
+	return 0, fuse.EPERM
+}
+
 func (f *readOnlyFile) Fsync(flag int) (code fuse.Status) {
 	return fuse.OK
 }
 
+func (f *readOnlyFile) FsyncContext(ctx *fuse.Context, flag int) (code fuse.Status) {
+	return fuse.OK
+}
+
 func (f *readOnlyFile) Truncate(size uint64) fuse.Status {
 	return fuse.EPERM
 }
 
+func (f *readOnlyFile) TruncateContext(ctx *fuse.Context, size uint64) fuse.Status {
+	return fuse.EPERM
+}
+
 func (f *readOnlyFile) Chmod(mode uint32) fuse.Status {
 	return fuse.EPERM
 }
 
+func (f *readOnlyFile) ChmodContext(ctx *fuse.Context, mode uint32) fuse.Status {
+	return fuse.EPERM
+}
+
 func (f *readOnlyFile) Chown(uid uint32, gid uint32) fuse.Status {
 	return fuse.EPERM
 }
 
+func (f *readOnlyFile) ChownContext(ctx *fuse.Context, uid uint32, gid uint32) fuse.Status {
+	return fuse.EPERM
+}
+
 func (f *readOnlyFile) Allocate(off uint64, sz uint64, mode uint32) fuse.Status {
 	return fuse.EPERM
 }
+
+func (f *readOnlyFile) AllocateContext(ctx *fuse.Context, off uint64, sz uint64, mode uint32) fuse.Status {
+	return fuse.EPERM
+}
@@ -11,8 +11,11 @@ package nodefs
 import (
 	"log"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 	"unsafe"
 
@@ -46,6 +49,10 @@ type FileSystemConnector struct {
 	// The lock is shared: several concurrent Lookups are allowed to be
 	// run simultaneously, while Forget is exclusive.
 	lookupLock sync.RWMutex
+
+	// Set to 1 once a file system is mounted below the root.
+	// Accessed atomically.
+	submounted int32
 }
 
 // NewOptions generates FUSE options that correspond to libfuse's
@@ -101,9 +108,10 @@ func (c *FileSystemConnector) verify() {
 	root.verify(c.rootNode.mountPoint)
 }
 
-// childLookup fills entry information for a newly created child inode
-func (c *rawBridge) childLookup(out *fuse.EntryOut, n *Inode, context *fuse.Context) {
-	n.Node().GetAttr(&out.Attr, nil, context)
+// childLookup fills entry information for a newly created child
+// inode, and returns the status of getting its attributes.
+func (c *rawBridge) childLookup(out *fuse.EntryOut, n *Inode, context *fuse.Context) fuse.Status {
+	code := n.Node().GetAttr(&out.Attr, nil, context)
 	n.mount.fillEntry(out)
 	out.NodeId, out.Generation = c.fsConn().lookupUpdate(n)
 	if out.Ino == 0 {
@@ -114,6 +122,7 @@ func (c *rawBridge) childLookup(out *fuse.EntryOut, n *Inode, context *fuse.Cont
 		// operations.
 		out.Nlink = 1
 	}
+	return code
 }
 
 func (c *rawBridge) toInode(nodeid uint64) *Inode {
@@ -124,6 +133,20 @@ func (c *rawBridge) toInode(nodeid uint64) *Inode {
 	return i
 }
 
+// liveInode returns the inode for nodeid. It returns ESTALE if the
+// node ID was reserved by LoadInodeTable, and ENOTCONN if the mount
+// of the inode was removed by LazyUnmount.
+func (c *rawBridge) liveInode(nodeid uint64) (*Inode, fuse.Status) {
+	node := c.toInode(nodeid)
+	if node == nil {
+		return nil, fuse.ESTALE
+	}
+	if node.mount.isDead() {
+		return nil, fuse.ENOTCONN
+	}
+	return node, fuse.OK
+}
+
 // Must run outside treeLock.  Returns the nodeId and generation.
 func (c *FileSystemConnector) lookupUpdate(node *Inode) (id, generation uint64) {
 	id, generation = c.inodeMap.Register(&node.handled)
@@ -135,7 +158,7 @@ func (c *FileSystemConnector) lookupUpdate(node *Inode) (id, generation uint64)
 // Must run outside treeLock.
 func (c *FileSystemConnector) forgetUpdate(nodeID uint64, forgetCount int) {
 	if nodeID == fuse.FUSE_ROOT_ID {
-		c.rootNode.Node().OnUnmount()
+		c.rootNode.mountPoint.onUnmount()
 
 		// We never got a lookup for root, so don't try to
 		// forget root.
@@ -145,6 +168,12 @@ func (c *FileSystemConnector) forgetUpdate(nodeID uint64, forgetCount int) {
 	// Prevent concurrent modification of the tree while we are processing
 	// the FORGET
 	node := (*Inode)(unsafe.Pointer(c.inodeMap.Decode(nodeID)))
+	if node == nil {
+		// A node ID reserved by LoadInodeTable. The kernel
+		// is done with it, so it may be handed out again.
+		c.inodeMap.Forget(nodeID, forgetCount)
+		return
+	}
 	node.mount.treeLock.Lock()
 	defer node.mount.treeLock.Unlock()
 
@@ -179,6 +208,38 @@ func (c *FileSystemConnector) InodeHandleCount() int {
 	return c.inodeMap.Count()
 }
 
+// MountInfo describes a file system mounted in a
+// FileSystemConnector.
+type MountInfo struct {
+	// Path of the mount point relative to the root of the FUSE
+	// mount; "" for the root file system.
+	Path string
+
+	// Root is the Node that was mounted.
+	Root Node
+
+	// Options of the mount.
+	Options *Options
+
+	// Inodes is the number of inodes of this mount that are in
+	// the tree, including the root.
+	Inodes int
+
+	// OpenFiles is the number of open files and directories.
+	OpenFiles int
+
+	mount *fileSystemMount
+}
+
+// Mounts returns the root file system and all submounts, sorted by
+// path, so a mount comes before the mounts below it.
+func (c *FileSystemConnector) Mounts() []MountInfo {
+	var out []MountInfo
+	c.rootNode.collectMounts("", &out)
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
 // Finds a node within the currently known inodes, returns the last
 // known node and the remaining unknown path components.  If parent is
 // nil, start from FUSE mountpoint.
@@ -244,9 +305,76 @@ func (c *FileSystemConnector) LookupNode(parent *Inode, path string) *Inode {
 	return parent
 }
 
+// maxSymlinks is the number of symbolic links ResolveNode follows
+// before failing with ELOOP. This is MAXSYMLINKS on Linux.
+const maxSymlinks = 40
+
+// ResolveNode is like LookupNode, but it follows symbolic links,
+// including one in the last component, and handles "." and ".."
+// components. Absolute link targets are resolved from the root of
+// the FUSE mount, as the connector does not know where it is
+// mounted. If parent is nil, resolution starts at the root.
+//
+// It returns ENOTDIR if a component other than the last is not a
+// directory, and ELOOP if resolving the path takes more than 40
+// symbolic links, which happens for symlink cycles.
+func (c *FileSystemConnector) ResolveNode(parent *Inode, path string) (*Inode, fuse.Status) {
+	if parent == nil {
+		parent = c.rootNode
+	}
+	node := parent
+	comps := strings.Split(path, "/")
+	links := 0
+	for len(comps) > 0 {
+		name := comps[0]
+		comps = comps[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			node = node.dotdot(c)
+			continue
+		}
+		if !node.IsDir() {
+			return nil, fuse.ENOTDIR
+		}
+
+		var a fuse.Attr
+		var dummy fuse.InHeader
+		child, code := c.internalLookup(nil, &a, node, name, &dummy)
+		if !code.Ok() {
+			return nil, code
+		}
+		if child == nil {
+			return nil, fuse.ENOENT
+		}
+		if a.Mode&syscall.S_IFMT != syscall.S_IFLNK {
+			node = child
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return nil, fuse.Status(syscall.ELOOP)
+		}
+		target, code := child.fsInode.Readlink(&fuse.Context{})
+		if !code.Ok() {
+			return nil, code
+		}
+		if strings.HasPrefix(string(target), "/") {
+			node = c.rootNode
+		}
+		comps = append(strings.Split(string(target), "/"), comps...)
+	}
+	return node, fuse.OK
+}
+
 func (c *FileSystemConnector) mountRoot(opts *Options) {
 	c.rootNode.mountFs(opts)
 	c.rootNode.mount.connector = c
+	if opts.StatsFile != "" {
+		c.rootNode.NewChild(opts.StatsFile, false, newStatsNode(c))
+	}
 	c.verify()
 }
 
@@ -255,34 +383,64 @@ func (c *FileSystemConnector) mountRoot(opts *Options) {
 // system are inherited.  The encompassing filesystem should pretend
 // the mount point does not exist.
 //
-// It returns ENOENT if the directory containing the mount point does
-// not exist, and EBUSY if the intended mount point already exists.
+// It returns ENOTDIR if parent is not a directory, and EINVAL if name
+// is not a valid file name. If the intended mount point already
+// exists, the MountPolicy of opts decides: by default, Mount returns
+// EBUSY.
+//
+// All submounts report the st_dev of the FUSE mount: the kernel
+// assigns the device number, and the protocol has no field to
+// override it. Tools that stay on one file system, such as find
+// -xdev, therefore cross into submounts. File systems that must
+// be told apart this way should be served by separate FUSE mounts.
 func (c *FileSystemConnector) Mount(parent *Inode, name string, root Node, opts *Options) fuse.Status {
 	node, code := c.lockMount(parent, name, root, opts)
 	if !code.Ok() {
 		return code
 	}
 
+	if node.mountPoint.shadowed != nil && c.server != nil {
+		// The kernel may still have the shadowed directory in
+		// its dentry cache.
+		c.EntryNotify(parent, name)
+	} else if parent.mount.options.CacheDirs && c.server != nil {
+		// The new mount point is listed in parent.
+		c.DirNotify(parent)
+	}
+	atomic.StoreInt32(&c.submounted, 1)
 	node.Node().OnMount(c)
 	return code
 }
 
 func (c *FileSystemConnector) lockMount(parent *Inode, name string, root Node, opts *Options) (*Inode, fuse.Status) {
 	defer c.verify()
-	parent.mount.treeLock.Lock()
-	defer parent.mount.treeLock.Unlock()
-	node := parent.children[name]
-	if node != nil {
-		return nil, fuse.EBUSY
+	if !parent.IsDir() {
+		return nil, fuse.ENOTDIR
+	}
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return nil, fuse.EINVAL
 	}
-
-	node = newInode(true, root)
 	if opts == nil {
 		opts = c.rootNode.mountPoint.options
 	}
 
+	parent.mount.treeLock.Lock()
+	defer parent.mount.treeLock.Unlock()
+	var shadowed *Inode
+	if old := parent.children[name]; old != nil {
+		if opts.MountPolicy != MountShadow || old.mountPoint != nil {
+			return nil, fuse.EBUSY
+		}
+		if !old.IsDir() {
+			return nil, fuse.ENOTDIR
+		}
+		shadowed = parent.rmChild(name)
+	}
+
+	node := newInode(true, root)
 	node.mountFs(opts)
 	node.mount.connector = c
+	node.mountPoint.shadowed = shadowed
 	parent.addChild(name, node)
 
 	node.mountPoint.parentInode = parent
@@ -330,7 +488,7 @@ func (c *FileSystemConnector) Unmount(node *Inode) fuse.Status {
 	}
 
 	delete(parentNode.children, name)
-	node.Node().OnUnmount()
+	mount.onUnmount()
 
 	parentId := c.inodeMap.Handle(&parentNode.handled)
 	if parentNode == c.rootNode {
@@ -372,7 +530,55 @@ func (c *FileSystemConnector) Unmount(node *Inode) fuse.Status {
 	mount.treeLock.Lock()
 	mount.mountInode = nil
 	node.mountPoint = nil
+	if mount.shadowed != nil && parentNode.children[name] == nil {
+		parentNode.addChild(name, mount.shadowed)
+	}
+
+	return fuse.OK
+}
 
+// LazyUnmount removes the mount at the given inode like `umount -l`:
+// the mount point disappears right away, even if files are still
+// open. Further operations on the inodes of the mount, other than
+// closing files, fail with ENOTCONN, and OnUnmount is called once
+// the last open file is released. It returns EINVAL if node is not
+// a mount point, and EBUSY if there are submounts below it.
+func (c *FileSystemConnector) LazyUnmount(node *Inode) fuse.Status {
+	if node.mountPoint == nil || node.mountPoint.parentInode == nil {
+		return fuse.EINVAL
+	}
+	mount := node.mountPoint
+	parentNode := mount.parentInode
+
+	parentNode.mount.treeLock.Lock()
+	mount.treeLock.Lock()
+	for _, ch := range node.children {
+		if !ch.canUnmountSubtree() {
+			mount.treeLock.Unlock()
+			parentNode.mount.treeLock.Unlock()
+			return fuse.EBUSY
+		}
+	}
+	name := mount.mountName()
+	delete(parentNode.children, name)
+	atomic.StoreInt32(&mount.dead, 1)
+	if mount.shadowed != nil {
+		parentNode.addChild(name, mount.shadowed)
+	}
+	mount.treeLock.Unlock()
+	parentNode.mount.treeLock.Unlock()
+
+	parentID := c.inodeMap.Handle(&parentNode.handled)
+	if parentNode == c.rootNode {
+		parentID = fuse.FUSE_ROOT_ID
+	}
+	if parentID != 0 {
+		c.server.DeleteNotify(parentID, c.inodeMap.Handle(&node.handled), name)
+	}
+
+	if mount.openFiles.Count() == 0 {
+		mount.onUnmount()
+	}
 	return fuse.OK
 }
 
@@ -395,6 +601,14 @@ func (c *FileSystemConnector) FileNotify(node *Inode, off int64, length int64) f
 	return c.server.InodeNotify(nID, off, length)
 }
 
+// DirNotify invalidates the kernel's cached listing of the directory
+// dir, for mounts with Options.CacheDirs. Changes made through the
+// mount invalidate the cache already; this is needed when the
+// contents change otherwise, eg. in a remote backend.
+func (c *FileSystemConnector) DirNotify(dir *Inode) fuse.Status {
+	return c.FileNotify(dir, 0, 0)
+}
+
 // FileNotifyStoreCache notifies the kernel about changed data of the inode.
 //
 // This call is similar to FileNotify, but instead of only invalidating a data
@@ -0,0 +1,890 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestMounts(t *testing.T) {
+	root := NewDefaultNode()
+	c := NewFileSystemConnector(root, nil)
+	rootInode := root.Inode()
+	dir := rootInode.NewChild("dir", true, NewDefaultNode())
+	dir.NewChild("file", false, NewDefaultNode())
+
+	sub := NewDefaultNode()
+	if code := c.Mount(dir, "sub", sub, nil); !code.Ok() {
+		t.Fatalf("Mount: %v", code)
+	}
+	sub.Inode().NewChild("a", false, NewDefaultNode())
+
+	mounts := c.Mounts()
+	if len(mounts) != 2 {
+		t.Fatalf("got %d mounts, want 2", len(mounts))
+	}
+	if m := mounts[0]; m.Path != "" || m.Root != root || m.Inodes != 3 || m.OpenFiles != 0 {
+		t.Errorf("got root mount %+v", m)
+	}
+	if m := mounts[1]; m.Path != "dir/sub" || m.Root != sub || m.Inodes != 2 {
+		t.Errorf("got submount %+v", m)
+	}
+}
+
+func TestMountPolicy(t *testing.T) {
+	root := NewDefaultNode()
+	c := NewFileSystemConnector(root, nil)
+	dir := root.Inode().NewChild("dir", true, NewDefaultNode())
+	file := root.Inode().NewChild("file", false, NewDefaultNode())
+
+	if code := c.Mount(file, "sub", NewDefaultNode(), nil); code != fuse.ENOTDIR {
+		t.Errorf("Mount below file: got %v, want ENOTDIR", code)
+	}
+	if code := c.Mount(root.Inode(), "a/b", NewDefaultNode(), nil); code != fuse.EINVAL {
+		t.Errorf("Mount on a/b: got %v, want EINVAL", code)
+	}
+	if code := c.Mount(root.Inode(), "dir", NewDefaultNode(), nil); code != fuse.EBUSY {
+		t.Errorf("Mount on dir: got %v, want EBUSY", code)
+	}
+
+	opts := NewOptions()
+	opts.MountPolicy = MountShadow
+	if code := c.Mount(root.Inode(), "file", NewDefaultNode(), opts); code != fuse.ENOTDIR {
+		t.Errorf("shadow file: got %v, want ENOTDIR", code)
+	}
+	sub := NewDefaultNode()
+	if code := c.Mount(root.Inode(), "dir", sub, opts); !code.Ok() {
+		t.Fatalf("shadow dir: %v", code)
+	}
+	if got := root.Inode().GetChild("dir"); got != sub.Inode() {
+		t.Errorf("got child %v, want mount root %v", got, sub.Inode())
+	}
+	if sub.Inode().mountPoint.shadowed != dir {
+		t.Errorf("shadowed directory not recorded")
+	}
+	if code := c.Mount(root.Inode(), "dir", NewDefaultNode(), opts); code != fuse.EBUSY {
+		t.Errorf("shadow mount point: got %v, want EBUSY", code)
+	}
+}
+
+func TestGetFile(t *testing.T) {
+	root := NewDefaultNode()
+	NewFileSystemConnector(root, nil)
+	m := root.Inode().mountPoint
+
+	if f := m.getFile(0); f != nil {
+		t.Errorf("getFile(0) = %#v, want nil", f)
+	}
+	file := NewDefaultFile()
+	h, _ := m.registerFileHandle(root.Inode(), nil, &WithFlags{File: file}, 0)
+	if f := m.getFile(h); f != file {
+		t.Errorf("getFile(%d) = %v, want %v", h, f, file)
+	}
+	m.unregisterFileHandle(h, root.Inode())
+}
+
+type setAttrRecorder struct {
+	Node
+	log []string
+}
+
+func (n *setAttrRecorder) Chown(file File, uid uint32, gid uint32, context *fuse.Context) fuse.Status {
+	n.log = append(n.log, "chown")
+	return fuse.EPERM
+}
+
+func (n *setAttrRecorder) Chmod(file File, perms uint32, context *fuse.Context) fuse.Status {
+	n.log = append(n.log, "chmod")
+	return fuse.OK
+}
+
+func (n *setAttrRecorder) Truncate(file File, size uint64, context *fuse.Context) fuse.Status {
+	n.log = append(n.log, "truncate")
+	return fuse.EIO
+}
+
+func (n *setAttrRecorder) Utimens(file File, atime *time.Time, mtime *time.Time, context *fuse.Context) fuse.Status {
+	n.log = append(n.log, "utimens")
+	return fuse.OK
+}
+
+func TestSetAttrs(t *testing.T) {
+	n := &setAttrRecorder{Node: NewDefaultNode()}
+	input := &fuse.SetAttrIn{
+		SetAttrInCommon: fuse.SetAttrInCommon{
+			Valid: fuse.FATTR_MODE | fuse.FATTR_UID | fuse.FATTR_SIZE | fuse.FATTR_MTIME,
+		},
+	}
+	if code := setAttrs(n, input, nil, &fuse.Context{}); code != fuse.EPERM {
+		t.Errorf("got %v, want EPERM", code)
+	}
+	if got, want := strings.Join(n.log, ","), "chown,chmod,truncate,utimens"; got != want {
+		t.Errorf("got calls %s, want %s", got, want)
+	}
+}
+
+type ctimeRecorder struct {
+	Node
+	ctime time.Time
+}
+
+func (n *ctimeRecorder) SetCtime(file File, ctime time.Time, context *fuse.Context) fuse.Status {
+	n.ctime = ctime
+	return fuse.OK
+}
+
+func TestSetAttrsCtime(t *testing.T) {
+	input := &fuse.SetAttrIn{
+		SetAttrInCommon: fuse.SetAttrInCommon{
+			Valid:     fuse.FATTR_CTIME,
+			Ctime:     1234,
+			Ctimensec: 5678,
+		},
+	}
+	n := &ctimeRecorder{Node: NewDefaultNode()}
+	if code := setAttrs(n, input, nil, &fuse.Context{}); !code.Ok() {
+		t.Fatalf("setAttrs: %v", code)
+	}
+	if want := time.Unix(1234, 5678); !n.ctime.Equal(want) {
+		t.Errorf("got ctime %v, want %v", n.ctime, want)
+	}
+
+	// Nodes that do not keep the ctime ignore it.
+	if code := setAttrs(NewDefaultNode(), input, nil, &fuse.Context{}); !code.Ok() {
+		t.Errorf("setAttrs without CtimeSetter: %v", code)
+	}
+}
+
+type accessCounter struct {
+	Node
+	calls int
+}
+
+func (n *accessCounter) Access(mode uint32, context *fuse.Context) fuse.Status {
+	n.calls++
+	return n.Node.Access(mode, context)
+}
+
+func TestAccessENOSYS(t *testing.T) {
+	root := &accessCounter{Node: NewDefaultNode()}
+	c := NewFileSystemConnector(root, nil)
+	raw := c.RawFS()
+	in := &fuse.AccessIn{InHeader: fuse.InHeader{NodeId: fuse.FUSE_ROOT_ID}}
+
+	for i := 0; i < 2; i++ {
+		if code := raw.Access(nil, in); code != fuse.ENOSYS {
+			t.Errorf("Access: got %v, want ENOSYS", code)
+		}
+	}
+	if root.calls != 1 {
+		t.Errorf("got %d calls, want 1", root.calls)
+	}
+
+	// With a submount, ENOSYS would also disable Access for the
+	// submount, so the request is granted instead.
+	if code := c.Mount(root.Inode(), "sub", NewDefaultNode(), nil); !code.Ok() {
+		t.Fatalf("Mount: %v", code)
+	}
+	if code := raw.Access(nil, in); code != fuse.OK {
+		t.Errorf("Access with submount: got %v, want OK", code)
+	}
+	if root.calls != 1 {
+		t.Errorf("got %d calls, want 1", root.calls)
+	}
+}
+
+func TestLookupDots(t *testing.T) {
+	root := NewDefaultNode()
+	c := NewFileSystemConnector(root, nil)
+	raw := c.RawFS()
+	dir := root.Inode().NewChild("dir", true, NewDefaultNode())
+	sub := NewDefaultNode()
+	if code := c.Mount(dir, "sub", sub, nil); !code.Ok() {
+		t.Fatalf("Mount: %v", code)
+	}
+
+	lookup := func(id uint64, name string) (fuse.EntryOut, fuse.Status) {
+		var out fuse.EntryOut
+		code := raw.Lookup(nil, &fuse.InHeader{NodeId: id}, name, &out)
+		return out, code
+	}
+
+	dirOut, code := lookup(fuse.FUSE_ROOT_ID, "dir")
+	if !code.Ok() {
+		t.Fatalf("Lookup dir: %v", code)
+	}
+	subOut, code := lookup(dirOut.NodeId, "sub")
+	if !code.Ok() {
+		t.Fatalf("Lookup sub: %v", code)
+	}
+
+	if out, code := lookup(dirOut.NodeId, "."); !code.Ok() || out.NodeId != dirOut.NodeId || out.Generation != dirOut.Generation {
+		t.Errorf(`Lookup dir/.: got %v %+v, want %+v`, code, out, dirOut)
+	}
+	if out, code := lookup(subOut.NodeId, ".."); !code.Ok() || out.NodeId != dirOut.NodeId {
+		t.Errorf(`Lookup sub/..: got %v %+v, want node %d`, code, out, dirOut.NodeId)
+	}
+	if out, code := lookup(dirOut.NodeId, ".."); !code.Ok() || out.NodeId != fuse.FUSE_ROOT_ID {
+		t.Errorf(`Lookup dir/..: got %v %+v, want root`, code, out)
+	}
+
+	// dir was looked up three times.
+	raw.Forget(subOut.NodeId, 1)
+	raw.Forget(dirOut.NodeId, 3)
+	if _, code := lookup(dirOut.NodeId, "."); code != fuse.ESTALE {
+		t.Errorf(`Lookup of forgotten node: got %v, want ESTALE`, code)
+	}
+	if _, code := lookup(1<<40, "."); code != fuse.ESTALE {
+		t.Errorf(`Lookup of unknown node: got %v, want ESTALE`, code)
+	}
+}
+
+func TestInodeTable(t *testing.T) {
+	newTree := func(withGone bool) (*FileSystemConnector, fuse.RawFileSystem) {
+		root := NewDefaultNode()
+		c := NewFileSystemConnector(root, nil)
+		dir := root.Inode().NewChild("dir", true, NewDefaultNode())
+		dir.NewChild("file", false, NewDefaultNode())
+		if withGone {
+			dir.NewChild("gone", false, NewDefaultNode())
+		}
+		root.Inode().NewChild("new", false, NewDefaultNode())
+		root.Inode().NewChild("newer", false, NewDefaultNode())
+		return c, c.RawFS()
+	}
+	lookup := func(raw fuse.RawFileSystem, id uint64, name string) fuse.EntryOut {
+		var out fuse.EntryOut
+		if code := raw.Lookup(nil, &fuse.InHeader{NodeId: id}, name, &out); !code.Ok() {
+			t.Fatalf("Lookup %q: %v", name, code)
+		}
+		return out
+	}
+
+	c, raw := newTree(true)
+	dirOut := lookup(raw, fuse.FUSE_ROOT_ID, "dir")
+	lookup(raw, fuse.FUSE_ROOT_ID, "dir")
+	goneOut := lookup(raw, dirOut.NodeId, "gone")
+	fileOut := lookup(raw, dirOut.NodeId, "file")
+
+	var buf bytes.Buffer
+	if err := c.SaveInodeTable(&buf); err != nil {
+		t.Fatalf("SaveInodeTable: %v", err)
+	}
+
+	c, raw = newTree(false)
+	missing, err := c.LoadInodeTable(&buf)
+	if err != nil {
+		t.Fatalf("LoadInodeTable: %v", err)
+	}
+	if want := []string{"dir/gone"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("got missing %v, want %v", missing, want)
+	}
+
+	if out := lookup(raw, fuse.FUSE_ROOT_ID, "dir"); out.NodeId != dirOut.NodeId || out.Generation != dirOut.Generation {
+		t.Errorf("dir: got %d/%d, want %d/%d", out.NodeId, out.Generation, dirOut.NodeId, dirOut.Generation)
+	}
+	if out := lookup(raw, dirOut.NodeId, "file"); out.NodeId != fileOut.NodeId || out.Generation != fileOut.Generation {
+		t.Errorf("file: got %d/%d, want %d/%d", out.NodeId, out.Generation, fileOut.NodeId, fileOut.Generation)
+	}
+	if got := c.rootNode.GetChild("dir").handled.count; got != 3 {
+		t.Errorf("dir lookup count: got %d, want 3", got)
+	}
+
+	var out fuse.EntryOut
+	if code := raw.Lookup(nil, &fuse.InHeader{NodeId: goneOut.NodeId}, ".", &out); code != fuse.ESTALE {
+		t.Errorf("Lookup of missing node: got %v, want ESTALE", code)
+	}
+	if out := lookup(raw, fuse.FUSE_ROOT_ID, "new"); out.NodeId == goneOut.NodeId || out.Generation <= fileOut.Generation {
+		t.Errorf("new node reuses old ID: got %d/%d", out.NodeId, out.Generation)
+	}
+
+	var attrOut fuse.AttrOut
+	if code := raw.GetAttr(nil, &fuse.GetAttrIn{InHeader: fuse.InHeader{NodeId: goneOut.NodeId}}, &attrOut); code != fuse.ESTALE {
+		t.Errorf("GetAttr of missing node: got %v, want ESTALE", code)
+	}
+	raw.Forget(goneOut.NodeId, 1)
+	if out := lookup(raw, fuse.FUSE_ROOT_ID, "newer"); out.NodeId != goneOut.NodeId {
+		t.Errorf("forgotten reserved ID: got ID %d for new node, want %d", out.NodeId, goneOut.NodeId)
+	}
+}
+
+func TestStatsFile(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+
+	backing := testutil.TempDir()
+	defer os.RemoveAll(backing)
+	root := NewMemNodeFSRoot(backing + "/")
+	s, _, err := Mount(dir, root, &fuse.MountOptions{
+		EnableStats: true,
+		Debug:       testutil.VerboseTest(),
+	}, &Options{StatsFile: ".fuse-stats"})
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	defer s.Unmount()
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+
+	if err := ioutil.WriteFile(dir+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	content, err := ioutil.ReadFile(dir + "/.fuse-stats")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	for _, want := range []string{"inodes: ", "mounts: 1\n", "open_files: ", "LOOKUP: count="} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("stats %q missing %q", content, want)
+		}
+	}
+
+	if err := ioutil.WriteFile(dir+"/.fuse-stats", []byte("x"), 0644); err == nil {
+		t.Errorf("writing stats file succeeded")
+	}
+}
+
+func TestServeControl(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	mnt := dir + "/mnt"
+	backing := dir + "/backing/"
+	if err := os.Mkdir(mnt, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(backing, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := NewOptions()
+	s, c, err := Mount(mnt, NewMemNodeFSRoot(backing), &fuse.MountOptions{
+		Debug: testutil.VerboseTest(),
+	}, opts)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	serveDone := make(chan struct{})
+	go func() {
+		s.Serve()
+		close(serveDone)
+	}()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	l, err := net.Listen("unix", dir+"/control")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	go c.ServeControl(l)
+
+	conn, err := net.Dial("unix", dir+"/control")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	// command returns the output of cmd, including the final
+	// ok or error line.
+	command := func(cmd string) string {
+		if _, err := fmt.Fprintf(conn, "%s\n", cmd); err != nil {
+			t.Fatalf("write %q: %v", cmd, err)
+		}
+		var out string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read reply to %q: %v", cmd, err)
+			}
+			out += line
+			if line == "ok\n" || strings.HasPrefix(line, "error: ") {
+				return strings.TrimSpace(out)
+			}
+		}
+	}
+
+	if err := ioutil.WriteFile(mnt+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	// Timeouts change while requests are served.
+	stop := make(chan struct{})
+	statDone := make(chan struct{})
+	go func() {
+		defer close(statDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				os.Stat(mnt + "/file")
+				os.Stat(mnt + "/missing")
+			}
+		}
+	}()
+	for _, cmd := range []string{"entry-timeout 0s", "negative-timeout 1s", "attr-timeout 0s", "attr-timeout 5s"} {
+		if got := command(cmd); got != "ok" {
+			t.Errorf("%s: got %q", cmd, got)
+		}
+	}
+	close(stop)
+	<-statDone
+	if got := c.Mounts()[0].Options.AttrTimeout; got != 5*time.Second {
+		t.Errorf("got AttrTimeout %v, want 5s", got)
+	}
+	if opts.AttrTimeout != time.Second {
+		t.Errorf("caller's Options modified: %v", opts.AttrTimeout)
+	}
+	if got := command("flush"); got != "ok" {
+		t.Errorf("flush: got %q", got)
+	}
+	if got := command("attr-timeout -1s"); !strings.HasPrefix(got, "error: ") {
+		t.Errorf("negative timeout: got %q", got)
+	}
+	if got := command("bogus"); !strings.HasPrefix(got, "error: ") {
+		t.Errorf("bogus: got %q", got)
+	}
+	if got := command("stats"); !strings.HasPrefix(got, "inodes: ") || !strings.HasSuffix(got, "\nok") {
+		t.Errorf("stats: got %q", got)
+	}
+	if _, err := ioutil.ReadFile(mnt + "/file"); err != nil {
+		t.Errorf("ReadFile after flush: %v", err)
+	}
+
+	if got := command("unmount"); got != "ok" {
+		t.Fatalf("unmount: got %q", got)
+	}
+	select {
+	case <-serveDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after unmount")
+	}
+}
+
+type testSymlinkNode struct {
+	Node
+	target string
+}
+
+func (n *testSymlinkNode) GetAttr(out *fuse.Attr, file File, context *fuse.Context) fuse.Status {
+	out.Mode = fuse.S_IFLNK | 0777
+	return fuse.OK
+}
+
+func (n *testSymlinkNode) Readlink(c *fuse.Context) ([]byte, fuse.Status) {
+	return []byte(n.target), fuse.OK
+}
+
+func TestResolveNode(t *testing.T) {
+	root := NewDefaultNode()
+	c := NewFileSystemConnector(root, nil)
+	symlink := func(parent *Inode, name, target string) {
+		parent.NewChild(name, false, &testSymlinkNode{Node: NewDefaultNode(), target: target})
+	}
+	dir := root.Inode().NewChild("dir", true, NewDefaultNode())
+	file := dir.NewChild("file", false, NewDefaultNode())
+	symlink(root.Inode(), "rel", "dir/file")
+	symlink(dir, "up", "../rel")
+	symlink(dir, "abs", "/dir")
+	symlink(root.Inode(), "loop1", "loop2")
+	symlink(root.Inode(), "loop2", "loop1")
+	symlink(root.Inode(), "self", "self/x")
+
+	for _, tc := range []struct {
+		path string
+		want *Inode
+		code fuse.Status
+	}{
+		{"dir/file", file, fuse.OK},
+		{"rel", file, fuse.OK},
+		{"dir/up", file, fuse.OK},
+		{"dir/abs/./file", file, fuse.OK},
+		{"../dir/abs/../dir/file", file, fuse.OK},
+		{"", root.Inode(), fuse.OK},
+		{"rel/x", nil, fuse.ENOTDIR},
+		{"loop1", nil, fuse.Status(syscall.ELOOP)},
+		{"self", nil, fuse.Status(syscall.ELOOP)},
+	} {
+		got, code := c.ResolveNode(nil, tc.path)
+		if got != tc.want || code != tc.code {
+			t.Errorf("ResolveNode(%q): got %v, %v want %v, %v", tc.path, got, code, tc.want, tc.code)
+		}
+	}
+}
+
+func TestNameLengthLimits(t *testing.T) {
+	root := NewDefaultNode()
+	c := NewFileSystemConnector(root, &Options{MaxNameLength: 8, MaxPathLength: 16})
+	raw := c.RawFS()
+	dir := root.Inode().NewChild("dir", true, NewDefaultNode())
+	dir.NewChild("file", false, NewDefaultNode())
+
+	var out fuse.EntryOut
+	if code := raw.Lookup(nil, &fuse.InHeader{NodeId: fuse.FUSE_ROOT_ID}, "dir", &out); !code.Ok() {
+		t.Fatalf("Lookup: %v", code)
+	}
+	dirID := out.NodeId
+	if code := raw.Lookup(nil, &fuse.InHeader{NodeId: fuse.FUSE_ROOT_ID}, "123456789", &out); code != fuse.Status(syscall.ENAMETOOLONG) {
+		t.Errorf("Lookup(long name): got %v, want ENAMETOOLONG", code)
+	}
+	// "/dir/12345678" is 13 bytes, plus the NUL byte.
+	if code := raw.Mkdir(nil, &fuse.MkdirIn{InHeader: fuse.InHeader{NodeId: dirID}}, "12345678", &out); code != fuse.ENOSYS {
+		t.Errorf("Mkdir: got %v, want ENOSYS from the file system", code)
+	}
+	if code := raw.Mkdir(nil, &fuse.MkdirIn{InHeader: fuse.InHeader{NodeId: dirID}}, "1234567890", &out); code != fuse.Status(syscall.ENAMETOOLONG) {
+		t.Errorf("Mkdir(long name): got %v, want ENAMETOOLONG", code)
+	}
+
+	dir.NewChild("sub", true, NewDefaultNode())
+	if code := raw.Lookup(nil, &fuse.InHeader{NodeId: dirID}, "sub", &out); !code.Ok() {
+		t.Fatalf("Lookup(sub): %v", code)
+	}
+	// "/dir/sub/abcdefg" is 16 bytes.
+	if code := raw.Lookup(nil, &fuse.InHeader{NodeId: out.NodeId}, "abcdefg", &out); code != fuse.Status(syscall.ENAMETOOLONG) {
+		t.Errorf("Lookup(long path): got %v, want ENAMETOOLONG", code)
+	}
+}
+
+type countingDirNode struct {
+	Node
+	n        int
+	produced int64
+	closed   chan struct{}
+}
+
+func (n *countingDirNode) OpenDirStream(context *fuse.Context) (DirStream, fuse.Status) {
+	return &countingDirStream{node: n}, fuse.OK
+}
+
+type countingDirStream struct {
+	node *countingDirNode
+	i    int
+}
+
+func (s *countingDirStream) HasNext() bool {
+	return s.i < s.node.n
+}
+
+func (s *countingDirStream) Next() (fuse.DirEntry, fuse.Status) {
+	atomic.AddInt64(&s.node.produced, 1)
+	s.i++
+	return fuse.DirEntry{Name: fmt.Sprintf("file%05d", s.i), Mode: fuse.S_IFREG}, fuse.OK
+}
+
+func (s *countingDirStream) Close() {
+	close(s.node.closed)
+}
+
+func TestDirStream(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+
+	root := &countingDirNode{Node: NewDefaultNode(), n: 10000, closed: make(chan struct{})}
+	s, _, err := MountRoot(dir, root, &Options{Debug: testutil.VerboseTest()})
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	defer s.Unmount()
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	names, err := f.Readdirnames(10)
+	if err != nil || len(names) != 10 {
+		t.Fatalf("Readdirnames: %v, %v", names, err)
+	}
+	if got := atomic.LoadInt64(&root.produced); got >= int64(root.n) {
+		t.Errorf("produced %d entries before the first reply", got)
+	}
+
+	rest, err := f.Readdirnames(-1)
+	if err != nil {
+		t.Fatalf("Readdirnames: %v", err)
+	}
+	names = append(names, rest...)
+	if len(names) != root.n {
+		t.Errorf("got %d entries, want %d", len(names), root.n)
+	}
+	for i, n := range names {
+		if want := fmt.Sprintf("file%05d", i+1); n != want {
+			t.Fatalf("entry %d: got %q, want %q", i, n, want)
+		}
+	}
+
+	f.Close()
+	select {
+	case <-root.closed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("stream was not closed on release")
+	}
+}
+
+type openDirCounter struct {
+	Node
+	opens int64
+}
+
+func (n *openDirCounter) OpenDir(context *fuse.Context) ([]fuse.DirEntry, fuse.Status) {
+	atomic.AddInt64(&n.opens, 1)
+	return []fuse.DirEntry{{Name: "file", Mode: fuse.S_IFREG}}, fuse.OK
+}
+
+func TestCacheDirs(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+
+	root := &openDirCounter{Node: NewDefaultNode()}
+	s, c, err := MountRoot(dir, root, &Options{
+		CacheDirs:   true,
+		AttrTimeout: time.Hour,
+		Debug:       testutil.VerboseTest(),
+	})
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	defer s.Unmount()
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	if _, minor := s.ProtocolVersion(); minor < 28 {
+		t.Skip("kernel does not support FOPEN_CACHE_DIR")
+	}
+
+	list := func() {
+		f, err := os.Open(dir)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		defer f.Close()
+		names, err := f.Readdirnames(-1)
+		if err != nil || len(names) != 1 {
+			t.Fatalf("Readdirnames: %v, %v", names, err)
+		}
+	}
+	list()
+	list()
+	if got := atomic.LoadInt64(&root.opens); got != 1 {
+		t.Errorf("got %d OpenDir calls, want 1", got)
+	}
+
+	if code := c.DirNotify(c.rootNode); !code.Ok() {
+		t.Fatalf("DirNotify: %v", code)
+	}
+	list()
+	if got := atomic.LoadInt64(&root.opens); got != 2 {
+		t.Errorf("after DirNotify: got %d OpenDir calls, want 2", got)
+	}
+}
+
+type statFsNode struct {
+	Node
+	st fuse.StatfsOut
+}
+
+func (n *statFsNode) StatFs() *fuse.StatfsOut {
+	st := n.st
+	return &st
+}
+
+func TestStatFsAggregate(t *testing.T) {
+	root := &statFsNode{NewDefaultNode(), fuse.StatfsOut{Blocks: 100, Files: 10, Frsize: 4096}}
+	opts := NewOptions()
+	opts.StatFsPolicy = StatFsAggregate
+	c := NewFileSystemConnector(root, opts)
+	raw := c.RawFS()
+
+	dir := root.Inode().NewChild("dir", true, NewDefaultNode())
+	sub := &statFsNode{NewDefaultNode(), fuse.StatfsOut{Blocks: 10, Files: 1, Frsize: 8192}}
+	if code := c.Mount(dir, "sub", sub, nil); !code.Ok() {
+		t.Fatalf("Mount: %v", code)
+	}
+	nested := &statFsNode{NewDefaultNode(), fuse.StatfsOut{Blocks: 5, Files: 2, Bsize: 4096}}
+	if code := c.Mount(sub.Inode(), "nested", nested, nil); !code.Ok() {
+		t.Fatalf("Mount: %v", code)
+	}
+
+	var out fuse.StatfsOut
+	if code := raw.StatFs(nil, &fuse.InHeader{NodeId: fuse.FUSE_ROOT_ID}, &out); !code.Ok() {
+		t.Fatalf("StatFs: %v", code)
+	}
+	if out.Blocks != 125 || out.Files != 13 || out.Frsize != 4096 {
+		t.Errorf("got %d blocks, %d files of frsize %d, want 125, 13, 4096", out.Blocks, out.Files, out.Frsize)
+	}
+
+	// The submount inherits the policy, and adds the nested mount.
+	id, _ := c.inodeMap.Register(&sub.Inode().handled)
+	if code := raw.StatFs(nil, &fuse.InHeader{NodeId: id}, &out); !code.Ok() {
+		t.Fatalf("StatFs: %v", code)
+	}
+	if out.Blocks != 12 {
+		t.Errorf("submount: got %d blocks, want 12", out.Blocks)
+	}
+}
+
+type atimeNode struct {
+	Node
+	attr    fuse.Attr
+	utimens int
+}
+
+func (n *atimeNode) Read(file File, dest []byte, off int64, context *fuse.Context) (fuse.ReadResult, fuse.Status) {
+	return fuse.ReadResultData(nil), fuse.OK
+}
+
+func (n *atimeNode) GetAttr(out *fuse.Attr, file File, context *fuse.Context) fuse.Status {
+	*out = n.attr
+	return fuse.OK
+}
+
+func (n *atimeNode) Utimens(file File, atime *time.Time, mtime *time.Time, context *fuse.Context) fuse.Status {
+	n.utimens++
+	n.attr.Atime = uint64(atime.Unix())
+	return fuse.OK
+}
+
+func TestAtimePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy AtimePolicy
+		want   int
+	}{
+		{NoAtime, 0},
+		{RelAtime, 1},
+		{StrictAtime, 2},
+	} {
+		opts := NewOptions()
+		opts.AtimePolicy = tc.policy
+		root := NewDefaultNode()
+		c := NewFileSystemConnector(root, opts)
+
+		// Modified after it was last read.
+		now := uint64(time.Now().Unix())
+		node := &atimeNode{
+			Node: NewDefaultNode(),
+			attr: fuse.Attr{Mode: fuse.S_IFREG | 0644, Atime: now - 10, Mtime: now - 5, Ctime: now - 5},
+		}
+		ch := root.Inode().NewChild("file", false, node)
+		id, _ := c.inodeMap.Register(&ch.handled)
+
+		for i := 0; i < 2; i++ {
+			in := &fuse.ReadIn{InHeader: fuse.InHeader{NodeId: id}, Size: 10}
+			if _, code := c.RawFS().Read(nil, in, make([]byte, 10)); !code.Ok() {
+				t.Fatalf("Read: %v", code)
+			}
+		}
+		if node.utimens != tc.want {
+			t.Errorf("policy %d: got %d Utimens calls, want %d", tc.policy, node.utimens, tc.want)
+		}
+	}
+}
+
+// concurrencyFile records the highest number of concurrent Write
+// calls.
+type concurrencyFile struct {
+	File
+	mu      sync.Mutex
+	active  int
+	highest int
+}
+
+func (f *concurrencyFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	f.mu.Lock()
+	f.active++
+	if f.active > f.highest {
+		f.highest = f.active
+	}
+	f.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	f.mu.Lock()
+	f.active--
+	f.mu.Unlock()
+	return uint32(len(data)), fuse.OK
+}
+
+func TestSerializeWrites(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		mountWide  bool
+		withFlags  bool
+		concurrent bool
+	}{
+		{"default", false, false, true},
+		{"mount", true, false, false},
+		{"handle", false, true, false},
+	} {
+		opts := NewOptions()
+		opts.SerializeWrites = tc.mountWide
+		root := NewDefaultNode()
+		c := NewFileSystemConnector(root, opts)
+
+		ch := root.Inode().NewChild("file", false, NewDefaultNode())
+		id, _ := c.inodeMap.Register(&ch.handled)
+		f := &concurrencyFile{File: NewDefaultFile()}
+		var registered File = f
+		if tc.withFlags {
+			registered = &WithFlags{File: f, SerializeWrites: true}
+		}
+		fh, _ := ch.mount.registerFileHandle(ch, nil, registered, uint32(os.O_WRONLY))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				in := &fuse.WriteIn{InHeader: fuse.InHeader{NodeId: id}, Fh: fh, Offset: uint64(i), Size: 1}
+				c.RawFS().Write(nil, in, []byte{'x'})
+			}(i)
+		}
+		wg.Wait()
+
+		if got := f.highest > 1; got != tc.concurrent {
+			t.Errorf("%s: got %d concurrent writes", tc.name, f.highest)
+		}
+	}
+}
+
+func TestDefaultOwner(t *testing.T) {
+	m := &fileSystemMount{options: &Options{
+		DefaultOwner: &fuse.Owner{Uid: 42, Gid: 43},
+	}}
+	a := fuse.Attr{Owner: fuse.Owner{Uid: 5}}
+	m.setOwner(&a)
+	if a.Uid != 5 || a.Gid != 43 {
+		t.Errorf("got owner %d:%d, want 5:43", a.Uid, a.Gid)
+	}
+
+	// Owner replaces all owners.
+	m.options.Owner = &fuse.Owner{Uid: 7, Gid: 8}
+	a = fuse.Attr{Owner: fuse.Owner{Uid: 5}}
+	m.setOwner(&a)
+	if a.Uid != 7 || a.Gid != 8 {
+		t.Errorf("got owner %d:%d, want 7:8", a.Uid, a.Gid)
+	}
+}
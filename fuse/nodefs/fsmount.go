@@ -7,6 +7,9 @@ package nodefs
 import (
 	"log"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
@@ -19,15 +22,53 @@ type openedFile struct {
 	WithFlags
 
 	dir *connectorDir
+
+	// Set to 1 by Flush. Accessed atomically.
+	flushed int32
+
+	// backingId is the kernel's ID for the passthrough backing
+	// file, or 0.
+	backingId int32
+
+	// writeMu serializes writes if WithFlags.SerializeWrites or
+	// Options.SerializeWrites is set.
+	writeMu sync.Mutex
+}
+
+func (f *openedFile) releaseInfo(input *fuse.ReleaseIn) *ReleaseInfo {
+	return &ReleaseInfo{
+		OpenFlags:    f.WithFlags.OpenFlags,
+		ReleaseFlags: input.ReleaseFlags,
+		Flushed:      atomic.LoadInt32(&f.flushed) != 0,
+	}
 }
 
 type fileSystemMount struct {
+	// Timeouts from the options, in nanoseconds, which
+	// ServeControl can change while requests are served. Accessed
+	// atomically, so they come first for 64-bit alignment.
+	entryTimeout    int64
+	attrTimeout     int64
+	negativeTimeout int64
+
 	// Node that we were mounted on.
 	mountInode *Inode
 
 	// Parent to the mountInode.
 	parentInode *Inode
 
+	// Inode hidden by this mount; see MountShadow.
+	shadowed *Inode
+
+	// Makes sure OnUnmount is called only once.
+	unmountOnce sync.Once
+
+	// Set to 1 by LazyUnmount. Accessed atomically.
+	dead int32
+
+	// Set to 1 once Access returned ENOSYS. Accessed atomically.
+	noAccess int32
+
 	// Options for the mount.
 	options *Options
 
@@ -57,23 +98,89 @@ func (m *fileSystemMount) mountName() string {
 	panic("not found")
 }
 
+// onUnmount calls OnUnmount on the root of the mount, unless that
+// already happened.
+func (m *fileSystemMount) onUnmount() {
+	m.unmountOnce.Do(func() {
+		m.mountInode.Node().OnUnmount()
+	})
+}
+
+// isDead returns true if the mount was removed by LazyUnmount.
+// Operations on its inodes then fail with ENOTCONN.
+func (m *fileSystemMount) isDead() bool {
+	return atomic.LoadInt32(&m.dead) != 0
+}
+
+const (
+	defaultMaxNameLength = 255
+	defaultMaxPathLength = 4096
+)
+
+// checkName returns ENAMETOOLONG if name, or its path in parent, is
+// longer than the options allow.
+func (m *fileSystemMount) checkName(parent *Inode, name string) fuse.Status {
+	maxName := m.options.MaxNameLength
+	if maxName == 0 {
+		maxName = defaultMaxNameLength
+	}
+	if maxName > 0 && len(name) > maxName {
+		return fuse.Status(syscall.ENAMETOOLONG)
+	}
+
+	maxPath := m.options.MaxPathLength
+	if maxPath == 0 {
+		maxPath = defaultMaxPathLength
+	}
+	if maxPath > 0 {
+		// Like PATH_MAX, the limit includes the terminating
+		// NUL byte.
+		if len(name) >= maxPath {
+			return fuse.Status(syscall.ENAMETOOLONG)
+		}
+		if l := parent.pathLength(); l >= 0 && l+1+len(name) >= maxPath {
+			return fuse.Status(syscall.ENAMETOOLONG)
+		}
+	}
+	return fuse.OK
+}
+
 func (m *fileSystemMount) setOwner(attr *fuse.Attr) {
 	if m.options.Owner != nil {
 		attr.Owner = *m.options.Owner
+		return
+	}
+	if d := m.options.DefaultOwner; d != nil {
+		if attr.Uid == 0 {
+			attr.Uid = d.Uid
+		}
+		if attr.Gid == 0 {
+			attr.Gid = d.Gid
+		}
 	}
 }
 
+// currentOptions returns a copy of the options, with the timeouts
+// as they are now.
+func (m *fileSystemMount) currentOptions() *Options {
+	opts := *m.options
+	opts.EntryTimeout = time.Duration(atomic.LoadInt64(&m.entryTimeout))
+	opts.AttrTimeout = time.Duration(atomic.LoadInt64(&m.attrTimeout))
+	opts.NegativeTimeout = time.Duration(atomic.LoadInt64(&m.negativeTimeout))
+	return &opts
+}
+
 func (m *fileSystemMount) fillEntry(out *fuse.EntryOut) {
-	out.SetEntryTimeout(m.options.EntryTimeout)
-	out.SetAttrTimeout(m.options.AttrTimeout)
+	out.SetEntryTimeout(time.Duration(atomic.LoadInt64(&m.entryTimeout)))
+	out.SetAttrTimeout(time.Duration(atomic.LoadInt64(&m.attrTimeout)))
 	m.setOwner(&out.Attr)
-	if out.Mode&fuse.S_IFDIR == 0 && out.Nlink == 0 {
+	if !out.IsDir() && out.Nlink == 0 {
 		out.Nlink = 1
 	}
 }
 
 func (m *fileSystemMount) fillAttr(out *fuse.AttrOut, nodeId uint64) {
-	out.SetTimeout(m.options.AttrTimeout)
+	out.SetTimeout(time.Duration(atomic.LoadInt64(&m.attrTimeout)))
 	m.setOwner(&out.Attr)
 	if out.Ino == 0 {
 		out.Ino = nodeId
@@ -92,6 +199,15 @@ func (m *fileSystemMount) getOpenedFile(h uint64) *openedFile {
 	return b
 }
 
+// getFile returns the File registered for handle h, or nil if there
+// is none, eg. because the file was opened without a handle.
+func (m *fileSystemMount) getFile(h uint64) File {
+	if opened := m.getOpenedFile(h); opened != nil {
+		return opened.WithFlags.File
+	}
+	return nil
+}
+
 func (m *fileSystemMount) unregisterFileHandle(handle uint64, node *Inode) *openedFile {
 	_, obj := m.openFiles.Forget(handle, 1)
 	opened := (*openedFile)(unsafe.Pointer(obj))
@@ -113,6 +229,11 @@ func (m *fileSystemMount) unregisterFileHandle(handle uint64, node *Inode) *open
 	node.openFiles = node.openFiles[:l-1]
 	node.openFilesMutex.Unlock()
 
+	if m.isDead() && m.openFiles.Count() == 0 {
+		// The last release completes a LazyUnmount.
+		m.onUnmount()
+	}
+
 	return opened
 }
 
@@ -151,6 +272,7 @@ func (m *fileSystemMount) registerFileHandle(node *Inode, dir *connectorDir, f F
 		b.WithFlags.File = withFlags.File
 		b.WithFlags.FuseFlags |= withFlags.FuseFlags
 		b.WithFlags.Description += withFlags.Description
+		b.WithFlags.SerializeWrites = b.WithFlags.SerializeWrites || withFlags.SerializeWrites
 		f = withFlags.File
 	}
 
@@ -177,9 +299,9 @@ func (m *fileSystemMount) registerFileHandle(node *Inode, dir *connectorDir, f F
 
 // Creates a return entry for a non-existent path.
 func (m *fileSystemMount) negativeEntry(out *fuse.EntryOut) bool {
-	if m.options.NegativeTimeout > 0.0 {
+	if d := time.Duration(atomic.LoadInt64(&m.negativeTimeout)); d > 0 {
 		out.NodeId = 0
-		out.SetEntryTimeout(m.options.NegativeTimeout)
+		out.SetEntryTimeout(d)
 		return true
 	}
 	return false
@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
@@ -24,11 +26,14 @@ func (c *FileSystemConnector) RawFS() fuse.RawFileSystem {
 type rawBridge FileSystemConnector
 
 func (c *rawBridge) Fsync(cancel <-chan struct{}, input *fuse.FsyncIn) fuse.Status {
-	node := c.toInode(input.NodeId)
+	node, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
 	opened := node.mount.getOpenedFile(input.Fh)
 
 	if opened != nil {
-		return opened.WithFlags.File.Fsync(int(input.FsyncFlags))
+		return ToContextFile(opened.WithFlags.File).FsyncContext(&fuse.Context{Caller: input.Caller, Cancel: cancel}, int(input.FsyncFlags))
 	}
 
 	return fuse.ENOSYS
@@ -61,6 +66,15 @@ func (c *rawBridge) Init(s *fuse.Server) {
 	c.rootNode.Node().OnMount((*FileSystemConnector)(c))
 }
 
+// Destroy calls OnUnmount on the roots of all mounted file systems,
+// submounts before the file systems they are mounted in.
+func (c *rawBridge) Destroy() {
+	mounts := c.fsConn().Mounts()
+	for i := len(mounts) - 1; i >= 0; i-- {
+		mounts[i].mount.onUnmount()
+	}
+}
+
 func (c *FileSystemConnector) lookupMountUpdate(out *fuse.Attr, mount *fileSystemMount) (node *Inode, code fuse.Status) {
 	code = mount.mountInode.Node().GetAttr(out, nil, nil)
 	if !code.Ok() {
@@ -99,11 +113,21 @@ func (c *rawBridge) Lookup(cancel <-chan struct{}, header *fuse.InHeader, name s
 	c.lookupLock.RLock()
 	defer c.lookupLock.RUnlock()
 
-	parent := c.toInode(header.NodeId)
+	if name == "." || name == ".." {
+		return c.lookupDots(cancel, header, name, out)
+	}
+
+	parent, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return code
+	}
 	if !parent.IsDir() {
 		log.Printf("Lookup %q called on non-Directory node %d", name, header.NodeId)
 		return fuse.ENOTDIR
 	}
+	if code := parent.mount.checkName(parent, name); !code.Ok() {
+		return code
+	}
 
 	child, code := c.fsConn().internalLookup(cancel, &out.Attr, parent, name, header)
 	if code == fuse.ENOENT && parent.mount.negativeEntry(out) {
@@ -125,6 +149,47 @@ func (c *rawBridge) Lookup(cancel <-chan struct{}, header *fuse.InHeader, name s
 	return fuse.OK
 }
 
+// lookupDots answers the lookups of "." and ".." that the kernel
+// sends when the mount is exported over NFS; see
+// fuse.MountOptions.ExportSupport. The node ID may come from an NFS
+// file handle, so it may have been forgotten already.
+func (c *rawBridge) lookupDots(cancel <-chan struct{}, header *fuse.InHeader, name string, out *fuse.EntryOut) fuse.Status {
+	if header.NodeId != fuse.FUSE_ROOT_ID && !c.inodeMap.Has(header.NodeId) {
+		return fuse.ESTALE
+	}
+	node, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if name == ".." {
+		if !node.IsDir() {
+			return fuse.ENOTDIR
+		}
+		if node.mountPoint != nil {
+			if node != c.rootNode {
+				node = node.mountPoint.parentInode
+			}
+		} else if node, _ = node.Parent(); node == nil {
+			return fuse.ENOENT
+		}
+	}
+
+	code = node.fsInode.GetAttr(&out.Attr, nil, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+	if !code.Ok() {
+		return code
+	}
+	node.mount.fillEntry(out)
+	if node == c.rootNode {
+		out.NodeId, out.Generation = fuse.FUSE_ROOT_ID, 0
+	} else {
+		out.NodeId, out.Generation = c.fsConn().lookupUpdate(node)
+	}
+	if out.Ino == 0 {
+		out.Ino = out.NodeId
+	}
+	return fuse.OK
+}
+
 func (c *rawBridge) Forget(nodeID, nlookup uint64) {
 	// Prevent Lookup() and Forget() from running concurrently.
 	c.lookupLock.Lock()
@@ -134,13 +199,14 @@ func (c *rawBridge) Forget(nodeID, nlookup uint64) {
 }
 
 func (c *rawBridge) GetAttr(cancel <-chan struct{}, input *fuse.GetAttrIn, out *fuse.AttrOut) (code fuse.Status) {
-	node := c.toInode(input.NodeId)
+	node, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
 
 	var f File
 	if input.Flags()&fuse.FUSE_GETATTR_FH != 0 {
-		if opened := node.mount.getOpenedFile(input.Fh()); opened != nil {
-			f = opened.WithFlags.File
-		}
+		f = node.mount.getFile(input.Fh())
 	}
 
 	dest := &out.Attr
@@ -160,7 +226,10 @@ func (c *rawBridge) GetAttr(cancel <-chan struct{}, input *fuse.GetAttrIn, out *
 }
 
 func (c *rawBridge) OpenDir(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.OpenOut) (code fuse.Status) {
-	node := c.toInode(input.NodeId)
+	node, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
 	de := &connectorDir{
 		inode: node,
 		node:  node.Node(),
@@ -168,24 +237,41 @@ func (c *rawBridge) OpenDir(cancel <-chan struct{}, input *fuse.OpenIn, out *fus
 	}
 	h, opened := node.mount.registerFileHandle(node, de, nil, input.Flags)
 	out.OpenFlags = opened.FuseFlags
+	if node.mount.options.CacheDirs && c.server != nil {
+		if _, minor := c.server.ProtocolVersion(); minor >= 28 {
+			out.OpenFlags |= fuse.FOPEN_CACHE_DIR | fuse.FOPEN_KEEP_CACHE
+		}
+	}
 	out.Fh = h
 	return fuse.OK
 }
 
 func (c *rawBridge) ReadDir(cancel <-chan struct{}, input *fuse.ReadIn, out *fuse.DirEntryList) fuse.Status {
-	node := c.toInode(input.NodeId)
+	node, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
 	opened := node.mount.getOpenedFile(input.Fh)
 	return opened.dir.ReadDir(cancel, input, out)
 }
 
 func (c *rawBridge) ReadDirPlus(cancel <-chan struct{}, input *fuse.ReadIn, out *fuse.DirEntryList) fuse.Status {
-	node := c.toInode(input.NodeId)
+	node, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
 	opened := node.mount.getOpenedFile(input.Fh)
 	return opened.dir.ReadDirPlus(cancel, input, out)
 }
 
 func (c *rawBridge) Open(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.OpenOut) (status fuse.Status) {
-	node := c.toInode(input.NodeId)
+	node, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if node.mount.options.ReadOnly && isWriteOpen(input.Flags) {
+		return fuse.EROFS
+	}
 	f, code := node.fsInode.Open(input.Flags, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 	if !code.Ok() {
 		return code
@@ -193,48 +279,57 @@ func (c *rawBridge) Open(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.O
 	h, opened := node.mount.registerFileHandle(node, nil, f, input.Flags)
 	out.OpenFlags = opened.FuseFlags
 	out.Fh = h
+	c.passthrough(opened, out)
 	return fuse.OK
 }
 
-func (c *rawBridge) SetAttr(cancel <-chan struct{}, input *fuse.SetAttrIn, out *fuse.AttrOut) (code fuse.Status) {
-	node := c.toInode(input.NodeId)
-
-	var f File
-	if fh, ok := input.GetFh(); ok {
-		if opened := node.mount.getOpenedFile(fh); opened != nil {
-			f = opened.WithFlags.File
-		}
+// passthrough lets the kernel do I/O on the backing file of opened,
+// if the File supports it and passthrough was negotiated.
+func (c *rawBridge) passthrough(opened *openedFile, out *fuse.OpenOut) {
+	pf, ok := opened.WithFlags.File.(PassthroughFile)
+	if !ok || c.server == nil || c.server.KernelSettings().Flags2&fuse.CAP2_PASSTHROUGH == 0 {
+		return
 	}
-
-	if permissions, ok := input.GetMode(); ok {
-		code = node.fsInode.Chmod(f, permissions, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	fd, ok := pf.PassthroughFd()
+	if !ok {
+		return
+	}
+	id, code := c.server.RegisterBackingFd(fd)
+	if !code.Ok() {
+		return
 	}
+	opened.backingId = id
+	out.OpenFlags |= fuse.FOPEN_PASSTHROUGH
+	out.BackingId = id
+}
 
-	uid, uok := input.GetUID()
-	gid, gok := input.GetGID()
+// isWriteOpen returns whether open flags allow changing the file.
+func isWriteOpen(flags uint32) bool {
+	return flags&syscall.O_ACCMODE != syscall.O_RDONLY || flags&syscall.O_TRUNC != 0
+}
 
-	if code.Ok() && (uok || gok) {
-		code = node.fsInode.Chown(f, uid, gid, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+func (c *rawBridge) SetAttr(cancel <-chan struct{}, input *fuse.SetAttrIn, out *fuse.AttrOut) (code fuse.Status) {
+	node, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
 	}
-	if sz, ok := input.GetSize(); code.Ok() && ok {
-		code = node.fsInode.Truncate(f, sz, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	if node.mount.options.ReadOnly {
+		return fuse.EROFS
 	}
 
-	atime, aok := input.GetATime()
-	mtime, mok := input.GetMTime()
-	if code.Ok() && (aok || mok) {
-		var a, m *time.Time
-
-		if aok {
-			a = &atime
-		}
-		if mok {
-			m = &mtime
-		}
-
-		code = node.fsInode.Utimens(f, a, m, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	var f File
+	if fh, ok := input.GetFh(); ok {
+		f = node.mount.getFile(fh)
 	}
 
+	context := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	code = fuse.ENOSYS
+	if sa, ok := node.fsInode.(SetAttrer); ok {
+		code = sa.SetAttr(input, f, context)
+	}
+	if code == fuse.ENOSYS {
+		code = setAttrs(node.fsInode, input, f, context)
+	}
 	if !code.Ok() {
 		return code
 	}
@@ -242,64 +337,148 @@ func (c *rawBridge) SetAttr(cancel <-chan struct{}, input *fuse.SetAttrIn, out *
 	// Must call GetAttr(); the filesystem may override some of
 	// the changes we effect here.
 	attr := &out.Attr
-	code = node.fsInode.GetAttr(attr, nil, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	code = node.fsInode.GetAttr(attr, f, context)
 	if code.Ok() {
 		node.mount.fillAttr(out, input.NodeId)
 	}
 	return code
 }
 
+// setAttrs applies the attributes in input one at a time. The owner
+// is changed before the mode, because chown may clear the setuid
+// bits, and the size before the times, because truncating updates
+// the mtime. The ctime comes last, as the other changes update it.
+// All attributes are attempted; the first error is returned.
+func setAttrs(node Node, input *fuse.SetAttrIn, f File, context *fuse.Context) fuse.Status {
+	code := fuse.OK
+	update := func(c fuse.Status) {
+		if code.Ok() {
+			code = c
+		}
+	}
+
+	uid, uok := input.GetUID()
+	gid, gok := input.GetGID()
+	if uok || gok {
+		update(node.Chown(f, uid, gid, context))
+	}
+	if mode, ok := input.GetMode(); ok {
+		update(node.Chmod(f, mode, context))
+	}
+	if sz, ok := input.GetSize(); ok {
+		update(node.Truncate(f, sz, context))
+	}
+
+	if atime, mtime := input.GetTimes(); atime != nil || mtime != nil {
+		update(node.Utimens(f, atime, mtime, context))
+	}
+
+	if ctime, ok := input.GetCTime(); ok {
+		if cs, ok := node.(CtimeSetter); ok {
+			if c := cs.SetCtime(f, ctime, context); c != fuse.ENOSYS {
+				update(c)
+			}
+		}
+	}
+	return code
+}
+
 func (c *rawBridge) Fallocate(cancel <-chan struct{}, input *fuse.FallocateIn) (code fuse.Status) {
-	n := c.toInode(input.NodeId)
-	opened := n.mount.getOpenedFile(input.Fh)
+	n, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if n.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
+	f := n.mount.getFile(input.Fh)
 
-	return n.fsInode.Fallocate(opened, input.Offset, input.Length, input.Mode, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return n.fsInode.Fallocate(f, input.Offset, input.Length, input.Mode, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) Readlink(cancel <-chan struct{}, header *fuse.InHeader) (out []byte, code fuse.Status) {
-	n := c.toInode(header.NodeId)
+	n, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return nil, code
+	}
 	return n.fsInode.Readlink(&fuse.Context{Caller: header.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) Mknod(cancel <-chan struct{}, input *fuse.MknodIn, name string, out *fuse.EntryOut) (code fuse.Status) {
-	parent := c.toInode(input.NodeId)
+	parent, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if parent.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
+	if code := parent.mount.checkName(parent, name); !code.Ok() {
+		return code
+	}
 
 	child, code := parent.fsInode.Mknod(name, input.Mode, uint32(input.Rdev), &fuse.Context{Caller: input.Caller, Cancel: cancel})
 	if code.Ok() {
-		c.childLookup(out, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
-		code = child.fsInode.GetAttr(&out.Attr, nil, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		code = c.childLookup(out, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 	}
 	return code
 }
 
 func (c *rawBridge) Mkdir(cancel <-chan struct{}, input *fuse.MkdirIn, name string, out *fuse.EntryOut) (code fuse.Status) {
-	parent := c.toInode(input.NodeId)
+	parent, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if parent.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
+	if code := parent.mount.checkName(parent, name); !code.Ok() {
+		return code
+	}
 
 	child, code := parent.fsInode.Mkdir(name, input.Mode, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 	if code.Ok() {
-		c.childLookup(out, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
-		code = child.fsInode.GetAttr(&out.Attr, nil, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		code = c.childLookup(out, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 	}
 	return code
 }
 
 func (c *rawBridge) Unlink(cancel <-chan struct{}, header *fuse.InHeader, name string) (code fuse.Status) {
-	parent := c.toInode(header.NodeId)
+	parent, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if parent.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
 	return parent.fsInode.Unlink(name, &fuse.Context{Caller: header.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) Rmdir(cancel <-chan struct{}, header *fuse.InHeader, name string) (code fuse.Status) {
-	parent := c.toInode(header.NodeId)
+	parent, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if parent.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
 	return parent.fsInode.Rmdir(name, &fuse.Context{Caller: header.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) Symlink(cancel <-chan struct{}, header *fuse.InHeader, pointedTo string, linkName string, out *fuse.EntryOut) (code fuse.Status) {
-	parent := c.toInode(header.NodeId)
+	parent, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if parent.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
+	if code := parent.mount.checkName(parent, linkName); !code.Ok() {
+		return code
+	}
 
 	child, code := parent.fsInode.Symlink(linkName, pointedTo, &fuse.Context{Caller: header.Caller, Cancel: cancel})
 	if code.Ok() {
-		c.childLookup(out, child, &fuse.Context{Caller: header.Caller, Cancel: cancel})
-		code = child.fsInode.GetAttr(&out.Attr, nil, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+		code = c.childLookup(out, child, &fuse.Context{Caller: header.Caller, Cancel: cancel})
 	}
 	return code
 }
@@ -308,7 +487,20 @@ func (c *rawBridge) Rename(cancel <-chan struct{}, input *fuse.RenameIn, oldName
 	if input.Flags != 0 {
 		return fuse.ENOSYS
 	}
-	oldParent := c.toInode(input.NodeId)
+	oldParent, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	newParent, code := c.liveInode(input.Newdir)
+	if !code.Ok() {
+		return code
+	}
+	if oldParent.mount.options.ReadOnly || newParent.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
+	if code := newParent.mount.checkName(newParent, newName); !code.Ok() {
+		return code
+	}
 
 	child := oldParent.GetChild(oldName)
 	if child == nil {
@@ -318,17 +510,31 @@ func (c *rawBridge) Rename(cancel <-chan struct{}, input *fuse.RenameIn, oldName
 		return fuse.EBUSY
 	}
 
-	newParent := c.toInode(input.Newdir)
 	if oldParent.mount != newParent.mount {
-		return fuse.EXDEV
+		if !oldParent.mount.options.CopyOnCrossMountRename || !newParent.mount.options.CopyOnCrossMountRename {
+			return fuse.EXDEV
+		}
+		return c.fsConn().copyRename(oldParent, oldName, newParent, newName, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 	}
 
 	return oldParent.fsInode.Rename(oldName, newParent.fsInode, newName, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) Link(cancel <-chan struct{}, input *fuse.LinkIn, name string, out *fuse.EntryOut) (code fuse.Status) {
-	existing := c.toInode(input.Oldnodeid)
-	parent := c.toInode(input.NodeId)
+	existing, code := c.liveInode(input.Oldnodeid)
+	if !code.Ok() {
+		return code
+	}
+	parent, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if parent.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
+	if code := parent.mount.checkName(parent, name); !code.Ok() {
+		return code
+	}
 
 	if existing.mount != parent.mount {
 		return fuse.EXDEV
@@ -336,20 +542,54 @@ func (c *rawBridge) Link(cancel <-chan struct{}, input *fuse.LinkIn, name string
 
 	child, code := parent.fsInode.Link(name, existing.fsInode, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 	if code.Ok() {
-		c.childLookup(out, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
-		code = child.fsInode.GetAttr(&out.Attr, nil, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		code = c.childLookup(out, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 	}
 
 	return code
 }
 
 func (c *rawBridge) Access(cancel <-chan struct{}, input *fuse.AccessIn) (code fuse.Status) {
-	n := c.toInode(input.NodeId)
-	return n.fsInode.Access(input.Mask, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	n, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if n.mount.options.ReadOnly && input.Mask&fuse.W_OK != 0 {
+		return fuse.EROFS
+	}
+	if atomic.LoadInt32(&n.mount.noAccess) != 0 {
+		return c.noAccess()
+	}
+	code = n.fsInode.Access(input.Mask, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	if code == fuse.ENOSYS {
+		atomic.StoreInt32(&n.mount.noAccess, 1)
+		return c.noAccess()
+	}
+	return code
+}
+
+// noAccess is the result of ACCESS for a mount that does not
+// implement Access. The kernel stops sending ACCESS after ENOSYS, so
+// that is returned if the root is the only mount. Otherwise, other
+// mounts may implement Access, and the request is granted, which
+// is what the kernel does after ENOSYS.
+func (c *rawBridge) noAccess() fuse.Status {
+	if atomic.LoadInt32(&c.submounted) != 0 {
+		return fuse.OK
+	}
+	return fuse.ENOSYS
 }
 
 func (c *rawBridge) Create(cancel <-chan struct{}, input *fuse.CreateIn, name string, out *fuse.CreateOut) (code fuse.Status) {
-	parent := c.toInode(input.NodeId)
+	parent, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if parent.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
+	if code := parent.mount.checkName(parent, name); !code.Ok() {
+		return code
+	}
 	f, child, code := parent.fsInode.Create(name, uint32(input.Flags), input.Mode, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 	if !code.Ok() {
 		return code
@@ -358,6 +598,35 @@ func (c *rawBridge) Create(cancel <-chan struct{}, input *fuse.CreateIn, name st
 	c.childLookup(&out.EntryOut, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 	handle, opened := parent.mount.registerFileHandle(child, nil, f, input.Flags)
 
+	out.OpenOut.OpenFlags = opened.FuseFlags
+	out.OpenOut.Fh = handle
+	c.passthrough(opened, &out.OpenOut)
+	return code
+}
+
+func (c *rawBridge) TmpFile(cancel <-chan struct{}, input *fuse.CreateIn, out *fuse.CreateOut) (code fuse.Status) {
+	parent, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if parent.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
+	uc, ok := parent.fsInode.(UnnamedCreater)
+	if !ok {
+		return fuse.ENOSYS
+	}
+	context := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	f, node, code := uc.CreateUnnamed(uint32(input.Flags), input.Mode, context)
+	if !code.Ok() {
+		return code
+	}
+
+	child := newInode(false, node)
+	child.mount = parent.mount
+	c.childLookup(&out.EntryOut, child, context)
+	handle, opened := parent.mount.registerFileHandle(child, nil, f, input.Flags)
+
 	out.OpenOut.OpenFlags = opened.FuseFlags
 	out.OpenOut.Fh = handle
 	return code
@@ -367,20 +636,36 @@ func (c *rawBridge) Release(cancel <-chan struct{}, input *fuse.ReleaseIn) {
 	if input.Fh != 0 {
 		node := c.toInode(input.NodeId)
 		opened := node.mount.unregisterFileHandle(input.Fh, node)
-		opened.WithFlags.File.Release()
+		if opened.backingId != 0 {
+			c.server.UnregisterBackingFd(opened.backingId)
+		}
+		if r, ok := opened.WithFlags.File.(FileReleaser); ok {
+			r.ReleaseWithInfo(opened.releaseInfo(input))
+		} else {
+			ToContextFile(opened.WithFlags.File).ReleaseContext(&fuse.Context{Caller: input.Caller, Cancel: cancel})
+		}
 	}
 }
 
 func (c *rawBridge) ReleaseDir(input *fuse.ReleaseIn) {
 	if input.Fh != 0 {
 		node := c.toInode(input.NodeId)
-		node.mount.unregisterFileHandle(input.Fh, node)
+		opened := node.mount.unregisterFileHandle(input.Fh, node)
+		opened.dir.release()
+		if r, ok := node.Node().(DirReleaser); ok {
+			r.ReleaseDir(opened.releaseInfo(input))
+		}
 	}
 }
 func (c *rawBridge) GetXAttr(cancel <-chan struct{}, header *fuse.InHeader, attribute string, dest []byte) (sz uint32, code fuse.Status) {
-	node := c.toInode(header.NodeId)
+	node, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return 0, code
+	}
 	data, errno := node.fsInode.GetXAttr(attribute, &fuse.Context{Caller: header.Caller, Cancel: cancel})
-
+	if !errno.Ok() {
+		return 0, errno
+	}
 	if len(data) > len(dest) {
 		return uint32(len(data)), fuse.ERANGE
 	}
@@ -389,22 +674,40 @@ func (c *rawBridge) GetXAttr(cancel <-chan struct{}, header *fuse.InHeader, attr
 }
 
 func (c *rawBridge) GetXAttrData(cancel <-chan struct{}, header *fuse.InHeader, attribute string) (data []byte, code fuse.Status) {
-	node := c.toInode(header.NodeId)
+	node, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return nil, code
+	}
 	return node.fsInode.GetXAttr(attribute, &fuse.Context{Caller: header.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) RemoveXAttr(cancel <-chan struct{}, header *fuse.InHeader, attr string) fuse.Status {
-	node := c.toInode(header.NodeId)
+	node, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if node.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
 	return node.fsInode.RemoveXAttr(attr, &fuse.Context{Caller: header.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) SetXAttr(cancel <-chan struct{}, input *fuse.SetXAttrIn, attr string, data []byte) fuse.Status {
-	node := c.toInode(input.NodeId)
+	node, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	if node.mount.options.ReadOnly {
+		return fuse.EROFS
+	}
 	return node.fsInode.SetXAttr(attr, data, int(input.Flags), &fuse.Context{Caller: input.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) ListXAttr(cancel <-chan struct{}, header *fuse.InHeader, dest []byte) (uint32, fuse.Status) {
-	node := c.toInode(header.NodeId)
+	node, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return 0, code
+	}
 	attrs, code := node.fsInode.ListXAttr(&fuse.Context{Caller: header.Caller, Cancel: cancel})
 	if code != fuse.OK {
 		return 0, code
@@ -432,66 +735,137 @@ func (c *rawBridge) ListXAttr(cancel <-chan struct{}, header *fuse.InHeader, des
 // files.
 
 func (c *rawBridge) Write(cancel <-chan struct{}, input *fuse.WriteIn, data []byte) (written uint32, code fuse.Status) {
-	node := c.toInode(input.NodeId)
-	opened := node.mount.getOpenedFile(input.Fh)
-
+	node, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return 0, code
+	}
+	if node.mount.options.ReadOnly {
+		return 0, fuse.EROFS
+	}
 	var f File
-	if opened != nil {
+	if opened := node.mount.getOpenedFile(input.Fh); opened != nil {
 		f = opened.WithFlags.File
+		if opened.SerializeWrites || node.mount.options.SerializeWrites {
+			opened.writeMu.Lock()
+			defer opened.writeMu.Unlock()
+		}
 	}
-
 	return node.Node().Write(f, data, int64(input.Offset), &fuse.Context{Caller: input.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) Read(cancel <-chan struct{}, input *fuse.ReadIn, buf []byte) (fuse.ReadResult, fuse.Status) {
-	node := c.toInode(input.NodeId)
-	opened := node.mount.getOpenedFile(input.Fh)
-
-	var f File
-	if opened != nil {
-		f = opened.WithFlags.File
+	node, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return nil, code
+	}
+	f := node.mount.getFile(input.Fh)
+	context := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	res, code := node.Node().Read(f, buf, int64(input.Offset), context)
+	if code.Ok() {
+		c.updateAtime(node, f, context)
 	}
+	return res, code
+}
 
-	return node.Node().Read(f, buf, int64(input.Offset), &fuse.Context{Caller: input.Caller, Cancel: cancel})
+// updateAtime sets the access time of node after a read, as
+// prescribed by the AtimePolicy of its mount.
+func (c *rawBridge) updateAtime(node *Inode, f File, context *fuse.Context) {
+	opts := node.mount.options
+	if opts.AtimePolicy == NoAtime || opts.ReadOnly {
+		return
+	}
+	now := time.Now()
+	if opts.AtimePolicy == RelAtime {
+		var attr fuse.Attr
+		if !node.fsInode.GetAttr(&attr, f, context).Ok() {
+			return
+		}
+		atime := attr.AccessTime()
+		mtime := attr.ModTime()
+		ctime := attr.ChangeTime()
+		if atime.After(mtime) && atime.After(ctime) && now.Sub(atime) < 24*time.Hour {
+			return
+		}
+	}
+	node.fsInode.Utimens(f, &now, nil, context)
 }
 
 func (c *rawBridge) GetLk(cancel <-chan struct{}, input *fuse.LkIn, out *fuse.LkOut) (code fuse.Status) {
-	n := c.toInode(input.NodeId)
-	opened := n.mount.getOpenedFile(input.Fh)
+	n, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	f := n.mount.getFile(input.Fh)
 
-	return n.fsInode.GetLk(opened, input.Owner, &input.Lk, input.LkFlags, &out.Lk, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return n.fsInode.GetLk(f, input.Owner, &input.Lk, input.LkFlags, &out.Lk, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) SetLk(cancel <-chan struct{}, input *fuse.LkIn) (code fuse.Status) {
-	n := c.toInode(input.NodeId)
-	opened := n.mount.getOpenedFile(input.Fh)
+	n, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	f := n.mount.getFile(input.Fh)
 
-	return n.fsInode.SetLk(opened, input.Owner, &input.Lk, input.LkFlags, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return n.fsInode.SetLk(f, input.Owner, &input.Lk, input.LkFlags, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) SetLkw(cancel <-chan struct{}, input *fuse.LkIn) (code fuse.Status) {
-	n := c.toInode(input.NodeId)
-	opened := n.mount.getOpenedFile(input.Fh)
+	n, code := c.liveInode(input.NodeId)
+	if !code.Ok() {
+		return code
+	}
+	f := n.mount.getFile(input.Fh)
 
-	return n.fsInode.SetLkw(opened, input.Owner, &input.Lk, input.LkFlags, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return n.fsInode.SetLkw(f, input.Owner, &input.Lk, input.LkFlags, &fuse.Context{Caller: input.Caller, Cancel: cancel})
 }
 
 func (c *rawBridge) StatFs(cancel <-chan struct{}, header *fuse.InHeader, out *fuse.StatfsOut) fuse.Status {
-	node := c.toInode(header.NodeId)
+	node, code := c.liveInode(header.NodeId)
+	if !code.Ok() {
+		return code
+	}
 	s := node.Node().StatFs()
 	if s == nil {
 		return fuse.ENOSYS
 	}
 	*out = *s
+	if node.mount.options.StatFsPolicy == StatFsAggregate && node.IsDir() {
+		for _, m := range node.subMounts() {
+			if sub := m.Root.StatFs(); sub != nil {
+				addStatFs(out, sub)
+			}
+		}
+	}
 	return fuse.OK
 }
 
+// addStatFs adds the counts of sub to out, in the block size of out.
+func addStatFs(out, sub *fuse.StatfsOut) {
+	frsize := func(s *fuse.StatfsOut) uint64 {
+		if s.Frsize != 0 {
+			return uint64(s.Frsize)
+		}
+		if s.Bsize != 0 {
+			return uint64(s.Bsize)
+		}
+		return 1
+	}
+	num, den := frsize(sub), frsize(out)
+	out.Blocks += sub.Blocks * num / den
+	out.Bfree += sub.Bfree * num / den
+	out.Bavail += sub.Bavail * num / den
+	out.Files += sub.Files
+	out.Ffree += sub.Ffree
+}
+
 func (c *rawBridge) Flush(cancel <-chan struct{}, input *fuse.FlushIn) fuse.Status {
 	node := c.toInode(input.NodeId)
 	opened := node.mount.getOpenedFile(input.Fh)
 
 	if opened != nil {
-		return opened.WithFlags.File.Flush()
+		atomic.StoreInt32(&opened.flushed, 1)
+		return ToContextFile(opened.WithFlags.File).FlushContext(&fuse.Context{Caller: input.Caller, Cancel: cancel})
 	}
 	return fuse.OK
 }
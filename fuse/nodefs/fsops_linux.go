@@ -0,0 +1,41 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"golang.org/x/sys/unix"
+)
+
+func (c *rawBridge) Statx(cancel <-chan struct{}, input *fuse.StatxIn, out *fuse.StatxOut) (code fuse.Status) {
+	in := fuse.GetAttrIn{
+		InHeader: input.InHeader,
+		Flags_:   input.GetattrFlags,
+		Fh_:      input.Fh,
+	}
+	var attrOut fuse.AttrOut
+	if code := c.GetAttr(cancel, &in, &attrOut); !code.Ok() {
+		return code
+	}
+	out.AttrValid = attrOut.AttrValid
+	out.AttrValidNsec = attrOut.AttrValidNsec
+	out.Statx.FromAttr(&attrOut.Attr)
+
+	node := c.toInode(input.NodeId)
+	if bt, ok := node.fsInode.(BirthTimer); ok {
+		var f File
+		if input.GetattrFlags&fuse.FUSE_GETATTR_FH != 0 {
+			f = node.mount.getFile(input.Fh)
+		}
+		t, code := bt.BirthTime(f, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		if code.Ok() {
+			out.Btime.FromTime(t)
+			out.Mask |= unix.STATX_BTIME
+		} else if code != fuse.ENOSYS {
+			return code
+		}
+	}
+	return fuse.OK
+}
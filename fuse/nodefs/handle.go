@@ -27,11 +27,27 @@ type handleMap interface {
 	// Forget decrements the reference counter for "handle" by "count" and drops
 	// the object if the refcount reaches zero.
 	// Returns a boolean whether the object was dropped and the object itself.
+	// Forgetting a handle reserved by Restore makes it available again.
 	Forget(handle uint64, count int) (bool, *handled)
 	// Handle gets the object's NodeId.
 	Handle(obj *handled) uint64
 	// Has checks if NodeId is stored.
 	Has(uint64) bool
+	// Replace moves the handle and reference count of "old" to
+	// "obj", which must not have a handle. It returns false if
+	// that is not possible.
+	Replace(old, obj *handled) bool
+	// Generation returns the last generation number handed out.
+	Generation() uint64
+	// Restore registers "obj" under a handle and generation given
+	// out by an earlier incarnation of the map, with the given
+	// reference count. If obj is nil, the handle is reserved so
+	// it is never handed out again. It returns false if the handle
+	// is in use.
+	Restore(obj *handled, handle, generation uint64, count int) bool
+	// SetGeneration makes sure new registrations get a generation
+	// above "generation".
+	SetGeneration(generation uint64)
 }
 
 type handled struct {
@@ -129,6 +145,11 @@ func (m *portableHandleMap) Decode(h uint64) *handled {
 func (m *portableHandleMap) Forget(h uint64, count int) (forgotten bool, obj *handled) {
 	m.Lock()
 	obj = m.handles[h]
+	if obj == nil {
+		m.unreserve(h)
+		m.Unlock()
+		return false, nil
+	}
 	obj.count -= count
 	if obj.count < 0 {
 		log.Panicf("underflow: handle %d, count %d, object %d", h, count, obj.count)
@@ -145,7 +166,77 @@ func (m *portableHandleMap) Forget(h uint64, count int) (forgotten bool, obj *ha
 
 func (m *portableHandleMap) Has(h uint64) bool {
 	m.RLock()
-	ok := m.handles[h] != nil
+	ok := h < uint64(len(m.handles)) && m.handles[h] != nil
 	m.RUnlock()
 	return ok
 }
+
+func (m *portableHandleMap) Replace(old, obj *handled) bool {
+	m.Lock()
+	defer m.Unlock()
+	if old.count == 0 || obj.count != 0 {
+		return false
+	}
+	*obj = *old
+	m.handles[obj.handle] = obj
+	*old = handled{}
+	return true
+}
+
+func (m *portableHandleMap) Generation() uint64 {
+	m.RLock()
+	defer m.RUnlock()
+	return m.generation
+}
+
+func (m *portableHandleMap) SetGeneration(generation uint64) {
+	m.Lock()
+	defer m.Unlock()
+	if generation > m.generation {
+		m.generation = generation
+	}
+}
+
+func (m *portableHandleMap) Restore(obj *handled, handle, generation uint64, count int) bool {
+	m.Lock()
+	defer m.Unlock()
+	if handle < 2 || (obj != nil && (obj.count != 0 || count <= 0)) {
+		return false
+	}
+	// Slots we skip over become free.
+	for uint64(len(m.handles)) <= handle {
+		m.freeIds = append(m.freeIds, uint64(len(m.handles)))
+		m.handles = append(m.handles, nil)
+	}
+	if m.handles[handle] != nil {
+		return false
+	}
+	for i, id := range m.freeIds {
+		if id == handle {
+			m.freeIds = append(m.freeIds[:i], m.freeIds[i+1:]...)
+			break
+		}
+	}
+	if generation > m.generation {
+		m.generation = generation
+	}
+	if obj == nil {
+		return true
+	}
+	obj.handle = handle
+	obj.generation = generation
+	obj.count = count
+	m.handles[handle] = obj
+	m.used++
+	return true
+}
+
+// unreserve puts a handle reserved by Restore back on the free list.
+func (m *portableHandleMap) unreserve(h uint64) {
+	for _, id := range m.freeIds {
+		if id == h {
+			return
+		}
+	}
+	m.freeIds = append(m.freeIds, h)
+}
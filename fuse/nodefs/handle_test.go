@@ -159,3 +159,24 @@ func TestHandleMapGenerationKnown(t *testing.T) {
 		t.Fatalf("register known should reuse generation: got %d want %d.", g3, g1)
 	}
 }
+
+func TestHandleMapReplace(t *testing.T) {
+	hm := newPortableHandleMap()
+	old := new(handled)
+	h, g := hm.Register(old)
+	hm.Register(old)
+
+	obj := new(handled)
+	if !hm.Replace(old, obj) {
+		t.Fatal("Replace failed")
+	}
+	if hm.Decode(h) != obj || obj.handle != h || obj.generation != g || obj.count != 2 {
+		t.Fatalf("got %+v for handle %d", obj, h)
+	}
+	if hm.Handle(old) != 0 {
+		t.Errorf("old object still has a handle")
+	}
+	if hm.Replace(old, obj) {
+		t.Errorf("Replace of unregistered object succeeded")
+	}
+}
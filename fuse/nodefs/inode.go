@@ -106,6 +106,45 @@ func (n *Inode) Parent() (parent *Inode, name string) {
 	return nil, ""
 }
 
+// dotdot returns the directory that ".." in n refers to. Unlike
+// Parent, it crosses submounts. The parent of the root is the root.
+func (n *Inode) dotdot(c *FileSystemConnector) *Inode {
+	if n.mountPoint != nil {
+		if p := n.mountPoint.parentInode; p != nil {
+			return p
+		}
+		return c.rootNode
+	}
+	if p, _ := n.Parent(); p != nil {
+		return p
+	}
+	return n
+}
+
+// pathLength returns the length of the path of n below the root of
+// the FUSE mount, or -1 if n is no longer in the tree.
+func (n *Inode) pathLength() int {
+	l := 0
+	for {
+		if m := n.mountPoint; m != nil {
+			if m.parentInode == nil {
+				return l
+			}
+			m.parentInode.mount.treeLock.RLock()
+			l += len(m.mountName()) + 1
+			m.parentInode.mount.treeLock.RUnlock()
+			n = m.parentInode
+			continue
+		}
+		p, name := n.Parent()
+		if p == nil {
+			return -1
+		}
+		l += len(name) + 1
+		n = p
+	}
+}
+
 // FsChildren returns all the children from the same filesystem.  It
 // will skip mountpoints.
 func (n *Inode) FsChildren() (out map[string]*Inode) {
@@ -228,9 +267,12 @@ func (n *Inode) rmChild(name string) *Inode {
 // Can only be called on untouched root inodes.
 func (n *Inode) mountFs(opts *Options) {
 	n.mountPoint = &fileSystemMount{
-		openFiles:  newPortableHandleMap(),
-		mountInode: n,
-		options:    opts,
+		openFiles:       newPortableHandleMap(),
+		mountInode:      n,
+		options:         opts,
+		entryTimeout:    int64(opts.EntryTimeout),
+		attrTimeout:     int64(opts.AttrTimeout),
+		negativeTimeout: int64(opts.NegativeTimeout),
 	}
 	n.mount = n.mountPoint
 }
@@ -254,6 +296,20 @@ func (n *Inode) canUnmount() bool {
 	return ok
 }
 
+// canUnmountSubtree returns false if there is a mount point at or
+// below n. Must be called with treeLock held.
+func (n *Inode) canUnmountSubtree() bool {
+	if n.mountPoint != nil {
+		return false
+	}
+	for _, v := range n.children {
+		if !v.canUnmountSubtree() {
+			return false
+		}
+	}
+	return true
+}
+
 func (n *Inode) getMountDirEntries() (out []fuse.DirEntry) {
 	n.mount.treeLock.RLock()
 	for k, v := range n.children {
@@ -269,6 +325,68 @@ func (n *Inode) getMountDirEntries() (out []fuse.DirEntry) {
 	return out
 }
 
+// subMounts returns the mounts below the directory n, including
+// mounts nested in them.
+func (n *Inode) subMounts() []MountInfo {
+	n.mount.treeLock.RLock()
+	defer n.mount.treeLock.RUnlock()
+
+	var out []MountInfo
+	seen := map[*Inode]bool{n: true}
+	var walk func(dir *Inode)
+	walk = func(dir *Inode) {
+		for _, ch := range dir.children {
+			if ch.mountPoint != nil {
+				ch.collectMounts("", &out)
+			} else if !seen[ch] {
+				seen[ch] = true
+				walk(ch)
+			}
+		}
+	}
+	walk(n)
+	return out
+}
+
+// collectMounts appends the mount rooted at n, and all mounts below
+// it, to out. Must be called on a mount point.
+func (n *Inode) collectMounts(path string, out *[]MountInfo) {
+	m := n.mountPoint
+	m.treeLock.RLock()
+	defer m.treeLock.RUnlock()
+
+	idx := len(*out)
+	*out = append(*out, MountInfo{
+		Path:      path,
+		Root:      n.Node(),
+		Options:   m.currentOptions(),
+		OpenFiles: m.openFiles.Count(),
+		mount:     m,
+	})
+
+	// With hard links, an inode can be reached more than once.
+	seen := map[*Inode]bool{n: true}
+	var walk func(dir *Inode, dirPath string)
+	walk = func(dir *Inode, dirPath string) {
+		for name, ch := range dir.children {
+			p := name
+			if dirPath != "" {
+				p = dirPath + "/" + name
+			}
+			if ch.mountPoint != nil {
+				ch.collectMounts(p, out)
+				continue
+			}
+			if !seen[ch] {
+				seen[ch] = true
+				walk(ch, p)
+			}
+		}
+	}
+	walk(n, path)
+	(*out)[idx].Inodes = len(seen)
+}
+
 const initDirSize = 20
 
 func (n *Inode) verify(cur *fileSystemMount) {
@@ -0,0 +1,139 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// inodeTable is the on-disk format of SaveInodeTable.
+type inodeTable struct {
+	// The generation counter of the handle map.
+	Generation uint64
+	Inodes     []inodeTableEntry
+}
+
+type inodeTableEntry struct {
+	// Path relative to the root of the connector. With hard
+	// links, one of the paths is chosen.
+	Path       string
+	NodeId     uint64
+	Generation uint64
+	// Lookups is the number of lookups the kernel has not
+	// forgotten yet.
+	Lookups int
+}
+
+// SaveInodeTable writes the node IDs, generations and lookup counts
+// of the inodes known to the kernel, along with their paths, to w.
+// Together with LoadInodeTable, this lets a restarted daemon that
+// takes over the kernel connection (or serves NFS clients holding
+// file handles) keep using the node IDs the kernel already knows.
+//
+// The kernel connection must be idle while saving, for example
+// because the server has stopped reading requests.
+func (c *FileSystemConnector) SaveInodeTable(w io.Writer) error {
+	table := inodeTable{Generation: c.inodeMap.Generation()}
+
+	seen := map[*Inode]bool{c.rootNode: true}
+	var walk func(dir *Inode, dirPath string)
+	walk = func(dir *Inode, dirPath string) {
+		for name, ch := range dir.Children() {
+			if seen[ch] {
+				continue
+			}
+			seen[ch] = true
+			p := name
+			if dirPath != "" {
+				p = dirPath + "/" + name
+			}
+			// No lookups or forgets run concurrently, so
+			// the counts are stable.
+			h := ch.handled
+			if h.count > 0 {
+				table.Inodes = append(table.Inodes, inodeTableEntry{
+					Path:       p,
+					NodeId:     h.handle,
+					Generation: h.generation,
+					Lookups:    h.count,
+				})
+			}
+			if ch.IsDir() {
+				walk(ch, p)
+			}
+		}
+	}
+	walk(c.rootNode, "")
+
+	sort.Slice(table.Inodes, func(i, j int) bool {
+		return table.Inodes[i].NodeId < table.Inodes[j].NodeId
+	})
+	return json.NewEncoder(w).Encode(&table)
+}
+
+// LoadInodeTable reads a table written by SaveInodeTable, looks up
+// the paths in it, and registers the inodes found under their old
+// node IDs and generations. It must be called before the connector
+// serves any requests, after mounting any submounts. Node IDs whose
+// path can no longer be found are reserved, so the kernel gets
+// ESTALE rather than a different file for them, and generation
+// numbers continue from the saved table.
+//
+// The returned slice contains the paths that could not be restored.
+func (c *FileSystemConnector) LoadInodeTable(r io.Reader) (missing []string, err error) {
+	var table inodeTable
+	if err := json.NewDecoder(r).Decode(&table); err != nil {
+		return nil, err
+	}
+
+	c.lookupLock.Lock()
+	defer c.lookupLock.Unlock()
+
+	c.inodeMap.SetGeneration(table.Generation)
+
+	nodes := map[string]*Inode{"": c.rootNode}
+	for _, e := range table.Inodes {
+		node := c.restoreLookup(nodes, e.Path)
+		if node == nil || node.handled.count != 0 ||
+			!c.inodeMap.Restore(&node.handled, e.NodeId, e.Generation, e.Lookups) {
+			missing = append(missing, e.Path)
+			if !c.inodeMap.Restore(nil, e.NodeId, e.Generation, 0) {
+				return missing, fmt.Errorf("node ID %d for %q is already in use", e.NodeId, e.Path)
+			}
+		}
+	}
+	c.verify()
+	return missing, nil
+}
+
+// restoreLookup looks up path, using and filling the cache of
+// resolved paths in nodes. It returns nil if the path cannot be
+// found.
+func (c *FileSystemConnector) restoreLookup(nodes map[string]*Inode, path string) *Inode {
+	if node, ok := nodes[path]; ok {
+		return node
+	}
+	dirPath, name := "", path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		dirPath, name = path[:i], path[i+1:]
+	}
+	var node *Inode
+	if parent := c.restoreLookup(nodes, dirPath); parent != nil && parent.IsDir() {
+		var attr fuse.Attr
+		var code fuse.Status
+		node, code = c.internalLookup(nil, &attr, parent, name, &fuse.InHeader{})
+		if !code.Ok() {
+			node = nil
+		}
+	}
+	nodes[path] = node
+	return node
+}
@@ -37,21 +37,33 @@ func (f *lockingFile) String() string {
 }
 
 func (f *lockingFile) Read(buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	return f.ReadContext(&fuse.Context{}, buf, off)
+}
+
+func (f *lockingFile) ReadContext(ctx *fuse.Context, buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.file.Read(buf, off)
+	return ToContextFile(f.file).ReadContext(ctx, buf, off)
 }
 
 func (f *lockingFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	return f.WriteContext(&fuse.Context{}, data, off)
+}
+
+func (f *lockingFile) WriteContext(ctx *fuse.Context, data []byte, off int64) (uint32, fuse.Status) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.file.Write(data, off)
+	return ToContextFile(f.file).WriteContext(ctx, data, off)
 }
 
 func (f *lockingFile) Flush() fuse.Status {
+	return f.FlushContext(&fuse.Context{})
+}
+
+func (f *lockingFile) FlushContext(ctx *fuse.Context) fuse.Status {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.file.Flush()
+	return ToContextFile(f.file).FlushContext(ctx)
 }
 
 func (f *lockingFile) GetLk(owner uint64, lk *fuse.FileLock, flags uint32, out *fuse.FileLock) (code fuse.Status) {
@@ -73,49 +85,81 @@ func (f *lockingFile) SetLkw(owner uint64, lk *fuse.FileLock, flags uint32) (cod
 }
 
 func (f *lockingFile) Release() {
+	f.ReleaseContext(&fuse.Context{})
+}
+
+func (f *lockingFile) ReleaseContext(ctx *fuse.Context) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	f.file.Release()
+	ToContextFile(f.file).ReleaseContext(ctx)
 }
 
 func (f *lockingFile) GetAttr(a *fuse.Attr) fuse.Status {
+	return f.GetAttrContext(&fuse.Context{}, a)
+}
+
+func (f *lockingFile) GetAttrContext(ctx *fuse.Context, a *fuse.Attr) fuse.Status {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.file.GetAttr(a)
+	return ToContextFile(f.file).GetAttrContext(ctx, a)
 }
 
 func (f *lockingFile) Fsync(flags int) (code fuse.Status) {
+	return f.FsyncContext(&fuse.Context{}, flags)
+}
+
+func (f *lockingFile) FsyncContext(ctx *fuse.Context, flags int) (code fuse.Status) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.file.Fsync(flags)
+	return ToContextFile(f.file).FsyncContext(ctx, flags)
 }
 
 func (f *lockingFile) Utimens(atime *time.Time, mtime *time.Time) fuse.Status {
+	return f.UtimensContext(&fuse.Context{}, atime, mtime)
+}
+
+func (f *lockingFile) UtimensContext(ctx *fuse.Context, atime *time.Time, mtime *time.Time) fuse.Status {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.file.Utimens(atime, mtime)
+	return ToContextFile(f.file).UtimensContext(ctx, atime, mtime)
 }
 
 func (f *lockingFile) Truncate(size uint64) fuse.Status {
+	return f.TruncateContext(&fuse.Context{}, size)
+}
+
+func (f *lockingFile) TruncateContext(ctx *fuse.Context, size uint64) fuse.Status {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.file.Truncate(size)
+	return ToContextFile(f.file).TruncateContext(ctx, size)
 }
 
 func (f *lockingFile) Chown(uid uint32, gid uint32) fuse.Status {
+	return f.ChownContext(&fuse.Context{}, uid, gid)
+}
+
+func (f *lockingFile) ChownContext(ctx *fuse.Context, uid uint32, gid uint32) fuse.Status {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.file.Chown(uid, gid)
+	return ToContextFile(f.file).ChownContext(ctx, uid, gid)
 }
 
 func (f *lockingFile) Chmod(perms uint32) fuse.Status {
+	return f.ChmodContext(&fuse.Context{}, perms)
+}
+
+func (f *lockingFile) ChmodContext(ctx *fuse.Context, perms uint32) fuse.Status {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.file.Chmod(perms)
+	return ToContextFile(f.file).ChmodContext(ctx, perms)
 }
 
 func (f *lockingFile) Allocate(off uint64, size uint64, mode uint32) (code fuse.Status) {
+	return f.AllocateContext(&fuse.Context{}, off, size, mode)
+}
+
+func (f *lockingFile) AllocateContext(ctx *fuse.Context, off uint64, size uint64, mode uint32) (code fuse.Status) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.file.Allocate(off, size, mode)
+	return ToContextFile(f.file).AllocateContext(ctx, off, size, mode)
 }
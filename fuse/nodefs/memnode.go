@@ -61,6 +61,7 @@ func (fs *memNodeFs) newNode() *memNode {
 	}
 	now := time.Now()
 	n.info.SetTimes(&now, &now, &now)
+	n.btime = now
 	n.info.Mode = fuse.S_IFDIR | 0777
 	return n
 }
@@ -75,9 +76,10 @@ type memNode struct {
 	fs *memNodeFs
 	id int
 
-	mu   sync.Mutex
-	link string
-	info fuse.Attr
+	mu    sync.Mutex
+	link  string
+	info  fuse.Attr
+	btime time.Time
 }
 
 func (n *memNode) filename() string {
@@ -105,6 +107,28 @@ func (n *memNode) Mkdir(name string, mode uint32, context *fuse.Context) (newNod
 	return ch.Inode(), fuse.OK
 }
 
+func (n *memNode) Mknod(name string, mode uint32, dev uint32, context *fuse.Context) (newNode *Inode, code fuse.Status) {
+	ch := n.fs.newNode()
+	switch mode & syscall.S_IFMT {
+	case 0, syscall.S_IFREG:
+		f, err := os.Create(ch.filename())
+		if err != nil {
+			return nil, fuse.ToStatus(err)
+		}
+		f.Close()
+		mode |= syscall.S_IFREG
+	case syscall.S_IFIFO, syscall.S_IFSOCK, syscall.S_IFCHR, syscall.S_IFBLK:
+		// Special files have no contents; the kernel
+		// handles I/O on them without calling Open.
+		ch.info.Rdev = dev
+	default:
+		return nil, fuse.EINVAL
+	}
+	ch.info.Mode = mode
+	n.Inode().NewChild(name, false, ch)
+	return ch.Inode(), fuse.OK
+}
+
 func (n *memNode) Unlink(name string, context *fuse.Context) (code fuse.Status) {
 	ch := n.Inode().RmChild(name)
 	if ch == nil {
@@ -138,6 +162,19 @@ func (n *memNode) Link(name string, existing Node, context *fuse.Context) (*Inod
 }
 
 func (n *memNode) Create(name string, flags uint32, mode uint32, context *fuse.Context) (file File, node *Inode, code fuse.Status) {
+	if existing := n.Inode().GetChild(name); existing != nil {
+		// The kernel only calls Create if it thinks name does
+		// not exist, but its negative entry may be stale.
+		if flags&syscall.O_EXCL != 0 {
+			return nil, nil, fuse.Status(syscall.EEXIST)
+		}
+		if existing.IsDir() {
+			return nil, nil, fuse.EISDIR
+		}
+		file, code = existing.Node().Open(flags, context)
+		return file, existing, code
+	}
+
 	ch := n.fs.newNode()
 	ch.info.Mode = mode | fuse.S_IFREG
 
@@ -149,6 +186,17 @@ func (n *memNode) Create(name string, flags uint32, mode uint32, context *fuse.C
 	return ch.newFile(f), ch.Inode(), fuse.OK
 }
 
+func (n *memNode) CreateUnnamed(flags uint32, mode uint32, context *fuse.Context) (file File, child Node, code fuse.Status) {
+	ch := n.fs.newNode()
+	ch.info.Mode = mode | fuse.S_IFREG
+
+	f, err := os.Create(ch.filename())
+	if err != nil {
+		return nil, nil, fuse.ToStatus(err)
+	}
+	return ch.newFile(f), ch, fuse.OK
+}
+
 type memNodeFile struct {
 	File
 	node *memNode
@@ -162,6 +210,20 @@ func (n *memNodeFile) InnerFile() File {
 	return n.File
 }
 
+func (n *memNodeFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	written, code := n.File.Write(data, off)
+	if code.Ok() {
+		// Keep the size current for attributes returned before
+		// Flush, eg. by Link.
+		n.node.mu.Lock()
+		if end := uint64(off) + uint64(written); end > n.node.info.Size {
+			n.node.info.Size = end
+		}
+		n.node.mu.Unlock()
+	}
+	return written, code
+}
+
 func (n *memNodeFile) Flush() fuse.Status {
 	code := n.File.Flush()
 
@@ -205,7 +267,7 @@ func (n *memNode) GetAttr(fi *fuse.Attr, file File, context *fuse.Context) (code
 
 func (n *memNode) Truncate(file File, size uint64, context *fuse.Context) (code fuse.Status) {
 	if file != nil {
-		code = file.Truncate(size)
+		code = ToContextFile(file).TruncateContext(context, size)
 	} else {
 		err := os.Truncate(n.filename(), int64(size))
 		code = fuse.ToStatus(err)
@@ -232,6 +294,18 @@ func (n *memNode) Utimens(file File, atime *time.Time, mtime *time.Time, context
 	return fuse.OK
 }
 
+func (n *memNode) BirthTime(file File, context *fuse.Context) (time.Time, fuse.Status) {
+	return n.btime, fuse.OK
+}
+
+func (n *memNode) SetCtime(file File, ctime time.Time, context *fuse.Context) (code fuse.Status) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.info.SetTimes(nil, nil, &ctime)
+	return fuse.OK
+}
+
 func (n *memNode) Chmod(file File, perms uint32, context *fuse.Context) (code fuse.Status) {
 	n.info.Mode = (n.info.Mode &^ 07777) | perms
 	now := time.Now()
@@ -242,11 +316,15 @@ func (n *memNode) Chmod(file File, perms uint32, context *fuse.Context) (code fu
 }
 
 func (n *memNode) Chown(file File, uid uint32, gid uint32, context *fuse.Context) (code fuse.Status) {
-	n.info.Uid = uid
-	n.info.Gid = gid
 	now := time.Now()
 	n.mu.Lock()
 	defer n.mu.Unlock()
+	if uid != ^uint32(0) {
+		n.info.Uid = uid
+	}
+	if gid != ^uint32(0) {
+		n.info.Gid = gid
+	}
 	n.info.SetTimes(nil, nil, &now)
 	return fuse.OK
 }
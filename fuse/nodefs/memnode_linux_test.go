@@ -0,0 +1,88 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"golang.org/x/sys/unix"
+)
+
+func TestMemNodeTmpFile(t *testing.T) {
+	wd, _, clean := setupMemNodeTest(t)
+	defer clean()
+
+	fd, err := syscall.Open(wd, unix.O_TMPFILE|syscall.O_RDWR, 0644)
+	if err == syscall.EOPNOTSUPP {
+		t.Skip("kernel does not send TMPFILE")
+	}
+	if err != nil {
+		t.Fatalf("Open(O_TMPFILE): %v", err)
+	}
+	if _, err := syscall.Write(fd, []byte("hello")); err != nil {
+		syscall.Close(fd)
+		t.Fatalf("Write: %v", err)
+	}
+
+	names, err := ioutil.ReadDir(wd)
+	if err != nil || len(names) != 0 {
+		t.Fatalf("ReadDir: %v, %v", names, err)
+	}
+
+	if err := unix.Linkat(unix.AT_FDCWD, fmt.Sprintf("/proc/self/fd/%d", fd), unix.AT_FDCWD, wd+"/named", unix.AT_SYMLINK_FOLLOW); err != nil {
+		t.Fatalf("Linkat: %v", err)
+	}
+	if err := syscall.Close(fd); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	content, err := ioutil.ReadFile(wd + "/named")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(content) != "hello" {
+		t.Errorf("got %q, want %q", content, "hello")
+	}
+}
+
+// statx calls statx(2) on path without following symlinks.
+func statx(path string, mask uint32) (*fuse.Statx, error) {
+	var st unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, int(mask), &st); err != nil {
+		return nil, err
+	}
+	var out fuse.Statx
+	out.FromStatx(&st)
+	return &out, nil
+}
+
+func TestMemNodeBirthTime(t *testing.T) {
+	wd, _, clean := setupMemNodeTest(t)
+	defer clean()
+
+	before := time.Now()
+	if err := ioutil.WriteFile(wd+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	after := time.Now()
+
+	st, err := statx(wd+"/file", unix.STATX_BASIC_STATS|unix.STATX_BTIME)
+	if err != nil {
+		t.Fatalf("statx: %v", err)
+	}
+	if st.Mask&unix.STATX_BTIME == 0 {
+		t.Skip("kernel does not send STATX")
+	}
+	if btime := st.Btime.Time(); btime.Before(before) || btime.After(after) {
+		t.Errorf("got btime %v, want between %v and %v", btime, before, after)
+	}
+	if st.Size != 5 || st.Mode&syscall.S_IFMT != syscall.S_IFREG {
+		t.Errorf("got size %d mode %o, want 5, regular file", st.Size, st.Mode)
+	}
+}
@@ -6,7 +6,9 @@ package nodefs
 
 import (
 	"io/ioutil"
+	"net"
 	"os"
+	"syscall"
 	"testing"
 	"time"
 
@@ -125,4 +127,103 @@ func TestMemNodeSetattr(t *testing.T) {
 			t.Errorf("got (%d, %d), want 42,21", attr.Uid, attr.Gid)
 		}
 	}
+
+	// chgrp leaves the owner alone.
+	if err := f.Chown(-1, 43); err != nil {
+		t.Errorf("Chown: %v", err)
+	}
+	if fi, err := f.Stat(); err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	} else {
+		attr := fuse.ToStatT(fi)
+		if attr.Gid != 43 || attr.Uid != 21 {
+			t.Errorf("got (%d, %d), want 21,43", attr.Uid, attr.Gid)
+		}
+	}
+}
+
+func TestMemNodeMknod(t *testing.T) {
+	wd, _, clean := setupMemNodeTest(t)
+	defer clean()
+
+	if err := syscall.Mkfifo(wd+"/fifo", 0644); err != nil {
+		t.Fatalf("Mkfifo: %v", err)
+	}
+	l, err := net.Listen("unix", wd+"/socket")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+
+	for name, want := range map[string]os.FileMode{
+		"fifo":   os.ModeNamedPipe,
+		"socket": os.ModeSocket,
+	} {
+		fi, err := os.Lstat(wd + "/" + name)
+		if err != nil {
+			t.Fatalf("Lstat(%q): %v", name, err)
+		}
+		if fi.Mode()&os.ModeType != want {
+			t.Errorf("%s: got mode %v, want type %v", name, fi.Mode(), want)
+		}
+	}
+
+	// Data through the FIFO does not reach the file system.
+	done := make(chan error, 1)
+	go func() {
+		done <- ioutil.WriteFile(wd+"/fifo", []byte("hello"), 0644)
+	}()
+	content, err := ioutil.ReadFile(wd + "/fifo")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if string(content) != "hello" {
+		t.Errorf("got %q, want %q", content, "hello")
+	}
+
+	go func() {
+		c, err := l.Accept()
+		if err == nil {
+			c.Write([]byte("x"))
+			c.Close()
+		}
+	}()
+	c, err := net.Dial("unix", wd+"/socket")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+	buf := make([]byte, 1)
+	if _, err := c.Read(buf); err != nil || buf[0] != 'x' {
+		t.Errorf("Read: %q, %v", buf, err)
+	}
+}
+
+func TestMemNodeCreateExclusive(t *testing.T) {
+	back := testutil.TempDir()
+	defer os.RemoveAll(back)
+	root := NewMemNodeFSRoot(back + "/")
+	NewFileSystemConnector(root, nil)
+
+	flags := uint32(os.O_WRONLY | os.O_CREATE | os.O_EXCL)
+	f, node, code := root.Create("lock", flags, 0644, nil)
+	if !code.Ok() {
+		t.Fatalf("Create: %v", code)
+	}
+	f.Release()
+
+	if _, _, code := root.Create("lock", flags, 0644, nil); code != fuse.Status(syscall.EEXIST) {
+		t.Errorf("Create with O_EXCL: got %v, want EEXIST", code)
+	}
+	f, again, code := root.Create("lock", uint32(os.O_WRONLY|os.O_CREATE), 0644, nil)
+	if !code.Ok() {
+		t.Fatalf("Create without O_EXCL: %v", code)
+	}
+	f.Release()
+	if again != node {
+		t.Errorf("Create without O_EXCL returned a new node")
+	}
 }
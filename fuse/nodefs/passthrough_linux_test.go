@@ -0,0 +1,93 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+// passthroughNode serves a file on the host, counting reads that
+// reach the File.
+type passthroughNode struct {
+	Node
+	path  string
+	reads int32
+}
+
+type countingReadFile struct {
+	File
+	node *passthroughNode
+}
+
+func (f *countingReadFile) PassthroughFd() (int, bool) {
+	return f.File.(PassthroughFile).PassthroughFd()
+}
+
+func (f *countingReadFile) Read(buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	atomic.AddInt32(&f.node.reads, 1)
+	return f.File.Read(buf, off)
+}
+
+func (n *passthroughNode) Open(flags uint32, context *fuse.Context) (File, fuse.Status) {
+	f, err := os.OpenFile(n.path, int(flags), 0)
+	if err != nil {
+		return nil, fuse.ToStatus(err)
+	}
+	return &countingReadFile{File: NewLoopbackFile(f), node: n}, fuse.OK
+}
+
+func (n *passthroughNode) GetAttr(out *fuse.Attr, file File, context *fuse.Context) fuse.Status {
+	fi, err := os.Lstat(n.path)
+	if err != nil {
+		return fuse.ToStatus(err)
+	}
+	out.Mode = fuse.S_IFREG | 0644
+	out.Size = uint64(fi.Size())
+	return fuse.OK
+}
+
+func TestPassthrough(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	backing := dir + "/backing"
+	if err := ioutil.WriteFile(backing, []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	mnt := dir + "/mnt"
+	os.Mkdir(mnt, 0755)
+
+	root := NewDefaultNode()
+	s, _, err := Mount(mnt, root, &fuse.MountOptions{
+		EnablePassthrough: true,
+		Debug:             testutil.VerboseTest(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	defer s.Unmount()
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatalf("WaitMount: %v", err)
+	}
+	node := &passthroughNode{Node: NewDefaultNode(), path: backing}
+	root.Inode().NewChild("file", false, node)
+
+	content, err := ioutil.ReadFile(mnt + "/file")
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("ReadFile: %q, %v", content, err)
+	}
+	if s.KernelSettings().Flags2&fuse.CAP2_PASSTHROUGH == 0 {
+		t.Skip("kernel does not support passthrough")
+	}
+	if reads := atomic.LoadInt32(&node.reads); reads != 0 {
+		t.Errorf("got %d reads in the file system, want 0", reads)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// NewReadAheadFile wraps a File to prefetch data for sequential
+// readers. Once a read continues where the previous one stopped,
+// the next window bytes are read from f in the background, and later
+// reads are served from memory. When the reader enters a prefetched
+// window, the window after it is fetched, so a steady reader does
+// not wait on the backend. This hides backend latency for streaming
+// reads beyond what the kernel's readahead covers. At most two
+// windows are kept in memory.
+//
+// The prefetch runs concurrently with other calls on f, so f.Read
+// must be safe for concurrent use. Writes through this File discard
+// prefetched data; changes made to the backend by other means may
+// not be seen until the next prefetch.
+func NewReadAheadFile(f File, window int) File {
+	return &readAheadFile{
+		forwardingFile: forwardingFile{f},
+		window:         window,
+	}
+}
+
+type readAheadFile struct {
+	forwardingFile
+	window int
+
+	mu sync.Mutex
+
+	// next is the offset following the last read.
+	next int64
+
+	// cache holds prefetched data at cacheOff, at most two
+	// windows. If cacheEOF is set, the file ends at the end of
+	// cache.
+	cache    []byte
+	cacheOff int64
+	cacheEOF bool
+
+	// pending is the prefetch in flight, if any.
+	pending *readAhead
+
+	// gen is incremented when the file is changed, to discard
+	// prefetches that started earlier.
+	gen uint64
+}
+
+type readAhead struct {
+	off  int64
+	done chan struct{}
+}
+
+func (f *readAheadFile) InnerFile() File {
+	return f.File
+}
+
+func (f *readAheadFile) String() string {
+	return fmt.Sprintf("readAheadFile(%d, %s)", f.window, f.File.String())
+}
+
+func (f *readAheadFile) Read(buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	return f.ReadContext(&fuse.Context{}, buf, off)
+}
+
+func (f *readAheadFile) ReadContext(ctx *fuse.Context, buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sequential := off == f.next
+	f.next = off + int64(len(buf))
+	if p := f.pending; p != nil && off+int64(len(buf)) > p.off && off < p.off+int64(f.window) {
+		f.mu.Unlock()
+		<-p.done
+		f.mu.Lock()
+	}
+
+	res, code := f.readCacheLocked(buf, off)
+	if res == nil {
+		f.mu.Unlock()
+		res, code = ToContextFile(f.File).ReadContext(ctx, buf, off)
+		f.mu.Lock()
+	}
+	if code.Ok() && sequential && f.pending == nil {
+		if !f.cachedLocked(f.next) {
+			if !f.pastEOFLocked(f.next) {
+				f.prefetchLocked(f.next)
+			}
+		} else if end := f.cacheOff + int64(len(f.cache)); !f.cacheEOF && f.next >= end-int64(f.window) {
+			// The reader is in the last window, so fetch
+			// the one after it.
+			f.prefetchLocked(end)
+		}
+	}
+	return res, code
+}
+
+// cachedLocked returns whether off is in the prefetched data.
+func (f *readAheadFile) cachedLocked(off int64) bool {
+	return f.cache != nil && off >= f.cacheOff && off < f.cacheOff+int64(len(f.cache))
+}
+
+// pastEOFLocked returns whether the prefetched data shows that off
+// is beyond the end of the file.
+func (f *readAheadFile) pastEOFLocked(off int64) bool {
+	return f.cache != nil && f.cacheEOF && off >= f.cacheOff+int64(len(f.cache))
+}
+
+// readCacheLocked serves a read from the prefetched data. It returns
+// nil if the data is not available.
+func (f *readAheadFile) readCacheLocked(buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	if f.cache == nil || off < f.cacheOff {
+		return nil, fuse.OK
+	}
+	end := f.cacheOff + int64(len(f.cache))
+	if off > end || (off+int64(len(buf)) > end && !f.cacheEOF) {
+		return nil, fuse.OK
+	}
+	n := copy(buf, f.cache[off-f.cacheOff:])
+	return fuse.ReadResultData(buf[:n]), fuse.OK
+}
+
+func (f *readAheadFile) prefetchLocked(off int64) {
+	p := &readAhead{off: off, done: make(chan struct{})}
+	f.pending = p
+	gen := f.gen
+	go func() {
+		defer close(p.done)
+		// The prefetch outlives the read that started it, so it
+		// does not get that read's context.
+		buf := make([]byte, f.window)
+		res, code := ToContextFile(f.File).ReadContext(&fuse.Context{}, buf, off)
+		var data []byte
+		if code.Ok() {
+			data, code = res.Bytes(buf)
+			res.Done()
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.pending == p {
+			f.pending = nil
+		}
+		if code.Ok() && gen == f.gen {
+			f.storeLocked(data, off)
+		}
+	}()
+}
+
+// storeLocked adds prefetched data at off to the cache. If it
+// follows the cached data, the last window of that is kept, since
+// the reader may still be in it.
+func (f *readAheadFile) storeLocked(data []byte, off int64) {
+	end := f.cacheOff + int64(len(f.cache))
+	if f.cache != nil && off == end {
+		keep := f.cache
+		if len(keep) > f.window {
+			keep = keep[len(keep)-f.window:]
+		}
+		f.cache = append(append(make([]byte, 0, len(keep)+len(data)), keep...), data...)
+		f.cacheOff = end - int64(len(keep))
+	} else {
+		f.cache = data
+		f.cacheOff = off
+	}
+	f.cacheEOF = len(data) < f.window
+}
+
+func (f *readAheadFile) invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gen++
+	f.cache = nil
+}
+
+// modify runs a call that changes the file. Prefetched data is
+// discarded both before and after, since a prefetch may start while
+// the call runs.
+func (f *readAheadFile) modify(call func() fuse.Status) fuse.Status {
+	f.invalidate()
+	defer f.invalidate()
+	return call()
+}
+
+func (f *readAheadFile) Write(data []byte, off int64) (written uint32, code fuse.Status) {
+	return f.WriteContext(&fuse.Context{}, data, off)
+}
+
+func (f *readAheadFile) WriteContext(ctx *fuse.Context, data []byte, off int64) (written uint32, code fuse.Status) {
+	code = f.modify(func() fuse.Status {
+		written, code = ToContextFile(f.File).WriteContext(ctx, data, off)
+		return code
+	})
+	return written, code
+}
+
+func (f *readAheadFile) Truncate(size uint64) fuse.Status {
+	return f.TruncateContext(&fuse.Context{}, size)
+}
+
+func (f *readAheadFile) TruncateContext(ctx *fuse.Context, size uint64) fuse.Status {
+	return f.modify(func() fuse.Status {
+		return ToContextFile(f.File).TruncateContext(ctx, size)
+	})
+}
+
+func (f *readAheadFile) Allocate(off uint64, size uint64, mode uint32) fuse.Status {
+	return f.AllocateContext(&fuse.Context{}, off, size, mode)
+}
+
+func (f *readAheadFile) AllocateContext(ctx *fuse.Context, off uint64, size uint64, mode uint32) fuse.Status {
+	return f.modify(func() fuse.Status {
+		return ToContextFile(f.File).AllocateContext(ctx, off, size, mode)
+	})
+}
+
+func (f *readAheadFile) Release() {
+	f.ReleaseContext(&fuse.Context{})
+}
+
+func (f *readAheadFile) ReleaseContext(ctx *fuse.Context) {
+	f.mu.Lock()
+	f.gen++
+	f.cache = nil
+	p := f.pending
+	f.mu.Unlock()
+
+	// Do not call into f.File after it is released.
+	if p != nil {
+		<-p.done
+	}
+	ToContextFile(f.File).ReleaseContext(ctx)
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// syncFile is an in-memory File that is safe for concurrent use, and
+// counts backend reads.
+type syncFile struct {
+	File
+	mu    sync.Mutex
+	data  []byte
+	reads int
+}
+
+func (f *syncFile) Read(buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads++
+	if off >= int64(len(f.data)) {
+		return fuse.ReadResultData(nil), fuse.OK
+	}
+	n := copy(buf, f.data[off:])
+	return fuse.ReadResultData(buf[:n]), fuse.OK
+}
+
+func (f *syncFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return uint32(copy(f.data[off:], data)), fuse.OK
+}
+
+func TestReadAheadFile(t *testing.T) {
+	want := make([]byte, 100)
+	for i := range want {
+		want[i] = 'a' + byte(i%26)
+	}
+	back := &syncFile{File: NewDefaultFile(), data: append([]byte{}, want...)}
+	f := NewReadAheadFile(back, 32)
+
+	var got []byte
+	buf := make([]byte, 8)
+	for {
+		res, code := f.Read(buf, int64(len(got)))
+		if !code.Ok() {
+			t.Fatalf("Read: %v", code)
+		}
+		data, _ := res.Bytes(buf)
+		if len(data) == 0 {
+			break
+		}
+		got = append(got, data...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Writes discard prefetched data.
+	f.Write([]byte("XYZ"), 0)
+	res, code := f.Read(buf, 0)
+	if !code.Ok() {
+		t.Fatalf("Read: %v", code)
+	}
+	if data, _ := res.Bytes(buf); string(data) != "XYZdefgh" {
+		t.Errorf("Read after Write: got %q", data)
+	}
+
+	f.Release()
+	// 1 plain read, 4 prefetches of 32 bytes, the read after
+	// Write, and its prefetch.
+	if back.reads > 7 {
+		t.Errorf("got %d backend reads, want at most 7", back.reads)
+	}
+}
+
+func TestReadAheadFilePipelined(t *testing.T) {
+	back := &syncFile{File: NewDefaultFile(), data: make([]byte, 100)}
+	f := NewReadAheadFile(back, 32).(*readAheadFile)
+	defer f.Release()
+
+	wait := func() {
+		f.mu.Lock()
+		p := f.pending
+		f.mu.Unlock()
+		if p != nil {
+			<-p.done
+		}
+	}
+
+	buf := make([]byte, 8)
+	f.Read(buf, 0)
+	wait()
+	// The window at 8 is prefetched. Entering it starts the
+	// fetch of the window at 40.
+	f.Read(buf, 8)
+	wait()
+	back.mu.Lock()
+	reads := back.reads
+	back.mu.Unlock()
+	if reads != 3 {
+		t.Errorf("got %d backend reads, want 3", reads)
+	}
+
+	// The rest of the first window is still cached.
+	for off := int64(16); off < 72; off += 8 {
+		if _, code := f.Read(buf, off); !code.Ok() {
+			t.Fatalf("Read: %v", code)
+		}
+	}
+	wait()
+	back.mu.Lock()
+	reads = back.reads
+	back.mu.Unlock()
+	if reads != 4 {
+		t.Errorf("got %d backend reads, want 4", reads)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// statsNode is the file named by Options.StatsFile. Its contents
+// are generated on each open.
+type statsNode struct {
+	Node
+	conn *FileSystemConnector
+}
+
+func newStatsNode(c *FileSystemConnector) *statsNode {
+	return &statsNode{
+		Node: NewDefaultNode(),
+		conn: c,
+	}
+}
+
+// Deletable returns false, so the kernel forgetting the file does
+// not remove it from the tree.
+func (n *statsNode) Deletable() bool {
+	return false
+}
+
+func (n *statsNode) GetAttr(out *fuse.Attr, file File, context *fuse.Context) fuse.Status {
+	out.Mode = fuse.S_IFREG | 0444
+	out.Size = uint64(len(n.conn.statsData()))
+	now := time.Now()
+	out.SetTimes(&now, &now, &now)
+	return fuse.OK
+}
+
+func (n *statsNode) Open(flags uint32, context *fuse.Context) (File, fuse.Status) {
+	if flags&syscall.O_ACCMODE != syscall.O_RDONLY {
+		return nil, fuse.EACCES
+	}
+	return &WithFlags{
+		File:        NewReadOnlyFile(NewDataFile(n.conn.statsData())),
+		Description: "stats",
+		FuseFlags:   fuse.FOPEN_DIRECT_IO,
+	}, fuse.OK
+}
+
+// statsData formats the connector and server counters as text, one
+// "name: value" pair per line.
+func (c *FileSystemConnector) statsData() []byte {
+	var b bytes.Buffer
+	mounts := c.Mounts()
+	open := 0
+	for _, m := range mounts {
+		open += m.OpenFiles
+	}
+	fmt.Fprintf(&b, "inodes: %d\n", c.InodeHandleCount())
+	fmt.Fprintf(&b, "mounts: %d\n", len(mounts))
+	fmt.Fprintf(&b, "open_files: %d\n", open)
+	if c.server == nil {
+		return b.Bytes()
+	}
+	fmt.Fprintf(&b, "%s\n", c.server.DebugData())
+
+	stats := c.server.Stats()
+	var ops []string
+	for op := range stats {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		s := stats[op]
+		fmt.Fprintf(&b, "%s: count=%d errors=%d total=%v p50=%v p90=%v p99=%v\n",
+			op, s.Count, s.Errors, s.Total, s.P50, s.P90, s.P99)
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,213 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// NewWriteBehindFile wraps a File so writes return as soon as their
+// data is queued, and are written to f from a background goroutine.
+// At most maxDirty bytes are queued; a write beyond that waits for
+// the queue to drain. A write larger than maxDirty is queued once
+// the queue is empty.
+//
+// The first error from a queued write is returned by the next Flush
+// or Fsync, so it surfaces in close(2) or fsync(2). Read, GetAttr,
+// and calls that change the file wait for queued writes to finish.
+func NewWriteBehindFile(f File, maxDirty int) File {
+	wf := &writeBehindFile{
+		forwardingFile: forwardingFile{f},
+		maxDirty:       maxDirty,
+	}
+	wf.cond = sync.NewCond(&wf.mu)
+	return wf
+}
+
+type writeBehindFile struct {
+	forwardingFile
+	maxDirty int
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	// queue holds writes not yet passed to File, and dirty is the
+	// number of bytes in them and in the write in flight.
+	queue []queuedWrite
+	dirty int
+
+	// busy is set while the background goroutine runs.
+	busy bool
+
+	// err is the first failure of a queued write, not yet
+	// reported.
+	err fuse.Status
+}
+
+type queuedWrite struct {
+	data []byte
+	off  int64
+}
+
+func (f *writeBehindFile) InnerFile() File {
+	return f.File
+}
+
+func (f *writeBehindFile) String() string {
+	return fmt.Sprintf("writeBehindFile(%d, %s)", f.maxDirty, f.File.String())
+}
+
+func (f *writeBehindFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	// data is owned by the caller, and may be reused after we return.
+	w := queuedWrite{data: append([]byte{}, data...), off: off}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.dirty > 0 && f.dirty+len(data) > f.maxDirty {
+		f.cond.Wait()
+	}
+	f.queue = append(f.queue, w)
+	f.dirty += len(data)
+	if !f.busy {
+		f.busy = true
+		go f.drain()
+	}
+	return uint32(len(data)), fuse.OK
+}
+
+// WriteContext queues the write like Write. The data is written
+// after the call returns, so ctx is not used.
+func (f *writeBehindFile) WriteContext(ctx *fuse.Context, data []byte, off int64) (uint32, fuse.Status) {
+	return f.Write(data, off)
+}
+
+// drain writes out queued writes in order, until the queue is empty.
+func (f *writeBehindFile) drain() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.queue) > 0 {
+		w := f.queue[0]
+		f.queue = f.queue[1:]
+
+		f.mu.Unlock()
+		n, code := ToContextFile(f.File).WriteContext(&fuse.Context{}, w.data, w.off)
+		if code.Ok() && int(n) < len(w.data) {
+			code = fuse.EIO
+		}
+		f.mu.Lock()
+
+		if !code.Ok() && f.err.Ok() {
+			f.err = code
+		}
+		f.dirty -= len(w.data)
+		f.cond.Broadcast()
+	}
+	f.queue = nil
+	f.busy = false
+	f.cond.Broadcast()
+}
+
+// wait waits for queued writes to finish.
+func (f *writeBehindFile) wait() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.busy {
+		f.cond.Wait()
+	}
+}
+
+// sync waits for queued writes to finish, and returns and clears
+// the pending error.
+func (f *writeBehindFile) sync() fuse.Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.busy {
+		f.cond.Wait()
+	}
+	code := f.err
+	f.err = fuse.OK
+	return code
+}
+
+func (f *writeBehindFile) Read(buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	return f.ReadContext(&fuse.Context{}, buf, off)
+}
+
+func (f *writeBehindFile) ReadContext(ctx *fuse.Context, buf []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	f.wait()
+	return ToContextFile(f.File).ReadContext(ctx, buf, off)
+}
+
+func (f *writeBehindFile) GetAttr(out *fuse.Attr) fuse.Status {
+	return f.GetAttrContext(&fuse.Context{}, out)
+}
+
+func (f *writeBehindFile) GetAttrContext(ctx *fuse.Context, out *fuse.Attr) fuse.Status {
+	f.wait()
+	return ToContextFile(f.File).GetAttrContext(ctx, out)
+}
+
+func (f *writeBehindFile) Truncate(size uint64) fuse.Status {
+	return f.TruncateContext(&fuse.Context{}, size)
+}
+
+func (f *writeBehindFile) TruncateContext(ctx *fuse.Context, size uint64) fuse.Status {
+	f.wait()
+	return ToContextFile(f.File).TruncateContext(ctx, size)
+}
+
+func (f *writeBehindFile) Allocate(off uint64, size uint64, mode uint32) fuse.Status {
+	return f.AllocateContext(&fuse.Context{}, off, size, mode)
+}
+
+func (f *writeBehindFile) AllocateContext(ctx *fuse.Context, off uint64, size uint64, mode uint32) fuse.Status {
+	f.wait()
+	return ToContextFile(f.File).AllocateContext(ctx, off, size, mode)
+}
+
+func (f *writeBehindFile) Utimens(atime *time.Time, mtime *time.Time) fuse.Status {
+	return f.UtimensContext(&fuse.Context{}, atime, mtime)
+}
+
+func (f *writeBehindFile) UtimensContext(ctx *fuse.Context, atime *time.Time, mtime *time.Time) fuse.Status {
+	f.wait()
+	return ToContextFile(f.File).UtimensContext(ctx, atime, mtime)
+}
+
+func (f *writeBehindFile) Flush() fuse.Status {
+	return f.FlushContext(&fuse.Context{})
+}
+
+func (f *writeBehindFile) FlushContext(ctx *fuse.Context) fuse.Status {
+	code := f.sync()
+	if c := ToContextFile(f.File).FlushContext(ctx); code.Ok() {
+		code = c
+	}
+	return code
+}
+
+func (f *writeBehindFile) Fsync(flags int) fuse.Status {
+	return f.FsyncContext(&fuse.Context{}, flags)
+}
+
+func (f *writeBehindFile) FsyncContext(ctx *fuse.Context, flags int) fuse.Status {
+	if code := f.sync(); !code.Ok() {
+		return code
+	}
+	return ToContextFile(f.File).FsyncContext(ctx, flags)
+}
+
+func (f *writeBehindFile) Release() {
+	f.ReleaseContext(&fuse.Context{})
+}
+
+func (f *writeBehindFile) ReleaseContext(ctx *fuse.Context) {
+	f.wait()
+	ToContextFile(f.File).ReleaseContext(ctx)
+}
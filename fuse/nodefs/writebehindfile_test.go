@@ -0,0 +1,79 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// gatedFile is a syncFile whose writes wait for a token on gate, and
+// fail at or beyond failAt.
+type gatedFile struct {
+	syncFile
+	gate   chan struct{}
+	failAt int64
+}
+
+func (f *gatedFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	<-f.gate
+	if off >= f.failAt {
+		return 0, fuse.EIO
+	}
+	return f.syncFile.Write(data, off)
+}
+
+func TestWriteBehindFile(t *testing.T) {
+	back := &gatedFile{
+		syncFile: syncFile{File: NewDefaultFile(), data: make([]byte, 100)},
+		gate:     make(chan struct{}, 10),
+		failAt:   50,
+	}
+	f := NewWriteBehindFile(back, 16)
+
+	// Writes return before reaching the backend.
+	if n, code := f.Write([]byte("0123456789"), 0); !code.Ok() || n != 10 {
+		t.Fatalf("Write: %d, %v", n, code)
+	}
+
+	// Beyond maxDirty, writes wait for the queue to drain.
+	done := make(chan struct{})
+	go func() {
+		f.Write([]byte("abcdefghij"), 10)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Write beyond maxDirty did not wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	back.gate <- struct{}{}
+	<-done
+	back.gate <- struct{}{}
+
+	buf := make([]byte, 20)
+	res, code := f.Read(buf, 0)
+	if !code.Ok() {
+		t.Fatalf("Read: %v", code)
+	}
+	if got, _ := res.Bytes(buf); string(got) != "0123456789abcdefghij" {
+		t.Errorf("Read: got %q", got)
+	}
+
+	// Failures are reported at the next Flush, once.
+	back.gate <- struct{}{}
+	if _, code := f.Write([]byte("x"), 60); !code.Ok() {
+		t.Fatalf("Write: %v", code)
+	}
+	if code := f.Flush(); code != fuse.EIO {
+		t.Errorf("Flush: got %v, want EIO", code)
+	}
+	if code := f.Flush(); !code.Ok() {
+		t.Errorf("second Flush: %v", code)
+	}
+	f.Release()
+}
@@ -61,6 +61,13 @@ const (
 	_OP_RENAME2         = uint32(45) // protocol version 23.
 	_OP_LSEEK           = uint32(46) // protocol version 24
 	_OP_COPY_FILE_RANGE = uint32(47) // protocol version 28.
+	_OP_TMPFILE         = uint32(51) // protocol version 37.
+	_OP_STATX           = uint32(52) // protocol version 39.
+
+	// OSXFUSE extensions. These are never sent by Linux.
+	_OP_SETVOLNAME = uint32(61)
+	_OP_GETXTIMES  = uint32(62)
+	_OP_EXCHANGE   = uint32(63)
 
 	// The following entries don't have to be compatible across Go-FUSE versions.
 	_OP_NOTIFY_INVAL_ENTRY    = uint32(100)
@@ -68,14 +75,29 @@ const (
 	_OP_NOTIFY_STORE_CACHE    = uint32(102)
 	_OP_NOTIFY_RETRIEVE_CACHE = uint32(103)
 	_OP_NOTIFY_DELETE         = uint32(104) // protocol version 18
+	_OP_NOTIFY_POLL           = uint32(105)
+
+	_OPCODE_COUNT = uint32(106)
 
-	_OPCODE_COUNT = uint32(105)
+	// CUSE_INIT replaces INIT on a CUSE device. It doesn't fit the
+	// operationHandlers table, and is handled by cuseInitHandler.
+	_OP_CUSE_INIT = uint32(CUSE_INIT)
 )
 
 ////////////////////////////////////////////////////////////////
 
 func doInit(server *Server, req *request) {
 	input := (*InitIn)(req.inData)
+	if input.Major > _FUSE_KERNEL_VERSION {
+		// The kernel will send another INIT with our major version.
+		out := (*InitOut)(req.outData())
+		*out = InitOut{
+			Major: _FUSE_KERNEL_VERSION,
+			Minor: _OUR_MINOR_VERSION,
+		}
+		req.status = OK
+		return
+	}
 	if input.Major != _FUSE_KERNEL_VERSION {
 		log.Printf("Major versions does not match. Given %d, want %d\n", input.Major, _FUSE_KERNEL_VERSION)
 		req.status = EIO
@@ -91,13 +113,41 @@ func doInit(server *Server, req *request) {
 	server.kernelSettings = *input
 	server.kernelSettings.Flags = input.Flags & (CAP_ASYNC_READ | CAP_BIG_WRITES | CAP_FILE_OPS |
 		CAP_READDIRPLUS | CAP_NO_OPEN_SUPPORT | CAP_PARALLEL_DIROPS)
+	server.kernelSettings.Flags2 = 0
 
 	if server.opts.EnableLocks {
 		server.kernelSettings.Flags |= CAP_FLOCK_LOCKS | CAP_POSIX_LOCKS
 	}
 
+	if server.opts.EnableWritebackCache {
+		server.kernelSettings.Flags |= input.Flags & CAP_WRITEBACK_CACHE
+	}
+
 	if server.opts.EnableAcl {
-		server.kernelSettings.Flags |= CAP_POSIX_ACL
+		server.kernelSettings.Flags |= input.Flags & CAP_POSIX_ACL
+	}
+	if server.opts.EnableSecurityContext && input.Minor >= 38 && input.Flags&CAP_INIT_EXT != 0 {
+		// Before 7.38, the contexts came without
+		// InHeader.TotalExtlen, so we could not find them.
+		server.kernelSettings.Flags |= CAP_INIT_EXT
+		server.kernelSettings.Flags2 |= input.Flags2 & CAP2_SECURITY_CTX
+	}
+	maxStackDepth := uint32(0)
+	if server.opts.EnablePassthrough && !server.opts.EnableWritebackCache &&
+		input.Flags&CAP_INIT_EXT != 0 && input.Flags2&CAP2_PASSTHROUGH != 0 {
+		server.kernelSettings.Flags |= CAP_INIT_EXT
+		server.kernelSettings.Flags2 |= CAP2_PASSTHROUGH
+		// Backing files must be on file systems that do not
+		// stack on other file systems themselves.
+		maxStackDepth = 1
+	}
+	if server.opts.EnableIoUring && input.Flags&CAP_INIT_EXT != 0 &&
+		input.Flags2&CAP2_OVER_IO_URING != 0 {
+		server.kernelSettings.Flags |= CAP_INIT_EXT
+		server.kernelSettings.Flags2 |= CAP2_OVER_IO_URING
+	}
+	if server.opts.ExportSupport {
+		server.kernelSettings.Flags |= input.Flags & CAP_EXPORT_SUPPORT
 	}
 	if server.opts.SyncRead {
 		// Clear CAP_ASYNC_READ
@@ -131,17 +181,25 @@ func doInit(server *Server, req *request) {
 		Minor:               _OUR_MINOR_VERSION,
 		MaxReadAhead:        input.MaxReadAhead,
 		Flags:               server.kernelSettings.Flags,
+		Flags2:              server.kernelSettings.Flags2,
+		MaxStackDepth:       maxStackDepth,
 		MaxWrite:            uint32(server.opts.MaxWrite),
 		CongestionThreshold: uint16(server.opts.MaxBackground * 3 / 4),
 		MaxBackground:       uint16(server.opts.MaxBackground),
 	}
 
-	if server.opts.MaxReadAhead != 0 && uint32(server.opts.MaxReadAhead) < out.MaxReadAhead {
+	if server.opts.MaxReadAhead < 0 {
+		out.MaxReadAhead = 0
+	} else if server.opts.MaxReadAhead != 0 && uint32(server.opts.MaxReadAhead) < out.MaxReadAhead {
 		out.MaxReadAhead = uint32(server.opts.MaxReadAhead)
 	}
 	if out.Minor > input.Minor {
 		out.Minor = input.Minor
 	}
+	server.reqMu.Lock()
+	server.protoMinor = out.Minor
+	server.maxReadAhead = out.MaxReadAhead
+	server.reqMu.Unlock()
 
 	if out.Minor <= 22 {
 		tweaked := *req.handler
@@ -154,9 +212,24 @@ func doInit(server *Server, req *request) {
 	req.status = OK
 }
 
+// openFlags adjusts open flags for writeback cache mode, where the
+// kernel handles appending, and reads pages of files opened
+// write-only to fill its cache.
+func (server *Server) openFlags(flags uint32) uint32 {
+	if server.kernelSettings.Flags&CAP_WRITEBACK_CACHE != 0 {
+		flags &^= syscall.O_APPEND
+		if flags&syscall.O_ACCMODE == syscall.O_WRONLY {
+			flags = flags&^syscall.O_ACCMODE | syscall.O_RDWR
+		}
+	}
+	return flags
+}
+
 func doOpen(server *Server, req *request) {
 	out := (*OpenOut)(req.outData())
-	status := server.fileSystem.Open(req.cancel, (*OpenIn)(req.inData), out)
+	in := (*OpenIn)(req.inData)
+	in.Flags = server.openFlags(in.Flags)
+	status := server.fileSystem.Open(req.cancel, in, out)
 	req.status = status
 	if status != OK {
 		return
@@ -165,8 +238,13 @@ func doOpen(server *Server, req *request) {
 
 func doCreate(server *Server, req *request) {
 	out := (*CreateOut)(req.outData())
-	status := server.fileSystem.Create(req.cancel, (*CreateIn)(req.inData), req.filenames[0], out)
+	in := (*CreateIn)(req.inData)
+	in.Flags = server.openFlags(in.Flags)
+	status := server.fileSystem.Create(req.cancel, in, req.filenames[0], out)
 	req.status = status
+	if status.Ok() {
+		server.setSecurityContexts(req, out.NodeId)
+	}
 }
 
 func doReadDir(server *Server, req *request) {
@@ -201,7 +279,13 @@ func doSetattr(server *Server, req *request) {
 }
 
 func doWrite(server *Server, req *request) {
-	n, status := server.fileSystem.Write(req.cancel, (*WriteIn)(req.inData), req.arg)
+	var n uint32
+	var status Status
+	if req.splicePair != nil {
+		n, status = server.spliceWriteRequest(req, (*WriteIn)(req.inData))
+	} else {
+		n, status = server.fileSystem.Write(req.cancel, (*WriteIn)(req.inData), req.arg)
+	}
 	o := (*WriteOut)(req.outData())
 	o.Size = n
 	req.status = status
@@ -257,8 +341,8 @@ func doGetXAttr(server *Server, req *request) {
 
 	if server.opts.IgnoreSecurityLabels && req.inHeader.Opcode == _OP_GETXATTR {
 		fn := req.filenames[0]
-		if fn == _SECURITY_CAPABILITY || fn == _SECURITY_ACL_DEFAULT ||
-			fn == _SECURITY_ACL {
+		isACL := fn == _SECURITY_ACL_DEFAULT || fn == _SECURITY_ACL
+		if fn == _SECURITY_CAPABILITY || (isACL && !server.opts.EnableAcl) {
 			req.status = ENOATTR
 			return
 		}
@@ -283,6 +367,14 @@ func doGetXAttr(server *Server, req *request) {
 		// For input.size==0, returning ERANGE is an error.
 		req.status = OK
 		out.Size = n
+	} else if req.status.Ok() && n > _XATTR_SIZE_MAX {
+		// Like local file systems, refuse values that the kernel
+		// would not let us set.
+		req.status = Status(syscall.E2BIG)
+		req.flatData = req.flatData[:0]
+	} else if req.status.Ok() && input.Size > 0 && n > input.Size {
+		req.status = ERANGE
+		req.flatData = req.flatData[:0]
 	} else if req.status.Ok() {
 		// ListXAttr called with an empty buffer returns the current size of
 		// the list but does not touch the buffer (see man 2 listxattr).
@@ -311,11 +403,9 @@ func doForget(server *Server, req *request) {
 // doBatchForget - forget a list of NodeIds
 func doBatchForget(server *Server, req *request) {
 	in := (*_BatchForgetIn)(req.inData)
-	wantBytes := uintptr(in.Count) * unsafe.Sizeof(_ForgetOne{})
-	if uintptr(len(req.arg)) < wantBytes {
-		// We have no return value to complain, so log an error.
-		log.Printf("Too few bytes for batch forget. Got %d bytes, want %d (%d entries)",
-			len(req.arg), wantBytes, in.Count)
+	// The payload size was checked in parse.
+	if in.Count == 0 {
+		return
 	}
 
 	h := &reflect.SliceHeader{
@@ -327,7 +417,7 @@ func doBatchForget(server *Server, req *request) {
 	forgets := *(*[]_ForgetOne)(unsafe.Pointer(h))
 	for i, f := range forgets {
 		if server.opts.Debug {
-			log.Printf("doBatchForget: rx %d %d/%d: FORGET n%d {Nlookup=%d}",
+			server.logf("doBatchForget: rx %d %d/%d: FORGET n%d {Nlookup=%d}",
 				req.inHeader.Unique, i+1, len(forgets), f.NodeId, f.Nlookup)
 		}
 		if f.NodeId == pollHackInode {
@@ -351,11 +441,17 @@ func doMknod(server *Server, req *request) {
 	out := (*EntryOut)(req.outData())
 
 	req.status = server.fileSystem.Mknod(req.cancel, (*MknodIn)(req.inData), req.filenames[0], out)
+	if req.status.Ok() {
+		server.setSecurityContexts(req, out.NodeId)
+	}
 }
 
 func doMkdir(server *Server, req *request) {
 	out := (*EntryOut)(req.outData())
 	req.status = server.fileSystem.Mkdir(req.cancel, (*MkdirIn)(req.inData), req.filenames[0], out)
+	if req.status.Ok() {
+		server.setSecurityContexts(req, out.NodeId)
+	}
 }
 
 func doUnlink(server *Server, req *request) {
@@ -405,8 +501,9 @@ func doFsyncDir(server *Server, req *request) {
 }
 
 func doSetXAttr(server *Server, req *request) {
+	in := (*SetXAttrIn)(req.inData)
 	splits := bytes.SplitN(req.arg, []byte{0}, 2)
-	req.status = server.fileSystem.SetXAttr(req.cancel, (*SetXAttrIn)(req.inData), string(splits[0]), splits[1])
+	req.status = server.fileSystem.SetXAttr(req.cancel, in, req.filenames[0], splits[1][:in.Size])
 }
 
 func doRemoveXAttr(server *Server, req *request) {
@@ -420,6 +517,9 @@ func doAccess(server *Server, req *request) {
 func doSymlink(server *Server, req *request) {
 	out := (*EntryOut)(req.outData())
 	req.status = server.fileSystem.Symlink(req.cancel, req.inHeader, req.filenames[1], req.filenames[0], out)
+	if req.status.Ok() {
+		server.setSecurityContexts(req, out.NodeId)
+	}
 }
 
 func doRename(server *Server, req *request) {
@@ -446,11 +546,82 @@ func doStatFs(server *Server, req *request) {
 	}
 }
 
+func doCuseInit(server *Server, req *request) {
+	input := (*CuseInitIn)(req.inData)
+	if server.cuse == nil {
+		log.Printf("CUSE_INIT on a FUSE mount")
+		req.status = EIO
+		return
+	}
+	if input.Major != _FUSE_KERNEL_VERSION || input.Minor < _MINIMUM_MINOR_VERSION {
+		log.Printf("Unsupported CUSE protocol version %d.%d", input.Major, input.Minor)
+		req.status = EIO
+		return
+	}
+
+	out := (*CuseInitOut)(req.outData())
+	*out = CuseInitOut{
+		Major:    _FUSE_KERNEL_VERSION,
+		Minor:    _OUR_MINOR_VERSION,
+		MaxRead:  uint32(server.opts.MaxWrite),
+		MaxWrite: uint32(server.opts.MaxWrite),
+		DevMajor: server.cuse.DevMajor,
+		DevMinor: server.cuse.DevMinor,
+	}
+	if out.Minor > input.Minor {
+		out.Minor = input.Minor
+	}
+
+	server.reqMu.Lock()
+	server.kernelSettings = InitIn{
+		InHeader: input.InHeader,
+		Major:    input.Major,
+		Minor:    input.Minor,
+	}
+	server.protoMinor = out.Minor
+	server.reqMu.Unlock()
+
+	req.flatData = []byte("DEVNAME=" + server.cuse.DevName + "\x00")
+	req.status = OK
+}
+
 func doIoctl(server *Server, req *request) {
-	req.status = Status(syscall.ENOTTY)
+	ioctler, ok := server.fileSystem.(Ioctler)
+	if !ok {
+		req.status = Status(syscall.ENOTTY)
+		return
+	}
+
+	in := (*IoctlIn)(req.inData)
+	if int(in.InSize) > len(req.arg) {
+		req.status = EIO
+		return
+	}
+	result, data, status := ioctler.Ioctl(req.cancel, in, req.arg[:in.InSize])
+	if !status.Ok() {
+		req.status = status
+		return
+	}
+	if len(data) > int(in.OutSize) {
+		data = data[:in.OutSize]
+	}
+	out := (*IoctlOut)(req.outData())
+	out.Result = result
+	req.flatData = data
+	req.status = OK
+}
+
+func doPoll(server *Server, req *request) {
+	poller, ok := server.fileSystem.(Poller)
+	if !ok {
+		req.status = ENOSYS
+		return
+	}
+	req.status = poller.Poll(req.cancel, (*PollIn)(req.inData), (*PollOut)(req.outData()))
 }
 
 func doDestroy(server *Server, req *request) {
+	server.destroy()
 	req.status = OK
 }
 
@@ -476,6 +647,31 @@ func doLseek(server *Server, req *request) {
 	req.status = server.fileSystem.Lseek(req.cancel, in, out)
 }
 
+func doTmpFile(server *Server, req *request) {
+	tf, ok := server.fileSystem.(TmpFiler)
+	if !ok {
+		req.status = ENOSYS
+		return
+	}
+	out := (*CreateOut)(req.outData())
+	in := (*CreateIn)(req.inData)
+	in.Flags = server.openFlags(in.Flags)
+	req.status = tf.TmpFile(req.cancel, in, out)
+	if req.status.Ok() {
+		server.setSecurityContexts(req, out.NodeId)
+	}
+}
+
+func doStatx(server *Server, req *request) {
+	sx, ok := server.fileSystem.(Statxer)
+	if !ok {
+		req.status = ENOSYS
+		return
+	}
+	out := (*StatxOut)(req.outData())
+	req.status = sx.Statx(req.cancel, (*StatxIn)(req.inData), out)
+}
+
 func doCopyFileRange(server *Server, req *request) {
 	in := (*CopyFileRangeIn)(req.inData)
 	out := (*WriteOut)(req.outData())
@@ -483,6 +679,15 @@ func doCopyFileRange(server *Server, req *request) {
 	out.Size, req.status = server.fileSystem.CopyFileRange(req.cancel, in)
 }
 
+// doOSXUnsupported answers OSXFUSE-only requests. The kernel only
+// sends SETVOLNAME and GETXTIMES if we advertise CAP_VOL_RENAME and
+// CAP_XTIMES, which we don't. EXCHANGE (exchangedata(2)) is not
+// supported either. Having a handler avoids logging every such
+// request as unimplemented.
+func doOSXUnsupported(server *Server, req *request) {
+	req.status = ENOSYS
+}
+
 func doInterrupt(server *Server, req *request) {
 	input := (*InterruptIn)(req.inData)
 	server.reqMu.Lock()
@@ -517,6 +722,10 @@ type operationHandler struct {
 	DecodeOut   castPointerFunc
 	FileNames   int
 	FileNameOut bool
+
+	// MinMinor is the protocol minor version that introduced the
+	// opcode.
+	MinMinor uint32
 }
 
 var operationHandlers []*operationHandler
@@ -529,7 +738,13 @@ func operationName(op uint32) string {
 	return h.Name
 }
 
+// cuseInitHandler handles _OP_CUSE_INIT.
+var cuseInitHandler *operationHandler
+
 func getHandler(o uint32) *operationHandler {
+	if o == _OP_CUSE_INIT {
+		return cuseInitHandler
+	}
 	if o >= _OPCODE_COUNT {
 		return nil
 	}
@@ -580,14 +795,17 @@ func init() {
 		_OP_CREATE:          unsafe.Sizeof(CreateIn{}),
 		_OP_INTERRUPT:       unsafe.Sizeof(InterruptIn{}),
 		_OP_BMAP:            unsafe.Sizeof(_BmapIn{}),
-		_OP_IOCTL:           unsafe.Sizeof(_IoctlIn{}),
-		_OP_POLL:            unsafe.Sizeof(_PollIn{}),
+		_OP_IOCTL:           unsafe.Sizeof(IoctlIn{}),
+		_OP_POLL:            unsafe.Sizeof(PollIn{}),
 		_OP_NOTIFY_REPLY:    unsafe.Sizeof(NotifyRetrieveIn{}),
 		_OP_FALLOCATE:       unsafe.Sizeof(FallocateIn{}),
 		_OP_READDIRPLUS:     unsafe.Sizeof(ReadIn{}),
 		_OP_RENAME2:         unsafe.Sizeof(RenameIn{}),
 		_OP_LSEEK:           unsafe.Sizeof(LseekIn{}),
 		_OP_COPY_FILE_RANGE: unsafe.Sizeof(CopyFileRangeIn{}),
+		_OP_TMPFILE:         unsafe.Sizeof(CreateIn{}),
+		_OP_STATX:           unsafe.Sizeof(StatxIn{}),
+		_OP_EXCHANGE:        unsafe.Sizeof(ExchangeIn{}),
 	} {
 		operationHandlers[op].InputSize = sz
 		if sz > maxInputSize {
@@ -613,15 +831,19 @@ func init() {
 		_OP_GETLK:                 unsafe.Sizeof(LkOut{}),
 		_OP_CREATE:                unsafe.Sizeof(CreateOut{}),
 		_OP_BMAP:                  unsafe.Sizeof(_BmapOut{}),
-		_OP_IOCTL:                 unsafe.Sizeof(_IoctlOut{}),
-		_OP_POLL:                  unsafe.Sizeof(_PollOut{}),
+		_OP_IOCTL:                 unsafe.Sizeof(IoctlOut{}),
+		_OP_POLL:                  unsafe.Sizeof(PollOut{}),
 		_OP_NOTIFY_INVAL_ENTRY:    unsafe.Sizeof(NotifyInvalEntryOut{}),
 		_OP_NOTIFY_INVAL_INODE:    unsafe.Sizeof(NotifyInvalInodeOut{}),
 		_OP_NOTIFY_STORE_CACHE:    unsafe.Sizeof(NotifyStoreOut{}),
 		_OP_NOTIFY_RETRIEVE_CACHE: unsafe.Sizeof(NotifyRetrieveOut{}),
 		_OP_NOTIFY_DELETE:         unsafe.Sizeof(NotifyInvalDeleteOut{}),
+		_OP_NOTIFY_POLL:           unsafe.Sizeof(NotifyPollWakeupOut{}),
 		_OP_LSEEK:                 unsafe.Sizeof(LseekOut{}),
 		_OP_COPY_FILE_RANGE:       unsafe.Sizeof(WriteOut{}),
+		_OP_TMPFILE:               unsafe.Sizeof(CreateOut{}),
+		_OP_STATX:                 unsafe.Sizeof(StatxOut{}),
+		_OP_GETXTIMES:             unsafe.Sizeof(GetxtimesOut{}),
 	} {
 		operationHandlers[op].OutputSize = sz
 	}
@@ -672,11 +894,17 @@ func init() {
 		_OP_NOTIFY_STORE_CACHE:    "NOTIFY_STORE",
 		_OP_NOTIFY_RETRIEVE_CACHE: "NOTIFY_RETRIEVE",
 		_OP_NOTIFY_DELETE:         "NOTIFY_DELETE",
+		_OP_NOTIFY_POLL:           "NOTIFY_POLL",
 		_OP_FALLOCATE:             "FALLOCATE",
 		_OP_READDIRPLUS:           "READDIRPLUS",
 		_OP_RENAME2:               "RENAME2",
 		_OP_LSEEK:                 "LSEEK",
 		_OP_COPY_FILE_RANGE:       "COPY_FILE_RANGE",
+		_OP_TMPFILE:               "TMPFILE",
+		_OP_STATX:                 "STATX",
+		_OP_SETVOLNAME:            "SETVOLNAME",
+		_OP_GETXTIMES:             "GETXTIMES",
+		_OP_EXCHANGE:              "EXCHANGE",
 	} {
 		operationHandlers[op].Name = v
 	}
@@ -717,6 +945,7 @@ func init() {
 		_OP_RENAME:          doRename,
 		_OP_STATFS:          doStatFs,
 		_OP_IOCTL:           doIoctl,
+		_OP_POLL:            doPoll,
 		_OP_DESTROY:         doDestroy,
 		_OP_NOTIFY_REPLY:    doNotifyReply,
 		_OP_FALLOCATE:       doFallocate,
@@ -725,6 +954,11 @@ func init() {
 		_OP_INTERRUPT:       doInterrupt,
 		_OP_COPY_FILE_RANGE: doCopyFileRange,
 		_OP_LSEEK:           doLseek,
+		_OP_TMPFILE:         doTmpFile,
+		_OP_STATX:           doStatx,
+		_OP_SETVOLNAME:      doOSXUnsupported,
+		_OP_GETXTIMES:       doOSXUnsupported,
+		_OP_EXCHANGE:        doOSXUnsupported,
 	} {
 		operationHandlers[op].Func = v
 	}
@@ -746,11 +980,17 @@ func init() {
 		_OP_NOTIFY_STORE_CACHE:    func(ptr unsafe.Pointer) interface{} { return (*NotifyStoreOut)(ptr) },
 		_OP_NOTIFY_RETRIEVE_CACHE: func(ptr unsafe.Pointer) interface{} { return (*NotifyRetrieveOut)(ptr) },
 		_OP_NOTIFY_DELETE:         func(ptr unsafe.Pointer) interface{} { return (*NotifyInvalDeleteOut)(ptr) },
+		_OP_NOTIFY_POLL:           func(ptr unsafe.Pointer) interface{} { return (*NotifyPollWakeupOut)(ptr) },
+		_OP_IOCTL:                 func(ptr unsafe.Pointer) interface{} { return (*IoctlOut)(ptr) },
+		_OP_POLL:                  func(ptr unsafe.Pointer) interface{} { return (*PollOut)(ptr) },
 		_OP_STATFS:                func(ptr unsafe.Pointer) interface{} { return (*StatfsOut)(ptr) },
 		_OP_SYMLINK:               func(ptr unsafe.Pointer) interface{} { return (*EntryOut)(ptr) },
 		_OP_GETLK:                 func(ptr unsafe.Pointer) interface{} { return (*LkOut)(ptr) },
 		_OP_LSEEK:                 func(ptr unsafe.Pointer) interface{} { return (*LseekOut)(ptr) },
 		_OP_COPY_FILE_RANGE:       func(ptr unsafe.Pointer) interface{} { return (*WriteOut)(ptr) },
+		_OP_TMPFILE:               func(ptr unsafe.Pointer) interface{} { return (*CreateOut)(ptr) },
+		_OP_STATX:                 func(ptr unsafe.Pointer) interface{} { return (*StatxOut)(ptr) },
+		_OP_GETXTIMES:             func(ptr unsafe.Pointer) interface{} { return (*GetxtimesOut)(ptr) },
 	} {
 		operationHandlers[op].DecodeOut = f
 	}
@@ -764,7 +1004,8 @@ func init() {
 		_OP_LISTXATTR:       func(ptr unsafe.Pointer) interface{} { return (*GetXAttrIn)(ptr) },
 		_OP_SETATTR:         func(ptr unsafe.Pointer) interface{} { return (*SetAttrIn)(ptr) },
 		_OP_INIT:            func(ptr unsafe.Pointer) interface{} { return (*InitIn)(ptr) },
-		_OP_IOCTL:           func(ptr unsafe.Pointer) interface{} { return (*_IoctlIn)(ptr) },
+		_OP_IOCTL:           func(ptr unsafe.Pointer) interface{} { return (*IoctlIn)(ptr) },
+		_OP_POLL:            func(ptr unsafe.Pointer) interface{} { return (*PollIn)(ptr) },
 		_OP_OPEN:            func(ptr unsafe.Pointer) interface{} { return (*OpenIn)(ptr) },
 		_OP_MKNOD:           func(ptr unsafe.Pointer) interface{} { return (*MknodIn)(ptr) },
 		_OP_CREATE:          func(ptr unsafe.Pointer) interface{} { return (*CreateIn)(ptr) },
@@ -789,13 +1030,34 @@ func init() {
 		_OP_INTERRUPT:       func(ptr unsafe.Pointer) interface{} { return (*InterruptIn)(ptr) },
 		_OP_LSEEK:           func(ptr unsafe.Pointer) interface{} { return (*LseekIn)(ptr) },
 		_OP_COPY_FILE_RANGE: func(ptr unsafe.Pointer) interface{} { return (*CopyFileRangeIn)(ptr) },
+		_OP_TMPFILE:         func(ptr unsafe.Pointer) interface{} { return (*CreateIn)(ptr) },
+		_OP_STATX:           func(ptr unsafe.Pointer) interface{} { return (*StatxIn)(ptr) },
+		_OP_EXCHANGE:        func(ptr unsafe.Pointer) interface{} { return (*ExchangeIn)(ptr) },
 	} {
 		operationHandlers[op].DecodeIn = f
 	}
 
+	// Opcodes introduced after the minimum version we support.
+	for op, minor := range map[uint32]uint32{
+		_OP_IOCTL:           11,
+		_OP_POLL:            11,
+		_OP_NOTIFY_REPLY:    15,
+		_OP_BATCH_FORGET:    16,
+		_OP_FALLOCATE:       19,
+		_OP_READDIRPLUS:     21,
+		_OP_RENAME2:         23,
+		_OP_LSEEK:           24,
+		_OP_COPY_FILE_RANGE: 28,
+		// _OP_TMPFILE and _OP_STATX are not listed: the kernel
+		// sends them regardless of the negotiated minor version.
+	} {
+		operationHandlers[op].MinMinor = minor
+	}
+
 	// File name args.
 	for op, count := range map[uint32]int{
 		_OP_CREATE:      1,
+		_OP_TMPFILE:     1,
 		_OP_SETXATTR:    1,
 		_OP_GETXATTR:    1,
 		_OP_LINK:        1,
@@ -808,10 +1070,21 @@ func init() {
 		_OP_RMDIR:       1,
 		_OP_SYMLINK:     2,
 		_OP_UNLINK:      1,
+		_OP_SETVOLNAME:  1,
+		_OP_EXCHANGE:    2,
 	} {
 		operationHandlers[op].FileNames = count
 	}
 
+	cuseInitHandler = &operationHandler{
+		Name:       "CUSE_INIT",
+		Func:       doCuseInit,
+		InputSize:  unsafe.Sizeof(CuseInitIn{}),
+		OutputSize: unsafe.Sizeof(CuseInitOut{}),
+		DecodeIn:   func(ptr unsafe.Pointer) interface{} { return (*CuseInitIn)(ptr) },
+		DecodeOut:  func(ptr unsafe.Pointer) interface{} { return (*CuseInitOut)(ptr) },
+	}
+
 	var r request
 	sizeOfOutHeader := unsafe.Sizeof(OutHeader{})
 	for code, h := range operationHandlers {
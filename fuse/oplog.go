@@ -0,0 +1,115 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// OpRecord describes a request that was handled, for
+// MountOptions.OpHook.
+type OpRecord struct {
+	// Op is the operation name, eg. "LOOKUP".
+	Op string
+
+	// NodeId is the node the request operates on, or 0.
+	NodeId uint64
+
+	// Path is the path of NodeId, if the RawFileSystem implements
+	// NodePather.
+	Path string
+
+	// Names holds the file name arguments, eg. the name looked up
+	// by LOOKUP, or the old and new names of RENAME.
+	Names []string
+
+	// The calling process.
+	Caller
+
+	// Start is when the request was read from the kernel.
+	Start time.Time
+
+	// Latency is the time from reading the request until the
+	// reply was sent.
+	Latency time.Duration
+
+	// Status is the result of the request.
+	Status Status
+
+	// Err is the cause of a failure, if the file system recorded
+	// one with Fail.
+	Err error
+}
+
+// logOp passes the request to MountOptions.OpHook.
+func (ms *Server) logOp(req *request) {
+	rec := OpRecord{
+		Op:      operationName(req.inHeader.Opcode),
+		NodeId:  req.inHeader.NodeId,
+		Names:   req.filenames,
+		Caller:  req.inHeader.Caller,
+		Start:   req.startTime,
+		Latency: time.Now().Sub(req.startTime),
+		Status:  req.status,
+		Err:     req.cause,
+	}
+	if p, ok := ms.fileSystem.(NodePather); ok && rec.NodeId != 0 {
+		rec.Path = p.NodePath(rec.NodeId)
+	}
+	ms.opts.OpHook(&rec)
+}
+
+type jsonOpRecord struct {
+	Time      time.Time `json:"time"`
+	Op        string    `json:"op"`
+	NodeId    uint64    `json:"node,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Names     []string  `json:"names,omitempty"`
+	Uid       uint32    `json:"uid"`
+	Gid       uint32    `json:"gid"`
+	Pid       uint32    `json:"pid"`
+	LatencyNs int64     `json:"latency_ns"`
+	Errno     int32     `json:"errno"`
+	Error     string    `json:"error,omitempty"`
+	Cause     string    `json:"cause,omitempty"`
+}
+
+// NewJSONOpHook returns an OpHook that writes each request to w as a
+// JSON object on a line of its own, eg.
+//
+//	{"time":"2021-04-01T10:00:00.123456Z","op":"LOOKUP","node":1,"names":["file"],"uid":1000,"gid":1000,"pid":4242,"latency_ns":51234,"errno":2,"error":"no such file or directory"}
+//
+// Writes are serialized; errors writing to w are ignored.
+func NewJSONOpHook(w io.Writer) func(*OpRecord) {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(r *OpRecord) {
+		j := jsonOpRecord{
+			Time:      r.Start.UTC(),
+			Op:        r.Op,
+			NodeId:    r.NodeId,
+			Path:      r.Path,
+			Names:     r.Names,
+			Uid:       r.Uid,
+			Gid:       r.Gid,
+			Pid:       r.Pid,
+			LatencyNs: int64(r.Latency),
+			Errno:     int32(r.Status),
+		}
+		if !r.Status.Ok() {
+			j.Error = syscall.Errno(r.Status).Error()
+		}
+		if r.Err != nil {
+			j.Cause = r.Err.Error()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(&j)
+	}
+}
@@ -6,6 +6,13 @@
 // "github.com/hanwen/go-fuse/v2/fs" instead.
 //
 // Package pathfs provides a file system API expressed in filenames.
+//
+// Because every call is addressed by path, file systems with hard
+// links or content-addressed storage are awkward to express here.
+// Such file systems should implement per-inode objects with the fs
+// package instead: there, Lookup returns child inodes, the kernel
+// inode number comes from StableAttr.Ino, and the library owns the
+// tree.
 package pathfs
 
 import (
@@ -39,11 +46,18 @@ type FileSystem interface {
 
 	// These should update the file's ctime too.
 	Chmod(name string, mode uint32, context *fuse.Context) (code fuse.Status)
+	// Chown leaves an ID of ^uint32(0) (-1) unchanged.
 	Chown(name string, uid uint32, gid uint32, context *fuse.Context) (code fuse.Status)
+	// Utimens sets the access and modification times. A nil
+	// time is left unchanged; a time for which fuse.IsUtimeNow
+	// holds means "now", eg. from touch(1).
 	Utimens(name string, Atime *time.Time, Mtime *time.Time, context *fuse.Context) (code fuse.Status)
 
 	Truncate(name string, size uint64, context *fuse.Context) (code fuse.Status)
 
+	// Access checks permissions. Returning ENOSYS means that
+	// Access is not implemented: it is not called again, and all
+	// access is granted.
 	Access(name string, mode uint32, context *fuse.Context) (code fuse.Status)
 
 	// Tree structure
@@ -62,6 +76,10 @@ type FileSystem interface {
 
 	// Called after mount.
 	OnMount(nodeFs *PathNodeFs)
+
+	// Called when the file system is unmounted: when it is a
+	// submount that is removed, or when the FUSE mount is
+	// destroyed.
 	OnUnmount()
 
 	// File handling.  If opening for writing, the file's mtime
@@ -79,6 +97,81 @@ type FileSystem interface {
 	StatFs(name string) *fuse.StatfsOut
 }
 
+// SetAttrer is an optional interface for FileSystem. If implemented,
+// SetAttr is called with all attribute changes of a setattr request,
+// so they can be applied at once. If it returns ENOSYS, the changes
+// are made with Chown, Chmod, Truncate and Utimens instead.
+type SetAttrer interface {
+	SetAttr(name string, input *fuse.SetAttrIn, context *fuse.Context) fuse.Status
+}
+
+// BirthTimer is an optional interface for FileSystem, to report when
+// a file was created, for statx(2). See nodefs.BirthTimer.
+type BirthTimer interface {
+	BirthTime(name string, context *fuse.Context) (time.Time, fuse.Status)
+}
+
+// CtimeSetter is an optional interface for FileSystem. If
+// implemented, SetCtime is called with the ctime that the kernel
+// sends in writeback cache mode, after the other attribute changes
+// of the request. See nodefs.CtimeSetter.
+type CtimeSetter interface {
+	SetCtime(name string, ctime time.Time, context *fuse.Context) (code fuse.Status)
+}
+
+// DirStreamer is an optional interface for FileSystem. If
+// implemented, OpenDirStream is used instead of OpenDir to list
+// directories, so entries can be produced while the kernel reads
+// them. If it returns ENOSYS, OpenDir is called instead.
+type DirStreamer interface {
+	OpenDirStream(name string, context *fuse.Context) (stream nodefs.DirStream, code fuse.Status)
+}
+
+// DirChunker is an optional interface for FileSystem, for backends
+// whose listings are paginated. If implemented, directories are
+// listed by calling ReadDirChunk as the kernel reads them, instead of
+// OpenDir. The first call for a listing passes an empty token; each
+// call returns some entries and the token for the next chunk, or an
+// empty token after the last chunk. Tokens are remembered with the
+// directory offset they start at, so when the kernel reads from an
+// earlier offset, the listing resumes from the chunk containing it.
+// Each call gets the context of the READDIR request that needs the
+// chunk.
+type DirChunker interface {
+	ReadDirChunk(name string, token string, context *fuse.Context) (entries []fuse.DirEntry, next string, code fuse.Status)
+}
+
+// DirReleaser is an optional interface for FileSystem. If
+// implemented, ReleaseDir is called when a directory opened for
+// OpenDir is closed. Files opened with Open or Create get
+// nodefs.FileReleaser instead.
+type DirReleaser interface {
+	ReleaseDir(name string, info *nodefs.ReleaseInfo)
+}
+
+// Lookuper is an optional interface for FileSystem, for backends
+// that look up a name in a directory more cheaply than they stat a
+// path. If implemented, Lookup is called instead of GetAttr to find
+// name in the directory dir. For a symlink, it may also return the
+// link target, which then answers Readlink on the node without
+// calling the file system, until the node is looked up again or its
+// attributes are read with GetAttr.
+type Lookuper interface {
+	Lookup(dir string, name string, context *fuse.Context) (attr *fuse.Attr, linkTarget string, code fuse.Status)
+}
+
+// AttrCreater is an optional interface for FileSystem. If
+// implemented, its methods are called instead of Create, Mknod, Mkdir
+// and Symlink. They return the attributes of the new node, which
+// saves the GetAttr call that otherwise follows. If they return a nil
+// Attr, GetAttr is called as usual.
+type AttrCreater interface {
+	CreateAttr(name string, flags uint32, mode uint32, context *fuse.Context) (file nodefs.File, attr *fuse.Attr, code fuse.Status)
+	MknodAttr(name string, mode uint32, dev uint32, context *fuse.Context) (*fuse.Attr, fuse.Status)
+	MkdirAttr(name string, mode uint32, context *fuse.Context) (*fuse.Attr, fuse.Status)
+	SymlinkAttr(value string, linkName string, context *fuse.Context) (*fuse.Attr, fuse.Status)
+}
+
 type PathNodeFsOptions struct {
 	// If ClientInodes is set, use Inode returned from GetAttr to
 	// find hard-linked files.
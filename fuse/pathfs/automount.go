@@ -0,0 +1,242 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+// AutoMountOptions configures an AutoMountFileSystem.
+type AutoMountOptions struct {
+	// IdleTimeout is how long a submount may go without lookups
+	// or opens before it is unmounted. Submounts with open files
+	// stay mounted. If zero, submounts are never unmounted.
+	IdleTimeout time.Duration
+
+	// Options for the submounts. If nil, the options of the
+	// AutoMountFileSystem's own mount are used.
+	MountOptions *nodefs.Options
+
+	// Options for the PathNodeFs that wraps each FileSystem.
+	PathNodeFsOptions *PathNodeFsOptions
+}
+
+// AutoMountFileSystem is a directory in which file systems are
+// mounted on demand, like amd or autofs do. The first time a name in
+// it is looked up, a callback produces the FileSystem to mount
+// there; if IdleTimeout is set, the submount is removed again once
+// it is idle, and recreated on the next lookup.
+type AutoMountFileSystem struct {
+	factory func(name string, context *fuse.Context) (FileSystem, fuse.Status)
+	opts    AutoMountOptions
+	root    *autoMountRoot
+
+	// mu serializes mounting, so a name is not mounted twice.
+	mu        sync.Mutex
+	connector *nodefs.FileSystemConnector
+	mounts    map[string]*autoMount
+	closed    bool
+}
+
+type autoMount struct {
+	fs    *activityFileSystem
+	timer *time.Timer
+}
+
+// NewAutoMountFileSystem returns an AutoMountFileSystem that calls
+// factory to get the file system for a name. If factory returns an
+// error, the lookup fails with it. Calls to factory are serialized.
+// Mount the result of Root to use it.
+func NewAutoMountFileSystem(factory func(name string, context *fuse.Context) (FileSystem, fuse.Status), opts *AutoMountOptions) *AutoMountFileSystem {
+	fs := &AutoMountFileSystem{
+		factory: factory,
+		mounts:  map[string]*autoMount{},
+	}
+	if opts != nil {
+		fs.opts = *opts
+	}
+	fs.root = &autoMountRoot{
+		Node: nodefs.NewDefaultNode(),
+		fs:   fs,
+	}
+	return fs
+}
+
+// Root returns the directory node, to be mounted with nodefs.Mount
+// or FileSystemConnector.Mount.
+func (fs *AutoMountFileSystem) Root() nodefs.Node {
+	return fs.root
+}
+
+// Mounted returns the names that are currently mounted.
+func (fs *AutoMountFileSystem) Mounted() []string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	var names []string
+	for name := range fs.mounts {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (fs *AutoMountFileSystem) String() string {
+	return fmt.Sprintf("AutoMountFileSystem(%v)", fs.Mounted())
+}
+
+// lookup mounts the file system for name, and returns its root.
+func (fs *AutoMountFileSystem) lookup(out *fuse.Attr, name string, context *fuse.Context) (*nodefs.Inode, fuse.Status) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.closed || fs.connector == nil {
+		return nil, fuse.ENOENT
+	}
+
+	parent := fs.root.Inode()
+	if ch := parent.GetChild(name); ch != nil {
+		// Mounted by a concurrent lookup.
+		return ch, ch.Node().GetAttr(out, nil, context)
+	}
+
+	sub, code := fs.factory(name, context)
+	if !code.Ok() {
+		return nil, code
+	}
+	m := &autoMount{fs: &activityFileSystem{FileSystem: sub}}
+	m.fs.touch()
+	pathFs := NewPathNodeFs(m.fs, fs.opts.PathNodeFsOptions)
+	if code := fs.connector.Mount(parent, name, pathFs.Root(), fs.opts.MountOptions); !code.Ok() {
+		return nil, code
+	}
+	fs.mounts[name] = m
+	if fs.opts.IdleTimeout > 0 {
+		m.timer = time.AfterFunc(fs.opts.IdleTimeout, func() { fs.expire(name, m) })
+	}
+
+	ch := parent.GetChild(name)
+	return ch, ch.Node().GetAttr(out, nil, context)
+}
+
+// expire unmounts name if it has been idle for IdleTimeout, and
+// otherwise checks again later. It must not hold mu while
+// unmounting: Unmount waits for the kernel to forget the submount,
+// which needs the lookups that may be waiting for mu to finish.
+func (fs *AutoMountFileSystem) expire(name string, m *autoMount) {
+	fs.mu.Lock()
+	if fs.closed || fs.mounts[name] != m {
+		fs.mu.Unlock()
+		return
+	}
+	idle := time.Since(m.fs.lastAccess())
+	if idle < fs.opts.IdleTimeout {
+		m.timer.Reset(fs.opts.IdleTimeout - idle)
+		fs.mu.Unlock()
+		return
+	}
+	node := fs.root.Inode().GetChild(name)
+	fs.mu.Unlock()
+
+	code := fuse.ENOENT
+	if node != nil {
+		code = fs.connector.Unmount(node)
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.mounts[name] != m {
+		// Remounted by a lookup while we were unmounting.
+		return
+	}
+	if code.Ok() || code == fuse.ENOENT {
+		delete(fs.mounts, name)
+	} else if !fs.closed {
+		// Busy; try again later.
+		m.timer.Reset(fs.opts.IdleTimeout)
+	}
+}
+
+type autoMountRoot struct {
+	nodefs.Node
+	fs *AutoMountFileSystem
+}
+
+func (n *autoMountRoot) OnMount(conn *nodefs.FileSystemConnector) {
+	n.fs.mu.Lock()
+	defer n.fs.mu.Unlock()
+	n.fs.connector = conn
+}
+
+func (n *autoMountRoot) OnUnmount() {
+	n.fs.mu.Lock()
+	defer n.fs.mu.Unlock()
+	n.fs.closed = true
+	for _, m := range n.fs.mounts {
+		if m.timer != nil {
+			m.timer.Stop()
+		}
+	}
+}
+
+func (n *autoMountRoot) Lookup(out *fuse.Attr, name string, context *fuse.Context) (*nodefs.Inode, fuse.Status) {
+	return n.fs.lookup(out, name, context)
+}
+
+// activityFileSystem records when a file system was last looked
+// up in or opened.
+type activityFileSystem struct {
+	FileSystem
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func (fs *activityFileSystem) touch() {
+	fs.mu.Lock()
+	fs.last = time.Now()
+	fs.mu.Unlock()
+}
+
+func (fs *activityFileSystem) lastAccess() time.Time {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.last
+}
+
+func (fs *activityFileSystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	fs.touch()
+	return fs.FileSystem.GetAttr(name, context)
+}
+
+func (fs *activityFileSystem) Open(name string, flags uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	fs.touch()
+	return fs.FileSystem.Open(name, flags, context)
+}
+
+func (fs *activityFileSystem) Create(name string, flags uint32, mode uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	fs.touch()
+	return fs.FileSystem.Create(name, flags, mode, context)
+}
+
+func (fs *activityFileSystem) OpenDir(name string, context *fuse.Context) ([]fuse.DirEntry, fuse.Status) {
+	fs.touch()
+	return fs.FileSystem.OpenDir(name, context)
+}
+
+func (fs *activityFileSystem) SetAttr(name string, input *fuse.SetAttrIn, context *fuse.Context) fuse.Status {
+	if sa, ok := fs.FileSystem.(SetAttrer); ok {
+		return sa.SetAttr(name, input, context)
+	}
+	return fuse.ENOSYS
+}
+
+func (fs *activityFileSystem) ReleaseDir(name string, info *nodefs.ReleaseInfo) {
+	if r, ok := fs.FileSystem.(DirReleaser); ok {
+		r.ReleaseDir(name, info)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestAutoMountFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+	if err := ioutil.WriteFile(orig+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	amfs := NewAutoMountFileSystem(func(name string, context *fuse.Context) (FileSystem, fuse.Status) {
+		if name != "host" {
+			return nil, fuse.ENOENT
+		}
+		mu.Lock()
+		calls[name]++
+		mu.Unlock()
+		return NewLoopbackFileSystem(orig), fuse.OK
+	}, &AutoMountOptions{IdleTimeout: 50 * time.Millisecond})
+
+	opts := nodefs.NewOptions()
+	opts.Debug = testutil.VerboseTest()
+	server, _, err := nodefs.Mount(mnt, amfs.Root(), &fuse.MountOptions{
+		Debug: testutil.VerboseTest(),
+	}, opts)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go server.Serve()
+	if err := server.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer server.Unmount()
+
+	if _, err := os.Lstat(mnt + "/other"); !os.IsNotExist(err) {
+		t.Errorf("Lstat other: got %v, want ENOENT", err)
+	}
+	if content, err := ioutil.ReadFile(mnt + "/host/file"); err != nil || string(content) != "hello" {
+		t.Fatalf("ReadFile: %q, %v", content, err)
+	}
+	if got := amfs.Mounted(); len(got) != 1 || got[0] != "host" {
+		t.Errorf("Mounted: got %v, want [host]", got)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(amfs.Mounted()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := amfs.Mounted(); len(got) != 0 {
+		t.Fatalf("idle submount was not unmounted: %v", got)
+	}
+
+	if content, err := ioutil.ReadFile(mnt + "/host/file"); err != nil || string(content) != "hello" {
+		t.Fatalf("ReadFile after expiry: %q, %v", content, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["host"] != 2 {
+		t.Errorf("factory called %d times, want 2", calls["host"])
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+type attrCacheEntry struct {
+	attr    *fuse.Attr
+	code    fuse.Status
+	expires time.Time
+}
+
+type linkCacheEntry struct {
+	target  string
+	code    fuse.Status
+	expires time.Time
+}
+
+type dirCacheEntry struct {
+	stream  []fuse.DirEntry
+	code    fuse.Status
+	expires time.Time
+}
+
+// cachingFileSystem memoizes the results of GetAttr, Readlink and
+// OpenDir of the wrapped FileSystem.
+type cachingFileSystem struct {
+	FileSystem
+
+	ttl time.Duration
+
+	mu sync.Mutex
+	// generation is incremented by each invalidation. Results
+	// fetched while it changed may be stale, and are not stored.
+	generation uint64
+	attrs      map[string]*attrCacheEntry
+	links      map[string]*linkCacheEntry
+	dirs       map[string]*dirCacheEntry
+}
+
+// NewCachingFileSystem returns a wrapper that caches the results of
+// GetAttr, Readlink and OpenDir for the given TTL. This is useful for
+// expensive (eg. networked) backends, where the kernel attribute
+// cache is not sufficient, for example because the same FileSystem
+// is used by several mounts.
+//
+// Entries are dropped when they are modified through the wrapper.
+// Changes made to the backing storage by other means, or by writing
+// to an open file, only become visible after the TTL expires.
+func NewCachingFileSystem(fs FileSystem, ttl time.Duration) FileSystem {
+	return &cachingFileSystem{
+		FileSystem: fs,
+		ttl:        ttl,
+		attrs:      make(map[string]*attrCacheEntry),
+		links:      make(map[string]*linkCacheEntry),
+		dirs:       make(map[string]*dirCacheEntry),
+	}
+}
+
+func (fs *cachingFileSystem) String() string {
+	return "CachingFileSystem(" + fs.FileSystem.String() + ")"
+}
+
+// cacheable returns true if the result may be stored. Transient
+// errors are not cached.
+func cacheable(code fuse.Status) bool {
+	return code.Ok() || code == fuse.ENOENT
+}
+
+func (fs *cachingFileSystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	now := time.Now()
+	fs.mu.Lock()
+	e := fs.attrs[name]
+	gen := fs.generation
+	fs.mu.Unlock()
+	if e != nil && now.Before(e.expires) {
+		if !e.code.Ok() {
+			return nil, e.code
+		}
+		a := *e.attr
+		return &a, fuse.OK
+	}
+
+	a, code := fs.FileSystem.GetAttr(name, context)
+	if cacheable(code) && (a != nil || !code.Ok()) {
+		e := &attrCacheEntry{code: code, expires: now.Add(fs.ttl)}
+		if code.Ok() {
+			c := *a
+			e.attr = &c
+		}
+		fs.mu.Lock()
+		if fs.generation == gen {
+			fs.attrs[name] = e
+		}
+		fs.mu.Unlock()
+	}
+	return a, code
+}
+
+func (fs *cachingFileSystem) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
+	now := time.Now()
+	fs.mu.Lock()
+	e := fs.links[name]
+	gen := fs.generation
+	fs.mu.Unlock()
+	if e != nil && now.Before(e.expires) {
+		return e.target, e.code
+	}
+
+	target, code := fs.FileSystem.Readlink(name, context)
+	if cacheable(code) {
+		fs.mu.Lock()
+		if fs.generation == gen {
+			fs.links[name] = &linkCacheEntry{target, code, now.Add(fs.ttl)}
+		}
+		fs.mu.Unlock()
+	}
+	return target, code
+}
+
+func (fs *cachingFileSystem) OpenDir(name string, context *fuse.Context) ([]fuse.DirEntry, fuse.Status) {
+	now := time.Now()
+	fs.mu.Lock()
+	e := fs.dirs[name]
+	gen := fs.generation
+	fs.mu.Unlock()
+	if e != nil && now.Before(e.expires) {
+		return append([]fuse.DirEntry(nil), e.stream...), e.code
+	}
+
+	stream, code := fs.FileSystem.OpenDir(name, context)
+	if cacheable(code) {
+		fs.mu.Lock()
+		if fs.generation == gen {
+			fs.dirs[name] = &dirCacheEntry{append([]fuse.DirEntry(nil), stream...), code, now.Add(fs.ttl)}
+		}
+		fs.mu.Unlock()
+	}
+	return stream, code
+}
+
+// invalidate drops all cached data for the given name, and the
+// listing of its parent directory.
+func (fs *cachingFileSystem) invalidate(names ...string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.generation++
+	for _, n := range names {
+		delete(fs.attrs, n)
+		delete(fs.links, n)
+		delete(fs.dirs, n)
+		delete(fs.dirs, parentName(n))
+	}
+}
+
+// invalidateTree drops all cached data for name and everything
+// below it.
+func (fs *cachingFileSystem) invalidateTree(name string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.generation++
+	prefix := name + "/"
+	for k := range fs.attrs {
+		if k == name || strings.HasPrefix(k, prefix) {
+			delete(fs.attrs, k)
+		}
+	}
+	for k := range fs.links {
+		if k == name || strings.HasPrefix(k, prefix) {
+			delete(fs.links, k)
+		}
+	}
+	for k := range fs.dirs {
+		if k == name || strings.HasPrefix(k, prefix) {
+			delete(fs.dirs, k)
+		}
+	}
+	delete(fs.dirs, parentName(name))
+}
+
+func parentName(name string) string {
+	dir := filepath.Dir(name)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+func (fs *cachingFileSystem) Chmod(name string, mode uint32, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Chmod(name, mode, context)
+	fs.invalidate(name)
+	return code
+}
+
+func (fs *cachingFileSystem) Chown(name string, uid uint32, gid uint32, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Chown(name, uid, gid, context)
+	fs.invalidate(name)
+	return code
+}
+
+func (fs *cachingFileSystem) Utimens(name string, Atime *time.Time, Mtime *time.Time, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Utimens(name, Atime, Mtime, context)
+	fs.invalidate(name)
+	return code
+}
+
+func (fs *cachingFileSystem) Truncate(name string, size uint64, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Truncate(name, size, context)
+	fs.invalidate(name)
+	return code
+}
+
+func (fs *cachingFileSystem) Link(oldName string, newName string, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Link(oldName, newName, context)
+	fs.invalidate(oldName, newName)
+	return code
+}
+
+func (fs *cachingFileSystem) Mkdir(name string, mode uint32, context *fuse.Context) fuse.Status {
+	code := fs.FileSystem.Mkdir(name, mode, context)
+	fs.invalidate(name)
+	return code
+}
+
+func (fs *cachingFileSystem) Mknod(name string, mode uint32, dev uint32, context *fuse.Context) fuse.Status {
+	code := fs.FileSystem.Mknod(name, mode, dev, context)
+	fs.invalidate(name)
+	return code
+}
+
+func (fs *cachingFileSystem) Rename(oldName string, newName string, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Rename(oldName, newName, context)
+	fs.invalidateTree(oldName)
+	fs.invalidateTree(newName)
+	return code
+}
+
+func (fs *cachingFileSystem) Rmdir(name string, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Rmdir(name, context)
+	fs.invalidateTree(name)
+	return code
+}
+
+func (fs *cachingFileSystem) Unlink(name string, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Unlink(name, context)
+	fs.invalidate(name)
+	return code
+}
+
+func (fs *cachingFileSystem) SetXAttr(name string, attr string, data []byte, flags int, context *fuse.Context) fuse.Status {
+	code := fs.FileSystem.SetXAttr(name, attr, data, flags, context)
+	fs.invalidate(name)
+	return code
+}
+
+func (fs *cachingFileSystem) RemoveXAttr(name string, attr string, context *fuse.Context) fuse.Status {
+	code := fs.FileSystem.RemoveXAttr(name, attr, context)
+	fs.invalidate(name)
+	return code
+}
+
+func (fs *cachingFileSystem) Open(name string, flags uint32, context *fuse.Context) (file nodefs.File, code fuse.Status) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR|syscall.O_TRUNC) != 0 {
+		fs.invalidate(name)
+	}
+	return fs.FileSystem.Open(name, flags, context)
+}
+
+func (fs *cachingFileSystem) Create(name string, flags uint32, mode uint32, context *fuse.Context) (file nodefs.File, code fuse.Status) {
+	file, code = fs.FileSystem.Create(name, flags, mode, context)
+	fs.invalidate(name)
+	return file, code
+}
+
+func (fs *cachingFileSystem) Symlink(value string, linkName string, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Symlink(value, linkName, context)
+	fs.invalidate(linkName)
+	return code
+}
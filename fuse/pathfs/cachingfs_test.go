@@ -0,0 +1,168 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+type countingFs struct {
+	FileSystem
+	getAttrs  int
+	openDirs  int
+	readlinks int
+}
+
+func (fs *countingFs) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
+	fs.readlinks++
+	return fs.FileSystem.Readlink(name, context)
+}
+
+func (fs *countingFs) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	fs.getAttrs++
+	return fs.FileSystem.GetAttr(name, context)
+}
+
+func (fs *countingFs) OpenDir(name string, context *fuse.Context) ([]fuse.DirEntry, fuse.Status) {
+	fs.openDirs++
+	return fs.FileSystem.OpenDir(name, context)
+}
+
+func TestCachingFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(dir+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	counter := &countingFs{FileSystem: NewLoopbackFileSystem(dir)}
+	fs := NewCachingFileSystem(counter, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		a, code := fs.GetAttr("file", nil)
+		if !code.Ok() || a.Size != 5 {
+			t.Fatalf("GetAttr: %v %v", a, code)
+		}
+		if _, code := fs.OpenDir("", nil); !code.Ok() {
+			t.Fatalf("OpenDir: %v", code)
+		}
+	}
+	if counter.getAttrs != 1 || counter.openDirs != 1 {
+		t.Errorf("got %d GetAttr, %d OpenDir calls, want 1 each", counter.getAttrs, counter.openDirs)
+	}
+
+	if code := fs.Truncate("file", 2, nil); !code.Ok() {
+		t.Fatalf("Truncate: %v", code)
+	}
+	if a, code := fs.GetAttr("file", nil); !code.Ok() || a.Size != 2 {
+		t.Errorf("GetAttr after Truncate: %v %v", a, code)
+	}
+
+	if code := fs.Unlink("file", nil); !code.Ok() {
+		t.Fatalf("Unlink: %v", code)
+	}
+	if _, code := fs.GetAttr("file", nil); code != fuse.ENOENT {
+		t.Errorf("GetAttr after Unlink: %v", code)
+	}
+	if stream, code := fs.OpenDir("", nil); !code.Ok() || len(stream) != 0 {
+		t.Errorf("OpenDir after Unlink: %v %v", stream, code)
+	}
+}
+
+// stallingFs stalls GetAttr after it fetched the attributes.
+type stallingFs struct {
+	FileSystem
+	fetched chan struct{}
+	resume  chan struct{}
+}
+
+func (fs *stallingFs) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	a, code := fs.FileSystem.GetAttr(name, context)
+	fs.fetched <- struct{}{}
+	<-fs.resume
+	return a, code
+}
+
+func TestCachingFileSystemInvalidateDuringGetAttr(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(dir+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	staller := &stallingFs{
+		FileSystem: NewLoopbackFileSystem(dir),
+		fetched:    make(chan struct{}),
+		resume:     make(chan struct{}),
+	}
+	fs := NewCachingFileSystem(staller, time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		fs.GetAttr("file", nil)
+		close(done)
+	}()
+	<-staller.fetched
+	if code := fs.Truncate("file", 2, nil); !code.Ok() {
+		t.Fatalf("Truncate: %v", code)
+	}
+	close(staller.resume)
+	<-done
+
+	// The result of the GetAttr that was running during the
+	// Truncate must not have been cached.
+	go func() {
+		for range staller.fetched {
+		}
+	}()
+	defer close(staller.fetched)
+	if a, code := fs.GetAttr("file", nil); !code.Ok() || a.Size != 2 {
+		t.Errorf("GetAttr after Truncate: %v %v", a, code)
+	}
+}
+
+func TestSymlinkCachingFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	if err := os.Symlink("a", dir+"/link"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	counter := &countingFs{FileSystem: NewLoopbackFileSystem(dir)}
+	fs := NewSymlinkCachingFileSystem(counter)
+	for i := 0; i < 3; i++ {
+		if target, code := fs.Readlink("link", nil); !code.Ok() || target != "a" {
+			t.Fatalf("Readlink: %q %v", target, code)
+		}
+	}
+	if counter.readlinks != 1 {
+		t.Errorf("got %d Readlink calls, want 1", counter.readlinks)
+	}
+
+	if code := fs.Unlink("link", nil); !code.Ok() {
+		t.Fatalf("Unlink: %v", code)
+	}
+	if _, code := fs.Readlink("link", nil); code != fuse.ENOENT {
+		t.Errorf("Readlink after Unlink: %v", code)
+	}
+	if code := fs.Symlink("b", "link", nil); !code.Ok() {
+		t.Fatalf("Symlink: %v", code)
+	}
+	if code := fs.Rename("link", "moved", nil); !code.Ok() {
+		t.Fatalf("Rename: %v", code)
+	}
+	if target, code := fs.Readlink("moved", nil); !code.Ok() || target != "b" {
+		t.Errorf("Readlink after Rename: %q %v", target, code)
+	}
+	if _, code := fs.Readlink("link", nil); code != fuse.ENOENT {
+		t.Errorf("Readlink of old name: %v", code)
+	}
+}
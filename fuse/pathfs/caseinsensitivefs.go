@@ -0,0 +1,282 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+type caseInsensitiveFileSystem struct {
+	FileSystem
+
+	mu sync.Mutex
+	// dirs holds the folded names of recently searched
+	// directories, keyed by their name in FileSystem.
+	dirs map[string]*foldedDir
+}
+
+// foldedDir maps the folded names of a directory to its names. It
+// is valid as long as the modification and change times of the
+// directory stay the same.
+type foldedDir struct {
+	mtime, ctime time.Time
+	names        map[string]string
+}
+
+// maxFoldedDirs bounds the number of cached directories.
+const maxFoldedDirs = 256
+
+// NewCaseInsensitiveFileSystem returns a wrapper that looks up names
+// in fs without regard to case, like Windows does. Names are
+// preserved: directory listings show the names of fs, and new files
+// get the name they were created with.
+//
+// Creating a file whose name only differs in case from an existing
+// one refers to the existing file, so Mkdir fails with EEXIST, and
+// Create opens the existing file unless O_EXCL is given. Renaming a
+// file to a different case of its own name changes its name. If fs
+// has names in one directory that only differ in case, all of them
+// are listed, and a name matching one of them exactly refers to that
+// one.
+//
+// The kernel still considers names that differ in case to be
+// different entries, so each spelling that is used gets its own
+// inode, which aliases the same file of fs. Data and attributes the
+// kernel caches for one spelling are not invalidated by changes made
+// through another.
+//
+// To resolve names that do not exist as given, the folded names of
+// a directory are read once, and reused until the directory's
+// modification or change time changes, or an entry is added or
+// removed through the returned FileSystem.
+func NewCaseInsensitiveFileSystem(fs FileSystem) FileSystem {
+	return &caseInsensitiveFileSystem{
+		FileSystem: fs,
+		dirs:       map[string]*foldedDir{},
+	}
+}
+
+// foldName returns the key under which names equal under case
+// folding are the same.
+func foldName(name string) string {
+	return strings.ToLower(strings.ToUpper(name))
+}
+
+// lookup returns the name in dir that equals name under case
+// folding, or "" if there is none.
+func (fs *caseInsensitiveFileSystem) lookup(dir string, name string, context *fuse.Context) string {
+	a, code := fs.FileSystem.GetAttr(dir, context)
+	if !code.Ok() {
+		return ""
+	}
+	mtime, ctime := a.ModTime(), a.ChangeTime()
+
+	fs.mu.Lock()
+	d := fs.dirs[dir]
+	fs.mu.Unlock()
+	if d == nil || !d.mtime.Equal(mtime) || !d.ctime.Equal(ctime) {
+		stream, code := fs.FileSystem.OpenDir(dir, context)
+		if !code.Ok() {
+			return ""
+		}
+		d = &foldedDir{
+			mtime: mtime,
+			ctime: ctime,
+			names: make(map[string]string, len(stream)),
+		}
+		for _, e := range stream {
+			k := foldName(e.Name)
+			if _, ok := d.names[k]; !ok {
+				d.names[k] = e.Name
+			}
+		}
+
+		fs.mu.Lock()
+		if len(fs.dirs) >= maxFoldedDirs {
+			for k := range fs.dirs {
+				delete(fs.dirs, k)
+				break
+			}
+		}
+		fs.dirs[dir] = d
+		fs.mu.Unlock()
+	}
+	return d.names[foldName(name)]
+}
+
+// forget drops the folded names of the directory of name, after
+// an entry in it was added or removed. Backends with coarse
+// timestamps may not show that change in the directory times.
+func (fs *caseInsensitiveFileSystem) forget(name string) {
+	dir := filepath.Dir(name)
+	if dir == "." {
+		dir = ""
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.dirs, dir)
+}
+
+// resolve returns the name in the wrapped file system that name
+// refers to. Components for which no file exists are kept as is.
+func (fs *caseInsensitiveFileSystem) resolve(name string, context *fuse.Context) string {
+	if name == "" {
+		return name
+	}
+	if _, code := fs.FileSystem.GetAttr(name, context); code.Ok() {
+		return name
+	}
+
+	comps := strings.Split(name, "/")
+	dir := ""
+	for i, c := range comps {
+		p := filepath.Join(dir, c)
+		if _, code := fs.FileSystem.GetAttr(p, context); !code.Ok() {
+			match := fs.lookup(dir, c, context)
+			if match == "" {
+				return filepath.Join(append([]string{dir}, comps[i:]...)...)
+			}
+			p = filepath.Join(dir, match)
+		}
+		dir = p
+	}
+	return dir
+}
+
+// resolveNew resolves the directory of name, keeping the last
+// component as is.
+func (fs *caseInsensitiveFileSystem) resolveNew(name string, context *fuse.Context) string {
+	dir, base := filepath.Split(name)
+	return filepath.Join(fs.resolve(strings.TrimSuffix(dir, "/"), context), base)
+}
+
+func (fs *caseInsensitiveFileSystem) String() string {
+	return fmt.Sprintf("caseInsensitiveFileSystem(%s)", fs.FileSystem.String())
+}
+
+func (fs *caseInsensitiveFileSystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	return fs.FileSystem.GetAttr(fs.resolve(name, context), context)
+}
+
+func (fs *caseInsensitiveFileSystem) Chmod(name string, mode uint32, context *fuse.Context) (code fuse.Status) {
+	return fs.FileSystem.Chmod(fs.resolve(name, context), mode, context)
+}
+
+func (fs *caseInsensitiveFileSystem) Chown(name string, uid uint32, gid uint32, context *fuse.Context) (code fuse.Status) {
+	return fs.FileSystem.Chown(fs.resolve(name, context), uid, gid, context)
+}
+
+func (fs *caseInsensitiveFileSystem) Utimens(name string, Atime *time.Time, Mtime *time.Time, context *fuse.Context) (code fuse.Status) {
+	return fs.FileSystem.Utimens(fs.resolve(name, context), Atime, Mtime, context)
+}
+
+func (fs *caseInsensitiveFileSystem) Truncate(name string, size uint64, context *fuse.Context) (code fuse.Status) {
+	return fs.FileSystem.Truncate(fs.resolve(name, context), size, context)
+}
+
+func (fs *caseInsensitiveFileSystem) Access(name string, mode uint32, context *fuse.Context) (code fuse.Status) {
+	return fs.FileSystem.Access(fs.resolve(name, context), mode, context)
+}
+
+func (fs *caseInsensitiveFileSystem) Link(oldName string, newName string, context *fuse.Context) (code fuse.Status) {
+	newInner := fs.resolve(newName, context)
+	code = fs.FileSystem.Link(fs.resolve(oldName, context), newInner, context)
+	fs.forget(newInner)
+	return code
+}
+
+func (fs *caseInsensitiveFileSystem) Mkdir(name string, mode uint32, context *fuse.Context) fuse.Status {
+	inner := fs.resolve(name, context)
+	code := fs.FileSystem.Mkdir(inner, mode, context)
+	fs.forget(inner)
+	return code
+}
+
+func (fs *caseInsensitiveFileSystem) Mknod(name string, mode uint32, dev uint32, context *fuse.Context) fuse.Status {
+	inner := fs.resolve(name, context)
+	code := fs.FileSystem.Mknod(inner, mode, dev, context)
+	fs.forget(inner)
+	return code
+}
+
+func (fs *caseInsensitiveFileSystem) Rename(oldName string, newName string, context *fuse.Context) (code fuse.Status) {
+	oldInner := fs.resolve(oldName, context)
+	newInner := fs.resolve(newName, context)
+	if newInner == oldInner {
+		// Changing the case of a name.
+		newInner = fs.resolveNew(newName, context)
+	}
+	code = fs.FileSystem.Rename(oldInner, newInner, context)
+	fs.forget(oldInner)
+	fs.forget(newInner)
+	return code
+}
+
+func (fs *caseInsensitiveFileSystem) Rmdir(name string, context *fuse.Context) (code fuse.Status) {
+	inner := fs.resolve(name, context)
+	code = fs.FileSystem.Rmdir(inner, context)
+	fs.forget(inner)
+	return code
+}
+
+func (fs *caseInsensitiveFileSystem) Unlink(name string, context *fuse.Context) (code fuse.Status) {
+	inner := fs.resolve(name, context)
+	code = fs.FileSystem.Unlink(inner, context)
+	fs.forget(inner)
+	return code
+}
+
+func (fs *caseInsensitiveFileSystem) GetXAttr(name string, attribute string, context *fuse.Context) (data []byte, code fuse.Status) {
+	return fs.FileSystem.GetXAttr(fs.resolve(name, context), attribute, context)
+}
+
+func (fs *caseInsensitiveFileSystem) ListXAttr(name string, context *fuse.Context) (attributes []string, code fuse.Status) {
+	return fs.FileSystem.ListXAttr(fs.resolve(name, context), context)
+}
+
+func (fs *caseInsensitiveFileSystem) RemoveXAttr(name string, attr string, context *fuse.Context) fuse.Status {
+	return fs.FileSystem.RemoveXAttr(fs.resolve(name, context), attr, context)
+}
+
+func (fs *caseInsensitiveFileSystem) SetXAttr(name string, attr string, data []byte, flags int, context *fuse.Context) fuse.Status {
+	return fs.FileSystem.SetXAttr(fs.resolve(name, context), attr, data, flags, context)
+}
+
+func (fs *caseInsensitiveFileSystem) Open(name string, flags uint32, context *fuse.Context) (file nodefs.File, code fuse.Status) {
+	return fs.FileSystem.Open(fs.resolve(name, context), flags, context)
+}
+
+func (fs *caseInsensitiveFileSystem) Create(name string, flags uint32, mode uint32, context *fuse.Context) (file nodefs.File, code fuse.Status) {
+	inner := fs.resolve(name, context)
+	file, code = fs.FileSystem.Create(inner, flags, mode, context)
+	fs.forget(inner)
+	return file, code
+}
+
+func (fs *caseInsensitiveFileSystem) OpenDir(name string, context *fuse.Context) (stream []fuse.DirEntry, code fuse.Status) {
+	return fs.FileSystem.OpenDir(fs.resolve(name, context), context)
+}
+
+func (fs *caseInsensitiveFileSystem) Symlink(value string, linkName string, context *fuse.Context) (code fuse.Status) {
+	inner := fs.resolve(linkName, context)
+	code = fs.FileSystem.Symlink(value, inner, context)
+	fs.forget(inner)
+	return code
+}
+
+func (fs *caseInsensitiveFileSystem) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
+	return fs.FileSystem.Readlink(fs.resolve(name, context), context)
+}
+
+func (fs *caseInsensitiveFileSystem) StatFs(name string) *fuse.StatfsOut {
+	return fs.FileSystem.StatFs(fs.resolve(name, nil))
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestCaseInsensitiveFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(dir+"/Docs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(dir+"/Docs/ReadMe.TXT", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewCaseInsensitiveFileSystem(NewLoopbackFileSystem(dir))
+	if a, code := fs.GetAttr("docs/README.txt", nil); !code.Ok() || a.Size != 5 {
+		t.Errorf("GetAttr: %v, %v", a, code)
+	}
+	if _, code := fs.GetAttr("docs/missing", nil); code != fuse.ENOENT {
+		t.Errorf("GetAttr(missing): got %v, want ENOENT", code)
+	}
+
+	// New names keep their case, in the existing directory.
+	if code := fs.Mkdir("DOCS/Sub", 0755, nil); !code.Ok() {
+		t.Fatalf("Mkdir: %v", code)
+	}
+	if got, want := dirNames(t, fs, "docs"), []string{"ReadMe.TXT", "Sub"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got entries %v, want %v", got, want)
+	}
+
+	// Conflicts refer to the existing file.
+	if code := fs.Mkdir("docs/sub", 0755, nil); code != fuse.Status(syscall.EEXIST) {
+		t.Errorf("Mkdir(conflict): got %v, want EEXIST", code)
+	}
+	if _, code := fs.Create("docs/readme.txt", uint32(os.O_WRONLY|os.O_CREATE|os.O_EXCL), 0644, nil); code != fuse.Status(syscall.EEXIST) {
+		t.Errorf("Create(O_EXCL): got %v, want EEXIST", code)
+	}
+	f, code := fs.Create("docs/readme.txt", uint32(os.O_WRONLY|os.O_CREATE), 0644, nil)
+	if !code.Ok() {
+		t.Fatalf("Create: %v", code)
+	}
+	f.Write([]byte(" world"), 5)
+	f.Release()
+	if content, err := ioutil.ReadFile(dir + "/Docs/ReadMe.TXT"); err != nil || string(content) != "hello world" {
+		t.Errorf("existing file: %q, %v", content, err)
+	}
+
+	// Renaming to another case changes the name.
+	if code := fs.Rename("docs/readme.txt", "docs/README.txt", nil); !code.Ok() {
+		t.Fatalf("Rename: %v", code)
+	}
+	if got, want := dirNames(t, fs, "Docs"), []string{"README.txt", "Sub"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got entries %v, want %v", got, want)
+	}
+}
+
+type openDirCountingFS struct {
+	FileSystem
+	count int32
+}
+
+func (fs *openDirCountingFS) OpenDir(name string, context *fuse.Context) ([]fuse.DirEntry, fuse.Status) {
+	atomic.AddInt32(&fs.count, 1)
+	return fs.FileSystem.OpenDir(name, context)
+}
+
+func TestCaseInsensitiveFileSystemDirCache(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(dir+"/Docs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(dir+"/Docs/ReadMe.TXT", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	counting := &openDirCountingFS{FileSystem: NewLoopbackFileSystem(dir)}
+	fs := NewCaseInsensitiveFileSystem(counting)
+	for i := 0; i < 10; i++ {
+		if _, code := fs.GetAttr(fmt.Sprintf("Docs/missing%d", i), nil); code != fuse.ENOENT {
+			t.Errorf("GetAttr(missing): got %v, want ENOENT", code)
+		}
+		if _, code := fs.GetAttr("docs/readme.txt", nil); !code.Ok() {
+			t.Errorf("GetAttr: %v", code)
+		}
+	}
+	if got := atomic.LoadInt32(&counting.count); got != 2 {
+		t.Errorf("got %d OpenDir calls, want 2", got)
+	}
+
+	// Changes made to the backing directory are seen.
+	if err := ioutil.WriteFile(dir+"/Docs/New.txt", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, code := fs.GetAttr("docs/NEW.TXT", nil); !code.Ok() {
+		t.Errorf("GetAttr(new): %v", code)
+	}
+}
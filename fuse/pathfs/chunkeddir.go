@@ -0,0 +1,96 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"sort"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// chunkMark is the continuation token for the chunk starting at a
+// directory offset.
+type chunkMark struct {
+	offset uint64
+	token  string
+}
+
+// chunkedDirStream is a nodefs.DirStream that reads a directory in
+// chunks from a DirChunker. Each chunk is fetched with the context of
+// the request that needs it.
+type chunkedDirStream struct {
+	fetch func(token string, context *fuse.Context) ([]fuse.DirEntry, string, fuse.Status)
+
+	// marks holds the chunks read so far, ordered by offset.
+	marks []chunkMark
+
+	// offset is the offset of chunk[0].
+	offset uint64
+	chunk  []fuse.DirEntry
+	next   string
+	done   bool
+	code   fuse.Status
+}
+
+func newChunkedDirStream(fetch func(token string, context *fuse.Context) ([]fuse.DirEntry, string, fuse.Status)) *chunkedDirStream {
+	return &chunkedDirStream{fetch: fetch}
+}
+
+func (s *chunkedDirStream) HasNext() bool {
+	return s.HasNextContext(&fuse.Context{})
+}
+
+func (s *chunkedDirStream) HasNextContext(context *fuse.Context) bool {
+	for len(s.chunk) == 0 && !s.done {
+		token := s.next
+		entries, next, code := s.fetch(token, context)
+		if !code.Ok() {
+			s.code = code
+			s.done = true
+			break
+		}
+		if n := len(s.marks); n == 0 || s.marks[n-1].offset < s.offset {
+			s.marks = append(s.marks, chunkMark{s.offset, token})
+		}
+		s.chunk = entries
+		s.next = next
+		s.done = next == ""
+	}
+	return len(s.chunk) > 0 || !s.code.Ok()
+}
+
+func (s *chunkedDirStream) Next() (fuse.DirEntry, fuse.Status) {
+	if len(s.chunk) == 0 {
+		code := s.code
+		s.code = fuse.OK
+		return fuse.DirEntry{}, code
+	}
+	e := s.chunk[0]
+	s.chunk = s.chunk[1:]
+	s.offset++
+	return e, fuse.OK
+}
+
+// SeekDir resumes from the last chunk starting at or before offset.
+func (s *chunkedDirStream) SeekDir(offset uint64) (uint64, fuse.Status) {
+	i := sort.Search(len(s.marks), func(i int) bool {
+		return s.marks[i].offset > offset
+	})
+	m := chunkMark{}
+	if i > 0 {
+		m = s.marks[i-1]
+	}
+	s.offset = m.offset
+	s.next = m.token
+	s.chunk = nil
+	s.done = false
+	s.code = fuse.OK
+	return s.offset, fuse.OK
+}
+
+func (s *chunkedDirStream) Close() {
+	s.chunk = nil
+	s.done = true
+}
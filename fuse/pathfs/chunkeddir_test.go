@@ -0,0 +1,142 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+// pagedFs lists its root in pages of pageSize, like an object store.
+type pagedFs struct {
+	FileSystem
+	n, pageSize int
+	fetches     int64
+}
+
+func (fs *pagedFs) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	if name == "" {
+		return &fuse.Attr{Mode: fuse.S_IFDIR | 0755}, fuse.OK
+	}
+	return &fuse.Attr{Mode: fuse.S_IFREG | 0644}, fuse.OK
+}
+
+func (fs *pagedFs) ReadDirChunk(name string, token string, context *fuse.Context) ([]fuse.DirEntry, string, fuse.Status) {
+	atomic.AddInt64(&fs.fetches, 1)
+	start := 0
+	if token != "" {
+		var err error
+		if start, err = strconv.Atoi(token); err != nil {
+			return nil, "", fuse.EINVAL
+		}
+	}
+	var entries []fuse.DirEntry
+	for i := start; i < fs.n && i < start+fs.pageSize; i++ {
+		entries = append(entries, fuse.DirEntry{Name: fmt.Sprintf("file%05d", i), Mode: fuse.S_IFREG})
+	}
+	next := ""
+	if end := start + fs.pageSize; end < fs.n {
+		next = strconv.Itoa(end)
+	}
+	return entries, next, fuse.OK
+}
+
+// readDirNames reads the names in the directory fd from its current
+// offset.
+func readDirNames(t *testing.T, fd int) []string {
+	var names []string
+	buf := make([]byte, 4096)
+	for {
+		n, err := syscall.ReadDirent(fd, buf)
+		if err != nil {
+			t.Fatalf("ReadDirent: %v", err)
+		}
+		if n == 0 {
+			return names
+		}
+		_, _, names = syscall.ParseDirent(buf[:n], -1, names)
+	}
+}
+
+func TestChunkedDir(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+
+	fs := &pagedFs{FileSystem: NewDefaultFileSystem(), n: 1000, pageSize: 100}
+	state, _, err := nodefs.MountRoot(dir, NewPathNodeFs(fs, nil).Root(), &nodefs.Options{Debug: testutil.VerboseTest()})
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	defer state.Unmount()
+	go state.Serve()
+	if err := state.WaitMount(); err != nil {
+		t.Fatalf("WaitMount: %v", err)
+	}
+
+	fd, err := syscall.Open(dir, syscall.O_RDONLY|syscall.O_DIRECTORY, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer syscall.Close(fd)
+
+	names := readDirNames(t, fd)
+	if len(names) != fs.n {
+		t.Fatalf("got %d entries, want %d", len(names), fs.n)
+	}
+	for i, n := range names {
+		if want := fmt.Sprintf("file%05d", i); n != want {
+			t.Fatalf("entry %d: got %q, want %q", i, n, want)
+		}
+	}
+	if got, want := atomic.LoadInt64(&fs.fetches), int64(fs.n/fs.pageSize); got != want {
+		t.Errorf("got %d fetches, want %d", got, want)
+	}
+
+	// Directory offsets count entries from 1.
+	if _, err := syscall.Seek(fd, 550, 0); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	before := atomic.LoadInt64(&fs.fetches)
+	names = readDirNames(t, fd)
+	if len(names) != fs.n-550 || names[0] != "file00550" {
+		t.Errorf("after seek: got %d entries starting at %q", len(names), names[0])
+	}
+	if got := atomic.LoadInt64(&fs.fetches) - before; got != 5 {
+		t.Errorf("after seek: got %d fetches, want 5", got)
+	}
+}
+
+func TestChunkedDirStreamContext(t *testing.T) {
+	var got []*fuse.Context
+	s := newChunkedDirStream(func(token string, context *fuse.Context) ([]fuse.DirEntry, string, fuse.Status) {
+		got = append(got, context)
+		if token == "" {
+			return []fuse.DirEntry{{Name: "a"}}, "b", fuse.OK
+		}
+		return []fuse.DirEntry{{Name: token}}, "", fuse.OK
+	})
+
+	// Each chunk is fetched for a different READDIR.
+	first := &fuse.Context{Cancel: make(chan struct{})}
+	second := &fuse.Context{Cancel: make(chan struct{})}
+	if !s.HasNextContext(first) {
+		t.Fatal("no first entry")
+	}
+	s.Next()
+	if !s.HasNextContext(second) {
+		t.Fatal("no second entry")
+	}
+	if len(got) != 2 || got[0] != first || got[1] != second {
+		t.Errorf("fetches got contexts %v, want [%p %p]", got, first, second)
+	}
+}
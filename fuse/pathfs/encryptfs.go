@@ -0,0 +1,534 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+const (
+	// encryptedBlockSize is the amount of plaintext in each
+	// encrypted block.
+	encryptedBlockSize = 4096
+
+	// Each block is stored with a random nonce and the GCM tag.
+	encryptedBlockOverhead = 12 + 16
+
+	// Files start with a random ID, which ties the blocks to
+	// the file.
+	encryptedHeaderSize = 16
+)
+
+// EncryptedKeySize is the size of the master key for
+// NewEncryptedFileSystem.
+const EncryptedKeySize = 32
+
+type encryptedFileSystem struct {
+	FileSystem
+
+	names    cipher.AEAD
+	nameMAC  []byte
+	contents cipher.AEAD
+
+	// locks holds a lock for each file that is open, by name.
+	locksMu sync.Mutex
+	locks   map[string]*encryptedFileLock
+}
+
+// encryptedFileLock serializes writes to one file, which read,
+// modify and write back whole blocks, against each other and
+// against reads.
+type encryptedFileLock struct {
+	sync.RWMutex
+	refs int
+}
+
+// lock returns the lock for the file name, which must be handed
+// back with unlock once the file is released.
+func (fs *encryptedFileSystem) lock(name string) *encryptedFileLock {
+	fs.locksMu.Lock()
+	defer fs.locksMu.Unlock()
+	l := fs.locks[name]
+	if l == nil {
+		l = &encryptedFileLock{}
+		fs.locks[name] = l
+	}
+	l.refs++
+	return l
+}
+
+func (fs *encryptedFileSystem) unlock(name string, l *encryptedFileLock) {
+	fs.locksMu.Lock()
+	defer fs.locksMu.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(fs.locks, name)
+	}
+}
+
+// NewEncryptedFileSystem returns a wrapper that encrypts file
+// contents, file names and symlink targets before they are stored in
+// fs, in the style of encfs. key must be EncryptedKeySize bytes.
+//
+// File contents are encrypted in blocks of 4096 bytes with AES-GCM,
+// each block under a fresh random nonce, so stored files are 16
+// bytes plus 28 bytes per block larger than their contents. Names are
+// encrypted deterministically, so equal names encrypt to the same
+// string in every directory, and must be at most 163 bytes long to
+// fit in 255 bytes after encoding. Entries of fs whose names cannot
+// be decrypted are hidden.
+//
+// Metadata such as sizes (rounded to blocks), timestamps,
+// permissions and the directory structure are not hidden.
+func NewEncryptedFileSystem(fs FileSystem, key []byte) (FileSystem, error) {
+	if len(key) != EncryptedKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", EncryptedKeySize, len(key))
+	}
+	derive := func(label string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(label))
+		return mac.Sum(nil)
+	}
+	newGCM := func(k []byte) (cipher.AEAD, error) {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	}
+
+	efs := &encryptedFileSystem{
+		nameMAC: derive("name-iv"),
+		locks:   map[string]*encryptedFileLock{},
+	}
+	var err error
+	if efs.names, err = newGCM(derive("name")); err != nil {
+		return nil, err
+	}
+	if efs.contents, err = newGCM(derive("content")); err != nil {
+		return nil, err
+	}
+	efs.FileSystem = NewTransformFileSystem(fs, efs.toInner, efs.toOuter)
+	return efs, nil
+}
+
+// encryptName encrypts a name or symlink target. The nonce is a MAC
+// of the plaintext, so the result is deterministic.
+func (fs *encryptedFileSystem) encryptName(name string) string {
+	mac := hmac.New(sha256.New, fs.nameMAC)
+	mac.Write([]byte(name))
+	nonce := mac.Sum(nil)[:fs.names.NonceSize()]
+	return base64.RawURLEncoding.EncodeToString(fs.names.Seal(nonce, nonce, []byte(name), nil))
+}
+
+func (fs *encryptedFileSystem) decryptName(name string) (string, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil || len(data) < fs.names.NonceSize() {
+		return "", false
+	}
+	n := fs.names.NonceSize()
+	plain, err := fs.names.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return "", false
+	}
+	return string(plain), true
+}
+
+func (fs *encryptedFileSystem) toInner(name string) (string, bool) {
+	if name == "" {
+		return "", true
+	}
+	comps := strings.Split(name, "/")
+	for i, c := range comps {
+		comps[i] = fs.encryptName(c)
+		if len(comps[i]) > 255 {
+			return "", false
+		}
+	}
+	return strings.Join(comps, "/"), true
+}
+
+func (fs *encryptedFileSystem) toOuter(dir string, name string) (string, bool) {
+	return fs.decryptName(name)
+}
+
+func (fs *encryptedFileSystem) String() string {
+	return fmt.Sprintf("encryptedFileSystem(%v)", fs.FileSystem)
+}
+
+// plainSize returns the size of the contents of a file that takes
+// size bytes on disk.
+func plainSize(size uint64) uint64 {
+	if size <= encryptedHeaderSize {
+		return 0
+	}
+	size -= encryptedHeaderSize
+	full := size / (encryptedBlockSize + encryptedBlockOverhead)
+	rest := size % (encryptedBlockSize + encryptedBlockOverhead)
+	if rest > encryptedBlockOverhead {
+		rest -= encryptedBlockOverhead
+	} else {
+		rest = 0
+	}
+	return full*encryptedBlockSize + rest
+}
+
+// blockOffset returns where block b starts on disk.
+func blockOffset(b int64) int64 {
+	return encryptedHeaderSize + b*(encryptedBlockSize+encryptedBlockOverhead)
+}
+
+func (fs *encryptedFileSystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	a, code := fs.FileSystem.GetAttr(name, context)
+	if code.Ok() && a.IsRegular() {
+		a.Size = plainSize(a.Size)
+	}
+	return a, code
+}
+
+// innerFlags returns the flags to open the backing file with:
+// writing needs to read back the blocks it modifies, and offsets are
+// translated, so O_APPEND is implemented by encryptedFile instead.
+func innerFlags(flags uint32) uint32 {
+	if flags&syscall.O_ACCMODE == syscall.O_WRONLY {
+		flags = flags&^syscall.O_ACCMODE | syscall.O_RDWR
+	}
+	return flags &^ syscall.O_APPEND
+}
+
+func (fs *encryptedFileSystem) newFile(f nodefs.File, name string, flags uint32) nodefs.File {
+	return &encryptedFile{
+		File:   f,
+		fs:     fs,
+		name:   name,
+		lock:   fs.lock(name),
+		append: flags&syscall.O_APPEND != 0,
+	}
+}
+
+func (fs *encryptedFileSystem) Open(name string, flags uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	f, code := fs.FileSystem.Open(name, innerFlags(flags), context)
+	if !code.Ok() {
+		return nil, code
+	}
+	return fs.newFile(f, name, flags), fuse.OK
+}
+
+func (fs *encryptedFileSystem) Create(name string, flags uint32, mode uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	f, code := fs.FileSystem.Create(name, innerFlags(flags), mode, context)
+	if !code.Ok() {
+		return nil, code
+	}
+	return fs.newFile(f, name, flags), fuse.OK
+}
+
+func (fs *encryptedFileSystem) Truncate(name string, size uint64, context *fuse.Context) fuse.Status {
+	f, code := fs.Open(name, uint32(syscall.O_RDWR), context)
+	if !code.Ok() {
+		return code
+	}
+	defer f.Release()
+	return f.Truncate(size)
+}
+
+func (fs *encryptedFileSystem) Symlink(value string, linkName string, context *fuse.Context) fuse.Status {
+	return fs.FileSystem.Symlink(fs.encryptName(value), linkName, context)
+}
+
+func (fs *encryptedFileSystem) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
+	target, code := fs.FileSystem.Readlink(name, context)
+	if !code.Ok() {
+		return "", code
+	}
+	plain, ok := fs.decryptName(target)
+	if !ok {
+		return "", fuse.EIO
+	}
+	return plain, fuse.OK
+}
+
+// encryptedFile encrypts and decrypts the contents of a file of the
+// backing FileSystem.
+type encryptedFile struct {
+	nodefs.File
+	fs   *encryptedFileSystem
+	name string
+	lock *encryptedFileLock
+
+	// append is set if the file was opened with O_APPEND, so
+	// writes go to the end of the file.
+	append bool
+}
+
+func (f *encryptedFile) InnerFile() nodefs.File {
+	return f.File
+}
+
+func (f *encryptedFile) Release() {
+	f.File.Release()
+	f.fs.unlock(f.name, f.lock)
+}
+
+func (f *encryptedFile) String() string {
+	return fmt.Sprintf("encryptedFile(%s)", f.File.String())
+}
+
+// innerSize returns the size of the backing file.
+func (f *encryptedFile) innerSize() (uint64, fuse.Status) {
+	var a fuse.Attr
+	code := f.File.GetAttr(&a)
+	return a.Size, code
+}
+
+// readAt reads len(buf) bytes of the backing file at off. It
+// returns the number of bytes read, which is less at the end of the
+// file.
+func (f *encryptedFile) readAt(buf []byte, off int64) (int, fuse.Status) {
+	res, code := f.File.Read(buf, off)
+	if !code.Ok() {
+		return 0, code
+	}
+	data, code := res.Bytes(buf)
+	res.Done()
+	if !code.Ok() {
+		return 0, code
+	}
+	return copy(buf, data), fuse.OK
+}
+
+func (f *encryptedFile) writeAt(data []byte, off int64) fuse.Status {
+	n, code := f.File.Write(data, off)
+	if code.Ok() && int(n) != len(data) {
+		code = fuse.EIO
+	}
+	return code
+}
+
+// fileID returns the random ID stored at the start of the backing
+// file. If the file is empty, it returns nil, or creates the ID if
+// create is set.
+func (f *encryptedFile) fileID(create bool) ([]byte, fuse.Status) {
+	id := make([]byte, encryptedHeaderSize)
+	n, code := f.readAt(id, 0)
+	if !code.Ok() {
+		return nil, code
+	}
+	if n == encryptedHeaderSize {
+		return id, fuse.OK
+	}
+	if n != 0 {
+		return nil, fuse.EIO
+	}
+	if !create {
+		return nil, fuse.OK
+	}
+	if _, err := rand.Read(id); err != nil {
+		return nil, fuse.EIO
+	}
+	return id, f.writeAt(id, 0)
+}
+
+func blockAD(id []byte, b int64) []byte {
+	ad := make([]byte, len(id)+8)
+	copy(ad, id)
+	binary.LittleEndian.PutUint64(ad[len(id):], uint64(b))
+	return ad
+}
+
+// readBlock returns the plaintext of block b, which is empty past
+// the end of the file.
+func (f *encryptedFile) readBlock(id []byte, b int64) ([]byte, fuse.Status) {
+	buf := make([]byte, encryptedBlockSize+encryptedBlockOverhead)
+	n, code := f.readAt(buf, blockOffset(b))
+	if !code.Ok() || n == 0 {
+		return nil, code
+	}
+	aead := f.fs.contents
+	if n <= aead.NonceSize() {
+		return nil, fuse.EIO
+	}
+	plain, err := aead.Open(nil, buf[:aead.NonceSize()], buf[aead.NonceSize():n], blockAD(id, b))
+	if err != nil {
+		return nil, fuse.EIO
+	}
+	return plain, fuse.OK
+}
+
+func (f *encryptedFile) writeBlock(id []byte, b int64, plain []byte) fuse.Status {
+	aead := f.fs.contents
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return fuse.EIO
+	}
+	return f.writeAt(aead.Seal(nonce, nonce, plain, blockAD(id, b)), blockOffset(b))
+}
+
+func (f *encryptedFile) Read(dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	id, code := f.fileID(false)
+	if !code.Ok() {
+		return nil, code
+	}
+	if id == nil {
+		return fuse.ReadResultData(nil), fuse.OK
+	}
+
+	n := 0
+	for n < len(dest) {
+		pos := off + int64(n)
+		b := pos / encryptedBlockSize
+		plain, code := f.readBlock(id, b)
+		if !code.Ok() {
+			return nil, code
+		}
+		start := int(pos % encryptedBlockSize)
+		if start >= len(plain) {
+			break
+		}
+		n += copy(dest[n:], plain[start:])
+		if len(plain) < encryptedBlockSize {
+			break
+		}
+	}
+	return fuse.ReadResultData(dest[:n]), fuse.OK
+}
+
+// write writes data at off. The caller must hold f.lock.
+func (f *encryptedFile) write(id []byte, data []byte, off int64) fuse.Status {
+	for len(data) > 0 {
+		b := off / encryptedBlockSize
+		start := int(off % encryptedBlockSize)
+		plain, code := f.readBlock(id, b)
+		if !code.Ok() {
+			return code
+		}
+		n := len(data)
+		if n > encryptedBlockSize-start {
+			n = encryptedBlockSize - start
+		}
+		if len(plain) < start+n {
+			plain = append(plain, make([]byte, start+n-len(plain))...)
+		}
+		copy(plain[start:], data[:n])
+		if code := f.writeBlock(id, b, plain); !code.Ok() {
+			return code
+		}
+		data = data[n:]
+		off += int64(n)
+	}
+	return fuse.OK
+}
+
+// zeroFill extends the file with zeros from size to end. The caller
+// must hold f.lock.
+func (f *encryptedFile) zeroFill(id []byte, size, end uint64) fuse.Status {
+	zeros := make([]byte, encryptedBlockSize)
+	for size < end {
+		n := end - size
+		if n > encryptedBlockSize {
+			n = encryptedBlockSize
+		}
+		if code := f.write(id, zeros[:n], int64(size)); !code.Ok() {
+			return code
+		}
+		size += n
+	}
+	return fuse.OK
+}
+
+func (f *encryptedFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	innerSize, code := f.innerSize()
+	if !code.Ok() {
+		return 0, code
+	}
+	id, code := f.fileID(true)
+	if !code.Ok() {
+		return 0, code
+	}
+	size := plainSize(innerSize)
+	if f.append {
+		off = int64(size)
+	}
+	if uint64(off) > size {
+		if code := f.zeroFill(id, size, uint64(off)); !code.Ok() {
+			return 0, code
+		}
+	}
+	if code := f.write(id, data, off); !code.Ok() {
+		return 0, code
+	}
+	return uint32(len(data)), fuse.OK
+}
+
+func (f *encryptedFile) Truncate(size uint64) fuse.Status {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	innerSize, code := f.innerSize()
+	if !code.Ok() {
+		return code
+	}
+	old := plainSize(innerSize)
+	switch {
+	case size == old:
+		return fuse.OK
+	case size == 0:
+		return f.File.Truncate(0)
+	case size > old:
+		id, code := f.fileID(true)
+		if !code.Ok() {
+			return code
+		}
+		return f.zeroFill(id, old, size)
+	}
+
+	b := int64(size / encryptedBlockSize)
+	rest := int(size % encryptedBlockSize)
+	if rest == 0 {
+		return f.File.Truncate(uint64(blockOffset(b)))
+	}
+	id, code := f.fileID(false)
+	if !code.Ok() {
+		return code
+	}
+	plain, code := f.readBlock(id, b)
+	if !code.Ok() {
+		return code
+	}
+	if code := f.writeBlock(id, b, plain[:rest]); !code.Ok() {
+		return code
+	}
+	return f.File.Truncate(uint64(blockOffset(b) + int64(rest) + encryptedBlockOverhead))
+}
+
+func (f *encryptedFile) GetAttr(out *fuse.Attr) fuse.Status {
+	code := f.File.GetAttr(out)
+	if code.Ok() {
+		out.Size = plainSize(out.Size)
+	}
+	return code
+}
+
+func (f *encryptedFile) Allocate(off uint64, size uint64, mode uint32) fuse.Status {
+	return fuse.ENOSYS
+}
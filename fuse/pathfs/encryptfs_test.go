@@ -0,0 +1,149 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestEncryptedFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+
+	key := bytes.Repeat([]byte{1}, EncryptedKeySize)
+	fs, err := NewEncryptedFileSystem(NewLoopbackFileSystem(orig), key)
+	if err != nil {
+		t.Fatalf("NewEncryptedFileSystem: %v", err)
+	}
+	server, err := Mount(mnt, fs, &fuse.MountOptions{Debug: testutil.VerboseTest()}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go server.Serve()
+	if err := server.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer server.Unmount()
+
+	// Spans several blocks and ends in a partial one.
+	want := make([]byte, 3*encryptedBlockSize+100)
+	rand.Read(want)
+	if err := os.Mkdir(mnt+"/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(mnt+"/dir/file", want, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(mnt+"/dir/file", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch := []byte("across a block boundary")
+	if _, err := f.WriteAt(patch, encryptedBlockSize-5); err != nil {
+		t.Fatal(err)
+	}
+	copy(want[encryptedBlockSize-5:], patch)
+	// Writing past the end leaves a hole of zeros.
+	if _, err := f.WriteAt([]byte("tail"), int64(len(want))+10); err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, make([]byte, 10)...)
+	want = append(want, "tail"...)
+	f.Close()
+
+	if got, err := ioutil.ReadFile(mnt + "/dir/file"); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("ReadFile: %v, content mismatch (got %d bytes, want %d)", err, len(got), len(want))
+	}
+	if fi, err := os.Stat(mnt + "/dir/file"); err != nil || fi.Size() != int64(len(want)) {
+		t.Errorf("Stat: got %v, %v, want size %d", fi, err, len(want))
+	}
+
+	if err := os.Truncate(mnt+"/dir/file", encryptedBlockSize+7); err != nil {
+		t.Fatal(err)
+	}
+	want = want[:encryptedBlockSize+7]
+	if got, err := ioutil.ReadFile(mnt + "/dir/file"); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("ReadFile after truncate: %v, content mismatch (got %d bytes, want %d)", err, len(got), len(want))
+	}
+
+	if err := os.Symlink("dir/file", mnt+"/link"); err != nil {
+		t.Fatal(err)
+	}
+	if target, err := os.Readlink(mnt + "/link"); err != nil || target != "dir/file" {
+		t.Errorf("Readlink: got %q, %v", target, err)
+	}
+
+	// Nothing is stored in the clear.
+	entries, err := ioutil.ReadDir(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() == "dir" || e.Name() == "link" {
+			t.Errorf("name %q stored in the clear", e.Name())
+		}
+	}
+	inner, _ := fs.(*encryptedFileSystem).toInner("dir/file")
+	stored, err := ioutil.ReadFile(orig + "/" + inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stored, patch) {
+		t.Errorf("contents stored in the clear")
+	}
+
+	// With another key, nothing decrypts.
+	other, _ := NewEncryptedFileSystem(NewLoopbackFileSystem(orig), bytes.Repeat([]byte{2}, EncryptedKeySize))
+	if stream, code := other.OpenDir("", nil); !code.Ok() || len(stream) != 0 {
+		t.Errorf("OpenDir with wrong key: got %v, %v", stream, code)
+	}
+}
+
+func TestEncryptedFileSystemAppend(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+
+	fs, err := NewEncryptedFileSystem(NewLoopbackFileSystem(dir), bytes.Repeat([]byte{1}, EncryptedKeySize))
+	if err != nil {
+		t.Fatalf("NewEncryptedFileSystem: %v", err)
+	}
+	f, code := fs.Create("file", uint32(os.O_WRONLY|os.O_APPEND), 0644, nil)
+	if !code.Ok() {
+		t.Fatalf("Create: %v", code)
+	}
+	defer f.Release()
+
+	// The offset is ignored: each write goes to the end.
+	for _, s := range []string{"one", "two"} {
+		if n, code := f.Write([]byte(s), 0); !code.Ok() || n != uint32(len(s)) {
+			t.Fatalf("Write: %d, %v", n, code)
+		}
+	}
+	r, code := fs.Open("file", uint32(os.O_RDONLY), nil)
+	if !code.Ok() {
+		t.Fatalf("Open: %v", code)
+	}
+	defer r.Release()
+	buf := make([]byte, 16)
+	res, code := r.Read(buf, 0)
+	if !code.Ok() {
+		t.Fatalf("Read: %v", code)
+	}
+	if got, _ := res.Bytes(buf); string(got) != "onetwo" {
+		t.Errorf("got %q, want %q", got, "onetwo")
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+// +build ignore
+
+// gen_normtables generates normtables.go from the Unicode Character
+// Database. Run it with "go generate" in this directory. The -ucd
+// flag takes a URL or a local directory holding UnicodeData.txt and
+// DerivedNormalizationProps.txt.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	version = flag.String("version", "14.0.0", "Unicode version")
+	ucd     = flag.String("ucd", "", "URL or directory of the UCD files (default: unicode.org for -version)")
+	output  = flag.String("output", "normtables.go", "output file")
+)
+
+// open opens the UCD file name.
+func open(name string) (io.ReadCloser, error) {
+	base := *ucd
+	if base == "" {
+		base = "https://www.unicode.org/Public/" + *version + "/ucd/"
+	}
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		return os.Open(filepath.Join(base, name))
+	}
+	resp, err := http.Get(strings.TrimSuffix(base, "/") + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", name, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// readFields calls fn with the semicolon separated fields of each
+// line of a UCD file, without comments and surrounding spaces.
+func readFields(name string, fn func(fields []string) error) error {
+	f, err := open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if err := fn(fields); err != nil {
+			return fmt.Errorf("%s: %q: %v", name, s.Text(), err)
+		}
+	}
+	return s.Err()
+}
+
+func parseRune(s string) (rune, error) {
+	r, err := strconv.ParseUint(s, 16, 32)
+	return rune(r), err
+}
+
+// parseRange parses "XXXX" or "XXXX..YYYY".
+func parseRange(s string) (lo, hi rune, err error) {
+	parts := strings.SplitN(s, "..", 2)
+	if lo, err = parseRune(parts[0]); err != nil {
+		return 0, 0, err
+	}
+	hi = lo
+	if len(parts) == 2 {
+		hi, err = parseRune(parts[1])
+	}
+	return lo, hi, err
+}
+
+func escape(r rune) string {
+	if r > 0xFFFF {
+		return fmt.Sprintf(`\U%08X`, r)
+	}
+	return fmt.Sprintf(`\u%04X`, r)
+}
+
+func main() {
+	flag.Parse()
+
+	decompositions := map[rune][]rune{}
+	classes := map[rune]uint8{}
+	err := readFields("UnicodeData.txt", func(fields []string) error {
+		if len(fields) < 6 {
+			return fmt.Errorf("want at least 6 fields")
+		}
+		r, err := parseRune(fields[0])
+		if err != nil {
+			return err
+		}
+		ccc, err := strconv.ParseUint(fields[3], 10, 8)
+		if err != nil {
+			return err
+		}
+		if ccc != 0 {
+			classes[r] = uint8(ccc)
+		}
+		// Compatibility decompositions start with a <tag>.
+		if d := fields[5]; d != "" && !strings.HasPrefix(d, "<") {
+			for _, f := range strings.Fields(d) {
+				c, err := parseRune(f)
+				if err != nil {
+					return err
+				}
+				decompositions[r] = append(decompositions[r], c)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	excluded := map[rune]bool{}
+	err = readFields("DerivedNormalizationProps.txt", func(fields []string) error {
+		if len(fields) < 2 || fields[1] != "Full_Composition_Exclusion" {
+			return nil
+		}
+		lo, hi, err := parseRange(fields[0])
+		if err != nil {
+			return err
+		}
+		for r := lo; r <= hi; r++ {
+			excluded[r] = true
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var runes []rune
+	for r := range decompositions {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Code generated from the Unicode Character Database, version %s. DO NOT EDIT.
+
+package pathfs
+
+`, *version)
+
+	buf.WriteString(`// normDecompositions holds the canonical decompositions, one level deep.
+// Hangul syllables are decomposed algorithmically.
+var normDecompositions = map[rune]string{
+`)
+	for _, r := range runes {
+		var d string
+		for _, c := range decompositions[r] {
+			d += escape(c)
+		}
+		fmt.Fprintf(&buf, "0x%04X: \"%s\",\n", r, d)
+	}
+	buf.WriteString("}\n\n")
+
+	buf.WriteString(`// normCompositionExclusions lists the runes with a two rune
+// decomposition that NFC does not compose.
+var normCompositionExclusions = map[rune]bool{
+`)
+	for _, r := range runes {
+		if len(decompositions[r]) == 2 && excluded[r] {
+			fmt.Fprintf(&buf, "0x%04X: true,\n", r)
+		}
+	}
+	buf.WriteString("}\n\n")
+
+	buf.WriteString(`// normCombiningClasses lists the ranges of runes with a nonzero
+// canonical combining class, sorted.
+var normCombiningClasses = []normCombiningRange{
+`)
+	var marks []rune
+	for r := range classes {
+		marks = append(marks, r)
+	}
+	sort.Slice(marks, func(i, j int) bool { return marks[i] < marks[j] })
+	for i := 0; i < len(marks); {
+		j := i + 1
+		for j < len(marks) && marks[j] == marks[j-1]+1 && classes[marks[j]] == classes[marks[i]] {
+			j++
+		}
+		fmt.Fprintf(&buf, "{0x%04X, 0x%04X, %d},\n", marks[i], marks[j-1], classes[marks[i]])
+		i = j
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+const gzipSuffix = ".gz"
+
+// gunzipMaxSkip is how far a read may be ahead of the position of an
+// open file's decompressor before decompression restarts from the
+// index instead.
+const gunzipMaxSkip = 1 << 20
+
+type gunzipFileSystem struct {
+	FileSystem
+
+	inner FileSystem
+
+	mu      sync.Mutex
+	indexes map[string]*gzipIndex
+}
+
+// gzipIndex records where the members of a gzip file start, so reads
+// can start decompressing at the member containing the offset.
+type gzipIndex struct {
+	// The size and mtime of the compressed file the index is for.
+	size    uint64
+	mtime   uint64
+	mtimens uint32
+
+	members []gzipMember
+	// The size of the decompressed contents.
+	total uint64
+}
+
+type gzipMember struct {
+	// Offsets in the compressed file and the decompressed contents.
+	in, out int64
+}
+
+// NewGunzipFileSystem returns a read-only wrapper that shows each
+// "name.gz" file of fs as "name", with the decompressed contents and
+// size. Other files are shown as is; if both "name" and "name.gz"
+// exist, "name.gz" is hidden.
+//
+// To find the size, and to support random access, each file is
+// decompressed once on first access, and an index of the gzip
+// members in it is kept until the file changes. Reads decompress
+// from the start of the member holding the requested offset, so files
+// written as many small members (as bgzip or "pigz --independent"
+// do) give fast random access, while single-member files are only
+// efficient to read sequentially.
+//
+// Only gzip is supported: the standard library has no zstd
+// decompressor.
+func NewGunzipFileSystem(fs FileSystem) FileSystem {
+	gfs := &gunzipFileSystem{
+		inner:   fs,
+		indexes: map[string]*gzipIndex{},
+	}
+	gfs.FileSystem = NewReadonlyFileSystem(NewTransformFileSystem(fs, gfs.toInner, gfs.toOuter))
+	return gfs
+}
+
+func (fs *gunzipFileSystem) toInner(name string) (string, bool) {
+	if name == "" {
+		return name, true
+	}
+	if _, code := fs.inner.GetAttr(name, nil); code.Ok() {
+		return name, true
+	}
+	if a, code := fs.inner.GetAttr(name+gzipSuffix, nil); code.Ok() && a.IsRegular() {
+		return name + gzipSuffix, true
+	}
+	return name, true
+}
+
+func (fs *gunzipFileSystem) toOuter(dir string, name string) (string, bool) {
+	if !strings.HasSuffix(name, gzipSuffix) {
+		return name, true
+	}
+	p := name
+	if dir != "" {
+		p = dir + "/" + name
+	}
+	if a, code := fs.inner.GetAttr(p, nil); !code.Ok() || !a.IsRegular() {
+		return name, true
+	}
+	plain := strings.TrimSuffix(p, gzipSuffix)
+	if _, code := fs.inner.GetAttr(plain, nil); code.Ok() {
+		return "", false
+	}
+	return strings.TrimSuffix(name, gzipSuffix), true
+}
+
+func (fs *gunzipFileSystem) String() string {
+	return fmt.Sprintf("gunzipFileSystem(%v)", fs.inner)
+}
+
+// compressedName returns the name of the gzip file for name, or ""
+// if name is not a decompressed file.
+func (fs *gunzipFileSystem) compressedName(name string) string {
+	inner, _ := fs.toInner(name)
+	if inner == name {
+		return ""
+	}
+	return inner
+}
+
+// index returns the index of the gzip file innerName, which is open
+// as f, building it if needed.
+func (fs *gunzipFileSystem) index(innerName string, f nodefs.File) (*gzipIndex, fuse.Status) {
+	var a fuse.Attr
+	if code := f.GetAttr(&a); !code.Ok() {
+		return nil, code
+	}
+
+	fs.mu.Lock()
+	idx := fs.indexes[innerName]
+	fs.mu.Unlock()
+	if idx != nil && idx.size == a.Size && idx.mtime == a.Mtime && idx.mtimens == a.Mtimensec {
+		return idx, fuse.OK
+	}
+
+	idx, err := buildGzipIndex(&fileReaderAt{f}, int64(a.Size))
+	if err != nil {
+		return nil, fuse.EIO
+	}
+	idx.size, idx.mtime, idx.mtimens = a.Size, a.Mtime, a.Mtimensec
+
+	fs.mu.Lock()
+	fs.indexes[innerName] = idx
+	fs.mu.Unlock()
+	return idx, fuse.OK
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func buildGzipIndex(r io.ReaderAt, size int64) (*gzipIndex, error) {
+	cr := &countingReader{r: io.NewSectionReader(r, 0, size)}
+	// gzip.Reader does not read past the end of a member from an
+	// io.ByteReader, so the position of the next member is what
+	// was read minus what is buffered.
+	br := bufio.NewReader(cr)
+	idx := &gzipIndex{}
+	var z *gzip.Reader
+	for {
+		start := cr.n - int64(br.Buffered())
+		if _, err := br.Peek(1); err == io.EOF {
+			break
+		}
+		var err error
+		if z == nil {
+			z, err = gzip.NewReader(br)
+		} else {
+			err = z.Reset(br)
+		}
+		if err != nil {
+			return nil, err
+		}
+		z.Multistream(false)
+		n, err := io.Copy(ioutil.Discard, z)
+		if err != nil {
+			return nil, err
+		}
+		idx.members = append(idx.members, gzipMember{in: start, out: int64(idx.total)})
+		idx.total += uint64(n)
+	}
+	return idx, nil
+}
+
+func (fs *gunzipFileSystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	a, code := fs.FileSystem.GetAttr(name, context)
+	gzName := fs.compressedName(name)
+	if !code.Ok() || gzName == "" {
+		return a, code
+	}
+
+	f, code := fs.inner.Open(gzName, uint32(syscall.O_RDONLY), context)
+	if !code.Ok() {
+		return nil, code
+	}
+	defer f.Release()
+	idx, code := fs.index(gzName, f)
+	if !code.Ok() {
+		return nil, code
+	}
+	a.Size = idx.total
+	a.Blocks = (a.Size + 511) / 512
+	return a, fuse.OK
+}
+
+func (fs *gunzipFileSystem) Open(name string, flags uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	if flags&fuse.O_ANYWRITE != 0 {
+		return nil, fuse.EPERM
+	}
+	gzName := fs.compressedName(name)
+	if gzName == "" {
+		return fs.FileSystem.Open(name, flags, context)
+	}
+
+	f, code := fs.inner.Open(gzName, flags, context)
+	if !code.Ok() {
+		return nil, code
+	}
+	idx, code := fs.index(gzName, f)
+	if !code.Ok() {
+		f.Release()
+		return nil, code
+	}
+	return nodefs.NewReadOnlyFile(&gunzipFile{File: f, index: idx}), fuse.OK
+}
+
+// fileReaderAt reads a nodefs.File as an io.ReaderAt.
+type fileReaderAt struct {
+	f nodefs.File
+}
+
+func (r *fileReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	res, code := r.f.Read(p, off)
+	if !code.Ok() {
+		return 0, syscall.Errno(code)
+	}
+	data, code := res.Bytes(p)
+	res.Done()
+	if !code.Ok() {
+		return 0, syscall.Errno(code)
+	}
+	n := copy(p, data)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// gunzipFile serves the decompressed contents of a gzip file.
+type gunzipFile struct {
+	nodefs.File
+	index *gzipIndex
+
+	// The decompressor of the last read, and its position in the
+	// decompressed contents, so sequential reads do not start over.
+	mu  sync.Mutex
+	z   *gzip.Reader
+	pos int64
+}
+
+func (f *gunzipFile) InnerFile() nodefs.File {
+	return f.File
+}
+
+func (f *gunzipFile) String() string {
+	return fmt.Sprintf("gunzipFile(%s)", f.File.String())
+}
+
+// seek positions the decompressor at off.
+func (f *gunzipFile) seek(off int64) error {
+	if f.z == nil || off < f.pos || off-f.pos > gunzipMaxSkip {
+		members := f.index.members
+		i := sort.Search(len(members), func(i int) bool { return members[i].out > off }) - 1
+		m := members[i]
+
+		r := bufio.NewReader(io.NewSectionReader(&fileReaderAt{f.File}, m.in, int64(f.index.size)-m.in))
+		var err error
+		if f.z == nil {
+			f.z, err = gzip.NewReader(r)
+		} else {
+			err = f.z.Reset(r)
+		}
+		if err != nil {
+			f.z = nil
+			return err
+		}
+		f.pos = m.out
+	}
+	n, err := io.CopyN(ioutil.Discard, f.z, off-f.pos)
+	f.pos += n
+	return err
+}
+
+func (f *gunzipFile) Read(dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	if off >= int64(f.index.total) || len(f.index.members) == 0 {
+		return fuse.ReadResultData(nil), fuse.OK
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.seek(off); err != nil {
+		f.z = nil
+		return nil, fuse.EIO
+	}
+	n, err := io.ReadFull(f.z, dest)
+	f.pos += int64(n)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		f.z = nil
+		return nil, fuse.EIO
+	}
+	return fuse.ReadResultData(dest[:n]), fuse.OK
+}
+
+func (f *gunzipFile) GetAttr(out *fuse.Attr) fuse.Status {
+	code := f.File.GetAttr(out)
+	if code.Ok() {
+		out.Size = f.index.total
+		out.Blocks = (out.Size + 511) / 512
+	}
+	return code
+}
@@ -0,0 +1,101 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+// writeGzip writes the parts to name, each as a separate gzip member.
+func writeGzip(t *testing.T, name string, parts ...[]byte) {
+	var buf bytes.Buffer
+	for _, p := range parts {
+		w := gzip.NewWriter(&buf)
+		w.Write(p)
+		w.Close()
+	}
+	if err := ioutil.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGunzipFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+
+	var parts [][]byte
+	var log []byte
+	for i := 0; i < 3; i++ {
+		var p []byte
+		for j := 0; j < 1000; j++ {
+			p = append(p, fmt.Sprintf("member %d line %d\n", i, j)...)
+		}
+		parts = append(parts, p)
+		log = append(log, p...)
+	}
+	writeGzip(t, dir+"/log.gz", parts...)
+	writeGzip(t, dir+"/single.gz", log)
+	writeGzip(t, dir+"/both.gz", []byte("compressed"))
+	if err := ioutil.WriteFile(dir+"/both", []byte("plain"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewGunzipFileSystem(NewLoopbackFileSystem(dir))
+	if got, want := dirNames(t, fs, ""), []string{"both", "log", "single"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OpenDir: got %v, want %v", got, want)
+	}
+	if a, code := fs.GetAttr("log", nil); !code.Ok() || a.Size != uint64(len(log)) {
+		t.Errorf("GetAttr(log): got %v, %v, want size %d", a, code, len(log))
+	}
+
+	for _, name := range []string{"log", "single"} {
+		f, code := fs.Open(name, uint32(os.O_RDONLY), nil)
+		if !code.Ok() {
+			t.Fatalf("Open(%s): %v", name, code)
+		}
+		for _, off := range []int{len(log) - 10, 5, 1000, len(parts[0]) - 3, len(log) + 5} {
+			dest := make([]byte, 20)
+			res, code := f.Read(dest, int64(off))
+			if !code.Ok() {
+				t.Fatalf("Read(%s, %d): %v", name, off, code)
+			}
+			got, _ := res.Bytes(dest)
+			want := []byte{}
+			if off < len(log) {
+				want = log[off:]
+				if len(want) > 20 {
+					want = want[:20]
+				}
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Read(%s, %d): got %q, want %q", name, off, got, want)
+			}
+		}
+		f.Release()
+	}
+
+	if f, code := fs.Open("both", uint32(os.O_RDONLY), nil); !code.Ok() {
+		t.Errorf("Open(both): %v", code)
+	} else {
+		res, _ := f.Read(make([]byte, 10), 0)
+		if got, _ := res.Bytes(make([]byte, 10)); string(got) != "plain" {
+			t.Errorf("Read(both): got %q, want plain", got)
+		}
+		f.Release()
+	}
+	if _, code := fs.Open("log", uint32(syscall.O_WRONLY), nil); code != fuse.EPERM {
+		t.Errorf("Open for writing: got %v, want EPERM", code)
+	}
+}
@@ -88,23 +88,7 @@ func (fs *loopbackFileSystem) OpenDir(name string, context *fuse.Context) (strea
 	output := make([]fuse.DirEntry, 0, want)
 	for {
 		infos, err := f.Readdir(want)
-		for i := range infos {
-			// workaround for https://code.google.com/p/go/issues/detail?id=5960
-			if infos[i] == nil {
-				continue
-			}
-			n := infos[i].Name()
-			d := fuse.DirEntry{
-				Name: n,
-			}
-			if s := fuse.ToStatT(infos[i]); s != nil {
-				d.Mode = uint32(s.Mode)
-				d.Ino = s.Ino
-			} else {
-				log.Printf("ReadDir entry %q for %q has no stat info", n, name)
-			}
-			output = append(output, d)
-		}
+		output = appendDirEntries(output, infos, name)
 		if len(infos) < want || err == io.EOF {
 			break
 		}
@@ -118,10 +102,85 @@ func (fs *loopbackFileSystem) OpenDir(name string, context *fuse.Context) (strea
 	return output, fuse.OK
 }
 
+// appendDirEntries appends the entries for infos, read from the
+// directory name, to output.
+func appendDirEntries(output []fuse.DirEntry, infos []os.FileInfo, name string) []fuse.DirEntry {
+	for i := range infos {
+		// workaround for https://code.google.com/p/go/issues/detail?id=5960
+		if infos[i] == nil {
+			continue
+		}
+		n := infos[i].Name()
+		d := fuse.DirEntry{
+			Name: n,
+		}
+		if s := fuse.ToStatT(infos[i]); s != nil {
+			d.Mode = uint32(s.Mode)
+			d.Ino = s.Ino
+		} else {
+			log.Printf("ReadDir entry %q for %q has no stat info", n, name)
+		}
+		output = append(output, d)
+	}
+	return output
+}
+
+// loopbackDirStream reads a directory in batches, as the kernel
+// asks for entries.
+type loopbackDirStream struct {
+	name  string
+	f     *os.File
+	batch []fuse.DirEntry
+	err   error
+}
+
+const loopbackDirBatch = 500
+
+func (fs *loopbackFileSystem) OpenDirStream(name string, context *fuse.Context) (nodefs.DirStream, fuse.Status) {
+	f, err := os.Open(fs.GetPath(name))
+	if err != nil {
+		return nil, fuse.ToStatus(err)
+	}
+	return &loopbackDirStream{name: name, f: f}, fuse.OK
+}
+
+func (s *loopbackDirStream) HasNext() bool {
+	for len(s.batch) == 0 && s.f != nil {
+		infos, err := s.f.Readdir(loopbackDirBatch)
+		s.batch = appendDirEntries(s.batch, infos, s.name)
+		if err == io.EOF || (err == nil && len(infos) < loopbackDirBatch) {
+			s.Close()
+		} else if err != nil {
+			// Report the error after the entries read so far.
+			s.err = err
+			s.Close()
+		}
+	}
+	return len(s.batch) > 0 || s.err != nil
+}
+
+func (s *loopbackDirStream) Next() (fuse.DirEntry, fuse.Status) {
+	if len(s.batch) == 0 {
+		err := s.err
+		s.err = nil
+		return fuse.DirEntry{}, fuse.ToStatus(err)
+	}
+	e := s.batch[0]
+	s.batch = s.batch[1:]
+	return e, fuse.OK
+}
+
+func (s *loopbackDirStream) Close() {
+	if s.f != nil {
+		s.f.Close()
+		s.f = nil
+	}
+}
+
 func (fs *loopbackFileSystem) Open(name string, flags uint32, context *fuse.Context) (fuseFile nodefs.File, status fuse.Status) {
-	// filter out append. The kernel layer will translate the
-	// offsets for us appropriately.
-	flags = flags &^ syscall.O_APPEND
+	// O_APPEND is kept, so writes from concurrent appenders
+	// land at the end of the file. In writeback cache mode, the
+	// fuse.Server removes it.
 	f, err := os.OpenFile(fs.GetPath(name), int(flags), 0)
 	if err != nil {
 		return nil, fuse.ToStatus(err)
@@ -135,7 +194,14 @@ func (fs *loopbackFileSystem) Chmod(path string, mode uint32, context *fuse.Cont
 }
 
 func (fs *loopbackFileSystem) Chown(path string, uid uint32, gid uint32, context *fuse.Context) (code fuse.Status) {
-	return fuse.ToStatus(os.Chown(fs.GetPath(path), int(uid), int(gid)))
+	suid, sgid := -1, -1
+	if uid != ^uint32(0) {
+		suid = int(uid)
+	}
+	if gid != ^uint32(0) {
+		sgid = int(gid)
+	}
+	return fuse.ToStatus(os.Chown(fs.GetPath(path), suid, sgid))
 }
 
 func (fs *loopbackFileSystem) Truncate(path string, offset uint64, context *fuse.Context) (code fuse.Status) {
@@ -193,7 +259,6 @@ func (fs *loopbackFileSystem) Access(name string, mode uint32, context *fuse.Con
 }
 
 func (fs *loopbackFileSystem) Create(path string, flags uint32, mode uint32, context *fuse.Context) (fuseFile nodefs.File, code fuse.Status) {
-	flags = flags &^ syscall.O_APPEND
 	f, err := os.OpenFile(fs.GetPath(path), int(flags)|os.O_CREATE, os.FileMode(mode))
 	return nodefs.NewLoopbackFile(f), fuse.ToStatus(err)
 }
@@ -8,11 +8,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
 	"github.com/hanwen/go-fuse/v2/internal/testutil"
 )
 
@@ -33,3 +35,222 @@ func TestLoopbackFileSystemUtimens(t *testing.T) {
 	}
 	testutil.TestLoopbackUtimens(t, path, utimensFn)
 }
+
+func TestLoopbackCreateExclusive(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+
+	nfs := NewPathNodeFs(NewLoopbackFileSystem(orig), nil)
+	state, _, err := nodefs.MountRoot(mnt, nfs.Root(), &nodefs.Options{
+		NegativeTimeout: time.Hour,
+		Debug:           testutil.VerboseTest(),
+	})
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	defer state.Unmount()
+	go state.Serve()
+	if err := state.WaitMount(); err != nil {
+		t.Fatalf("WaitMount: %v", err)
+	}
+
+	excl := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	f, err := os.OpenFile(mnt+"/lock", excl, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	f.Close()
+	if _, err := os.OpenFile(mnt+"/lock", excl, 0644); !os.IsExist(err) {
+		t.Errorf("second exclusive create: got %v, want EEXIST", err)
+	}
+
+	// The kernel caches that "other" does not exist, so it sends
+	// CREATE; the EEXIST from the file system must reach the
+	// caller.
+	if _, err := os.Lstat(mnt + "/other"); !os.IsNotExist(err) {
+		t.Fatalf("Lstat: %v", err)
+	}
+	if err := ioutil.WriteFile(orig+"/other", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := os.OpenFile(mnt+"/other", excl, 0644); !os.IsExist(err) {
+		t.Errorf("exclusive create of file created behind the kernel's back: got %v, want EEXIST", err)
+	}
+	if content, err := ioutil.ReadFile(orig + "/other"); err != nil || string(content) != "hello" {
+		t.Errorf("other was changed: %q, %v", content, err)
+	}
+}
+
+// attrCreatingFS counts successful GetAttr calls for the loopback
+// file system it wraps.
+type attrCreatingFS struct {
+	FileSystem
+	getAttrs int32
+}
+
+func (fs *attrCreatingFS) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	a, code := fs.FileSystem.GetAttr(name, context)
+	if code.Ok() && name != "" {
+		atomic.AddInt32(&fs.getAttrs, 1)
+	}
+	return a, code
+}
+
+func (fs *attrCreatingFS) CreateAttr(name string, flags uint32, mode uint32, context *fuse.Context) (nodefs.File, *fuse.Attr, fuse.Status) {
+	f, code := fs.FileSystem.Create(name, flags, mode, context)
+	if !code.Ok() {
+		return nil, nil, code
+	}
+	a, code := fs.FileSystem.GetAttr(name, context)
+	return f, a, code
+}
+
+func (fs *attrCreatingFS) MknodAttr(name string, mode uint32, dev uint32, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	if code := fs.FileSystem.Mknod(name, mode, dev, context); !code.Ok() {
+		return nil, code
+	}
+	return fs.FileSystem.GetAttr(name, context)
+}
+
+func (fs *attrCreatingFS) MkdirAttr(name string, mode uint32, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	if code := fs.FileSystem.Mkdir(name, mode, context); !code.Ok() {
+		return nil, code
+	}
+	return fs.FileSystem.GetAttr(name, context)
+}
+
+func (fs *attrCreatingFS) SymlinkAttr(value string, linkName string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	if code := fs.FileSystem.Symlink(value, linkName, context); !code.Ok() {
+		return nil, code
+	}
+	return fs.FileSystem.GetAttr(linkName, context)
+}
+
+func TestAttrCreater(t *testing.T) {
+	orig := testutil.TempDir()
+	defer os.RemoveAll(orig)
+	mnt := testutil.TempDir()
+	defer os.RemoveAll(mnt)
+
+	fs := &attrCreatingFS{FileSystem: NewLoopbackFileSystem(orig)}
+	opts := nodefs.NewOptions()
+	opts.Debug = testutil.VerboseTest()
+	state, _, err := nodefs.MountRoot(mnt, NewPathNodeFs(fs, nil).Root(), opts)
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	go state.Serve()
+	if err := state.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer state.Unmount()
+
+	if err := os.Mkdir(mnt+"/dir", 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := os.Symlink("dir", mnt+"/link"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if err := syscall.Mkfifo(mnt+"/fifo", 0644); err != nil {
+		t.Fatalf("Mkfifo: %v", err)
+	}
+	f, err := os.OpenFile(mnt+"/file", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	f.Close()
+
+	if n := atomic.LoadInt32(&fs.getAttrs); n != 0 {
+		t.Errorf("got %d GetAttr calls for created nodes, want 0", n)
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Lstat(mnt+"/link", &st); err != nil {
+		t.Fatalf("Lstat: %v", err)
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFLNK {
+		t.Errorf("link has mode %o", st.Mode)
+	}
+}
+
+type lookupFS struct {
+	FileSystem
+	lookups   int32
+	readlinks int32
+}
+
+func (fs *lookupFS) Lookup(dir string, name string, context *fuse.Context) (*fuse.Attr, string, fuse.Status) {
+	atomic.AddInt32(&fs.lookups, 1)
+	p := filepath.Join(dir, name)
+	a, code := fs.FileSystem.GetAttr(p, context)
+	if !code.Ok() || a.Mode&syscall.S_IFMT != syscall.S_IFLNK {
+		return a, "", code
+	}
+	target, code := fs.FileSystem.Readlink(p, context)
+	return a, target, code
+}
+
+func (fs *lookupFS) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
+	atomic.AddInt32(&fs.readlinks, 1)
+	return fs.FileSystem.Readlink(name, context)
+}
+
+func TestLookuper(t *testing.T) {
+	orig := testutil.TempDir()
+	defer os.RemoveAll(orig)
+	mnt := testutil.TempDir()
+	defer os.RemoveAll(mnt)
+
+	if err := os.Symlink("target", orig+"/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &lookupFS{FileSystem: NewLoopbackFileSystem(orig)}
+	opts := nodefs.NewOptions()
+	opts.Debug = testutil.VerboseTest()
+	// Revalidate attributes, but not entries, so the kernel
+	// sends GETATTR without LOOKUP.
+	opts.EntryTimeout = time.Hour
+	opts.AttrTimeout = 0
+	state, _, err := nodefs.MountRoot(mnt, NewPathNodeFs(fs, nil).Root(), opts)
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	go state.Serve()
+	if err := state.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer state.Unmount()
+
+	got, err := os.Readlink(mnt + "/link")
+	if err != nil {
+		t.Fatalf("Readlink: %v", err)
+	}
+	if got != "target" {
+		t.Errorf("Readlink: got %q, want %q", got, "target")
+	}
+	if n := atomic.LoadInt32(&fs.lookups); n == 0 {
+		t.Errorf("Lookup was not called")
+	}
+	if n := atomic.LoadInt32(&fs.readlinks); n != 0 {
+		t.Errorf("got %d Readlink calls, want 0", n)
+	}
+
+	// A GETATTR drops the target returned by Lookup.
+	if err := os.Remove(orig + "/link"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("other", orig+"/link"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(mnt + "/link"); err != nil {
+		t.Fatalf("Lstat: %v", err)
+	}
+	if got, err := os.Readlink(mnt + "/link"); err != nil || got != "other" {
+		t.Errorf("Readlink after change: got %q, %v, want %q", got, err, "other")
+	}
+}
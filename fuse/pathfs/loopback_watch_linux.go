@@ -0,0 +1,264 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+// watchMask selects the inotify events that change what the kernel
+// may have cached.
+const watchMask = syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF | syscall.IN_ONLYDIR
+
+type watchingLoopbackFileSystem struct {
+	*loopbackFileSystem
+
+	// inotify is the inotify instance; fd is its descriptor,
+	// which we keep because inotify.Fd() would make it blocking.
+	inotify *os.File
+	fd      int
+
+	nodeFs *PathNodeFs
+
+	mu sync.Mutex
+	// paths maps watch descriptors to directories, relative to
+	// the root.
+	paths map[int32]string
+	// watched maps the directories that have a watch to their
+	// descriptor.
+	watched map[string]int32
+	// epoch counts the prunes of watches for forgotten
+	// directories; added holds the epoch in which a watch was
+	// added. pruneAt is the number of watches that triggers the
+	// next prune.
+	epoch   int
+	added   map[int32]int
+	pruneAt int
+}
+
+// minPruneAt is the least number of watches that is pruned.
+const minPruneAt = 64
+
+// NewWatchingLoopbackFileSystem returns a loopback file system that
+// watches the directories the kernel has looked up with inotify.
+// Changes made directly in root then invalidate the kernel's
+// entries, attributes and data for the affected files, so they show
+// up in the mount before the entry and attribute timeouts expire.
+// If inotify drops events because its queue overflowed, everything
+// the kernel has cached is invalidated.
+//
+// Changes made through the mount are reported by inotify as well, so
+// they also invalidate the kernel's caches, and data written through
+// the mount is read again from root. This costs performance, but
+// does not lose data.
+//
+// Watches for directories the kernel has forgotten are removed when
+// more directories are watched.
+func NewWatchingLoopbackFileSystem(root string) (FileSystem, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	return &watchingLoopbackFileSystem{
+		loopbackFileSystem: NewLoopbackFileSystem(root).(*loopbackFileSystem),
+		inotify:            os.NewFile(uintptr(fd), "inotify"),
+		fd:                 fd,
+		paths:              map[int32]string{},
+		watched:            map[string]int32{},
+		added:              map[int32]int{},
+		pruneAt:            minPruneAt,
+	}, nil
+}
+
+func (fs *watchingLoopbackFileSystem) String() string {
+	return "Watching" + fs.loopbackFileSystem.String()
+}
+
+func (fs *watchingLoopbackFileSystem) OnMount(nodeFs *PathNodeFs) {
+	fs.nodeFs = nodeFs
+	fs.watch("")
+	go fs.readEvents()
+}
+
+func (fs *watchingLoopbackFileSystem) OnUnmount() {
+	fs.inotify.Close()
+}
+
+func (fs *watchingLoopbackFileSystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	a, code := fs.loopbackFileSystem.GetAttr(name, context)
+	if code.Ok() && a.IsDir() {
+		fs.watch(name)
+	}
+	return a, code
+}
+
+// watch adds a watch for the directory name, if it has none yet.
+func (fs *watchingLoopbackFileSystem) watch(name string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.watched[name]; ok {
+		return
+	}
+	if fs.nodeFs != nil && len(fs.watched) >= fs.pruneAt {
+		fs.pruneLocked()
+	}
+	wd, err := syscall.InotifyAddWatch(fs.fd, fs.GetPath(name), watchMask)
+	if err != nil {
+		return
+	}
+	if old, ok := fs.paths[int32(wd)]; ok {
+		// The directory was renamed by other means, and its
+		// event is not processed yet.
+		delete(fs.watched, old)
+	}
+	fs.paths[int32(wd)] = name
+	fs.watched[name] = int32(wd)
+	fs.added[int32(wd)] = fs.epoch
+}
+
+// removeLocked removes the watch wd.
+func (fs *watchingLoopbackFileSystem) removeLocked(wd int32) {
+	delete(fs.watched, fs.paths[wd])
+	delete(fs.paths, wd)
+	delete(fs.added, wd)
+	syscall.InotifyRmWatch(fs.fd, uint32(wd))
+}
+
+// unwatchLocked removes the watches of dir and the directories
+// below it, whose paths are no longer valid.
+func (fs *watchingLoopbackFileSystem) unwatchLocked(dir string) {
+	for wd, p := range fs.paths {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			fs.removeLocked(wd)
+		}
+	}
+}
+
+// pruneLocked removes the watches of directories the kernel has
+// forgotten. Watches added since the previous prune are kept, as
+// their directory may still be being looked up.
+func (fs *watchingLoopbackFileSystem) pruneLocked() {
+	for wd, p := range fs.paths {
+		if p != "" && fs.added[wd] < fs.epoch && fs.nodeFs.Node(p) == nil {
+			fs.removeLocked(wd)
+		}
+	}
+	fs.epoch++
+	fs.pruneAt = 2 * len(fs.watched)
+	if fs.pruneAt < minPruneAt {
+		fs.pruneAt = minPruneAt
+	}
+}
+
+func (fs *watchingLoopbackFileSystem) readEvents() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := fs.inotify.Read(buf)
+		if err != nil {
+			if !isClosedErr(err) {
+				log.Printf("inotify read: %v", err)
+			}
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBytes := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+			off += syscall.SizeofInotifyEvent + int(ev.Len)
+
+			// The name is padded with NUL bytes.
+			name := string(nameBytes)
+			for len(name) > 0 && name[len(name)-1] == 0 {
+				name = name[:len(name)-1]
+			}
+			fs.handleEvent(ev.Wd, ev.Mask, name)
+		}
+	}
+}
+
+func isClosedErr(err error) bool {
+	pe, ok := err.(*os.PathError)
+	return ok && pe.Err == os.ErrClosed
+}
+
+func (fs *watchingLoopbackFileSystem) handleEvent(wd int32, mask uint32, name string) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		fs.overflow()
+		return
+	}
+
+	fs.mu.Lock()
+	dir, ok := fs.paths[wd]
+	switch {
+	case !ok:
+	case mask&syscall.IN_IGNORED != 0:
+		// The watch is gone, because the directory was removed.
+		delete(fs.watched, dir)
+		delete(fs.paths, wd)
+		delete(fs.added, wd)
+	case mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0:
+		// The paths of the directory and the ones below it are
+		// stale; they are watched again under their new names
+		// once looked up.
+		fs.unwatchLocked(dir)
+	case mask&syscall.IN_MOVED_FROM != 0 && mask&syscall.IN_ISDIR != 0:
+		fs.unwatchLocked(filepath.Join(dir, name))
+	}
+	fs.mu.Unlock()
+	if !ok || name == "" {
+		return
+	}
+
+	path := filepath.Join(dir, name)
+	switch {
+	case mask&(syscall.IN_CREATE|syscall.IN_DELETE|syscall.IN_MOVED_FROM|syscall.IN_MOVED_TO) != 0:
+		fs.nodeFs.InvalidateEntry(dir, name)
+		fs.nodeFs.InvalidateAttr(dir)
+		fs.nodeFs.FileNotify(dir, 0, 0)
+	case mask&syscall.IN_MODIFY != 0:
+		fs.nodeFs.FileNotify(path, 0, 0)
+	case mask&syscall.IN_ATTRIB != 0:
+		fs.nodeFs.InvalidateAttr(path)
+	}
+}
+
+// overflow handles the loss of events. The watched paths may be
+// stale, so all watches are replaced, and everything the kernel
+// knows is invalidated, so it is looked up and watched again.
+func (fs *watchingLoopbackFileSystem) overflow() {
+	fs.mu.Lock()
+	for wd := range fs.paths {
+		fs.removeLocked(wd)
+	}
+	fs.mu.Unlock()
+	fs.watch("")
+	fs.invalidateTree("", fs.nodeFs.Root().Inode())
+}
+
+// invalidateTree invalidates the entries, attributes and data of
+// node, the directory dir, and of everything below it.
+func (fs *watchingLoopbackFileSystem) invalidateTree(dir string, node *nodefs.Inode) {
+	for name, child := range node.Children() {
+		path := filepath.Join(dir, name)
+		if child.IsDir() {
+			fs.invalidateTree(path, child)
+		} else {
+			fs.nodeFs.FileNotify(path, 0, 0)
+		}
+		fs.nodeFs.InvalidateEntry(dir, name)
+	}
+	fs.nodeFs.FileNotify(dir, 0, 0)
+}
@@ -0,0 +1,230 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+// waitFor polls cond until it holds, or a few seconds pass.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Errorf("timed out waiting for %s", what)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// mountWatching mounts a watching loopback of orig on mnt, with long
+// timeouts, so changes only show up through invalidations.
+func mountWatching(t *testing.T, orig string, mnt string) (*watchingLoopbackFileSystem, *PathNodeFs, func()) {
+	fs, err := NewWatchingLoopbackFileSystem(orig)
+	if err != nil {
+		t.Fatalf("NewWatchingLoopbackFileSystem: %v", err)
+	}
+	nfs := NewPathNodeFs(fs, nil)
+	state, _, err := nodefs.MountRoot(mnt, nfs.Root(), &nodefs.Options{
+		EntryTimeout:    time.Hour,
+		AttrTimeout:     time.Hour,
+		NegativeTimeout: time.Hour,
+		Debug:           testutil.VerboseTest(),
+	})
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	go state.Serve()
+	if err := state.WaitMount(); err != nil {
+		t.Fatalf("WaitMount: %v", err)
+	}
+	return fs.(*watchingLoopbackFileSystem), nfs, func() { state.Unmount() }
+}
+
+func TestWatchingLoopback(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+	os.Mkdir(orig+"/sub", 0755)
+	if err := ioutil.WriteFile(orig+"/sub/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	_, _, unmount := mountWatching(t, orig, mnt)
+	defer unmount()
+
+	// Populate the kernel caches.
+	if content, err := ioutil.ReadFile(mnt + "/sub/file"); err != nil || string(content) != "hello" {
+		t.Fatalf("ReadFile: %q, %v", content, err)
+	}
+	if _, err := os.Lstat(mnt + "/sub/new"); !os.IsNotExist(err) {
+		t.Fatalf("Lstat: %v", err)
+	}
+
+	if err := ioutil.WriteFile(orig+"/sub/file", []byte("hello world"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	waitFor(t, "changed content", func() bool {
+		content, _ := ioutil.ReadFile(mnt + "/sub/file")
+		return string(content) == "hello world"
+	})
+
+	if err := os.Chmod(orig+"/sub/file", 0600); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+	waitFor(t, "changed mode", func() bool {
+		fi, err := os.Lstat(mnt + "/sub/file")
+		return err == nil && fi.Mode().Perm() == 0600
+	})
+
+	if err := ioutil.WriteFile(orig+"/sub/new", nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	waitFor(t, "new file", func() bool {
+		_, err := os.Lstat(mnt + "/sub/new")
+		return err == nil
+	})
+
+	if err := os.Remove(orig + "/sub/file"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	waitFor(t, "removed file", func() bool {
+		_, err := os.Lstat(mnt + "/sub/file")
+		return os.IsNotExist(err)
+	})
+}
+
+func TestWatchingLoopbackRename(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(mnt, 0755)
+	if err := os.MkdirAll(orig+"/a/c", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, unmount := mountWatching(t, orig, mnt)
+	defer unmount()
+
+	if _, err := os.Lstat(mnt + "/a/c"); err != nil {
+		t.Fatalf("Lstat: %v", err)
+	}
+	if err := os.Rename(orig+"/a", orig+"/b"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "renamed directory", func() bool {
+		_, err := os.Lstat(mnt + "/a")
+		return os.IsNotExist(err)
+	})
+
+	// A new directory at the old path of c must be watched.
+	if err := os.MkdirAll(orig+"/a/c", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(orig+"/a/c/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "new directory", func() bool {
+		fi, err := os.Lstat(mnt + "/a/c/file")
+		return err == nil && fi.Size() == 5
+	})
+	if err := ioutil.WriteFile(orig+"/a/c/file", []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Opening the file refreshes its attributes, so check the
+	// cached ones.
+	waitFor(t, "changed size", func() bool {
+		fi, err := os.Lstat(mnt + "/a/c/file")
+		return err == nil && fi.Size() == 11
+	})
+}
+
+func TestWatchingLoopbackOverflow(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(mnt, 0755)
+	os.MkdirAll(orig+"/sub", 0755)
+	if err := ioutil.WriteFile(orig+"/sub/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Writes through a link outside the watched directories
+	// cause no events.
+	if err := os.Link(orig+"/sub/file", dir+"/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, _, unmount := mountWatching(t, orig, mnt)
+	defer unmount()
+
+	if _, err := os.Lstat(mnt + "/sub/file"); err != nil {
+		t.Fatalf("Lstat: %v", err)
+	}
+	if err := ioutil.WriteFile(dir+"/link", []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs.handleEvent(-1, syscall.IN_Q_OVERFLOW, "")
+	waitFor(t, "changed size", func() bool {
+		fi, err := os.Lstat(mnt + "/sub/file")
+		return err == nil && fi.Size() == 11
+	})
+}
+
+func TestWatchingLoopbackPrune(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+
+	fs, nfs, unmount := mountWatching(t, orig, mnt)
+	defer unmount()
+
+	var names []string
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("dir%d", i)
+		if err := os.Mkdir(orig+"/"+name, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Lstat(mnt + "/" + name); err != nil {
+			t.Fatalf("Lstat: %v", err)
+		}
+		names = append(names, name)
+	}
+	for _, name := range names {
+		nfs.InvalidateEntry("", name)
+	}
+	waitFor(t, "forgotten directories", func() bool {
+		for _, name := range names {
+			if nfs.Node(name) != nil {
+				return false
+			}
+		}
+		return true
+	})
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	// Watches are kept for one prune after they were added.
+	fs.pruneLocked()
+	fs.pruneLocked()
+	if len(fs.watched) != 1 || len(fs.paths) != 1 {
+		t.Errorf("got watches %v, want only the root", fs.paths)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+// Mount mounts fs on mountPoint. It wraps fs in a PathNodeFs,
+// connects it to a FileSystemConnector, and creates the Server.
+// Call Serve on the result to start handling requests, and Unmount
+// to stop. Both option arguments may be nil.
+func Mount(mountPoint string, fs FileSystem, mountOptions *fuse.MountOptions, opts *PathNodeFsOptions) (*fuse.Server, error) {
+	pathFs := NewPathNodeFs(fs, opts)
+	var nodeOpts *nodefs.Options
+	if mountOptions != nil && mountOptions.Debug {
+		nodeOpts = nodefs.NewOptions()
+		nodeOpts.Debug = true
+	}
+	server, _, err := nodefs.Mount(mountPoint, pathFs.Root(), mountOptions, nodeOpts)
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestMount(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+	if err := ioutil.WriteFile(orig+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := Mount(mnt, NewLoopbackFileSystem(orig), &fuse.MountOptions{
+		Debug: testutil.VerboseTest(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go server.Serve()
+	if err := server.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer server.Unmount()
+
+	if content, err := ioutil.ReadFile(mnt + "/file"); err != nil || string(content) != "hello" {
+		t.Errorf("ReadFile: %q, %v", content, err)
+	}
+}
+
+func TestSwapFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig1 := dir + "/orig1"
+	orig2 := dir + "/orig2"
+	mnt := dir + "/mnt"
+	for _, d := range []string{orig1, orig2, mnt} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(orig1+"/file", []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(orig2+"/file", []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := NewLoopbackFileSystem(orig1)
+	pathFs := NewPathNodeFs(old, nil)
+	opts := nodefs.NewOptions()
+	opts.EntryTimeout = 0
+	opts.AttrTimeout = 0
+	server, _, err := nodefs.Mount(mnt, pathFs.Root(), &fuse.MountOptions{
+		Debug: testutil.VerboseTest(),
+	}, opts)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go server.Serve()
+	if err := server.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer server.Unmount()
+
+	f, err := os.OpenFile(mnt+"/file", os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	if got := pathFs.SwapFileSystem(NewLoopbackFileSystem(orig2)); got != old {
+		t.Errorf("SwapFileSystem returned %v, want %v", got, old)
+	}
+
+	if content, err := ioutil.ReadFile(mnt + "/file"); err != nil || string(content) != "new" {
+		t.Errorf("ReadFile after swap: %q, %v", content, err)
+	}
+
+	// The file opened before the swap still goes to the old
+	// file system.
+	if _, err := f.Write([]byte("er")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if content, err := ioutil.ReadFile(orig1 + "/file"); err != nil || string(content) != "older" {
+		t.Errorf("old file: %q, %v", content, err)
+	}
+	if content, err := ioutil.ReadFile(orig2 + "/file"); err != nil || string(content) != "new" {
+		t.Errorf("new file: %q, %v", content, err)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// NormalizationForm is a Unicode normalization form for file names.
+type NormalizationForm int
+
+const (
+	// NFC is the composed form, used by most Linux programs and
+	// input methods.
+	NFC NormalizationForm = iota
+
+	// NFD is the decomposed form, used by HFS+ on macOS.
+	NFD
+)
+
+// NewNormalizingFileSystem returns a wrapper that shows the names of
+// fs in the form client, and passes names to fs in the form backend.
+// For example, with client NFC and backend NFD, files written on
+// macOS can be opened under the NFC names that Linux programs use.
+// If a name does not exist in fs in the backend form, but does
+// exist as given, the name is passed on as given, so entries that
+// were stored in another form can still be reached.
+//
+// Names are not checked for collisions: if a directory of fs holds
+// names that only differ in normalization, only one of them can be
+// reached.
+func NewNormalizingFileSystem(fs FileSystem, client, backend NormalizationForm) FileSystem {
+	return NewTransformFileSystem(fs,
+		func(name string) (string, bool) {
+			return toBackendPath(fs, name, backend), true
+		},
+		func(dir string, name string) (string, bool) {
+			return normalize(name, client), true
+		})
+}
+
+// toBackendPath normalizes each component of name to form, unless
+// only the original component exists in fs.
+func toBackendPath(fs FileSystem, name string, form NormalizationForm) string {
+	if isNormalASCII(name) {
+		return name
+	}
+	var inner string
+	for _, comp := range strings.Split(name, "/") {
+		norm := path.Join(inner, normalize(comp, form))
+		if literal := path.Join(inner, comp); literal != norm {
+			if _, code := fs.GetAttr(norm, nil); !code.Ok() {
+				if _, code := fs.GetAttr(literal, nil); code.Ok() {
+					norm = literal
+				}
+			}
+		}
+		inner = norm
+	}
+	return inner
+}
+
+//go:generate go run gen_normtables.go
+
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+type normCombiningRange struct {
+	lo, hi rune
+	ccc    uint8
+}
+
+// normCompositions maps pairs of runes to their composition.
+var normCompositions = map[[2]rune]rune{}
+
+func init() {
+	for r, d := range normDecompositions {
+		if normCompositionExclusions[r] || utf8.RuneCountInString(d) != 2 {
+			continue
+		}
+		first, n := utf8.DecodeRuneInString(d)
+		second, _ := utf8.DecodeRuneInString(d[n:])
+		normCompositions[[2]rune{first, second}] = r
+	}
+}
+
+// combiningClass returns the canonical combining class of r.
+func combiningClass(r rune) uint8 {
+	i := sort.Search(len(normCombiningClasses), func(i int) bool {
+		return normCombiningClasses[i].hi >= r
+	})
+	if i < len(normCombiningClasses) && normCombiningClasses[i].lo <= r {
+		return normCombiningClasses[i].ccc
+	}
+	return 0
+}
+
+// isNormalASCII returns true if s is ASCII, which is the same in
+// all normalization forms.
+func isNormalASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// decompose appends the full canonical decomposition of r to out.
+func decompose(out []rune, r rune) []rune {
+	if s := r - hangulSBase; s >= 0 && s < hangulSCount {
+		out = append(out, hangulLBase+s/hangulNCount, hangulVBase+(s%hangulNCount)/hangulTCount)
+		if t := s % hangulTCount; t != 0 {
+			out = append(out, hangulTBase+t)
+		}
+		return out
+	}
+	d, ok := normDecompositions[r]
+	if !ok {
+		return append(out, r)
+	}
+	for _, c := range d {
+		out = decompose(out, c)
+	}
+	return out
+}
+
+// compose returns the composition of a and b, if it exists.
+func compose(a, b rune) (rune, bool) {
+	if l := a - hangulLBase; l >= 0 && l < hangulLCount {
+		if v := b - hangulVBase; v >= 0 && v < hangulVCount {
+			return hangulSBase + (l*hangulVCount+v)*hangulTCount, true
+		}
+	}
+	if s := a - hangulSBase; s >= 0 && s < hangulSCount && s%hangulTCount == 0 {
+		if t := b - hangulTBase; t > 0 && t < hangulTCount {
+			return a + t, true
+		}
+	}
+	r, ok := normCompositions[[2]rune{a, b}]
+	return r, ok
+}
+
+// normalize returns s in the given normalization form. Invalid UTF-8
+// is passed through unchanged.
+func normalize(s string, form NormalizationForm) string {
+	if isNormalASCII(s) || !utf8.ValidString(s) {
+		return s
+	}
+	var rs []rune
+	for _, r := range s {
+		rs = decompose(rs, r)
+	}
+
+	// Put runs of combining marks in canonical order.
+	for i := 0; i < len(rs); {
+		if combiningClass(rs[i]) == 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(rs) && combiningClass(rs[j]) != 0 {
+			j++
+		}
+		run := rs[i:j]
+		sort.SliceStable(run, func(a, b int) bool {
+			return combiningClass(run[a]) < combiningClass(run[b])
+		})
+		i = j
+	}
+	if form == NFD {
+		return string(rs)
+	}
+
+	// Canonical composition: combine each rune with the last
+	// starter, unless it is blocked by a rune in between of the
+	// same or higher combining class.
+	out := rs[:0]
+	starter := -1
+	var lastClass uint8
+	for _, r := range rs {
+		class := combiningClass(r)
+		if starter >= 0 {
+			blocked := len(out)-1 != starter && (lastClass == 0 || lastClass >= class)
+			if !blocked {
+				if c, ok := compose(out[starter], r); ok {
+					out[starter] = c
+					continue
+				}
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		lastClass = class
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		in, nfc, nfd string
+	}{
+		{"plain", "plain", "plain"},
+		{"caf\u00e9", "caf\u00e9", "cafe\u0301"},
+		{"cafe\u0301", "caf\u00e9", "cafe\u0301"},
+		// Combining marks are put in canonical order.
+		{"a\u0302\u0323", "\u1ead", "a\u0323\u0302"},
+		{"\ud55c", "\ud55c", "\u1112\u1161\u11ab"},
+		{"\u1112\u1161\u11ab", "\ud55c", "\u1112\u1161\u11ab"},
+		// Composition exclusions stay decomposed.
+		{"\u0958", "\u0915\u093c", "\u0915\u093c"},
+		{"bad\xff", "bad\xff", "bad\xff"},
+	} {
+		if got := normalize(tc.in, NFC); got != tc.nfc {
+			t.Errorf("NFC(%q): got %q, want %q", tc.in, got, tc.nfc)
+		}
+		if got := normalize(tc.in, NFD); got != tc.nfd {
+			t.Errorf("NFD(%q): got %q, want %q", tc.in, got, tc.nfd)
+		}
+	}
+}
+
+func TestNormalizingFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	nfd := "re\u0301sume\u0301"
+	nfc := "r\u00e9sum\u00e9"
+	if err := os.Mkdir(dir+"/"+nfd, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(dir+"/"+nfd+"/"+nfd, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewNormalizingFileSystem(NewLoopbackFileSystem(dir), NFC, NFD)
+	if a, code := fs.GetAttr(nfc+"/"+nfc, nil); !code.Ok() || a.Size != 5 {
+		t.Errorf("GetAttr: %v, %v", a, code)
+	}
+	if names := dirNames(t, fs, nfc); len(names) != 1 || names[0] != nfc {
+		t.Errorf("got entries %v, want %q", names, nfc)
+	}
+	if code := fs.Mkdir("na\u00efve", 0755, nil); !code.Ok() {
+		t.Fatalf("Mkdir: %v", code)
+	}
+	if _, err := os.Stat(dir + "/nai\u0308ve"); err != nil {
+		t.Errorf("backing directory not in NFD: %v", err)
+	}
+
+	// Names stored in NFC on the backend are still reachable.
+	if err := ioutil.WriteFile(dir+"/"+nfd+"/caf\u00e9", []byte("coffee"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if a, code := fs.GetAttr(nfc+"/caf\u00e9", nil); !code.Ok() || a.Size != 6 {
+		t.Errorf("GetAttr of NFC backend name: %v, %v", a, code)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
@@ -31,8 +32,12 @@ type refCountedInode struct {
 // return value. The inode number ("clientInode") is used to indicate
 // linked files.
 type PathNodeFs struct {
-	debug     bool
-	fs        FileSystem
+	debug bool
+
+	// protects fs
+	fsLock sync.RWMutex
+	fs     FileSystem
+
 	root      *pathInode
 	connector *nodefs.FileSystemConnector
 
@@ -101,14 +106,45 @@ func (fs *PathNodeFs) Unmount(path string) fuse.Status {
 
 // String returns a name for this file system
 func (fs *PathNodeFs) String() string {
-	name := fs.fs.String()
+	pfs := fs.FileSystem()
+	name := pfs.String()
 	if name == "defaultFileSystem" {
-		name = fmt.Sprintf("%T", fs.fs)
+		name = fmt.Sprintf("%T", pfs)
 		name = strings.TrimLeft(name, "*")
 	}
 	return name
 }
 
+// FileSystem returns the FileSystem that operations are passed to.
+func (fs *PathNodeFs) FileSystem() FileSystem {
+	fs.fsLock.RLock()
+	defer fs.fsLock.RUnlock()
+	return fs.fs
+}
+
+// SwapFileSystem replaces the FileSystem that operations are passed
+// to, and returns the previous one. Operations that start after the
+// swap go to newFs, which gets an OnMount call first if this
+// PathNodeFs is mounted. Files and directories opened before the
+// swap keep working against the File objects that the old
+// FileSystem returned until they are released; the old FileSystem
+// does not get an OnUnmount call, so the caller decides when to shut
+// it down.
+//
+// The kernel may serve cached entries and attributes of the old
+// FileSystem until their timeouts expire. Use the notify methods of
+// the FileSystemConnector to drop them sooner.
+func (fs *PathNodeFs) SwapFileSystem(newFs FileSystem) FileSystem {
+	if fs.connector != nil {
+		newFs.OnMount(fs)
+	}
+	fs.fsLock.Lock()
+	defer fs.fsLock.Unlock()
+	old := fs.fs
+	fs.fs = newFs
+	return old
+}
+
 // Connector returns the FileSystemConnector (the bridge to the raw
 // protocol) for this PathNodeFs.
 func (fs *PathNodeFs) Connector() *nodefs.FileSystemConnector {
@@ -178,6 +214,39 @@ func (fs *PathNodeFs) Notify(path string) fuse.Status {
 	return fs.connector.FileNotify(node, 0, 0)
 }
 
+// InvalidateAttr makes the kernel drop its cached attributes for
+// path, so the next stat(2) results in a GetAttr call. It is meant
+// for file systems that learn about changes to their backing storage
+// by other means. It returns OK if the kernel has no data for path.
+func (fs *PathNodeFs) InvalidateAttr(path string) fuse.Status {
+	node, rest := fs.connector.Node(fs.root.Inode(), path)
+	if len(rest) > 0 {
+		return fuse.OK
+	}
+	return invalidateResult(fs.connector.FileNotify(node, -1, 0))
+}
+
+// InvalidateEntry makes the kernel drop its cached lookup result,
+// either positive or negative, for name in directory dir. It returns
+// OK if the kernel has no data for the entry.
+func (fs *PathNodeFs) InvalidateEntry(dir string, name string) fuse.Status {
+	node, rest := fs.connector.Node(fs.root.Inode(), dir)
+	if len(rest) > 0 {
+		return fuse.OK
+	}
+	return invalidateResult(fs.connector.EntryNotify(node, name))
+}
+
+// invalidateResult maps the kernel's answer to a notification to
+// the result of an invalidation: ENOENT means there was nothing to
+// invalidate.
+func invalidateResult(code fuse.Status) fuse.Status {
+	if code == fuse.ENOENT {
+		return fuse.OK
+	}
+	return code
+}
+
 // AllFiles returns all open files for the inode corresponding with
 // the given mask.
 func (fs *PathNodeFs) AllFiles(name string, mask uint32) []nodefs.WithFlags {
@@ -192,7 +261,6 @@ func (fs *PathNodeFs) AllFiles(name string, mask uint32) []nodefs.WithFlags {
 // path names.
 func NewPathNodeFs(fs FileSystem, opts *PathNodeFsOptions) *PathNodeFs {
 	root := &pathInode{}
-	root.fs = fs
 
 	if opts == nil {
 		opts = &PathNodeFsOptions{}
@@ -218,20 +286,43 @@ func (fs *PathNodeFs) Root() nodefs.Node {
 // there is a one-to-one mapping of paths and inodes.
 type pathInode struct {
 	pathFs *PathNodeFs
-	fs     FileSystem
 
 	// This is to correctly resolve hardlinks of the underlying
 	// real filesystem.
 	clientInode uint64
 	inode       *nodefs.Inode
+
+	// created holds the attributes returned by AttrCreater, for
+	// the GetAttr call that follows creating the node. The node
+	// is in the tree by then, so a concurrent LOOKUP may get to
+	// it first.
+	createdMu sync.Mutex
+	created   *fuse.Attr
+
+	// linkTarget holds the symlink target string returned by
+	// Lookuper, or "". GetAttr clears it, so a symlink that was
+	// replaced is read again once the kernel revalidates it.
+	linkTarget atomic.Value
+}
+
+// fs returns the FileSystem to pass operations to.
+func (n *pathInode) fs() FileSystem {
+	return n.pathFs.FileSystem()
 }
 
 func (n *pathInode) OnMount(conn *nodefs.FileSystemConnector) {
 	n.pathFs.connector = conn
-	n.pathFs.fs.OnMount(n.pathFs)
+	n.fs().OnMount(n.pathFs)
+}
+
+func (n *pathInode) ReleaseDir(info *nodefs.ReleaseInfo) {
+	if r, ok := n.fs().(DirReleaser); ok {
+		r.ReleaseDir(n.GetPath(), info)
+	}
 }
 
 func (n *pathInode) OnUnmount() {
+	n.fs().OnUnmount()
 }
 
 // Drop all known client inodes. Must have the treeLock.
@@ -301,7 +392,7 @@ func (n *pathInode) GetPath() string {
 
 	path := string(pathBytes)
 	if n.pathFs.debug {
-		log.Printf("Inode = %q (%s)", path, n.fs.String())
+		log.Printf("Inode = %q (%s)", path, n.fs().String())
 	}
 
 	if walkUp != n.pathFs.root.Inode() {
@@ -371,52 +462,75 @@ func (n *pathInode) OnForget() {
 // FS operations
 
 func (n *pathInode) StatFs() *fuse.StatfsOut {
-	return n.fs.StatFs(n.GetPath())
+	return n.fs().StatFs(n.GetPath())
 }
 
 func (n *pathInode) Readlink(c *fuse.Context) ([]byte, fuse.Status) {
+	if target, _ := n.linkTarget.Load().(string); target != "" {
+		return []byte(target), fuse.OK
+	}
 	path := n.GetPath()
 
-	val, err := n.fs.Readlink(path, c)
+	val, err := n.fs().Readlink(path, c)
 	return []byte(val), err
 }
 
 func (n *pathInode) Access(mode uint32, context *fuse.Context) (code fuse.Status) {
 	p := n.GetPath()
-	return n.fs.Access(p, mode, context)
+	return n.fs().Access(p, mode, context)
 }
 
 func (n *pathInode) GetXAttr(attribute string, context *fuse.Context) (data []byte, code fuse.Status) {
-	return n.fs.GetXAttr(n.GetPath(), attribute, context)
+	return n.fs().GetXAttr(n.GetPath(), attribute, context)
 }
 
 func (n *pathInode) RemoveXAttr(attr string, context *fuse.Context) fuse.Status {
 	p := n.GetPath()
-	return n.fs.RemoveXAttr(p, attr, context)
+	return n.fs().RemoveXAttr(p, attr, context)
 }
 
 func (n *pathInode) SetXAttr(attr string, data []byte, flags int, context *fuse.Context) fuse.Status {
-	return n.fs.SetXAttr(n.GetPath(), attr, data, flags, context)
+	return n.fs().SetXAttr(n.GetPath(), attr, data, flags, context)
 }
 
 func (n *pathInode) ListXAttr(context *fuse.Context) (attrs []string, code fuse.Status) {
-	return n.fs.ListXAttr(n.GetPath(), context)
+	return n.fs().ListXAttr(n.GetPath(), context)
 }
 
 func (n *pathInode) Flush(file nodefs.File, openFlags uint32, context *fuse.Context) (code fuse.Status) {
-	return file.Flush()
+	return nodefs.ToContextFile(file).FlushContext(context)
 }
 
 func (n *pathInode) OpenDir(context *fuse.Context) ([]fuse.DirEntry, fuse.Status) {
-	return n.fs.OpenDir(n.GetPath(), context)
+	return n.fs().OpenDir(n.GetPath(), context)
+}
+
+func (n *pathInode) OpenDirStream(context *fuse.Context) (nodefs.DirStream, fuse.Status) {
+	if c, ok := n.fs().(DirChunker); ok {
+		name := n.GetPath()
+		return newChunkedDirStream(func(token string, context *fuse.Context) ([]fuse.DirEntry, string, fuse.Status) {
+			return c.ReadDirChunk(name, token, context)
+		}), fuse.OK
+	}
+	if s, ok := n.fs().(DirStreamer); ok {
+		return s.OpenDirStream(n.GetPath(), context)
+	}
+	return nil, fuse.ENOSYS
 }
 
 func (n *pathInode) Mknod(name string, mode uint32, dev uint32, context *fuse.Context) (*nodefs.Inode, fuse.Status) {
 	fullPath := filepath.Join(n.GetPath(), name)
-	code := n.fs.Mknod(fullPath, mode, dev, context)
+	var attr *fuse.Attr
+	var code fuse.Status
+	if ac, ok := n.fs().(AttrCreater); ok {
+		attr, code = ac.MknodAttr(fullPath, mode, dev, context)
+	} else {
+		code = n.fs().Mknod(fullPath, mode, dev, context)
+	}
 	var child *nodefs.Inode
 	if code.Ok() {
 		pNode := n.createChild(name, false)
+		pNode.setCreated(attr)
 		child = pNode.Inode()
 	}
 	return child, code
@@ -424,17 +538,24 @@ func (n *pathInode) Mknod(name string, mode uint32, dev uint32, context *fuse.Co
 
 func (n *pathInode) Mkdir(name string, mode uint32, context *fuse.Context) (*nodefs.Inode, fuse.Status) {
 	fullPath := filepath.Join(n.GetPath(), name)
-	code := n.fs.Mkdir(fullPath, mode, context)
+	var attr *fuse.Attr
+	var code fuse.Status
+	if ac, ok := n.fs().(AttrCreater); ok {
+		attr, code = ac.MkdirAttr(fullPath, mode, context)
+	} else {
+		code = n.fs().Mkdir(fullPath, mode, context)
+	}
 	var child *nodefs.Inode
 	if code.Ok() {
 		pNode := n.createChild(name, true)
+		pNode.setCreated(attr)
 		child = pNode.Inode()
 	}
 	return child, code
 }
 
 func (n *pathInode) Unlink(name string, context *fuse.Context) (code fuse.Status) {
-	code = n.fs.Unlink(filepath.Join(n.GetPath(), name), context)
+	code = n.fs().Unlink(filepath.Join(n.GetPath(), name), context)
 	if code.Ok() {
 		n.Inode().RmChild(name)
 	}
@@ -442,7 +563,7 @@ func (n *pathInode) Unlink(name string, context *fuse.Context) (code fuse.Status
 }
 
 func (n *pathInode) Rmdir(name string, context *fuse.Context) (code fuse.Status) {
-	code = n.fs.Rmdir(filepath.Join(n.GetPath(), name), context)
+	code = n.fs().Rmdir(filepath.Join(n.GetPath(), name), context)
 	if code.Ok() {
 		n.Inode().RmChild(name)
 	}
@@ -451,10 +572,17 @@ func (n *pathInode) Rmdir(name string, context *fuse.Context) (code fuse.Status)
 
 func (n *pathInode) Symlink(name string, content string, context *fuse.Context) (*nodefs.Inode, fuse.Status) {
 	fullPath := filepath.Join(n.GetPath(), name)
-	code := n.fs.Symlink(content, fullPath, context)
+	var attr *fuse.Attr
+	var code fuse.Status
+	if ac, ok := n.fs().(AttrCreater); ok {
+		attr, code = ac.SymlinkAttr(content, fullPath, context)
+	} else {
+		code = n.fs().Symlink(content, fullPath, context)
+	}
 	var child *nodefs.Inode
 	if code.Ok() {
 		pNode := n.createChild(name, false)
+		pNode.setCreated(attr)
 		child = pNode.Inode()
 	}
 	return child, code
@@ -464,7 +592,7 @@ func (n *pathInode) Rename(oldName string, newParent nodefs.Node, newName string
 	p := newParent.(*pathInode)
 	oldPath := filepath.Join(n.GetPath(), oldName)
 	newPath := filepath.Join(p.GetPath(), newName)
-	code = n.fs.Rename(oldPath, newPath, context)
+	code = n.fs().Rename(oldPath, newPath, context)
 	if code.Ok() {
 		// The rename may have overwritten another file, remove it from the tree
 		p.Inode().RmChild(newName)
@@ -485,11 +613,11 @@ func (n *pathInode) Link(name string, existingFsnode nodefs.Node, context *fuse.
 	newPath := filepath.Join(n.GetPath(), name)
 	existing := existingFsnode.(*pathInode)
 	oldPath := existing.GetPath()
-	code := n.fs.Link(oldPath, newPath, context)
+	code := n.fs().Link(oldPath, newPath, context)
 
 	var a *fuse.Attr
 	if code.Ok() {
-		a, code = n.fs.GetAttr(newPath, context)
+		a, code = n.fs().GetAttr(newPath, context)
 	}
 
 	var child *nodefs.Inode
@@ -509,17 +637,33 @@ func (n *pathInode) Link(name string, existingFsnode nodefs.Node, context *fuse.
 func (n *pathInode) Create(name string, flags uint32, mode uint32, context *fuse.Context) (nodefs.File, *nodefs.Inode, fuse.Status) {
 	var child *nodefs.Inode
 	fullPath := filepath.Join(n.GetPath(), name)
-	file, code := n.fs.Create(fullPath, flags, mode, context)
+	var file nodefs.File
+	var attr *fuse.Attr
+	var code fuse.Status
+	if ac, ok := n.fs().(AttrCreater); ok {
+		file, attr, code = ac.CreateAttr(fullPath, flags, mode, context)
+	} else {
+		file, code = n.fs().Create(fullPath, flags, mode, context)
+	}
 	if code.Ok() {
-		pNode := n.createChild(name, false)
-		child = pNode.Inode()
+		// Without O_EXCL, Create may open a file we know
+		// already.
+		if ch := n.Inode().GetChild(name); ch != nil && !ch.IsDir() {
+			child = ch
+		} else {
+			if ch != nil {
+				n.Inode().RmChild(name)
+			}
+			pNode := n.createChild(name, false)
+			pNode.setCreated(attr)
+			child = pNode.Inode()
+		}
 	}
 	return file, child, code
 }
 
 func (n *pathInode) createChild(name string, isDir bool) *pathInode {
 	i := &pathInode{
-		fs:     n.fs,
 		pathFs: n.pathFs,
 	}
 
@@ -530,7 +674,7 @@ func (n *pathInode) createChild(name string, isDir bool) *pathInode {
 
 func (n *pathInode) Open(flags uint32, context *fuse.Context) (file nodefs.File, code fuse.Status) {
 	p := n.GetPath()
-	file, code = n.fs.Open(p, flags, context)
+	file, code = n.fs().Open(p, flags, context)
 	if n.pathFs.debug {
 		file = &nodefs.WithFlags{
 			File:        file,
@@ -542,7 +686,14 @@ func (n *pathInode) Open(flags uint32, context *fuse.Context) (file nodefs.File,
 
 func (n *pathInode) Lookup(out *fuse.Attr, name string, context *fuse.Context) (*nodefs.Inode, fuse.Status) {
 	fullPath := filepath.Join(n.GetPath(), name)
-	fi, code := n.fs.GetAttr(fullPath, context)
+	var fi *fuse.Attr
+	var target string
+	var code fuse.Status
+	if l, ok := n.fs().(Lookuper); ok {
+		fi, target, code = l.Lookup(n.GetPath(), name, context)
+	} else {
+		fi, code = n.fs().GetAttr(fullPath, context)
+	}
 	node := n.Inode().GetChild(name)
 	if node != nil && (!code.Ok() || node.IsDir() != fi.IsDir()) {
 		n.Inode().RmChild(name)
@@ -553,6 +704,9 @@ func (n *pathInode) Lookup(out *fuse.Attr, name string, context *fuse.Context) (
 		if node == nil {
 			node = n.findChild(fi, name, fullPath).Inode()
 		}
+		if p, ok := node.Node().(*pathInode); ok {
+			p.linkTarget.Store(target)
+		}
 		*out = *fi
 	}
 
@@ -582,7 +736,32 @@ func (n *pathInode) findChild(fi *fuse.Attr, name string, fullPath string) (out
 	return out
 }
 
+func (n *pathInode) setCreated(attr *fuse.Attr) {
+	n.createdMu.Lock()
+	n.created = attr
+	n.createdMu.Unlock()
+}
+
+// takeCreated returns and clears the attributes from AttrCreater.
+func (n *pathInode) takeCreated() *fuse.Attr {
+	n.createdMu.Lock()
+	defer n.createdMu.Unlock()
+	attr := n.created
+	n.created = nil
+	return attr
+}
+
 func (n *pathInode) GetAttr(out *fuse.Attr, file nodefs.File, context *fuse.Context) (code fuse.Status) {
+	n.linkTarget.Store("")
+	if fi := n.takeCreated(); fi != nil {
+		n.setClientInode(fi.Ino)
+		*out = *fi
+		if !out.IsDir() && out.Nlink == 0 {
+			out.Nlink = 1
+		}
+		return fuse.OK
+	}
+
 	var fi *fuse.Attr
 	if file == nil {
 		// Linux currently (tested on v4.4) does not pass a file descriptor for
@@ -592,7 +771,7 @@ func (n *pathInode) GetAttr(out *fuse.Attr, file nodefs.File, context *fuse.Cont
 	}
 	// If we have found an open file, try to fstat it.
 	if file != nil {
-		code = file.GetAttr(out)
+		code = nodefs.ToContextFile(file).GetAttrContext(context, out)
 		if code.Ok() {
 			return code
 		}
@@ -604,7 +783,7 @@ func (n *pathInode) GetAttr(out *fuse.Attr, file nodefs.File, context *fuse.Cont
 	// If we don't have an open file, or fstat on it failed due to an internal
 	// error, stat by path.
 	if file == nil || code == fuse.ENOSYS || code == fuse.EBADF {
-		fi, code = n.fs.GetAttr(n.GetPath(), context)
+		fi, code = n.fs().GetAttr(n.GetPath(), context)
 		if !code.Ok() {
 			return code
 		}
@@ -629,7 +808,7 @@ func (n *pathInode) Chmod(file nodefs.File, perms uint32, context *fuse.Context)
 	// Note that Linux currently (Linux 4.4) DOES NOT pass a file descriptor
 	// to FUSE for fchmod. We still check because that may change in the future.
 	if file != nil {
-		code = file.Chmod(perms)
+		code = nodefs.ToContextFile(file).ChmodContext(context, perms)
 		if code != fuse.ENOSYS {
 			return code
 		}
@@ -637,15 +816,14 @@ func (n *pathInode) Chmod(file nodefs.File, perms uint32, context *fuse.Context)
 
 	files := n.Inode().Files(fuse.O_ANYWRITE)
 	for _, f := range files {
-		// TODO - pass context
-		code = f.Chmod(perms)
+		code = nodefs.ToContextFile(f).ChmodContext(context, perms)
 		if code.Ok() {
 			return
 		}
 	}
 
 	if len(files) == 0 || code == fuse.ENOSYS || code == fuse.EBADF {
-		code = n.fs.Chmod(n.GetPath(), perms, context)
+		code = n.fs().Chmod(n.GetPath(), perms, context)
 	}
 	return code
 }
@@ -654,7 +832,7 @@ func (n *pathInode) Chown(file nodefs.File, uid uint32, gid uint32, context *fus
 	// Note that Linux currently (Linux 4.4) DOES NOT pass a file descriptor
 	// to FUSE for fchown. We still check because it may change in the future.
 	if file != nil {
-		code = file.Chown(uid, gid)
+		code = nodefs.ToContextFile(file).ChownContext(context, uid, gid)
 		if code != fuse.ENOSYS {
 			return code
 		}
@@ -662,24 +840,29 @@ func (n *pathInode) Chown(file nodefs.File, uid uint32, gid uint32, context *fus
 
 	files := n.Inode().Files(fuse.O_ANYWRITE)
 	for _, f := range files {
-		// TODO - pass context
-		code = f.Chown(uid, gid)
+		code = nodefs.ToContextFile(f).ChownContext(context, uid, gid)
 		if code.Ok() {
 			return code
 		}
 	}
 	if len(files) == 0 || code == fuse.ENOSYS || code == fuse.EBADF {
-		// TODO - can we get just FATTR_GID but not FATTR_UID ?
-		code = n.fs.Chown(n.GetPath(), uid, gid, context)
+		code = n.fs().Chown(n.GetPath(), uid, gid, context)
 	}
 	return code
 }
 
+func (n *pathInode) SetAttr(input *fuse.SetAttrIn, file nodefs.File, context *fuse.Context) fuse.Status {
+	if sa, ok := n.fs().(SetAttrer); ok {
+		return sa.SetAttr(n.GetPath(), input, context)
+	}
+	return fuse.ENOSYS
+}
+
 func (n *pathInode) Truncate(file nodefs.File, size uint64, context *fuse.Context) (code fuse.Status) {
 	// A file descriptor was passed in AND the filesystem implements the
 	// operation on the file handle. This the common case for ftruncate.
 	if file != nil {
-		code = file.Truncate(size)
+		code = nodefs.ToContextFile(file).TruncateContext(context, size)
 		if code != fuse.ENOSYS {
 			return code
 		}
@@ -687,14 +870,13 @@ func (n *pathInode) Truncate(file nodefs.File, size uint64, context *fuse.Contex
 
 	files := n.Inode().Files(fuse.O_ANYWRITE)
 	for _, f := range files {
-		// TODO - pass context
-		code = f.Truncate(size)
+		code = nodefs.ToContextFile(f).TruncateContext(context, size)
 		if code.Ok() {
 			return code
 		}
 	}
 	if len(files) == 0 || code == fuse.ENOSYS || code == fuse.EBADF {
-		code = n.fs.Truncate(n.GetPath(), size, context)
+		code = n.fs().Truncate(n.GetPath(), size, context)
 	}
 	return code
 }
@@ -703,7 +885,7 @@ func (n *pathInode) Utimens(file nodefs.File, atime *time.Time, mtime *time.Time
 	// Note that Linux currently (Linux 4.4) DOES NOT pass a file descriptor
 	// to FUSE for futimens. We still check because it may change in the future.
 	if file != nil {
-		code = file.Utimens(atime, mtime)
+		code = nodefs.ToContextFile(file).UtimensContext(context, atime, mtime)
 		if code != fuse.ENOSYS {
 			return code
 		}
@@ -711,21 +893,34 @@ func (n *pathInode) Utimens(file nodefs.File, atime *time.Time, mtime *time.Time
 
 	files := n.Inode().Files(fuse.O_ANYWRITE)
 	for _, f := range files {
-		// TODO - pass context
-		code = f.Utimens(atime, mtime)
+		code = nodefs.ToContextFile(f).UtimensContext(context, atime, mtime)
 		if code.Ok() {
 			return code
 		}
 	}
 	if len(files) == 0 || code == fuse.ENOSYS || code == fuse.EBADF {
-		code = n.fs.Utimens(n.GetPath(), atime, mtime, context)
+		code = n.fs().Utimens(n.GetPath(), atime, mtime, context)
 	}
 	return code
 }
 
+func (n *pathInode) BirthTime(file nodefs.File, context *fuse.Context) (time.Time, fuse.Status) {
+	if bt, ok := n.fs().(BirthTimer); ok {
+		return bt.BirthTime(n.GetPath(), context)
+	}
+	return time.Time{}, fuse.ENOSYS
+}
+
+func (n *pathInode) SetCtime(file nodefs.File, ctime time.Time, context *fuse.Context) (code fuse.Status) {
+	if cs, ok := n.fs().(CtimeSetter); ok {
+		return cs.SetCtime(n.GetPath(), ctime, context)
+	}
+	return fuse.ENOSYS
+}
+
 func (n *pathInode) Fallocate(file nodefs.File, off uint64, size uint64, mode uint32, context *fuse.Context) (code fuse.Status) {
 	if file != nil {
-		code = file.Allocate(off, size, mode)
+		code = nodefs.ToContextFile(file).AllocateContext(context, off, size, mode)
 		if code.Ok() {
 			return code
 		}
@@ -733,8 +928,7 @@ func (n *pathInode) Fallocate(file nodefs.File, off uint64, size uint64, mode ui
 
 	files := n.Inode().Files(fuse.O_ANYWRITE)
 	for _, f := range files {
-		// TODO - pass context
-		code = f.Allocate(off, size, mode)
+		code = nodefs.ToContextFile(f).AllocateContext(context, off, size, mode)
 		if code.Ok() {
 			return code
 		}
@@ -745,14 +939,14 @@ func (n *pathInode) Fallocate(file nodefs.File, off uint64, size uint64, mode ui
 
 func (n *pathInode) Read(file nodefs.File, dest []byte, off int64, context *fuse.Context) (fuse.ReadResult, fuse.Status) {
 	if file != nil {
-		return file.Read(dest, off)
+		return nodefs.ToContextFile(file).ReadContext(context, dest, off)
 	}
 	return nil, fuse.ENOSYS
 }
 
 func (n *pathInode) Write(file nodefs.File, data []byte, off int64, context *fuse.Context) (written uint32, code fuse.Status) {
 	if file != nil {
-		return file.Write(data, off)
+		return nodefs.ToContextFile(file).WriteContext(context, data, off)
 	}
 	return 0, fuse.ENOSYS
 }
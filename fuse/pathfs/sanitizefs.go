@@ -0,0 +1,99 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"strings"
+)
+
+// sanitizeChars are the characters that are not allowed in Windows
+// file names, besides the control characters. sanitizeChars[i] is
+// shown as sanitizeBase+0x20+i.
+const sanitizeChars = `"*:<>?\|`
+
+// sanitizeBase is the start of the private use area range of the
+// Services for Mac (SFM) mapping, which is also used by Samba's
+// catia module, so clients that understand it show the original
+// names.
+const sanitizeBase = 0xF000
+
+const (
+	sanitizeSpace = sanitizeBase + 0x28
+	sanitizeDot   = sanitizeBase + 0x29
+)
+
+// NewSanitizingFileSystem returns a wrapper that shows names of fs
+// that are invalid on Windows under valid names, so they stay
+// reachable when the mount is exported through SMB. It uses the
+// mapping of Services for Mac: control characters and the
+// characters "*:<>?\| are replaced by characters from the Unicode
+// private use area starting at U+F001, and so is a trailing space or
+// dot. Names are mapped back when passed to fs, so files can be
+// created with such names too.
+//
+// Names of fs that already contain characters of the mapping are
+// shown as is, and are changed when passed back, so they cannot be
+// reached.
+func NewSanitizingFileSystem(fs FileSystem) FileSystem {
+	return NewTransformFileSystem(fs,
+		func(name string) (string, bool) {
+			comps := strings.Split(name, "/")
+			for i, c := range comps {
+				comps[i] = unsanitizeName(c)
+			}
+			return strings.Join(comps, "/"), true
+		},
+		func(dir string, name string) (string, bool) {
+			return sanitizeName(name), true
+		})
+}
+
+// sanitizeName returns name with characters that are invalid on
+// Windows replaced.
+func sanitizeName(name string) string {
+	if name == "." || name == ".." {
+		return name
+	}
+	var b strings.Builder
+	n := len(name)
+	for i, r := range name {
+		switch {
+		case r > 0 && r < 0x20:
+			b.WriteRune(sanitizeBase + r)
+		case r < 0x80 && strings.ContainsRune(sanitizeChars, r):
+			b.WriteRune(sanitizeBase + 0x20 + rune(strings.IndexRune(sanitizeChars, r)))
+		case i == n-1 && r == ' ':
+			b.WriteRune(sanitizeSpace)
+		case i == n-1 && r == '.':
+			b.WriteRune(sanitizeDot)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// unsanitizeName reverses sanitizeName.
+func unsanitizeName(name string) string {
+	if strings.IndexFunc(name, func(r rune) bool { return r > sanitizeBase && r <= sanitizeDot }) < 0 {
+		return name
+	}
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r > sanitizeBase && r < sanitizeBase+0x20:
+			b.WriteRune(r - sanitizeBase)
+		case r >= sanitizeBase+0x20 && r < sanitizeBase+0x20+rune(len(sanitizeChars)):
+			b.WriteByte(sanitizeChars[r-sanitizeBase-0x20])
+		case r == sanitizeSpace:
+			b.WriteByte(' ')
+		case r == sanitizeDot:
+			b.WriteByte('.')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestSanitizeName(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+	}{
+		{"plain.txt", "plain.txt"},
+		{"a:b", "a\uf022b"},
+		{"\"*:<>?\\|", "\uf020\uf021\uf022\uf023\uf024\uf025\uf026\uf027"},
+		{"tab\there", "tab\uf009here"},
+		{"dots..", "dots.\uf029"},
+		{"space ", "space\uf028"},
+		{" lead", " lead"},
+		{"..", ".."},
+	} {
+		if got := sanitizeName(tc.in); got != tc.out {
+			t.Errorf("sanitizeName(%q): got %q, want %q", tc.in, got, tc.out)
+		}
+		if got := unsanitizeName(tc.out); got != tc.in {
+			t.Errorf("unsanitizeName(%q): got %q, want %q", tc.out, got, tc.in)
+		}
+	}
+}
+
+func TestSanitizingFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(dir+"/what?", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(dir+"/what?/a<b>.", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewSanitizingFileSystem(NewLoopbackFileSystem(dir))
+	if names := dirNames(t, fs, ""); len(names) != 1 || names[0] != "what\uf025" {
+		t.Errorf("got entries %q", names)
+	}
+	if names := dirNames(t, fs, "what\uf025"); len(names) != 1 || names[0] != "a\uf023b\uf024\uf029" {
+		t.Errorf("got entries %q", names)
+	}
+	if a, code := fs.GetAttr("what\uf025/a\uf023b\uf024\uf029", nil); !code.Ok() || a.Size != 5 {
+		t.Errorf("GetAttr: %v, %v", a, code)
+	}
+	if code := fs.Mkdir("x\uf022y", 0755, nil); !code.Ok() {
+		t.Fatalf("Mkdir: %v", code)
+	}
+	if _, err := os.Stat(dir + "/x:y"); err != nil {
+		t.Errorf("Stat: %v", err)
+	}
+}
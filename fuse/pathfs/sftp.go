@@ -0,0 +1,432 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+// This file contains a client for version 3 of the SFTP protocol,
+// as described in draft-ietf-secsh-filexfer-02 and implemented by
+// OpenSSH.
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+const (
+	sshFxpInit          = 1
+	sshFxpVersion       = 2
+	sshFxpOpen          = 3
+	sshFxpClose         = 4
+	sshFxpRead          = 5
+	sshFxpWrite         = 6
+	sshFxpLstat         = 7
+	sshFxpFstat         = 8
+	sshFxpSetstat       = 9
+	sshFxpFsetstat      = 10
+	sshFxpOpendir       = 11
+	sshFxpReaddir       = 12
+	sshFxpRemove        = 13
+	sshFxpMkdir         = 14
+	sshFxpRmdir         = 15
+	sshFxpRealpath      = 16
+	sshFxpStat          = 17
+	sshFxpRename        = 18
+	sshFxpReadlink      = 19
+	sshFxpSymlink       = 20
+	sshFxpStatus        = 101
+	sshFxpHandle        = 102
+	sshFxpData          = 103
+	sshFxpName          = 104
+	sshFxpAttrs         = 105
+	sshFxpExtended      = 200
+	sshFxpExtendedReply = 201
+)
+
+const (
+	sshFxOk               = 0
+	sshFxEOF              = 1
+	sshFxNoSuchFile       = 2
+	sshFxPermissionDenied = 3
+	sshFxFailure          = 4
+	sshFxBadMessage       = 5
+	sshFxNoConnection     = 6
+	sshFxConnectionLost   = 7
+	sshFxOpUnsupported    = 8
+)
+
+const (
+	sshFileXferAttrSize        = 0x1
+	sshFileXferAttrUIDGID      = 0x2
+	sshFileXferAttrPermissions = 0x4
+	sshFileXferAttrACModTime   = 0x8
+	sshFileXferAttrExtended    = 0x80000000
+)
+
+const (
+	sshFxfRead   = 0x1
+	sshFxfWrite  = 0x2
+	sshFxfAppend = 0x4
+	sshFxfCreat  = 0x8
+	sshFxfTrunc  = 0x10
+	sshFxfExcl   = 0x20
+)
+
+// sftpMaxData is the largest amount of data moved in a single READ
+// or WRITE. OpenSSH accepts up to 256k, but many servers only 32k.
+const sftpMaxData = 32 * 1024
+
+// sftpMaxPacket bounds the size of incoming packets.
+const sftpMaxPacket = 1 << 20
+
+// errSFTPConnection is returned for requests on a connection that
+// broke.
+var errSFTPConnection = errors.New("sftp: connection lost")
+
+// sftpBuffer builds the payload of a packet.
+type sftpBuffer []byte
+
+func (b *sftpBuffer) byte(v byte) {
+	*b = append(*b, v)
+}
+
+func (b *sftpBuffer) uint32(v uint32) {
+	*b = append(*b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32((*b)[len(*b)-4:], v)
+}
+
+func (b *sftpBuffer) uint64(v uint64) {
+	*b = append(*b, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64((*b)[len(*b)-8:], v)
+}
+
+func (b *sftpBuffer) string(s string) {
+	b.uint32(uint32(len(s)))
+	*b = append(*b, s...)
+}
+
+func (b *sftpBuffer) attrs(a *sftpAttrs) {
+	if a == nil {
+		b.uint32(0)
+		return
+	}
+	b.uint32(a.flags)
+	if a.flags&sshFileXferAttrSize != 0 {
+		b.uint64(a.size)
+	}
+	if a.flags&sshFileXferAttrUIDGID != 0 {
+		b.uint32(a.uid)
+		b.uint32(a.gid)
+	}
+	if a.flags&sshFileXferAttrPermissions != 0 {
+		b.uint32(a.perm)
+	}
+	if a.flags&sshFileXferAttrACModTime != 0 {
+		b.uint32(a.atime)
+		b.uint32(a.mtime)
+	}
+}
+
+// sftpReader parses a packet. After the first error, all reads
+// return zero values.
+type sftpReader struct {
+	b   []byte
+	err bool
+}
+
+func (r *sftpReader) byte() byte {
+	if len(r.b) < 1 {
+		r.err = true
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *sftpReader) uint32() uint32 {
+	if len(r.b) < 4 {
+		r.err = true
+		r.b = nil
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *sftpReader) uint64() uint64 {
+	if len(r.b) < 8 {
+		r.err = true
+		r.b = nil
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.b)
+	r.b = r.b[8:]
+	return v
+}
+
+func (r *sftpReader) string() string {
+	n := r.uint32()
+	if uint32(len(r.b)) < n {
+		r.err = true
+		r.b = nil
+		return ""
+	}
+	v := string(r.b[:n])
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *sftpReader) attrs() *sftpAttrs {
+	a := &sftpAttrs{flags: r.uint32()}
+	if a.flags&sshFileXferAttrSize != 0 {
+		a.size = r.uint64()
+	}
+	if a.flags&sshFileXferAttrUIDGID != 0 {
+		a.uid = r.uint32()
+		a.gid = r.uint32()
+	}
+	if a.flags&sshFileXferAttrPermissions != 0 {
+		a.perm = r.uint32()
+	}
+	if a.flags&sshFileXferAttrACModTime != 0 {
+		a.atime = r.uint32()
+		a.mtime = r.uint32()
+	}
+	if a.flags&sshFileXferAttrExtended != 0 {
+		for n := r.uint32(); n > 0 && !r.err; n-- {
+			r.string()
+			r.string()
+		}
+	}
+	return a
+}
+
+// sftpAttrs is the ATTRS structure of the protocol.
+type sftpAttrs struct {
+	flags        uint32
+	size         uint64
+	uid, gid     uint32
+	perm         uint32
+	atime, mtime uint32
+}
+
+func (a *sftpAttrs) fuseAttr() *fuse.Attr {
+	out := &fuse.Attr{
+		Size:  a.size,
+		Mode:  a.perm,
+		Owner: fuse.Owner{Uid: a.uid, Gid: a.gid},
+		Atime: uint64(a.atime),
+		Mtime: uint64(a.mtime),
+		Ctime: uint64(a.mtime),
+		Nlink: 1,
+	}
+	out.Blocks = (out.Size + 511) / 512
+	return out
+}
+
+func writeSFTPPacket(w io.Writer, typ byte, payload []byte) error {
+	hdr := make(sftpBuffer, 0, 5+len(payload))
+	hdr.uint32(uint32(len(payload) + 1))
+	hdr.byte(typ)
+	_, err := w.Write(append(hdr, payload...))
+	return err
+}
+
+func readSFTPPacket(r io.Reader) (typ byte, payload []byte, err error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:4])
+	if n < 1 || n > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("sftp: bad packet length %d", n)
+	}
+	payload = make([]byte, n-1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return hdr[4], payload, nil
+}
+
+type sftpResponse struct {
+	typ byte
+	r   *sftpReader
+}
+
+// sftpConn is a connection to an SFTP server. Requests may be issued
+// concurrently; responses are matched to them by ID.
+type sftpConn struct {
+	rwc        io.ReadWriteCloser
+	extensions map[string]string
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  uint32
+	pending map[uint32]chan sftpResponse
+	dead    bool
+}
+
+func newSFTPConn(rwc io.ReadWriteCloser) (*sftpConn, error) {
+	var b sftpBuffer
+	b.uint32(3)
+	if err := writeSFTPPacket(rwc, sshFxpInit, b); err != nil {
+		rwc.Close()
+		return nil, err
+	}
+	typ, payload, err := readSFTPPacket(rwc)
+	if err != nil {
+		rwc.Close()
+		return nil, err
+	}
+	r := &sftpReader{b: payload}
+	if typ != sshFxpVersion || r.uint32() != 3 {
+		rwc.Close()
+		return nil, fmt.Errorf("sftp: unsupported server version")
+	}
+	c := &sftpConn{
+		rwc:        rwc,
+		extensions: map[string]string{},
+		pending:    map[uint32]chan sftpResponse{},
+	}
+	for len(r.b) > 0 && !r.err {
+		name := r.string()
+		c.extensions[name] = r.string()
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *sftpConn) readLoop() {
+	for {
+		typ, payload, err := readSFTPPacket(c.rwc)
+		if err != nil {
+			c.close()
+			return
+		}
+		r := &sftpReader{b: payload}
+		id := r.uint32()
+		c.mu.Lock()
+		ch := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- sftpResponse{typ, r}
+		}
+	}
+}
+
+// close shuts down the connection, failing the pending requests.
+func (c *sftpConn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dead {
+		return
+	}
+	c.dead = true
+	c.rwc.Close()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
+
+func (c *sftpConn) isDead() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dead
+}
+
+// request sends a request, and waits for its response. The request
+// is abandoned with EINTR if cancel is closed, and with ETIMEDOUT
+// if there is no response within timeout. A server that does not
+// answer in time is considered hung, so the connection is closed.
+func (c *sftpConn) request(cancel <-chan struct{}, timeout time.Duration, typ byte, payload sftpBuffer) (sftpResponse, error) {
+	ch := make(chan sftpResponse, 1)
+	c.mu.Lock()
+	if c.dead {
+		c.mu.Unlock()
+		return sftpResponse{}, errSFTPConnection
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	var b sftpBuffer
+	b.uint32(id)
+	b = append(b, payload...)
+	c.writeMu.Lock()
+	err := writeSFTPPacket(c.rwc, typ, b)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.close()
+		return sftpResponse{}, errSFTPConnection
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return sftpResponse{}, errSFTPConnection
+		}
+		return resp, nil
+	case <-cancel:
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return sftpResponse{}, syscall.EINTR
+	case <-expired:
+		c.close()
+		return sftpResponse{}, syscall.ETIMEDOUT
+	}
+}
+
+// sftpStatus converts a STATUS response to a fuse.Status.
+func sftpStatus(code uint32) fuse.Status {
+	switch code {
+	case sshFxOk:
+		return fuse.OK
+	case sshFxEOF:
+		return fuse.Status(syscall.ENODATA)
+	case sshFxNoSuchFile:
+		return fuse.ENOENT
+	case sshFxPermissionDenied:
+		return fuse.EACCES
+	case sshFxOpUnsupported:
+		return fuse.ENOSYS
+	case sshFxBadMessage:
+		return fuse.EINVAL
+	}
+	return fuse.EIO
+}
+
+// check returns the status of a response that should have type
+// want, or be a STATUS.
+func (resp sftpResponse) check(want byte) fuse.Status {
+	if resp.typ == sshFxpStatus {
+		code := sftpStatus(resp.r.uint32())
+		if code.Ok() && want != sshFxpStatus {
+			return fuse.EIO
+		}
+		return code
+	}
+	if resp.typ != want || resp.r.err {
+		return fuse.EIO
+	}
+	return fuse.OK
+}
@@ -0,0 +1,832 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+// SFTPOptions configures NewSFTPFileSystem.
+type SFTPOptions struct {
+	// Dial opens a connection to an SFTP server. Use SSHDialer to
+	// reach a server through the ssh command, as sshfs does.
+	Dial func() (io.ReadWriteCloser, error)
+
+	// Root is the remote directory to serve. If empty, the
+	// directory the server starts in (usually the home
+	// directory) is used.
+	Root string
+
+	// Connections is the number of connections to spread
+	// requests over. If zero, one connection is used.
+	Connections int
+
+	// Timeout bounds how long a request may wait for its
+	// response. When it expires, the request fails with
+	// ETIMEDOUT, and the connection is considered hung and
+	// replaced. If zero, requests wait until the connection
+	// breaks or the kernel interrupts them.
+	Timeout time.Duration
+}
+
+type sftpFileSystem struct {
+	FileSystem
+
+	opts SFTPOptions
+	root string
+
+	mu    sync.Mutex
+	conns []*sftpConn
+	next  int
+}
+
+// NewSFTPFileSystem returns a FileSystem that serves a directory of
+// a remote host over SFTP (version 3, as spoken by OpenSSH), like
+// sshfs does. It connects once to check that the server is
+// reachable.
+//
+// Requests the kernel interrupts fail with EINTR right away; SFTP
+// cannot cancel requests, so the server still carries them out.
+// Broken connections are replaced on the next request. Operations
+// that can safely be repeated are retried once on a new connection,
+// and open files are opened again transparently; if that fails
+// because the file is gone, the file handle returns ESTALE.
+func NewSFTPFileSystem(opts SFTPOptions) (FileSystem, error) {
+	if opts.Dial == nil {
+		return nil, fmt.Errorf("sftp: Dial must be set")
+	}
+	if opts.Connections <= 0 {
+		opts.Connections = 1
+	}
+	fs := &sftpFileSystem{
+		FileSystem: NewDefaultFileSystem(),
+		opts:       opts,
+		root:       opts.Root,
+		conns:      make([]*sftpConn, opts.Connections),
+	}
+	if fs.root == "" {
+		fs.root = "."
+	}
+	if _, code := fs.conn(); !code.Ok() {
+		return nil, fmt.Errorf("sftp: connect: %v", code)
+	}
+	return fs, nil
+}
+
+// cmdConn is the standard input and output of a command.
+type cmdConn struct {
+	cmd *exec.Cmd
+	io.WriteCloser
+	io.Reader
+}
+
+func (c *cmdConn) Close() error {
+	c.WriteCloser.Close()
+	c.cmd.Process.Kill()
+	return c.cmd.Wait()
+}
+
+// SSHDialer returns a Dial function for SFTPOptions that starts the
+// sftp subsystem on host with the ssh command. The extra arguments
+// are passed to ssh before the host name. Authentication must not
+// need a terminal, for example by using ssh-agent. Host names that
+// start with "-" are rejected, so they cannot pass ssh options.
+func SSHDialer(host string, sshArgs ...string) func() (io.ReadWriteCloser, error) {
+	return func() (io.ReadWriteCloser, error) {
+		if strings.HasPrefix(host, "-") {
+			return nil, fmt.Errorf("sftp: invalid host %q", host)
+		}
+		args := append([]string{"-x", "-a", "-oClearAllForwardings=yes"}, sshArgs...)
+		args = append(args, "-s", "--", host, "sftp")
+		cmd := exec.Command("ssh", args...)
+		cmd.Stderr = os.Stderr
+		in, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &cmdConn{cmd, in, out}, nil
+	}
+}
+
+// conn returns the next connection of the pool, dialing a new one
+// if it is broken. Dialing happens without holding mu, so a slow
+// reconnect does not hold up requests on other connections.
+func (fs *sftpFileSystem) conn() (*sftpConn, fuse.Status) {
+	fs.mu.Lock()
+	i := fs.next
+	fs.next = (fs.next + 1) % len(fs.conns)
+	c := fs.conns[i]
+	fs.mu.Unlock()
+	if c != nil && !c.isDead() {
+		return c, fuse.OK
+	}
+
+	rwc, err := fs.opts.Dial()
+	if err != nil {
+		return nil, fuse.EIO
+	}
+	c, err = newSFTPConn(rwc)
+	if err != nil {
+		return nil, fuse.EIO
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if old := fs.conns[i]; old != nil && !old.isDead() {
+		// Another request replaced the connection meanwhile.
+		c.close()
+		return old, fuse.OK
+	}
+	fs.conns[i] = c
+	return c, fuse.OK
+}
+
+// request sends a request on c.
+func (fs *sftpFileSystem) request(c *sftpConn, context *fuse.Context, typ byte, payload sftpBuffer) (sftpResponse, fuse.Status) {
+	resp, err := c.request(cancelOf(context), fs.opts.Timeout, typ, payload)
+	switch err {
+	case nil:
+		return resp, fuse.OK
+	case errSFTPConnection:
+		return resp, fuse.EIO
+	}
+	return resp, fuse.ToStatus(err)
+}
+
+// retry returns whether a request that failed with code on c should
+// be tried again. Each connection of the pool may turn out to be
+// broken once, after which a working one has been dialed.
+func (fs *sftpFileSystem) retry(c *sftpConn, code fuse.Status, try int) bool {
+	return code == fuse.EIO && c.isDead() && try < len(fs.conns)
+}
+
+// run calls f with a connection. If the connection breaks during an
+// idempotent operation, f is tried again on a new connection.
+func (fs *sftpFileSystem) run(idempotent bool, f func(c *sftpConn) fuse.Status) fuse.Status {
+	for try := 0; ; try++ {
+		c, code := fs.conn()
+		if !code.Ok() {
+			return code
+		}
+		code = f(c)
+		if idempotent && fs.retry(c, code, try) {
+			continue
+		}
+		return code
+	}
+}
+
+// simple sends a request whose response is a STATUS.
+func (fs *sftpFileSystem) simple(context *fuse.Context, idempotent bool, typ byte, payload sftpBuffer) fuse.Status {
+	return fs.run(idempotent, func(c *sftpConn) fuse.Status {
+		resp, code := fs.request(c, context, typ, payload)
+		if !code.Ok() {
+			return code
+		}
+		return resp.check(sshFxpStatus)
+	})
+}
+
+func (fs *sftpFileSystem) remote(name string) string {
+	return path.Join(fs.root, name)
+}
+
+func (fs *sftpFileSystem) String() string {
+	return fmt.Sprintf("sftpFileSystem(%s)", fs.root)
+}
+
+func (fs *sftpFileSystem) OnUnmount() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, c := range fs.conns {
+		if c != nil {
+			c.close()
+		}
+	}
+}
+
+func (fs *sftpFileSystem) stat(name string, context *fuse.Context) (*sftpAttrs, fuse.Status) {
+	var attrs *sftpAttrs
+	var b sftpBuffer
+	b.string(fs.remote(name))
+	code := fs.run(true, func(c *sftpConn) fuse.Status {
+		resp, code := fs.request(c, context, sshFxpLstat, b)
+		if !code.Ok() {
+			return code
+		}
+		if code := resp.check(sshFxpAttrs); !code.Ok() {
+			return code
+		}
+		attrs = resp.r.attrs()
+		return fuse.OK
+	})
+	return attrs, code
+}
+
+func (fs *sftpFileSystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	attrs, code := fs.stat(name, context)
+	if !code.Ok() {
+		return nil, code
+	}
+	return attrs.fuseAttr(), fuse.OK
+}
+
+func (fs *sftpFileSystem) setstat(name string, attrs *sftpAttrs, context *fuse.Context) fuse.Status {
+	var b sftpBuffer
+	b.string(fs.remote(name))
+	b.attrs(attrs)
+	return fs.simple(context, true, sshFxpSetstat, b)
+}
+
+func (fs *sftpFileSystem) Chmod(name string, mode uint32, context *fuse.Context) fuse.Status {
+	return fs.setstat(name, &sftpAttrs{flags: sshFileXferAttrPermissions, perm: mode & 07777}, context)
+}
+
+func (fs *sftpFileSystem) Chown(name string, uid uint32, gid uint32, context *fuse.Context) fuse.Status {
+	attrs, code := chownAttrs(uid, gid, func() (*sftpAttrs, fuse.Status) { return fs.stat(name, context) })
+	if !code.Ok() {
+		return code
+	}
+	return fs.setstat(name, attrs, context)
+}
+
+// chownAttrs returns the attributes to set for a chown. SFTP sets
+// owner and group together, so if one of them is not given (-1), it
+// is taken from the current attributes.
+func chownAttrs(uid, gid uint32, current func() (*sftpAttrs, fuse.Status)) (*sftpAttrs, fuse.Status) {
+	if uid == ^uint32(0) || gid == ^uint32(0) {
+		cur, code := current()
+		if !code.Ok() {
+			return nil, code
+		}
+		if uid == ^uint32(0) {
+			uid = cur.uid
+		}
+		if gid == ^uint32(0) {
+			gid = cur.gid
+		}
+	}
+	return &sftpAttrs{flags: sshFileXferAttrUIDGID, uid: uid, gid: gid}, fuse.OK
+}
+
+// utimensAttrs returns the attributes to set times. SFTP sets both
+// times together, so a missing one is taken from the current
+// attributes.
+func utimensAttrs(atime *time.Time, mtime *time.Time, current func() (*sftpAttrs, fuse.Status)) (*sftpAttrs, fuse.Status) {
+	a := &sftpAttrs{flags: sshFileXferAttrACModTime}
+	if atime == nil || mtime == nil {
+		cur, code := current()
+		if !code.Ok() {
+			return nil, code
+		}
+		a.atime, a.mtime = cur.atime, cur.mtime
+	}
+	if atime != nil {
+		a.atime = uint32(atime.Unix())
+	}
+	if mtime != nil {
+		a.mtime = uint32(mtime.Unix())
+	}
+	return a, fuse.OK
+}
+
+func (fs *sftpFileSystem) Utimens(name string, atime *time.Time, mtime *time.Time, context *fuse.Context) fuse.Status {
+	attrs, code := utimensAttrs(atime, mtime, func() (*sftpAttrs, fuse.Status) { return fs.stat(name, context) })
+	if !code.Ok() {
+		return code
+	}
+	return fs.setstat(name, attrs, context)
+}
+
+func (fs *sftpFileSystem) Truncate(name string, size uint64, context *fuse.Context) fuse.Status {
+	return fs.setstat(name, &sftpAttrs{flags: sshFileXferAttrSize, size: size}, context)
+}
+
+func (fs *sftpFileSystem) Mkdir(name string, mode uint32, context *fuse.Context) fuse.Status {
+	var b sftpBuffer
+	b.string(fs.remote(name))
+	b.attrs(&sftpAttrs{flags: sshFileXferAttrPermissions, perm: mode & 07777})
+	return fs.simple(context, false, sshFxpMkdir, b)
+}
+
+func (fs *sftpFileSystem) Mknod(name string, mode uint32, dev uint32, context *fuse.Context) fuse.Status {
+	if mode&syscall.S_IFMT != syscall.S_IFREG {
+		return fuse.ENOSYS
+	}
+	f, code := fs.Create(name, uint32(os.O_WRONLY|os.O_EXCL), mode, context)
+	if !code.Ok() {
+		return code
+	}
+	f.Release()
+	return fuse.OK
+}
+
+func (fs *sftpFileSystem) Rmdir(name string, context *fuse.Context) fuse.Status {
+	var b sftpBuffer
+	b.string(fs.remote(name))
+	return fs.simple(context, false, sshFxpRmdir, b)
+}
+
+func (fs *sftpFileSystem) Unlink(name string, context *fuse.Context) fuse.Status {
+	var b sftpBuffer
+	b.string(fs.remote(name))
+	return fs.simple(context, false, sshFxpRemove, b)
+}
+
+// extended sends a request for an OpenSSH extension. It returns
+// ENOSYS if the server does not support it.
+func (fs *sftpFileSystem) extended(c *sftpConn, context *fuse.Context, name string, args sftpBuffer) (sftpResponse, fuse.Status) {
+	if _, ok := c.extensions[name]; !ok {
+		return sftpResponse{}, fuse.ENOSYS
+	}
+	var b sftpBuffer
+	b.string(name)
+	b = append(b, args...)
+	return fs.request(c, context, sshFxpExtended, b)
+}
+
+func (fs *sftpFileSystem) Rename(oldName string, newName string, context *fuse.Context) fuse.Status {
+	var b sftpBuffer
+	b.string(fs.remote(oldName))
+	b.string(fs.remote(newName))
+	return fs.run(false, func(c *sftpConn) fuse.Status {
+		// Plain SFTP rename fails if the target exists.
+		resp, code := fs.extended(c, context, "posix-rename@openssh.com", b)
+		if code == fuse.ENOSYS {
+			resp, code = fs.request(c, context, sshFxpRename, b)
+		}
+		if !code.Ok() {
+			return code
+		}
+		return resp.check(sshFxpStatus)
+	})
+}
+
+func (fs *sftpFileSystem) Link(oldName string, newName string, context *fuse.Context) fuse.Status {
+	var b sftpBuffer
+	b.string(fs.remote(oldName))
+	b.string(fs.remote(newName))
+	return fs.run(false, func(c *sftpConn) fuse.Status {
+		resp, code := fs.extended(c, context, "hardlink@openssh.com", b)
+		if !code.Ok() {
+			return code
+		}
+		return resp.check(sshFxpStatus)
+	})
+}
+
+func (fs *sftpFileSystem) Symlink(value string, linkName string, context *fuse.Context) fuse.Status {
+	// OpenSSH swapped the arguments with respect to the draft,
+	// and other servers followed it.
+	var b sftpBuffer
+	b.string(value)
+	b.string(fs.remote(linkName))
+	return fs.simple(context, false, sshFxpSymlink, b)
+}
+
+func (fs *sftpFileSystem) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
+	var target string
+	var b sftpBuffer
+	b.string(fs.remote(name))
+	code := fs.run(true, func(c *sftpConn) fuse.Status {
+		resp, code := fs.request(c, context, sshFxpReadlink, b)
+		if !code.Ok() {
+			return code
+		}
+		if code := resp.check(sshFxpName); !code.Ok() {
+			return code
+		}
+		if resp.r.uint32() != 1 {
+			return fuse.EIO
+		}
+		target = resp.r.string()
+		return fuse.OK
+	})
+	return target, code
+}
+
+func (fs *sftpFileSystem) OpenDir(name string, context *fuse.Context) ([]fuse.DirEntry, fuse.Status) {
+	var stream []fuse.DirEntry
+	var b sftpBuffer
+	b.string(fs.remote(name))
+	code := fs.run(true, func(c *sftpConn) fuse.Status {
+		stream = nil
+		resp, code := fs.request(c, context, sshFxpOpendir, b)
+		if !code.Ok() {
+			return code
+		}
+		if code := resp.check(sshFxpHandle); !code.Ok() {
+			return code
+		}
+		var h sftpBuffer
+		h.string(resp.r.string())
+		defer fs.request(c, nil, sshFxpClose, h)
+
+		for {
+			resp, code := fs.request(c, context, sshFxpReaddir, h)
+			if !code.Ok() {
+				return code
+			}
+			if code := resp.check(sshFxpName); code == fuse.Status(syscall.ENODATA) {
+				return fuse.OK
+			} else if !code.Ok() {
+				return code
+			}
+			for n := resp.r.uint32(); n > 0 && !resp.r.err; n-- {
+				e := fuse.DirEntry{Name: resp.r.string()}
+				resp.r.string()
+				e.Mode = resp.r.attrs().perm
+				if e.Name != "." && e.Name != ".." {
+					stream = append(stream, e)
+				}
+			}
+			if resp.r.err {
+				return fuse.EIO
+			}
+		}
+	})
+	return stream, code
+}
+
+func (fs *sftpFileSystem) StatFs(name string) *fuse.StatfsOut {
+	var out *fuse.StatfsOut
+	var b sftpBuffer
+	b.string(fs.remote(name))
+	fs.run(true, func(c *sftpConn) fuse.Status {
+		resp, code := fs.extended(c, nil, "statvfs@openssh.com", b)
+		if !code.Ok() {
+			return code
+		}
+		if code := resp.check(sshFxpExtendedReply); !code.Ok() {
+			return code
+		}
+		r := resp.r
+		bsize, frsize := r.uint64(), r.uint64()
+		blocks, bfree, bavail := r.uint64(), r.uint64(), r.uint64()
+		files, ffree := r.uint64(), r.uint64()
+		r.uint64() // favail
+		r.uint64() // fsid
+		r.uint64() // flag
+		namemax := r.uint64()
+		if r.err {
+			return fuse.EIO
+		}
+		out = &fuse.StatfsOut{
+			Blocks:  blocks,
+			Bfree:   bfree,
+			Bavail:  bavail,
+			Files:   files,
+			Ffree:   ffree,
+			Bsize:   uint32(bsize),
+			Frsize:  uint32(frsize),
+			NameLen: uint32(namemax),
+		}
+		return fuse.OK
+	})
+	return out
+}
+
+// sftpOpenFlags converts open(2) flags to SFTP flags.
+func sftpOpenFlags(flags uint32) uint32 {
+	var p uint32
+	switch flags & syscall.O_ACCMODE {
+	case syscall.O_RDONLY:
+		p = sshFxfRead
+	case syscall.O_WRONLY:
+		p = sshFxfWrite
+	default:
+		p = sshFxfRead | sshFxfWrite
+	}
+	if flags&syscall.O_APPEND != 0 {
+		p |= sshFxfAppend
+	}
+	if flags&syscall.O_CREAT != 0 {
+		p |= sshFxfCreat
+	}
+	if flags&syscall.O_TRUNC != 0 {
+		p |= sshFxfTrunc
+	}
+	if flags&syscall.O_EXCL != 0 {
+		p |= sshFxfExcl
+	}
+	return p
+}
+
+func (fs *sftpFileSystem) open(name string, pflags uint32, attrs *sftpAttrs, context *fuse.Context) (nodefs.File, fuse.Status) {
+	f := &sftpFile{
+		File: nodefs.NewDefaultFile(),
+		fs:   fs,
+		name: fs.remote(name),
+		// Reopening must not create or truncate again.
+		reopenFlags: pflags &^ (sshFxfCreat | sshFxfTrunc | sshFxfExcl),
+	}
+	code := fs.run(pflags&(sshFxfCreat|sshFxfTrunc|sshFxfExcl) == 0, func(c *sftpConn) fuse.Status {
+		return f.openOn(c, pflags, attrs, context)
+	})
+	if !code.Ok() {
+		return nil, code
+	}
+	return f, fuse.OK
+}
+
+func (fs *sftpFileSystem) Open(name string, flags uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	return fs.open(name, sftpOpenFlags(flags), nil, context)
+}
+
+func (fs *sftpFileSystem) Create(name string, flags uint32, mode uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	return fs.open(name, sftpOpenFlags(flags|syscall.O_CREAT), &sftpAttrs{flags: sshFileXferAttrPermissions, perm: mode & 07777}, context)
+}
+
+// sftpFile is a file opened over SFTP. Its handle belongs to one
+// connection; when that breaks, the file is opened again on another.
+type sftpFile struct {
+	nodefs.File
+
+	fs          *sftpFileSystem
+	name        string
+	reopenFlags uint32
+
+	mu     sync.Mutex
+	conn   *sftpConn
+	handle string
+	stale  bool
+}
+
+// openOn opens the file on c. The caller must hold mu, or own f
+// exclusively.
+func (f *sftpFile) openOn(c *sftpConn, pflags uint32, attrs *sftpAttrs, context *fuse.Context) fuse.Status {
+	var b sftpBuffer
+	b.string(f.name)
+	b.uint32(pflags)
+	b.attrs(attrs)
+	resp, code := f.fs.request(c, context, sshFxpOpen, b)
+	if !code.Ok() {
+		return code
+	}
+	if code := resp.check(sshFxpHandle); !code.Ok() {
+		return code
+	}
+	f.conn = c
+	f.handle = resp.r.string()
+	return fuse.OK
+}
+
+// handleRequest sends a request for the open file; the payload
+// follows the handle. If the connection of the handle broke, the
+// file is opened again on a new connection first.
+func (f *sftpFile) handleRequest(context *fuse.Context, typ byte, args sftpBuffer) (sftpResponse, fuse.Status) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for try := 0; ; try++ {
+		if f.stale {
+			return sftpResponse{}, fuse.Status(syscall.ESTALE)
+		}
+		c := f.conn
+		if c == nil || c.isDead() {
+			var code fuse.Status
+			if c, code = f.fs.conn(); !code.Ok() {
+				return sftpResponse{}, code
+			}
+			code = f.openOn(c, f.reopenFlags, nil, context)
+			if code == fuse.ENOENT {
+				f.stale = true
+				continue
+			}
+			if !code.Ok() {
+				if f.fs.retry(c, code, try) {
+					continue
+				}
+				return sftpResponse{}, code
+			}
+		}
+
+		var b sftpBuffer
+		b.string(f.handle)
+		b = append(b, args...)
+		resp, code := f.fs.request(c, context, typ, b)
+		if f.fs.retry(c, code, try) {
+			continue
+		}
+		return resp, code
+	}
+}
+
+func (f *sftpFile) String() string {
+	return fmt.Sprintf("sftpFile(%s)", f.name)
+}
+
+func (f *sftpFile) InnerFile() nodefs.File {
+	return nil
+}
+
+func (f *sftpFile) Read(dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	return f.ReadContext(nil, dest, off)
+}
+
+func (f *sftpFile) ReadContext(context *fuse.Context, dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	n := 0
+	for n < len(dest) {
+		size := len(dest) - n
+		if size > sftpMaxData {
+			size = sftpMaxData
+		}
+		var args sftpBuffer
+		args.uint64(uint64(off) + uint64(n))
+		args.uint32(uint32(size))
+		resp, code := f.handleRequest(context, sshFxpRead, args)
+		if !code.Ok() {
+			return nil, code
+		}
+		code = resp.check(sshFxpData)
+		if code == fuse.Status(syscall.ENODATA) {
+			break
+		}
+		if !code.Ok() {
+			return nil, code
+		}
+		data := resp.r.string()
+		if len(data) == 0 {
+			break
+		}
+		n += copy(dest[n:], data)
+	}
+	return fuse.ReadResultData(dest[:n]), fuse.OK
+}
+
+func (f *sftpFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	return f.WriteContext(nil, data, off)
+}
+
+func (f *sftpFile) WriteContext(context *fuse.Context, data []byte, off int64) (uint32, fuse.Status) {
+	n := 0
+	for n < len(data) {
+		chunk := data[n:]
+		if len(chunk) > sftpMaxData {
+			chunk = chunk[:sftpMaxData]
+		}
+		var args sftpBuffer
+		args.uint64(uint64(off) + uint64(n))
+		args.string(string(chunk))
+		resp, code := f.handleRequest(context, sshFxpWrite, args)
+		if !code.Ok() {
+			return uint32(n), code
+		}
+		if code := resp.check(sshFxpStatus); !code.Ok() {
+			return uint32(n), code
+		}
+		n += len(chunk)
+	}
+	return uint32(n), fuse.OK
+}
+
+func (f *sftpFile) Release() {
+	f.mu.Lock()
+	c, handle := f.conn, f.handle
+	f.conn = nil
+	f.mu.Unlock()
+	if c == nil || c.isDead() {
+		return
+	}
+	var b sftpBuffer
+	b.string(handle)
+	go c.request(nil, f.fs.opts.Timeout, sshFxpClose, b)
+}
+
+// ReleaseContext is like Release: the handle is closed in the
+// background, so interrupting the release does not leak it.
+func (f *sftpFile) ReleaseContext(context *fuse.Context) {
+	f.Release()
+}
+
+func (f *sftpFile) Flush() fuse.Status {
+	return fuse.OK
+}
+
+func (f *sftpFile) FlushContext(context *fuse.Context) fuse.Status {
+	return fuse.OK
+}
+
+func (f *sftpFile) Fsync(flags int) fuse.Status {
+	return f.FsyncContext(nil, flags)
+}
+
+func (f *sftpFile) FsyncContext(context *fuse.Context, flags int) fuse.Status {
+	f.mu.Lock()
+	c, handle := f.conn, f.handle
+	f.mu.Unlock()
+	if c == nil || c.isDead() {
+		return fuse.OK
+	}
+	var args sftpBuffer
+	args.string(handle)
+	resp, code := f.fs.extended(c, context, "fsync@openssh.com", args)
+	if code == fuse.ENOSYS {
+		return fuse.OK
+	}
+	if !code.Ok() {
+		return code
+	}
+	return resp.check(sshFxpStatus)
+}
+
+func (f *sftpFile) fstat(context *fuse.Context) (*sftpAttrs, fuse.Status) {
+	resp, code := f.handleRequest(context, sshFxpFstat, nil)
+	if !code.Ok() {
+		return nil, code
+	}
+	if code := resp.check(sshFxpAttrs); !code.Ok() {
+		return nil, code
+	}
+	return resp.r.attrs(), fuse.OK
+}
+
+func (f *sftpFile) fsetstat(context *fuse.Context, attrs *sftpAttrs) fuse.Status {
+	var args sftpBuffer
+	args.attrs(attrs)
+	resp, code := f.handleRequest(context, sshFxpFsetstat, args)
+	if !code.Ok() {
+		return code
+	}
+	return resp.check(sshFxpStatus)
+}
+
+func (f *sftpFile) GetAttr(out *fuse.Attr) fuse.Status {
+	return f.GetAttrContext(nil, out)
+}
+
+func (f *sftpFile) GetAttrContext(context *fuse.Context, out *fuse.Attr) fuse.Status {
+	attrs, code := f.fstat(context)
+	if !code.Ok() {
+		return code
+	}
+	*out = *attrs.fuseAttr()
+	return fuse.OK
+}
+
+func (f *sftpFile) Truncate(size uint64) fuse.Status {
+	return f.TruncateContext(nil, size)
+}
+
+func (f *sftpFile) TruncateContext(context *fuse.Context, size uint64) fuse.Status {
+	return f.fsetstat(context, &sftpAttrs{flags: sshFileXferAttrSize, size: size})
+}
+
+func (f *sftpFile) Chmod(perms uint32) fuse.Status {
+	return f.ChmodContext(nil, perms)
+}
+
+func (f *sftpFile) ChmodContext(context *fuse.Context, perms uint32) fuse.Status {
+	return f.fsetstat(context, &sftpAttrs{flags: sshFileXferAttrPermissions, perm: perms & 07777})
+}
+
+func (f *sftpFile) Chown(uid uint32, gid uint32) fuse.Status {
+	return f.ChownContext(nil, uid, gid)
+}
+
+func (f *sftpFile) ChownContext(context *fuse.Context, uid uint32, gid uint32) fuse.Status {
+	attrs, code := chownAttrs(uid, gid, func() (*sftpAttrs, fuse.Status) {
+		return f.fstat(context)
+	})
+	if !code.Ok() {
+		return code
+	}
+	return f.fsetstat(context, attrs)
+}
+
+func (f *sftpFile) Utimens(atime *time.Time, mtime *time.Time) fuse.Status {
+	return f.UtimensContext(nil, atime, mtime)
+}
+
+func (f *sftpFile) UtimensContext(context *fuse.Context, atime *time.Time, mtime *time.Time) fuse.Status {
+	attrs, code := utimensAttrs(atime, mtime, func() (*sftpAttrs, fuse.Status) {
+		return f.fstat(context)
+	})
+	if !code.Ok() {
+		return code
+	}
+	return f.fsetstat(context, attrs)
+}
+
+func (f *sftpFile) AllocateContext(context *fuse.Context, off uint64, size uint64, mode uint32) fuse.Status {
+	return f.Allocate(off, size, mode)
+}
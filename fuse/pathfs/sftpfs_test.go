@@ -0,0 +1,437 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+// testSFTPServer is a minimal SFTP server on the local file system,
+// speaking the subset of the protocol the client uses.
+type testSFTPServer struct {
+	mu    sync.Mutex
+	conns []net.Conn
+	// If set, requests after INIT are not answered.
+	hang bool
+}
+
+func (s *testSFTPServer) dial() (io.ReadWriteCloser, error) {
+	client, server := net.Pipe()
+	s.mu.Lock()
+	s.conns = append(s.conns, server)
+	s.mu.Unlock()
+	go s.serve(server)
+	return client, nil
+}
+
+// disconnect breaks all connections.
+func (s *testSFTPServer) disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+func testSFTPAttrs(fi os.FileInfo) *sftpAttrs {
+	a := fuse.ToAttr(fi)
+	return &sftpAttrs{
+		flags: sshFileXferAttrSize | sshFileXferAttrUIDGID | sshFileXferAttrPermissions | sshFileXferAttrACModTime,
+		size:  a.Size,
+		uid:   a.Uid,
+		gid:   a.Gid,
+		perm:  a.Mode,
+		atime: uint32(a.Atime),
+		mtime: uint32(a.Mtime),
+	}
+}
+
+func testSFTPStatus(err error) uint32 {
+	switch {
+	case err == nil:
+		return sshFxOk
+	case err == io.EOF:
+		return sshFxEOF
+	case os.IsNotExist(err):
+		return sshFxNoSuchFile
+	case os.IsPermission(err):
+		return sshFxPermissionDenied
+	}
+	return sshFxFailure
+}
+
+func (s *testSFTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	if typ, _, err := readSFTPPacket(conn); err != nil || typ != sshFxpInit {
+		return
+	}
+	var v sftpBuffer
+	v.uint32(3)
+	v.string("posix-rename@openssh.com")
+	v.string("1")
+	writeSFTPPacket(conn, sshFxpVersion, v)
+
+	files := map[string]*os.File{}
+	nextHandle := 0
+	for {
+		typ, payload, err := readSFTPPacket(conn)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		hang := s.hang
+		s.mu.Unlock()
+		if hang {
+			continue
+		}
+		r := &sftpReader{b: payload}
+		id := r.uint32()
+		var out sftpBuffer
+		out.uint32(id)
+		status := func(err error) {
+			out.uint32(testSFTPStatus(err))
+			out.string("")
+			out.string("")
+			writeSFTPPacket(conn, sshFxpStatus, out)
+		}
+		sendAttrs := func(fi os.FileInfo, err error) {
+			if err != nil {
+				status(err)
+				return
+			}
+			out.attrs(testSFTPAttrs(fi))
+			writeSFTPPacket(conn, sshFxpAttrs, out)
+		}
+		sendHandle := func(f *os.File) {
+			nextHandle++
+			h := string(rune('a' + nextHandle%26))
+			for files[h] != nil {
+				h += "x"
+			}
+			files[h] = f
+			out.string(h)
+			writeSFTPPacket(conn, sshFxpHandle, out)
+		}
+
+		switch typ {
+		case sshFxpLstat, sshFxpStat:
+			sendAttrs(os.Lstat(r.string()))
+		case sshFxpFstat:
+			f := files[r.string()]
+			sendAttrs(f.Stat())
+		case sshFxpSetstat:
+			name := r.string()
+			a := r.attrs()
+			var err error
+			if a.flags&sshFileXferAttrSize != 0 {
+				err = os.Truncate(name, int64(a.size))
+			}
+			if a.flags&sshFileXferAttrPermissions != 0 && err == nil {
+				err = os.Chmod(name, os.FileMode(a.perm&0777))
+			}
+			if a.flags&sshFileXferAttrACModTime != 0 && err == nil {
+				err = os.Chtimes(name, time.Unix(int64(a.atime), 0), time.Unix(int64(a.mtime), 0))
+			}
+			status(err)
+		case sshFxpFsetstat:
+			// The file may have been renamed, so use the
+			// descriptor.
+			f := files[r.string()]
+			a := r.attrs()
+			var err error
+			if a.flags&sshFileXferAttrSize != 0 {
+				err = f.Truncate(int64(a.size))
+			}
+			if a.flags&sshFileXferAttrPermissions != 0 && err == nil {
+				err = f.Chmod(os.FileMode(a.perm & 0777))
+			}
+			if a.flags&sshFileXferAttrACModTime != 0 && err == nil {
+				err = syscall.Futimes(int(f.Fd()), []syscall.Timeval{{Sec: int64(a.atime)}, {Sec: int64(a.mtime)}})
+			}
+			status(err)
+		case sshFxpOpen:
+			name, pflags := r.string(), r.uint32()
+			a := r.attrs()
+			flags := os.O_RDONLY
+			if pflags&sshFxfWrite != 0 {
+				flags = os.O_WRONLY
+				if pflags&sshFxfRead != 0 {
+					flags = os.O_RDWR
+				}
+			}
+			if pflags&sshFxfCreat != 0 {
+				flags |= os.O_CREATE
+			}
+			if pflags&sshFxfTrunc != 0 {
+				flags |= os.O_TRUNC
+			}
+			if pflags&sshFxfExcl != 0 {
+				flags |= os.O_EXCL
+			}
+			f, err := os.OpenFile(name, flags, os.FileMode(a.perm&0777))
+			if err != nil {
+				status(err)
+			} else {
+				sendHandle(f)
+			}
+		case sshFxpOpendir:
+			f, err := os.Open(r.string())
+			if err != nil {
+				status(err)
+			} else {
+				sendHandle(f)
+			}
+		case sshFxpReaddir:
+			fis, err := files[r.string()].Readdir(0)
+			if err == nil && len(fis) == 0 {
+				err = io.EOF
+			}
+			if err != nil {
+				status(err)
+				break
+			}
+			out.uint32(uint32(len(fis)))
+			for _, fi := range fis {
+				out.string(fi.Name())
+				out.string(fi.Name())
+				out.attrs(testSFTPAttrs(fi))
+			}
+			writeSFTPPacket(conn, sshFxpName, out)
+		case sshFxpClose:
+			h := r.string()
+			err := files[h].Close()
+			delete(files, h)
+			status(err)
+		case sshFxpRead:
+			f, off, n := files[r.string()], r.uint64(), r.uint32()
+			buf := make([]byte, n)
+			m, err := f.ReadAt(buf, int64(off))
+			if m == 0 {
+				status(err)
+				break
+			}
+			out.string(string(buf[:m]))
+			writeSFTPPacket(conn, sshFxpData, out)
+		case sshFxpWrite:
+			f, off, data := files[r.string()], r.uint64(), r.string()
+			_, err := f.WriteAt([]byte(data), int64(off))
+			status(err)
+		case sshFxpRemove:
+			status(syscall.Unlink(r.string()))
+		case sshFxpMkdir:
+			name := r.string()
+			status(os.Mkdir(name, os.FileMode(r.attrs().perm&0777)))
+		case sshFxpRmdir:
+			status(syscall.Rmdir(r.string()))
+		case sshFxpRename:
+			status(syscall.EEXIST)
+		case sshFxpExtended:
+			if r.string() != "posix-rename@openssh.com" {
+				status(syscall.ENOSYS)
+				break
+			}
+			status(os.Rename(r.string(), r.string()))
+		case sshFxpReadlink:
+			target, err := os.Readlink(r.string())
+			if err != nil {
+				status(err)
+				break
+			}
+			out.uint32(1)
+			out.string(target)
+			out.string(target)
+			out.attrs(nil)
+			writeSFTPPacket(conn, sshFxpName, out)
+		case sshFxpSymlink:
+			target := r.string()
+			status(os.Symlink(target, r.string()))
+		default:
+			out.uint32(sshFxOpUnsupported)
+			out.string("")
+			out.string("")
+			writeSFTPPacket(conn, sshFxpStatus, out)
+		}
+	}
+}
+
+func TestSFTPFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	remote := dir + "/remote"
+	mnt := dir + "/mnt"
+	os.Mkdir(remote, 0755)
+	os.Mkdir(mnt, 0755)
+
+	srv := &testSFTPServer{}
+	fs, err := NewSFTPFileSystem(SFTPOptions{
+		Dial:        srv.dial,
+		Root:        remote,
+		Connections: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewSFTPFileSystem: %v", err)
+	}
+	server, err := Mount(mnt, fs, &fuse.MountOptions{Debug: testutil.VerboseTest()}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go server.Serve()
+	if err := server.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer server.Unmount()
+
+	// More than one SFTP read or write.
+	content := make([]byte, 3*sftpMaxData+10)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	if err := os.Mkdir(mnt+"/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(mnt+"/dir/file", content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadFile(remote + "/dir/file"); err != nil || !reflect.DeepEqual(got, content) {
+		t.Fatalf("remote content: %v, mismatch %v", err, len(got))
+	}
+	if got, err := ioutil.ReadFile(mnt + "/dir/file"); err != nil || !reflect.DeepEqual(got, content) {
+		t.Fatalf("ReadFile: %v, mismatch %v", err, len(got))
+	}
+	if err := os.Rename(mnt+"/dir/file", mnt+"/file"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if err := os.Symlink("file", mnt+"/link"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if target, err := os.Readlink(mnt + "/link"); err != nil || target != "file" {
+		t.Errorf("Readlink: got %q, %v", target, err)
+	}
+	if err := os.Truncate(mnt+"/file", 5); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	if fi, err := os.Stat(mnt + "/file"); err != nil || fi.Size() != 5 {
+		t.Errorf("Stat after truncate: %v, %v", fi, err)
+	}
+	if err := os.Remove(mnt + "/dir"); err != nil {
+		t.Errorf("Rmdir: %v", err)
+	}
+	if got, want := dirNames(t, fs, ""), []string{"file", "link"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OpenDir: got %v, want %v", got, want)
+	}
+
+	// Open files survive reconnects. Use the FileSystem directly,
+	// so the kernel's page cache does not answer the reads.
+	f, code := fs.Open("file", uint32(os.O_RDONLY), nil)
+	if !code.Ok() {
+		t.Fatalf("Open: %v", code)
+	}
+	defer f.Release()
+	srv.disconnect()
+	buf := make([]byte, 5)
+	if res, code := f.Read(buf, 0); !code.Ok() {
+		t.Errorf("Read after reconnect: %v", code)
+	} else if got, _ := res.Bytes(buf); !reflect.DeepEqual(got, content[:5]) {
+		t.Errorf("Read after reconnect: got %v, want %v", got, content[:5])
+	}
+	if fi, err := os.Lstat(mnt + "/link"); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Lstat after reconnect: %v, %v", fi, err)
+	}
+
+	// If the file is gone after reconnecting, its handle is stale.
+	os.Remove(remote + "/file")
+	srv.disconnect()
+	if _, code := f.Read(buf, 0); code != fuse.Status(syscall.ESTALE) {
+		t.Errorf("Read of removed file: got %v, want ESTALE", code)
+	}
+}
+
+func TestSFTPFileSystemTimeout(t *testing.T) {
+	srv := &testSFTPServer{hang: true}
+	fs, err := NewSFTPFileSystem(SFTPOptions{
+		Dial:    srv.dial,
+		Timeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewSFTPFileSystem: %v", err)
+	}
+	if _, code := fs.GetAttr("file", nil); code != fuse.Status(syscall.ETIMEDOUT) {
+		t.Errorf("GetAttr: got %v, want ETIMEDOUT", code)
+	}
+
+	cancel := make(chan struct{})
+	close(cancel)
+	if _, code := fs.GetAttr("file", &fuse.Context{Cancel: cancel}); code != fuse.EINTR {
+		t.Errorf("GetAttr interrupted: got %v, want EINTR", code)
+	}
+}
+
+func TestSFTPFileInterrupt(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(dir+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &testSFTPServer{}
+	fs, err := NewSFTPFileSystem(SFTPOptions{
+		Dial: srv.dial,
+		Root: dir,
+	})
+	if err != nil {
+		t.Fatalf("NewSFTPFileSystem: %v", err)
+	}
+	f, code := fs.Open("file", uint32(os.O_RDWR), nil)
+	if !code.Ok() {
+		t.Fatalf("Open: %v", code)
+	}
+	defer f.Release()
+	cf, ok := f.(nodefs.ContextFile)
+	if !ok {
+		t.Fatalf("%v does not implement nodefs.ContextFile", f)
+	}
+
+	// Without a timeout, only the interrupt ends the calls.
+	srv.mu.Lock()
+	srv.hang = true
+	srv.mu.Unlock()
+	cancel := make(chan struct{})
+	close(cancel)
+	ctx := &fuse.Context{Cancel: cancel}
+	if _, code := cf.ReadContext(ctx, make([]byte, 5), 0); code != fuse.EINTR {
+		t.Errorf("ReadContext: got %v, want EINTR", code)
+	}
+	if _, code := cf.WriteContext(ctx, []byte("x"), 0); code != fuse.EINTR {
+		t.Errorf("WriteContext: got %v, want EINTR", code)
+	}
+	var a fuse.Attr
+	if code := cf.GetAttrContext(ctx, &a); code != fuse.EINTR {
+		t.Errorf("GetAttrContext: got %v, want EINTR", code)
+	}
+	if code := cf.TruncateContext(ctx, 0); code != fuse.EINTR {
+		t.Errorf("TruncateContext: got %v, want EINTR", code)
+	}
+}
+
+func TestSSHDialerRejectsOptions(t *testing.T) {
+	dial := SSHDialer("-oProxyCommand=touch /tmp/pwned")
+	if c, err := dial(); err == nil {
+		c.Close()
+		t.Fatal("dial succeeded for a host that is an ssh option")
+	}
+}
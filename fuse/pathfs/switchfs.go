@@ -0,0 +1,363 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+// SwitchedFileSystem is a FileSystem that serves the paths under
+// Prefix in a SwitchFileSystem.
+type SwitchedFileSystem struct {
+	Prefix     string
+	FileSystem FileSystem
+
+	// If StripPrefix is set, Prefix is removed from names before
+	// they are passed to FileSystem, so FileSystem sees Prefix as
+	// its root.
+	StripPrefix bool
+}
+
+type switchFileSystem struct {
+	// sorted by decreasing prefix length, so the first match is
+	// the longest.
+	fileSystems []*SwitchedFileSystem
+}
+
+// NewSwitchFileSystem returns a FileSystem that passes each operation
+// to the file system whose Prefix is the longest that matches the
+// path, counting whole path components. An empty Prefix matches all
+// paths. Directories leading up to the prefixes are shown even if no
+// file system provides them, and directory listings include the
+// first component of the prefixes below them. Unlike mounting the
+// file systems with PathNodeFs.Mount, this does not create
+// submounts, so renames and links across file systems fail with
+// EXDEV rather than being refused by the kernel.
+func NewSwitchFileSystem(fileSystems []SwitchedFileSystem) FileSystem {
+	fs := &switchFileSystem{}
+	for _, s := range fileSystems {
+		s := s
+		s.Prefix = strings.Trim(filepath.Clean("/"+s.Prefix), "/")
+		fs.fileSystems = append(fs.fileSystems, &s)
+	}
+	sort.SliceStable(fs.fileSystems, func(i, j int) bool {
+		return len(fs.fileSystems[i].Prefix) > len(fs.fileSystems[j].Prefix)
+	})
+	return fs
+}
+
+// findFileSystem returns the file system for name, and the name to
+// pass to it.
+func (fs *switchFileSystem) findFileSystem(name string) (string, *SwitchedFileSystem) {
+	for _, s := range fs.fileSystems {
+		if s.Prefix == "" || name == s.Prefix || strings.HasPrefix(name, s.Prefix+"/") {
+			if s.StripPrefix {
+				return strings.TrimPrefix(strings.TrimPrefix(name, s.Prefix), "/"), s
+			}
+			return name, s
+		}
+	}
+	return "", nil
+}
+
+// children returns the first path components below dir of the
+// prefixes under dir.
+func (fs *switchFileSystem) children(dir string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, s := range fs.fileSystems {
+		rest := s.Prefix
+		if dir != "" {
+			if !strings.HasPrefix(rest, dir+"/") {
+				continue
+			}
+			rest = rest[len(dir)+1:]
+		}
+		if rest == "" {
+			continue
+		}
+		child := strings.SplitN(rest, "/", 2)[0]
+		if !seen[child] {
+			seen[child] = true
+			names = append(names, child)
+		}
+	}
+	return names
+}
+
+// isJoinPoint returns whether name is a directory leading up to a
+// prefix.
+func (fs *switchFileSystem) isJoinPoint(name string) bool {
+	if name == "" {
+		return true
+	}
+	for _, s := range fs.fileSystems {
+		if strings.HasPrefix(s.Prefix, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (fs *switchFileSystem) String() string {
+	var names []string
+	for _, s := range fs.fileSystems {
+		names = append(names, fmt.Sprintf("%q:%v", s.Prefix, s.FileSystem))
+	}
+	return fmt.Sprintf("switchFileSystem(%s)", strings.Join(names, ","))
+}
+
+func (fs *switchFileSystem) SetDebug(debug bool) {
+	for _, s := range fs.fileSystems {
+		s.FileSystem.SetDebug(debug)
+	}
+}
+
+func (fs *switchFileSystem) OnMount(nodeFs *PathNodeFs) {
+	for _, s := range fs.fileSystems {
+		s.FileSystem.OnMount(nodeFs)
+	}
+}
+
+func (fs *switchFileSystem) OnUnmount() {
+	for _, s := range fs.fileSystems {
+		s.FileSystem.OnUnmount()
+	}
+}
+
+func (fs *switchFileSystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	code := fuse.ENOENT
+	if n, s := fs.findFileSystem(name); s != nil {
+		var a *fuse.Attr
+		if a, code = s.FileSystem.GetAttr(n, context); code.Ok() {
+			return a, code
+		}
+	}
+	if fs.isJoinPoint(name) {
+		return &fuse.Attr{Mode: fuse.S_IFDIR | 0755}, fuse.OK
+	}
+	return nil, code
+}
+
+func (fs *switchFileSystem) OpenDir(name string, context *fuse.Context) ([]fuse.DirEntry, fuse.Status) {
+	var stream []fuse.DirEntry
+	code := fuse.ENOENT
+	if n, s := fs.findFileSystem(name); s != nil {
+		stream, code = s.FileSystem.OpenDir(n, context)
+	}
+	if !fs.isJoinPoint(name) {
+		return stream, code
+	}
+
+	seen := map[string]bool{}
+	for _, e := range stream {
+		seen[e.Name] = true
+	}
+	for _, c := range fs.children(name) {
+		if !seen[c] {
+			stream = append(stream, fuse.DirEntry{Name: c, Mode: fuse.S_IFDIR})
+		}
+	}
+	return stream, fuse.OK
+}
+
+func (fs *switchFileSystem) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return "", fuse.ENOENT
+	}
+	return s.FileSystem.Readlink(n, context)
+}
+
+func (fs *switchFileSystem) Mknod(name string, mode uint32, dev uint32, context *fuse.Context) fuse.Status {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	return s.FileSystem.Mknod(n, mode, dev, context)
+}
+
+func (fs *switchFileSystem) Mkdir(name string, mode uint32, context *fuse.Context) fuse.Status {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	return s.FileSystem.Mkdir(n, mode, context)
+}
+
+func (fs *switchFileSystem) Unlink(name string, context *fuse.Context) (code fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	return s.FileSystem.Unlink(n, context)
+}
+
+func (fs *switchFileSystem) Rmdir(name string, context *fuse.Context) (code fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	return s.FileSystem.Rmdir(n, context)
+}
+
+func (fs *switchFileSystem) Symlink(value string, linkName string, context *fuse.Context) (code fuse.Status) {
+	n, s := fs.findFileSystem(linkName)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	return s.FileSystem.Symlink(value, n, context)
+}
+
+func (fs *switchFileSystem) Rename(oldName string, newName string, context *fuse.Context) (code fuse.Status) {
+	n1, s1 := fs.findFileSystem(oldName)
+	n2, s2 := fs.findFileSystem(newName)
+	if s1 == nil || s2 == nil {
+		return fuse.ENOENT
+	}
+	if s1 != s2 {
+		return fuse.EXDEV
+	}
+	return s1.FileSystem.Rename(n1, n2, context)
+}
+
+func (fs *switchFileSystem) Link(oldName string, newName string, context *fuse.Context) (code fuse.Status) {
+	n1, s1 := fs.findFileSystem(oldName)
+	n2, s2 := fs.findFileSystem(newName)
+	if s1 == nil || s2 == nil {
+		return fuse.ENOENT
+	}
+	if s1 != s2 {
+		return fuse.EXDEV
+	}
+	return s1.FileSystem.Link(n1, n2, context)
+}
+
+func (fs *switchFileSystem) Chmod(name string, mode uint32, context *fuse.Context) (code fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	return s.FileSystem.Chmod(n, mode, context)
+}
+
+func (fs *switchFileSystem) Chown(name string, uid uint32, gid uint32, context *fuse.Context) (code fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	return s.FileSystem.Chown(n, uid, gid, context)
+}
+
+func (fs *switchFileSystem) Truncate(name string, offset uint64, context *fuse.Context) (code fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	return s.FileSystem.Truncate(n, offset, context)
+}
+
+func (fs *switchFileSystem) Utimens(name string, Atime *time.Time, Mtime *time.Time, context *fuse.Context) (code fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	return s.FileSystem.Utimens(n, Atime, Mtime, context)
+}
+
+func (fs *switchFileSystem) SetAttr(name string, input *fuse.SetAttrIn, context *fuse.Context) fuse.Status {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	if sa, ok := s.FileSystem.(SetAttrer); ok {
+		return sa.SetAttr(n, input, context)
+	}
+	return fuse.ENOSYS
+}
+
+func (fs *switchFileSystem) Access(name string, mode uint32, context *fuse.Context) (code fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		if fs.isJoinPoint(name) {
+			return fuse.OK
+		}
+		return fuse.ENOENT
+	}
+	return s.FileSystem.Access(n, mode, context)
+}
+
+func (fs *switchFileSystem) Open(name string, flags uint32, context *fuse.Context) (file nodefs.File, code fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return nil, fuse.ENOENT
+	}
+	return s.FileSystem.Open(n, flags, context)
+}
+
+func (fs *switchFileSystem) Create(name string, flags uint32, mode uint32, context *fuse.Context) (file nodefs.File, code fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return nil, fuse.ENOENT
+	}
+	return s.FileSystem.Create(n, flags, mode, context)
+}
+
+func (fs *switchFileSystem) ReleaseDir(name string, info *nodefs.ReleaseInfo) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return
+	}
+	if r, ok := s.FileSystem.(DirReleaser); ok {
+		r.ReleaseDir(n, info)
+	}
+}
+
+func (fs *switchFileSystem) GetXAttr(name string, attr string, context *fuse.Context) ([]byte, fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return nil, fuse.ENOATTR
+	}
+	return s.FileSystem.GetXAttr(n, attr, context)
+}
+
+func (fs *switchFileSystem) SetXAttr(name string, attr string, data []byte, flags int, context *fuse.Context) fuse.Status {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOENT
+	}
+	return s.FileSystem.SetXAttr(n, attr, data, flags, context)
+}
+
+func (fs *switchFileSystem) ListXAttr(name string, context *fuse.Context) ([]string, fuse.Status) {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return nil, fuse.OK
+	}
+	return s.FileSystem.ListXAttr(n, context)
+}
+
+func (fs *switchFileSystem) RemoveXAttr(name string, attr string, context *fuse.Context) fuse.Status {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return fuse.ENOATTR
+	}
+	return s.FileSystem.RemoveXAttr(n, attr, context)
+}
+
+func (fs *switchFileSystem) StatFs(name string) *fuse.StatfsOut {
+	n, s := fs.findFileSystem(name)
+	if s == nil {
+		return &fuse.StatfsOut{}
+	}
+	return s.FileSystem.StatFs(n)
+}
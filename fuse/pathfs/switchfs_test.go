@@ -0,0 +1,78 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func dirNames(t *testing.T, fs FileSystem, name string) []string {
+	stream, code := fs.OpenDir(name, nil)
+	if !code.Ok() {
+		t.Fatalf("OpenDir(%q): %v", name, code)
+	}
+	var names []string
+	for _, e := range stream {
+		names = append(names, e.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSwitchFileSystem(t *testing.T) {
+	root := testutil.TempDir()
+	defer os.RemoveAll(root)
+	sub := testutil.TempDir()
+	defer os.RemoveAll(sub)
+
+	if err := ioutil.WriteFile(root+"/rootfile", []byte("root"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(sub+"/subfile", []byte("sub"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewSwitchFileSystem([]SwitchedFileSystem{
+		{Prefix: "", FileSystem: NewLoopbackFileSystem(root)},
+		{Prefix: "a/b", FileSystem: NewLoopbackFileSystem(sub), StripPrefix: true},
+	})
+
+	if got, want := dirNames(t, fs, ""), []string{"a", "rootfile"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("root: got %v, want %v", got, want)
+	}
+	if got, want := dirNames(t, fs, "a"), []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("a: got %v, want %v", got, want)
+	}
+	if got, want := dirNames(t, fs, "a/b"), []string{"subfile"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("a/b: got %v, want %v", got, want)
+	}
+
+	if a, code := fs.GetAttr("a", nil); !code.Ok() || !a.IsDir() {
+		t.Errorf("GetAttr(a): got %v, %v, want directory", a, code)
+	}
+	if a, code := fs.GetAttr("a/b/subfile", nil); !code.Ok() || a.Size != 3 {
+		t.Errorf("GetAttr(a/b/subfile): got %v, %v", a, code)
+	}
+	if _, code := fs.GetAttr("a/subfile", nil); code != fuse.ENOENT {
+		t.Errorf("GetAttr(a/subfile): got %v, want ENOENT", code)
+	}
+
+	if code := fs.Mkdir("a/b/dir", 0755, nil); !code.Ok() {
+		t.Errorf("Mkdir: %v", code)
+	}
+	if _, err := os.Stat(sub + "/dir"); err != nil {
+		t.Errorf("Mkdir did not reach the sub file system: %v", err)
+	}
+	if code := fs.Rename("rootfile", "a/b/rootfile", nil); code != fuse.EXDEV {
+		t.Errorf("Rename across file systems: got %v, want EXDEV", code)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// symlinkCachingFileSystem remembers the targets of symlinks.
+type symlinkCachingFileSystem struct {
+	FileSystem
+
+	mu    sync.Mutex
+	links map[string]string
+}
+
+// NewSymlinkCachingFileSystem returns a wrapper that remembers the
+// result of successful Readlink calls. The target of a symlink can
+// not be changed without removing the link, so entries do not
+// expire: they are dropped on Unlink, Symlink, Rename and Rmdir
+// through the wrapper. This helps workloads that resolve many paths
+// through the same symlinks, on backends where Readlink is
+// expensive.
+//
+// Changes made to the backing storage by other means are not
+// noticed.
+func NewSymlinkCachingFileSystem(fs FileSystem) FileSystem {
+	return &symlinkCachingFileSystem{
+		FileSystem: fs,
+		links:      make(map[string]string),
+	}
+}
+
+func (fs *symlinkCachingFileSystem) String() string {
+	return "SymlinkCachingFileSystem(" + fs.FileSystem.String() + ")"
+}
+
+func (fs *symlinkCachingFileSystem) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
+	fs.mu.Lock()
+	target, ok := fs.links[name]
+	fs.mu.Unlock()
+	if ok {
+		return target, fuse.OK
+	}
+
+	target, code := fs.FileSystem.Readlink(name, context)
+	if code.Ok() {
+		fs.mu.Lock()
+		fs.links[name] = target
+		fs.mu.Unlock()
+	}
+	return target, code
+}
+
+// forget drops the entries for name and everything below it.
+func (fs *symlinkCachingFileSystem) forget(name string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.links, name)
+	prefix := name + "/"
+	for k := range fs.links {
+		if strings.HasPrefix(k, prefix) {
+			delete(fs.links, k)
+		}
+	}
+}
+
+func (fs *symlinkCachingFileSystem) Unlink(name string, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Unlink(name, context)
+	fs.forget(name)
+	return code
+}
+
+func (fs *symlinkCachingFileSystem) Rmdir(name string, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Rmdir(name, context)
+	fs.forget(name)
+	return code
+}
+
+func (fs *symlinkCachingFileSystem) Symlink(value string, linkName string, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Symlink(value, linkName, context)
+	fs.forget(linkName)
+	return code
+}
+
+func (fs *symlinkCachingFileSystem) Rename(oldName string, newName string, context *fuse.Context) (code fuse.Status) {
+	code = fs.FileSystem.Rename(oldName, newName, context)
+	fs.forget(oldName)
+	fs.forget(newName)
+	return code
+}
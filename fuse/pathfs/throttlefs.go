@@ -0,0 +1,356 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+// Throttle limits the rate of a class of operations with token
+// buckets. A zero rate means no limit.
+type Throttle struct {
+	// Operations per second.
+	Ops float64
+
+	// Bytes per second. This only applies to reads and writes of
+	// file data.
+	Bytes float64
+
+	// Burst is the number of seconds' worth of operations and
+	// bytes that may be used at once after a quiet period. If
+	// zero, it is one second.
+	Burst float64
+}
+
+// ThrottleOptions configures NewThrottlingFileSystem.
+type ThrottleOptions struct {
+	// Metadata covers all operations on the FileSystem,
+	// including Open and Create.
+	Metadata Throttle
+
+	// Read covers File.Read.
+	Read Throttle
+
+	// Write covers File.Write.
+	Write Throttle
+}
+
+// tokenBucket holds up to burst tokens, which are refilled at rate
+// per second. A request may take more tokens than are available;
+// it then waits until the debt is paid off, and later requests wait
+// behind it.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	b := &tokenBucket{
+		rate:  rate,
+		burst: rate * burst,
+		now:   time.Now,
+	}
+	b.tokens = b.burst
+	b.last = b.now()
+	return b
+}
+
+// reserve takes n tokens, and returns how long the caller must wait
+// before using them.
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+type throttle struct {
+	ops   *tokenBucket
+	bytes *tokenBucket
+}
+
+func newThrottle(t Throttle) *throttle {
+	return &throttle{
+		ops:   newTokenBucket(t.Ops, t.Burst),
+		bytes: newTokenBucket(t.Bytes, t.Burst),
+	}
+}
+
+// wait blocks until an operation transferring n bytes may proceed. It
+// returns EINTR if cancel is closed first.
+func (t *throttle) wait(cancel <-chan struct{}, n int) fuse.Status {
+	d := t.ops.reserve(1)
+	if n > 0 {
+		if d2 := t.bytes.reserve(float64(n)); d2 > d {
+			d = d2
+		}
+	}
+	if d <= 0 {
+		return fuse.OK
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return fuse.OK
+	case <-cancel:
+		return fuse.EINTR
+	}
+}
+
+type throttlingFileSystem struct {
+	FileSystem
+	metadata *throttle
+	read     *throttle
+	write    *throttle
+}
+
+// NewThrottlingFileSystem limits the rate of operations on fs, and
+// of the data read and written through its files, so a mount backed
+// by a shared service stays within that service's rate limits.
+// Operations that exceed the rate wait; they fail with EINTR if the
+// kernel interrupts them meanwhile.
+func NewThrottlingFileSystem(fs FileSystem, opts ThrottleOptions) FileSystem {
+	return &throttlingFileSystem{
+		FileSystem: fs,
+		metadata:   newThrottle(opts.Metadata),
+		read:       newThrottle(opts.Read),
+		write:      newThrottle(opts.Write),
+	}
+}
+
+func cancelOf(context *fuse.Context) <-chan struct{} {
+	if context == nil {
+		return nil
+	}
+	return context.Cancel
+}
+
+func (fs *throttlingFileSystem) String() string {
+	return fmt.Sprintf("throttlingFileSystem(%v)", fs.FileSystem)
+}
+
+func (fs *throttlingFileSystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return nil, code
+	}
+	return fs.FileSystem.GetAttr(name, context)
+}
+
+func (fs *throttlingFileSystem) Chmod(name string, mode uint32, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Chmod(name, mode, context)
+}
+
+func (fs *throttlingFileSystem) Chown(name string, uid uint32, gid uint32, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Chown(name, uid, gid, context)
+}
+
+func (fs *throttlingFileSystem) Utimens(name string, atime *time.Time, mtime *time.Time, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Utimens(name, atime, mtime, context)
+}
+
+func (fs *throttlingFileSystem) Truncate(name string, size uint64, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Truncate(name, size, context)
+}
+
+func (fs *throttlingFileSystem) Access(name string, mode uint32, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Access(name, mode, context)
+}
+
+func (fs *throttlingFileSystem) Link(oldName string, newName string, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Link(oldName, newName, context)
+}
+
+func (fs *throttlingFileSystem) Mkdir(name string, mode uint32, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Mkdir(name, mode, context)
+}
+
+func (fs *throttlingFileSystem) Mknod(name string, mode uint32, dev uint32, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Mknod(name, mode, dev, context)
+}
+
+func (fs *throttlingFileSystem) Rename(oldName string, newName string, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Rename(oldName, newName, context)
+}
+
+func (fs *throttlingFileSystem) Rmdir(name string, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Rmdir(name, context)
+}
+
+func (fs *throttlingFileSystem) Unlink(name string, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Unlink(name, context)
+}
+
+func (fs *throttlingFileSystem) GetXAttr(name string, attribute string, context *fuse.Context) ([]byte, fuse.Status) {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return nil, code
+	}
+	return fs.FileSystem.GetXAttr(name, attribute, context)
+}
+
+func (fs *throttlingFileSystem) ListXAttr(name string, context *fuse.Context) ([]string, fuse.Status) {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return nil, code
+	}
+	return fs.FileSystem.ListXAttr(name, context)
+}
+
+func (fs *throttlingFileSystem) RemoveXAttr(name string, attr string, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.RemoveXAttr(name, attr, context)
+}
+
+func (fs *throttlingFileSystem) SetXAttr(name string, attr string, data []byte, flags int, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.SetXAttr(name, attr, data, flags, context)
+}
+
+func (fs *throttlingFileSystem) Open(name string, flags uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return nil, code
+	}
+	f, code := fs.FileSystem.Open(name, flags, context)
+	return fs.wrapFile(f), code
+}
+
+func (fs *throttlingFileSystem) Create(name string, flags uint32, mode uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return nil, code
+	}
+	f, code := fs.FileSystem.Create(name, flags, mode, context)
+	return fs.wrapFile(f), code
+}
+
+func (fs *throttlingFileSystem) OpenDir(name string, context *fuse.Context) ([]fuse.DirEntry, fuse.Status) {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return nil, code
+	}
+	return fs.FileSystem.OpenDir(name, context)
+}
+
+func (fs *throttlingFileSystem) Symlink(value string, linkName string, context *fuse.Context) fuse.Status {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return code
+	}
+	return fs.FileSystem.Symlink(value, linkName, context)
+}
+
+func (fs *throttlingFileSystem) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
+	if code := fs.metadata.wait(cancelOf(context), 0); !code.Ok() {
+		return "", code
+	}
+	return fs.FileSystem.Readlink(name, context)
+}
+
+func (fs *throttlingFileSystem) wrapFile(f nodefs.File) nodefs.File {
+	if f == nil {
+		return nil
+	}
+	return &throttlingFile{File: f, ContextFile: nodefs.ToContextFile(f), fs: fs}
+}
+
+// throttlingFile throttles reads and writes. The other calls,
+// including their nodefs.ContextFile versions, go to the wrapped
+// File directly.
+type throttlingFile struct {
+	nodefs.File
+	nodefs.ContextFile
+	fs *throttlingFileSystem
+}
+
+func (f *throttlingFile) InnerFile() nodefs.File {
+	return f.File
+}
+
+func (f *throttlingFile) String() string {
+	return fmt.Sprintf("throttlingFile(%s)", f.File.String())
+}
+
+func (f *throttlingFile) Read(dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	return f.ReadContext(&fuse.Context{}, dest, off)
+}
+
+func (f *throttlingFile) ReadContext(context *fuse.Context, dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	if code := f.fs.read.wait(cancelOf(context), len(dest)); !code.Ok() {
+		return nil, code
+	}
+	return f.ContextFile.ReadContext(context, dest, off)
+}
+
+func (f *throttlingFile) Write(data []byte, off int64) (uint32, fuse.Status) {
+	return f.WriteContext(&fuse.Context{}, data, off)
+}
+
+func (f *throttlingFile) WriteContext(context *fuse.Context, data []byte, off int64) (uint32, fuse.Status) {
+	if code := f.fs.write.wait(cancelOf(context), len(data)); !code.Ok() {
+		return 0, code
+	}
+	return f.ContextFile.WriteContext(context, data, off)
+}
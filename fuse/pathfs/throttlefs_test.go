@@ -0,0 +1,98 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTokenBucket(10, 2)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	// A burst of 20 tokens is available right away.
+	if d := b.reserve(20); d != 0 {
+		t.Errorf("burst: got wait %v, want 0", d)
+	}
+	if d := b.reserve(5); d != 500*time.Millisecond {
+		t.Errorf("got wait %v, want 500ms", d)
+	}
+	now = now.Add(time.Second)
+	if d := b.reserve(5); d != 0 {
+		t.Errorf("after refill: got wait %v, want 0", d)
+	}
+	now = now.Add(time.Hour)
+	if d := b.reserve(25); d != 500*time.Millisecond {
+		t.Errorf("refill is capped at burst: got wait %v, want 500ms", d)
+	}
+
+	var unlimited *tokenBucket
+	if d := unlimited.reserve(1e9); d != 0 {
+		t.Errorf("unlimited: got wait %v", d)
+	}
+}
+
+func TestThrottlingFileSystem(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(dir+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	fs := NewThrottlingFileSystem(NewLoopbackFileSystem(dir), ThrottleOptions{
+		Metadata: Throttle{Ops: 50, Burst: 0.02},
+		Read:     Throttle{Bytes: 100, Burst: 0.05},
+	})
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, code := fs.GetAttr("file", nil); !code.Ok() {
+			t.Fatalf("GetAttr: %v", code)
+		}
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("6 operations at 50/s took %v, want at least 100ms", d)
+	}
+
+	cancel := make(chan struct{})
+	close(cancel)
+	if _, code := fs.GetAttr("file", &fuse.Context{Cancel: cancel}); code != fuse.EINTR {
+		t.Errorf("interrupted GetAttr: got %v, want EINTR", code)
+	}
+
+	f, code := fs.Open("file", 0, nil)
+	if !code.Ok() {
+		t.Fatalf("Open: %v", code)
+	}
+	defer f.Release()
+	buf := make([]byte, 5)
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		if _, code := f.Read(buf, 0); !code.Ok() {
+			t.Fatalf("Read: %v", code)
+		}
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("reading 15 bytes at 100/s took %v, want at least 100ms", d)
+	}
+
+	// The bytes budget is used up, so this read has to wait.
+	cf, ok := f.(nodefs.ContextFile)
+	if !ok {
+		t.Fatalf("%v does not implement nodefs.ContextFile", f)
+	}
+	if _, code := cf.ReadContext(&fuse.Context{Cancel: cancel}, buf, 0); code != fuse.EINTR {
+		t.Errorf("interrupted Read: got %v, want EINTR", code)
+	}
+}
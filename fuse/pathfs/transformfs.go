@@ -0,0 +1,259 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+// ToInnerFunc translates a path as seen through the mount into a
+// path of the wrapped FileSystem. It returns false if the path
+// does not exist in the view.
+type ToInnerFunc func(name string) (string, bool)
+
+// ToOuterFunc translates the name of an entry of the wrapped
+// FileSystem directory dir into the name shown through the
+// mount. It returns false if the entry should be hidden.
+type ToOuterFunc func(dir string, name string) (string, bool)
+
+type transformFileSystem struct {
+	FileSystem FileSystem
+	toInner    ToInnerFunc
+	toOuter    ToOuterFunc
+}
+
+// NewTransformFileSystem returns a wrapper that rewrites paths
+// before passing them to fs, and rewrites directory listings on the
+// way back. This can be used to build "view" file systems that hide
+// file extensions, add suffixes or reorganize directory layouts.
+//
+// toInner is applied to every incoming path; toOuter is applied to
+// every name returned from OpenDir. If toOuter is nil, names are
+// returned as is. The two functions should be each other's
+// inverse, or the kernel will not be able to look up the entries it
+// read from a directory.
+func NewTransformFileSystem(fs FileSystem, toInner ToInnerFunc, toOuter ToOuterFunc) FileSystem {
+	if toOuter == nil {
+		toOuter = func(dir string, name string) (string, bool) { return name, true }
+	}
+	return &transformFileSystem{fs, toInner, toOuter}
+}
+
+func (fs *transformFileSystem) SetDebug(debug bool) {
+	fs.FileSystem.SetDebug(debug)
+}
+
+func (fs *transformFileSystem) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+	return fs.FileSystem.GetAttr(inner, context)
+}
+
+func (fs *transformFileSystem) Readlink(name string, context *fuse.Context) (string, fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return "", fuse.ENOENT
+	}
+	return fs.FileSystem.Readlink(inner, context)
+}
+
+func (fs *transformFileSystem) Mknod(name string, mode uint32, dev uint32, context *fuse.Context) fuse.Status {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Mknod(inner, mode, dev, context)
+}
+
+func (fs *transformFileSystem) Mkdir(name string, mode uint32, context *fuse.Context) fuse.Status {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Mkdir(inner, mode, context)
+}
+
+func (fs *transformFileSystem) Unlink(name string, context *fuse.Context) (code fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Unlink(inner, context)
+}
+
+func (fs *transformFileSystem) Rmdir(name string, context *fuse.Context) (code fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Rmdir(inner, context)
+}
+
+func (fs *transformFileSystem) Symlink(value string, linkName string, context *fuse.Context) (code fuse.Status) {
+	inner, ok := fs.toInner(linkName)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Symlink(value, inner, context)
+}
+
+func (fs *transformFileSystem) Rename(oldName string, newName string, context *fuse.Context) (code fuse.Status) {
+	oldInner, ok := fs.toInner(oldName)
+	if !ok {
+		return fuse.ENOENT
+	}
+	newInner, ok := fs.toInner(newName)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Rename(oldInner, newInner, context)
+}
+
+func (fs *transformFileSystem) Link(oldName string, newName string, context *fuse.Context) (code fuse.Status) {
+	oldInner, ok := fs.toInner(oldName)
+	if !ok {
+		return fuse.ENOENT
+	}
+	newInner, ok := fs.toInner(newName)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Link(oldInner, newInner, context)
+}
+
+func (fs *transformFileSystem) Chmod(name string, mode uint32, context *fuse.Context) (code fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Chmod(inner, mode, context)
+}
+
+func (fs *transformFileSystem) Chown(name string, uid uint32, gid uint32, context *fuse.Context) (code fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Chown(inner, uid, gid, context)
+}
+
+func (fs *transformFileSystem) Truncate(name string, offset uint64, context *fuse.Context) (code fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Truncate(inner, offset, context)
+}
+
+func (fs *transformFileSystem) Open(name string, flags uint32, context *fuse.Context) (file nodefs.File, code fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+	return fs.FileSystem.Open(inner, flags, context)
+}
+
+func (fs *transformFileSystem) OpenDir(name string, context *fuse.Context) (stream []fuse.DirEntry, status fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+	innerStream, status := fs.FileSystem.OpenDir(inner, context)
+	if !status.Ok() {
+		return nil, status
+	}
+	stream = make([]fuse.DirEntry, 0, len(innerStream))
+	for _, e := range innerStream {
+		outer, ok := fs.toOuter(inner, e.Name)
+		if !ok {
+			continue
+		}
+		e.Name = outer
+		stream = append(stream, e)
+	}
+	return stream, fuse.OK
+}
+
+func (fs *transformFileSystem) OnMount(nodeFs *PathNodeFs) {
+	fs.FileSystem.OnMount(nodeFs)
+}
+
+func (fs *transformFileSystem) OnUnmount() {
+	fs.FileSystem.OnUnmount()
+}
+
+func (fs *transformFileSystem) Access(name string, mode uint32, context *fuse.Context) (code fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Access(inner, mode, context)
+}
+
+func (fs *transformFileSystem) Create(name string, flags uint32, mode uint32, context *fuse.Context) (file nodefs.File, code fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+	return fs.FileSystem.Create(inner, flags, mode, context)
+}
+
+func (fs *transformFileSystem) Utimens(name string, Atime *time.Time, Mtime *time.Time, context *fuse.Context) (code fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.Utimens(inner, Atime, Mtime, context)
+}
+
+func (fs *transformFileSystem) GetXAttr(name string, attr string, context *fuse.Context) ([]byte, fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+	return fs.FileSystem.GetXAttr(inner, attr, context)
+}
+
+func (fs *transformFileSystem) SetXAttr(name string, attr string, data []byte, flags int, context *fuse.Context) fuse.Status {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.SetXAttr(inner, attr, data, flags, context)
+}
+
+func (fs *transformFileSystem) ListXAttr(name string, context *fuse.Context) ([]string, fuse.Status) {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+	return fs.FileSystem.ListXAttr(inner, context)
+}
+
+func (fs *transformFileSystem) RemoveXAttr(name string, attr string, context *fuse.Context) fuse.Status {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return fuse.ENOENT
+	}
+	return fs.FileSystem.RemoveXAttr(inner, attr, context)
+}
+
+func (fs *transformFileSystem) String() string {
+	return fmt.Sprintf("transformFileSystem(%s)", fs.FileSystem.String())
+}
+
+func (fs *transformFileSystem) StatFs(name string) *fuse.StatfsOut {
+	inner, ok := fs.toInner(name)
+	if !ok {
+		return nil
+	}
+	return fs.FileSystem.StatFs(inner)
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestTransformFileSystemHideExtension(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	for _, n := range []string{"a.txt", "b.txt", "c.bin"} {
+		if err := ioutil.WriteFile(dir+"/"+n, []byte(n), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	// Show only .txt files, without their extension.
+	toInner := func(name string) (string, bool) {
+		if name == "" {
+			return name, true
+		}
+		return name + ".txt", true
+	}
+	toOuter := func(dir string, name string) (string, bool) {
+		if !strings.HasSuffix(name, ".txt") {
+			return "", false
+		}
+		return strings.TrimSuffix(name, ".txt"), true
+	}
+	fs := NewTransformFileSystem(NewLoopbackFileSystem(dir), toInner, toOuter)
+
+	stream, code := fs.OpenDir("", nil)
+	if !code.Ok() {
+		t.Fatalf("OpenDir: %v", code)
+	}
+	got := map[string]bool{}
+	for _, e := range stream {
+		got[e.Name] = true
+	}
+	if len(got) != 2 || !got["a"] || !got["b"] {
+		t.Errorf("got entries %v, want a and b", got)
+	}
+
+	a, code := fs.GetAttr("a", nil)
+	if !code.Ok() || a.Size != uint64(len("a.txt")) {
+		t.Errorf("GetAttr: %v %v", a, code)
+	}
+	if _, code := fs.GetAttr("c", nil); code != fuse.ENOENT {
+		t.Errorf("GetAttr(c): got %v, want ENOENT", code)
+	}
+
+	if code := fs.Rename("a", "d", nil); !code.Ok() {
+		t.Fatalf("Rename: %v", code)
+	}
+	if _, err := os.Stat(dir + "/d.txt"); err != nil {
+		t.Errorf("Stat after rename: %v", err)
+	}
+}
@@ -46,6 +46,12 @@ var (
 		CAP_CACHE_SYMLINKS:      "CACHE_SYMLINKS",
 		CAP_NO_OPENDIR_SUPPORT:  "NO_OPENDIR_SUPPORT",
 		CAP_EXPLICIT_INVAL_DATA: "EXPLICIT_INVAL_DATA",
+		CAP_INIT_EXT:            "INIT_EXT",
+
+		// Flags2, shifted by 32.
+		CAP2_SECURITY_CTX << 32:  "SECURITY_CTX",
+		CAP2_PASSTHROUGH << 32:   "PASSTHROUGH",
+		CAP2_OVER_IO_URING << 32: "OVER_IO_URING",
 	}
 	releaseFlagNames = map[int64]string{
 		RELEASE_FLUSH: "FLUSH",
@@ -71,6 +77,7 @@ var (
 		FOPEN_NONSEEKABLE: "NONSEEK",
 		FOPEN_CACHE_DIR:   "CACHE_DIR",
 		FOPEN_STREAM:      "STREAM",
+		FOPEN_PASSTHROUGH: "PASSTHROUGH",
 	}
 	accessFlagName = map[int64]string{
 		X_OK: "x",
@@ -137,6 +144,9 @@ func (in *SetAttrIn) string() string {
 	if in.Valid&FATTR_MTIME != 0 {
 		s = append(s, fmt.Sprintf("mtime %d.%09d", in.Mtime, in.Mtimensec))
 	}
+	if in.Valid&FATTR_CTIME != 0 {
+		s = append(s, fmt.Sprintf("ctime %d.%09d", in.Ctime, in.Ctimensec))
+	}
 	if in.Valid&FATTR_FH != 0 {
 		s = append(s, fmt.Sprintf("fh %d", in.Fh))
 	}
@@ -156,6 +166,10 @@ func (in *OpenIn) string() string {
 }
 
 func (in *OpenOut) string() string {
+	if in.OpenFlags&FOPEN_PASSTHROUGH != 0 {
+		return fmt.Sprintf("{Fh %d %s backing %d}", in.Fh,
+			flagString(fuseOpenFlagNames, int64(in.OpenFlags), ""), in.BackingId)
+	}
 	return fmt.Sprintf("{Fh %d %s}", in.Fh,
 		flagString(fuseOpenFlagNames, int64(in.OpenFlags), ""))
 }
@@ -163,13 +177,13 @@ func (in *OpenOut) string() string {
 func (in *InitIn) string() string {
 	return fmt.Sprintf("{%d.%d Ra %d %s}",
 		in.Major, in.Minor, in.MaxReadAhead,
-		flagString(initFlagNames, int64(in.Flags), ""))
+		flagString(initFlagNames, int64(in.Flags)|int64(in.Flags2)<<32, ""))
 }
 
 func (o *InitOut) string() string {
 	return fmt.Sprintf("{%d.%d Ra %d %s %d/%d Wr %d Tg %d MaxPages %d}",
 		o.Major, o.Minor, o.MaxReadAhead,
-		flagString(initFlagNames, int64(o.Flags), ""),
+		flagString(initFlagNames, int64(o.Flags)|int64(o.Flags2)<<32, ""),
 		o.CongestionThreshold, o.MaxBackground, o.MaxWrite,
 		o.TimeGran, o.MaxPages)
 }
@@ -203,18 +217,35 @@ func (in *FlushIn) string() string {
 
 func (o *AttrOut) string() string {
 	return fmt.Sprintf(
-		"{tA=%gs %v}",
+		"{tA=%ss %v}",
 		ft(o.AttrValid, o.AttrValidNsec), &o.Attr)
 }
 
-// ft converts (seconds , nanoseconds) -> float(seconds)
-func ft(tsec uint64, tnsec uint32) float64 {
-	return float64(tsec) + float64(tnsec)*1E-9
+func (in *StatxIn) string() string {
+	return fmt.Sprintf("{Fh %d flags %x mask %x}", in.Fh, in.SxFlags, in.SxMask)
+}
+
+func (o *StatxOut) string() string {
+	return fmt.Sprintf("{tA=%ss %s}", ft(o.AttrValid, o.AttrValidNsec), o.Statx.string())
+}
+
+func (s *Statx) string() string {
+	return fmt.Sprintf(
+		"{mask %x M0%o SZ=%d L=%d %d:%d B%d*%d i%d A %d.%09d M %d.%09d C %d.%09d Bt %d.%09d}",
+		s.Mask, s.Mode, s.Size, s.Nlink, s.Uid, s.Gid, s.Blocks, s.Blksize, s.Ino,
+		s.Atime.Sec, s.Atime.Nsec, s.Mtime.Sec, s.Mtime.Nsec,
+		s.Ctime.Sec, s.Ctime.Nsec, s.Btime.Sec, s.Btime.Nsec)
+}
+
+// ft formats (seconds, nanoseconds) as seconds, without losing the
+// nanoseconds to floating point.
+func ft(tsec uint64, tnsec uint32) string {
+	return fmt.Sprintf("%d.%09d", int64(tsec), tnsec)
 }
 
 // Returned by LOOKUP
 func (o *EntryOut) string() string {
-	return fmt.Sprintf("{n%d g%d tE=%gs tA=%gs %v}",
+	return fmt.Sprintf("{n%d g%d tE=%ss tA=%ss %v}",
 		o.NodeId, o.Generation, ft(o.EntryValid, o.EntryValidNsec),
 		ft(o.AttrValid, o.AttrValidNsec), &o.Attr)
 }
@@ -293,6 +324,14 @@ func (o *LseekOut) string() string {
 	return fmt.Sprintf("{%d}", o.Offset)
 }
 
+func (in *ExchangeIn) string() string {
+	return fmt.Sprintf("{i%d => i%d 0x%x}", in.Olddir, in.Newdir, in.Options)
+}
+
+func (o *GetxtimesOut) string() string {
+	return fmt.Sprintf("{B %d.%09d Cr %d.%09d}", o.Bkuptime, o.Bkuptimensec, o.Crtime, o.Crtimensec)
+}
+
 // Print pretty prints FUSE data types for kernel communication
 func Print(obj interface{}) string {
 	t, ok := obj.(interface {
@@ -19,9 +19,9 @@ func (a *Attr) string() string {
 		"{M0%o SZ=%d L=%d "+
 			"%d:%d "+
 			"B%d*%d i%d:%d "+
-			"A %f "+
-			"M %f "+
-			"C %f}",
+			"A %s "+
+			"M %s "+
+			"C %s}",
 		a.Mode, a.Size, a.Nlink,
 		a.Uid, a.Gid,
 		a.Blocks, a.Blksize,
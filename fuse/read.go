@@ -26,10 +26,19 @@ func (r *readResultData) Bytes(buf []byte) ([]byte, Status) {
 	return r.Data, OK
 }
 
+// ReadResultData returns a ReadResult for data that is already in
+// memory. To avoid an extra copy, read into the buffer passed to the
+// Read call, and return the filled part of it.
 func ReadResultData(b []byte) ReadResult {
 	return &readResultData{b}
 }
 
+// ReadResultFd returns a ReadResult for sz bytes at offset off of
+// the file descriptor fd. If off is negative, the current file
+// offset is used. Where possible, the data is spliced from fd
+// into the FUSE device without passing through user space. The file
+// descriptor must stay open until the ReadResult's Done method is
+// called.
 func ReadResultFd(fd uintptr, off int64, sz int) ReadResult {
 	return &readResultFd{fd, off, sz}
 }
@@ -55,7 +64,13 @@ func (r *readResultFd) Bytes(buf []byte) ([]byte, Status) {
 		sz = len(buf)
 	}
 
-	n, err := syscall.Pread(int(r.Fd), buf[:sz], r.Off)
+	var n int
+	var err error
+	if r.Off < 0 {
+		n, err = syscall.Read(int(r.Fd), buf[:sz])
+	} else {
+		n, err = syscall.Pread(int(r.Fd), buf[:sz], r.Off)
+	}
 	if err == io.EOF {
 		err = nil
 	}
@@ -0,0 +1,44 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestReadResultFd(t *testing.T) {
+	f, err := ioutil.TempFile("", "TestReadResultFd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.WriteString("hello world"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 100)
+	got, code := ReadResultFd(f.Fd(), 6, 5).Bytes(buf)
+	if !code.Ok() || string(got) != "world" {
+		t.Errorf("Bytes at offset: got %q, %v", got, code)
+	}
+
+	// Negative offset reads from the current position.
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, code = ReadResultFd(f.Fd(), -1, 5).Bytes(buf)
+	if !code.Ok() || string(got) != "hello" {
+		t.Errorf("Bytes at current offset: got %q, %v", got, code)
+	}
+
+	// The result is truncated to the buffer.
+	got, code = ReadResultFd(f.Fd(), 0, 100).Bytes(buf[:3])
+	if !code.Ok() || string(got) != "hel" {
+		t.Errorf("Bytes with short buffer: got %q, %v", got, code)
+	}
+}
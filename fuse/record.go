@@ -0,0 +1,82 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// A recording (see MountOptions.Record) is a sequence of messages,
+// each framed as a kind byte, the length of the message as a 32-bit
+// little-endian integer, and the message as it was read from or
+// written to the FUSE device.
+const (
+	recordRequest byte = 'Q'
+	recordReply   byte = 'R'
+)
+
+type recorder struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// record writes a message, consisting of the concatenation of
+// parts. After the first error, recording stops.
+func (r *recorder) record(kind byte, parts ...[]byte) {
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+	var hdr [5]byte
+	hdr[0] = kind
+	binary.LittleEndian.PutUint32(hdr[1:], uint32(n))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if _, r.err = r.w.Write(hdr[:]); r.err != nil {
+		log.Printf("recording FUSE traffic: %v", r.err)
+		return
+	}
+	for _, p := range parts {
+		if _, r.err = r.w.Write(p); r.err != nil {
+			log.Printf("recording FUSE traffic: %v", r.err)
+			return
+		}
+	}
+}
+
+type recordedMessage struct {
+	kind byte
+	data []byte
+}
+
+// readRecording reads all messages of a recording.
+func readRecording(r io.Reader) ([]recordedMessage, error) {
+	var msgs []recordedMessage
+	for {
+		var hdr [5]byte
+		if _, err := io.ReadFull(r, hdr[:]); err == io.EOF {
+			return msgs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if hdr[0] != recordRequest && hdr[0] != recordReply {
+			return nil, fmt.Errorf("message %d: unknown kind %q", len(msgs), hdr[0])
+		}
+		data := make([]byte, binary.LittleEndian.Uint32(hdr[1:]))
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("message %d: %v", len(msgs), err)
+		}
+		msgs = append(msgs, recordedMessage{hdr[0], data})
+	}
+}
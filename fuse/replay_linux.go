@@ -0,0 +1,161 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// ReplayResult summarizes a Replay run.
+type ReplayResult struct {
+	// Requests is the number of requests sent to the file
+	// system.
+	Requests int
+
+	// Mismatches holds the Unique IDs of the requests whose reply
+	// differs from the recorded reply.
+	Mismatches []uint64
+
+	// Duration is the time it took to serve the requests.
+	Duration time.Duration
+}
+
+// Replay feeds the requests of a recording made with
+// MountOptions.Record to fs, without involving the kernel, and
+// compares the replies of fs with the recorded ones. The recording
+// must start with INIT. Requests are sent one at a time in recorded
+// order, so the replies are deterministic if fs is. INTERRUPT and
+// NOTIFY_REPLY messages are skipped, as their effect depends on
+// timing.
+func Replay(r io.Reader, fs RawFileSystem, opts *MountOptions) (*ReplayResult, error) {
+	msgs, err := readRecording(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var requests []recordedMessage
+	replies := map[uint64][]byte{}
+	for i, m := range msgs {
+		if len(m.data) < int(unsafe.Sizeof(OutHeader{})) {
+			return nil, fmt.Errorf("message %d: too short", i)
+		}
+		if m.kind == recordReply {
+			out := (*OutHeader)(unsafe.Pointer(&m.data[0]))
+			if out.Unique != 0 {
+				replies[out.Unique] = m.data
+			}
+			continue
+		}
+		if len(m.data) < int(unsafe.Sizeof(InHeader{})) {
+			return nil, fmt.Errorf("message %d: too short", i)
+		}
+		switch (*InHeader)(unsafe.Pointer(&m.data[0])).Opcode {
+		case _OP_INTERRUPT, _OP_NOTIFY_REPLY:
+			continue
+		}
+		requests = append(requests, m)
+	}
+	if len(requests) == 0 || (*InHeader)(unsafe.Pointer(&requests[0].data[0])).Opcode != _OP_INIT {
+		return nil, fmt.Errorf("recording does not start with INIT")
+	}
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_SEQPACKET|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	kernelFd := fds[1]
+	defer syscall.Close(kernelFd)
+
+	var o MountOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.Record = nil
+	if o.MaxBackground == 0 {
+		o.MaxBackground = _DEFAULT_BACKGROUND_TASKS
+	}
+	ms, err := newServer(fs, &o)
+	if err != nil {
+		syscall.Close(fds[0])
+		return nil, err
+	}
+	ms.mountFd = fds[0]
+
+	res := &ReplayResult{}
+	buf := make([]byte, MAX_KERNEL_WRITE+_FUSE_MIN_READ_BUFFER)
+	start := time.Now()
+
+	// send passes a request to the Server, and compares its
+	// reply with the recorded one.
+	send := func(req []byte, serve func() error) error {
+		in := (*InHeader)(unsafe.Pointer(&req[0]))
+		if _, err := syscall.Write(kernelFd, req); err != nil {
+			return err
+		}
+		res.Requests++
+		if serve != nil {
+			if err := serve(); err != nil {
+				return err
+			}
+		}
+		switch in.Opcode {
+		case _OP_FORGET, _OP_BATCH_FORGET:
+			return nil
+		}
+		for {
+			n, err := syscall.Read(kernelFd, buf)
+			if err != nil {
+				return err
+			}
+			if n < int(unsafe.Sizeof(OutHeader{})) {
+				return fmt.Errorf("short reply to %s: %d bytes", operationName(in.Opcode), n)
+			}
+			out := (*OutHeader)(unsafe.Pointer(&buf[0]))
+			if out.Unique == 0 {
+				// A notification; the file system may
+				// send these at any time.
+				continue
+			}
+			if out.Unique != in.Unique {
+				return fmt.Errorf("got reply for request %d, want %d", out.Unique, in.Unique)
+			}
+			if want, ok := replies[in.Unique]; ok && !bytes.Equal(want, buf[:n]) {
+				res.Mismatches = append(res.Mismatches, in.Unique)
+			}
+			return nil
+		}
+	}
+
+	err = send(requests[0].data, func() error {
+		if code := ms.handleInit(); !code.Ok() {
+			return fmt.Errorf("init: %s", code)
+		}
+		return nil
+	})
+	if err != nil {
+		syscall.Close(ms.mountFd)
+		return nil, err
+	}
+
+	ms.loops.Add(1)
+	go ms.loop(false)
+	for _, m := range requests[1:] {
+		if err = send(m.data, nil); err != nil {
+			break
+		}
+	}
+
+	// Closing our end makes the Server's reads return ENODEV.
+	syscall.Shutdown(kernelFd, syscall.SHUT_WR)
+	ms.loops.Wait()
+	syscall.Close(ms.mountFd)
+	res.Duration = time.Since(start)
+	return res, err
+}
@@ -9,8 +9,11 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
+
+	"github.com/hanwen/go-fuse/v2/splice"
 )
 
 var sizeOfOutHeader = unsafe.Sizeof(OutHeader{})
@@ -24,6 +27,14 @@ type request struct {
 	// written under Server.reqMu
 	interrupted bool
 
+	// failure is the cause recorded by Fail while serving is set.
+	failMu  sync.Mutex
+	serving bool
+	failure error
+
+	// set if the request arrived while the Server was draining.
+	refused bool
+
 	inputBuf []byte
 
 	// These split up inputBuf.
@@ -31,10 +42,12 @@ type request struct {
 	inData   unsafe.Pointer // per op data
 	arg      []byte         // flat data.
 
-	filenames []string // filename arguments
+	filenames  []string // filename arguments
+	extensions []byte   // extensions after the arguments
 
 	// Output data.
 	status   Status
+	cause    error // reason for status, see Fail.
 	flatData []byte
 	fdData   *readResultFd
 
@@ -42,6 +55,18 @@ type request struct {
 	// Done() on it.
 	readResult ReadResult
 
+	// For WRITE requests read with splice, the pipe holding
+	// the payload, and the payload size.
+	splicePair *splice.Pair
+	spliceSize int
+
+	// For requests received through io_uring, the ring entry
+	// that takes the reply.
+	ringEntry *ringEntry
+
+	// fd is the device descriptor the request was read from.
+	fd int
+
 	// Start timestamp for timing info.
 	startTime time.Time
 
@@ -69,12 +94,22 @@ func (r *request) clear() {
 	r.inData = nil
 	r.arg = nil
 	r.filenames = nil
+	r.extensions = nil
 	r.status = OK
+	r.cause = nil
+	r.failMu.Lock()
+	r.failure = nil
+	r.failMu.Unlock()
 	r.flatData = nil
 	r.fdData = nil
 	r.startTime = time.Time{}
 	r.handler = nil
 	r.readResult = nil
+	r.splicePair = nil
+	r.spliceSize = 0
+	r.ringEntry = nil
+	r.fd = 0
+	r.refused = false
 }
 
 func (r *request) InputDebug() string {
@@ -145,8 +180,16 @@ func (r *request) OutputDebug() string {
 	if extraStr != "" {
 		extraStr = ", " + extraStr
 	}
-	return fmt.Sprintf("tx %d:     %v%s",
-		r.inHeader.Unique, r.status, extraStr)
+	latency := ""
+	if !r.startTime.IsZero() {
+		latency = fmt.Sprintf(" (%v)", time.Since(r.startTime))
+	}
+	cause := ""
+	if r.cause != nil {
+		cause = fmt.Sprintf(" (%v)", r.cause)
+	}
+	return fmt.Sprintf("tx %d:     %v%s%s%s",
+		r.inHeader.Unique, r.status, cause, extraStr, latency)
 }
 
 // setInput returns true if it takes ownership of the argument, false if not.
@@ -181,6 +224,23 @@ func (r *request) parse() {
 		return
 	}
 
+	if int(r.inHeader.Length) != len(r.arg)+r.spliceSize {
+		log.Printf("Length mismatch for %v: header says %d, got %d",
+			operationName(r.inHeader.Opcode), r.inHeader.Length, len(r.arg)+r.spliceSize)
+		r.status = EIO
+		return
+	}
+
+	if r.inHeader.Opcode == _OP_INIT && len(r.arg) < int(r.handler.InputSize) &&
+		cap(r.arg) >= int(r.handler.InputSize) {
+		// Before 7.36, InitIn ends after Flags. Zero the rest.
+		n := len(r.arg)
+		r.arg = r.arg[:r.handler.InputSize]
+		for i := n; i < len(r.arg); i++ {
+			r.arg[i] = 0
+		}
+	}
+
 	if len(r.arg) < int(r.handler.InputSize) {
 		log.Printf("Short read for %v: %v", operationName(r.inHeader.Opcode), r.arg)
 		r.status = EIO
@@ -194,13 +254,32 @@ func (r *request) parse() {
 		r.arg = r.arg[unsafe.Sizeof(InHeader{}):]
 	}
 
+	if n := int(r.inHeader.TotalExtlen) * 8; n > 0 {
+		if n > len(r.arg) {
+			log.Printf("Short read for %v extensions: %v", operationName(r.inHeader.Opcode), r.arg)
+			r.status = EIO
+			return
+		}
+		r.extensions = r.arg[len(r.arg)-n:]
+		r.arg = r.arg[:len(r.arg)-n]
+	}
+
 	count := r.handler.FileNames
 	if count > 0 {
 		if count == 1 && r.inHeader.Opcode == _OP_SETXATTR {
 			// SETXATTR is special: the only opcode with a file name AND a
 			// binary argument.
 			splits := bytes.SplitN(r.arg, []byte{0}, 2)
+			if len(splits) != 2 || uint32(len(splits[1])) < (*SetXAttrIn)(r.inData).Size {
+				log.Printf("Malformed SETXATTR argument: %q", r.arg)
+				r.status = EIO
+				return
+			}
 			r.filenames = []string{string(splits[0])}
+		} else if len(r.arg) == 0 || r.arg[len(r.arg)-1] != 0 {
+			log.Printf("Unterminated file name for %v: %q", operationName(r.inHeader.Opcode), r.arg)
+			r.status = EIO
+			return
 		} else if count == 1 {
 			r.filenames = []string{string(r.arg[:len(r.arg)-1])}
 		} else {
@@ -212,10 +291,27 @@ func (r *request) parse() {
 			if len(names) != count {
 				log.Println("filename argument mismatch", names, count)
 				r.status = EIO
+				return
 			}
 		}
 	}
 
+	switch r.inHeader.Opcode {
+	case _OP_WRITE:
+		if sz := (*WriteIn)(r.inData).Size; int(sz) != len(r.arg)+r.spliceSize {
+			log.Printf("WRITE size mismatch: header says %d, got %d", sz, len(r.arg)+r.spliceSize)
+			r.status = EIO
+			return
+		}
+	case _OP_BATCH_FORGET:
+		want := uint64((*_BatchForgetIn)(r.inData).Count) * uint64(unsafe.Sizeof(_ForgetOne{}))
+		if uint64(len(r.arg)) < want {
+			log.Printf("Too few bytes for BATCH_FORGET: got %d, want %d", len(r.arg), want)
+			r.status = EIO
+			return
+		}
+	}
+
 	copy(r.outBuf[:r.handler.OutputSize+sizeOfOutHeader],
 		zeroOutBuf[:r.handler.OutputSize+sizeOfOutHeader])
 
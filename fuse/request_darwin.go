@@ -4,7 +4,9 @@
 
 package fuse
 
-const outputHeaderSize = 200
+// outputHeaderSize fits the OutHeader and the largest fixed-size
+// reply, StatxOut.
+const outputHeaderSize = 304
 
 const (
 	_FUSE_KERNEL_VERSION   = 7
@@ -0,0 +1,73 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// rawRequest returns a request as the kernel would send it: a
+// struct with the InHeader at its start, followed by payload.
+func rawRequest(opcode uint32, in unsafe.Pointer, inSize uintptr, payload string) []byte {
+	buf := make([]byte, inSize+uintptr(len(payload)))
+	if in != nil {
+		copy(buf, (*[1 << 16]byte)(in)[:inSize])
+	}
+	copy(buf[inSize:], payload)
+	hdr := (*InHeader)(unsafe.Pointer(&buf[0]))
+	hdr.Opcode = opcode
+	hdr.Unique = 1
+	hdr.NodeId = FUSE_ROOT_ID
+	hdr.Length = uint32(len(buf))
+	return buf
+}
+
+func TestParseMalformed(t *testing.T) {
+	hdrSize := unsafe.Sizeof(InHeader{})
+	header := func(opcode uint32, payload string) []byte {
+		return rawRequest(opcode, nil, hdrSize, payload)
+	}
+
+	longLength := header(_OP_LOOKUP, "file\x00")
+	(*InHeader)(unsafe.Pointer(&longLength[0])).Length++
+
+	rename := Rename1In{}
+	setxattr := SetXAttrIn{Size: 10}
+	write := WriteIn{Size: 100}
+	forget := _BatchForgetIn{Count: 3}
+
+	for _, tc := range []struct {
+		name string
+		buf  []byte
+	}{
+		{"length", longLength},
+		{"empty name", header(_OP_LOOKUP, "")},
+		{"unterminated name", header(_OP_LOOKUP, "file")},
+		{"one name for rename", rawRequest(_OP_RENAME, unsafe.Pointer(&rename), unsafe.Sizeof(rename), "old\x00")},
+		{"setxattr without name", rawRequest(_OP_SETXATTR, unsafe.Pointer(&setxattr), unsafe.Sizeof(setxattr), "user.attr")},
+		{"setxattr short value", rawRequest(_OP_SETXATTR, unsafe.Pointer(&setxattr), unsafe.Sizeof(setxattr), "user.attr\x00abc")},
+		{"short write", rawRequest(_OP_WRITE, unsafe.Pointer(&write), unsafe.Sizeof(write), "abc")},
+		{"short batch forget", rawRequest(_OP_BATCH_FORGET, unsafe.Pointer(&forget), unsafe.Sizeof(forget), "")},
+	} {
+		req := &request{}
+		req.setInput(tc.buf)
+		if code := req.parseHeader(); !code.Ok() {
+			t.Fatalf("%s: parseHeader: %v", tc.name, code)
+		}
+		req.parse()
+		if req.status != EIO {
+			t.Errorf("%s: got %v, want EIO", tc.name, req.status)
+		}
+	}
+
+	req := &request{}
+	req.setInput(header(_OP_LOOKUP, "file\x00"))
+	req.parseHeader()
+	req.parse()
+	if !req.status.Ok() || len(req.filenames) != 1 || req.filenames[0] != "file" {
+		t.Errorf("LOOKUP: got %v %q, want OK [file]", req.status, req.filenames)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// Priorities assigned by DefaultRequestPriority.
+const (
+	PriorityData     = 0
+	PriorityMetadata = 1
+)
+
+// DefaultRequestPriority puts requests that transfer file data
+// behind all other requests, so a process doing large reads or
+// writes does not hold up stat() and lookups by others.
+func DefaultRequestPriority(op string, header *InHeader) int {
+	switch op {
+	case "READ", "WRITE", "READDIR", "READDIRPLUS", "FSYNC", "FSYNCDIR",
+		"FALLOCATE", "COPY_FILE_RANGE", "FLUSH":
+		return PriorityData
+	}
+	return PriorityMetadata
+}
+
+type scheduledRequest struct {
+	req  *request
+	prio int
+	seq  uint64
+}
+
+// requestQueue is a heap ordered by descending priority, and by
+// arrival within a priority.
+type requestQueue []scheduledRequest
+
+func (q requestQueue) Len() int { return len(q) }
+func (q requestQueue) Less(i, j int) bool {
+	if q[i].prio != q[j].prio {
+		return q[i].prio > q[j].prio
+	}
+	return q[i].seq < q[j].seq
+}
+func (q requestQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *requestQueue) Push(x interface{}) { *q = append(*q, x.(scheduledRequest)) }
+func (q *requestQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = scheduledRequest{}
+	*q = old[:n-1]
+	return x
+}
+
+// scheduler hands requests read from the kernel to a fixed set of
+// workers, highest priority first.
+type scheduler struct {
+	ms       *Server
+	priority func(op string, header *InHeader) int
+
+	// limit is the maximum queue length, or 0 for no limit.
+	limit int
+
+	mu    sync.Mutex
+	cond  sync.Cond
+	queue requestQueue
+	// held are the requests that arrived while the queue was
+	// full, in order of arrival. At most limit are held.
+	held []scheduledRequest
+	// room is signaled when a held request enters the queue.
+	room    sync.Cond
+	seq     uint64
+	stopped bool
+	workers sync.WaitGroup
+}
+
+func newScheduler(ms *Server, workers int, priority func(string, *InHeader) int, limit int) *scheduler {
+	if priority == nil {
+		priority = DefaultRequestPriority
+	}
+	s := &scheduler{
+		ms:       ms,
+		priority: priority,
+		limit:    limit,
+	}
+	s.cond.L = &s.mu
+	s.room.L = &s.mu
+	s.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+// push queues a request. INTERRUPT, FORGET and NOTIFY_REPLY are
+// handled right away: INTERRUPT is meant to abort requests that may
+// be waiting in the queue, handlers that retrieve the kernel's cache
+// wait for NOTIFY_REPLY, and FORGET needs no worker. If the queue is
+// full, the request is held until a worker takes one from the queue.
+// If as many requests are held as fit in the queue, push blocks, so
+// the reader stops reading from the device until a worker is done.
+func (s *scheduler) push(req *request) {
+	switch req.inHeader.Opcode {
+	case _OP_INTERRUPT, _OP_NOTIFY_REPLY, _OP_FORGET, _OP_BATCH_FORGET:
+		s.ms.handleRequest(req)
+		return
+	}
+	prio := s.priority(operationName(req.inHeader.Opcode), req.inHeader)
+
+	s.mu.Lock()
+	s.seq++
+	r := scheduledRequest{req, prio, s.seq}
+	for s.fullLocked() && len(s.held) >= s.limit && !s.stopped {
+		s.room.Wait()
+	}
+	if s.fullLocked() || len(s.held) > 0 {
+		s.held = append(s.held, r)
+		s.mu.Unlock()
+		return
+	}
+	heap.Push(&s.queue, r)
+	s.mu.Unlock()
+	s.cond.Signal()
+}
+
+// full returns whether push would hold a request.
+func (s *scheduler) full() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fullLocked()
+}
+
+func (s *scheduler) fullLocked() bool {
+	return s.limit > 0 && len(s.queue) >= s.limit
+}
+
+// pop waits for a request, and returns nil once the scheduler is
+// stopped and the queue is empty.
+func (s *scheduler) pop() *request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) == 0 && !s.stopped {
+		s.cond.Wait()
+	}
+	if len(s.queue) == 0 {
+		return nil
+	}
+	r := heap.Pop(&s.queue).(scheduledRequest)
+	if len(s.held) > 0 {
+		heap.Push(&s.queue, s.held[0])
+		s.held[0] = scheduledRequest{}
+		s.held = s.held[1:]
+		s.room.Signal()
+	}
+	return r.req
+}
+
+func (s *scheduler) work() {
+	defer s.workers.Done()
+	for {
+		req := s.pop()
+		if req == nil {
+			return
+		}
+		s.ms.handleRequest(req)
+	}
+}
+
+// stop waits for queued requests to be handled, and for the workers
+// to exit.
+func (s *scheduler) stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+	s.room.Broadcast()
+	s.workers.Wait()
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+)
+
+func TestRequestQueueOrder(t *testing.T) {
+	var q requestQueue
+	ops := []uint32{_OP_READ, _OP_GETATTR, _OP_WRITE, _OP_LOOKUP, _OP_READ}
+	for i, op := range ops {
+		h := &InHeader{Opcode: op, Unique: uint64(i)}
+		prio := DefaultRequestPriority(operationName(op), h)
+		heap.Push(&q, scheduledRequest{&request{inHeader: h}, prio, uint64(i)})
+	}
+
+	var got []uint64
+	for q.Len() > 0 {
+		got = append(got, heap.Pop(&q).(scheduledRequest).req.inHeader.Unique)
+	}
+	want := []uint64{1, 3, 0, 2, 4}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got order %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSchedulerQueueLimit(t *testing.T) {
+	s := newScheduler(nil, 0, nil, 2)
+	push := func(op uint32, unique uint64) {
+		s.push(&request{inHeader: &InHeader{Opcode: op, Unique: unique}})
+	}
+	push(_OP_GETATTR, 1)
+	push(_OP_GETATTR, 2)
+	if !s.full() {
+		t.Fatal("queue of 2 not full")
+	}
+
+	// Requests beyond the limit are held, and only compete on
+	// priority once they enter the queue.
+	push(_OP_READ, 3)
+	push(_OP_GETATTR, 4)
+	if len(s.held) != 2 {
+		t.Fatalf("got %d held requests, want 2", len(s.held))
+	}
+
+	var got []uint64
+	for i := 0; i < 4; i++ {
+		got = append(got, s.pop().inHeader.Unique)
+	}
+	want := []uint64{1, 2, 4, 3}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got order %v, want %v", got, want)
+		}
+	}
+	s.stop()
+}
+
+func TestSchedulerBackpressure(t *testing.T) {
+	s := newScheduler(nil, 0, nil, 2)
+	pushed := make(chan uint64, 10)
+	go func() {
+		for i := uint64(1); i <= 6; i++ {
+			s.push(&request{inHeader: &InHeader{Opcode: _OP_GETATTR, Unique: i}})
+			pushed <- i
+		}
+	}()
+
+	// Two requests fill the queue, two more are held, and the
+	// fifth blocks the reader.
+	for i := 0; i < 4; i++ {
+		<-pushed
+	}
+	select {
+	case i := <-pushed:
+		t.Fatalf("push of request %d did not block", i)
+	case <-time.After(50 * time.Millisecond):
+	}
+	s.mu.Lock()
+	queued, held := len(s.queue), len(s.held)
+	s.mu.Unlock()
+	if queued != 2 || held != 2 {
+		t.Fatalf("got %d queued, %d held, want 2, 2", queued, held)
+	}
+
+	// Each request taken by a worker lets one more in.
+	for i := uint64(1); i <= 6; i++ {
+		if got := s.pop().inHeader.Unique; got != i {
+			t.Fatalf("pop: got %d, want %d", got, i)
+		}
+	}
+	if got := <-pushed; got != 5 {
+		t.Fatalf("got push %d, want 5", got)
+	}
+	s.stop()
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+)
+
+// SecurityContext is a security label, such as an SELinux context,
+// that the kernel sends along with a request that creates a node.
+type SecurityContext struct {
+	// Name is the extended attribute that holds the label, eg.
+	// "security.selinux".
+	Name  string
+	Value []byte
+}
+
+// Extension types below this value are security context headers,
+// whose type is the number of contexts.
+const _MAX_NR_SECCTX = 31
+
+// parseSecurityContexts decodes the security contexts in the
+// extensions of a request. Other extensions are skipped.
+func parseSecurityContexts(ext []byte) ([]SecurityContext, error) {
+	var result []SecurityContext
+	for len(ext) > 0 {
+		if len(ext) < 8 {
+			return nil, fmt.Errorf("short extension header: %d bytes", len(ext))
+		}
+		size := binary.LittleEndian.Uint32(ext)
+		typ := binary.LittleEndian.Uint32(ext[4:])
+		if size < 8 || int(size) > len(ext) {
+			return nil, fmt.Errorf("extension size %d out of range", size)
+		}
+		data := ext[8:size]
+		ext = ext[align8(int(size)):]
+		if typ > _MAX_NR_SECCTX {
+			continue
+		}
+
+		for i := uint32(0); i < typ; i++ {
+			if len(data) < 8 {
+				return nil, fmt.Errorf("short security context")
+			}
+			valueSize := int(binary.LittleEndian.Uint32(data))
+			rest := data[8:]
+			nul := bytes.IndexByte(rest, 0)
+			if nul < 0 || nul+1+valueSize > len(rest) {
+				return nil, fmt.Errorf("security context %d out of range", i)
+			}
+			result = append(result, SecurityContext{
+				Name:  string(rest[:nul]),
+				Value: append([]byte{}, rest[nul+1:nul+1+valueSize]...),
+			})
+			n := align8(8 + nul + 1 + valueSize)
+			if n > len(data) {
+				n = len(data)
+			}
+			data = data[n:]
+		}
+	}
+	return result, nil
+}
+
+func align8(n int) int {
+	return (n + 7) &^ 7
+}
+
+// setSecurityContexts stores the security contexts sent with a request
+// that created nodeID as extended attributes of the new node.
+// Errors are only logged: the node exists at this point.
+func (ms *Server) setSecurityContexts(req *request, nodeID uint64) {
+	if len(req.extensions) == 0 {
+		return
+	}
+	ctxs, err := parseSecurityContexts(req.extensions)
+	if err != nil {
+		log.Printf("%s: %v", operationName(req.inHeader.Opcode), err)
+		return
+	}
+	for _, c := range ctxs {
+		in := SetXAttrIn{
+			InHeader: *req.inHeader,
+			Size:     uint32(len(c.Value)),
+		}
+		in.Opcode = _OP_SETXATTR
+		in.NodeId = nodeID
+		in.TotalExtlen = 0
+		if code := ms.fileSystem.SetXAttr(req.cancel, &in, c.Name, c.Value); !code.Ok() && code != ENOSYS && ms.opts.Debug {
+			ms.logf("setting %s on n%d: %v", c.Name, nodeID, code)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// securityContextExt returns an extension holding one security
+// context.
+func securityContextExt(name, value string) []byte {
+	le := binary.LittleEndian
+	var ctx bytes.Buffer
+	binary.Write(&ctx, le, []uint32{uint32(len(value)), 0})
+	ctx.WriteString(name + "\x00" + value)
+	for ctx.Len()%8 != 0 {
+		ctx.WriteByte(0)
+	}
+	var ext bytes.Buffer
+	binary.Write(&ext, le, []uint32{uint32(8 + ctx.Len()), 1})
+	ext.Write(ctx.Bytes())
+	return ext.Bytes()
+}
+
+func TestParseSecurityContexts(t *testing.T) {
+	var ext bytes.Buffer
+	le := binary.LittleEndian
+
+	// An unknown extension, which is skipped.
+	binary.Write(&ext, le, []uint32{16, 32, 0, 0})
+
+	ext.Write(securityContextExt("security.selinux", "label"))
+
+	got, err := parseSecurityContexts(ext.Bytes())
+	if err != nil {
+		t.Fatalf("parseSecurityContexts: %v", err)
+	}
+	want := []SecurityContext{{Name: "security.selinux", Value: []byte("label")}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// A header without contexts, as sent if no security module
+	// provides a label.
+	if got, err := parseSecurityContexts([]byte{8, 0, 0, 0, 0, 0, 0, 0}); err != nil || len(got) != 0 {
+		t.Errorf("empty header: got %v, %v", got, err)
+	}
+
+	if _, err := parseSecurityContexts(ext.Bytes()[:ext.Len()-8]); err == nil {
+		t.Errorf("truncated extension: got no error")
+	}
+}
+
+type tmpFileXAttrFS struct {
+	RawFileSystem
+	node  uint64
+	attr  string
+	value string
+}
+
+func (fs *tmpFileXAttrFS) TmpFile(cancel <-chan struct{}, input *CreateIn, out *CreateOut) Status {
+	out.NodeId = 7
+	return OK
+}
+
+func (fs *tmpFileXAttrFS) SetXAttr(cancel <-chan struct{}, input *SetXAttrIn, attr string, data []byte) Status {
+	fs.node, fs.attr, fs.value = input.NodeId, attr, string(data)
+	return OK
+}
+
+func TestTmpFileSecurityContext(t *testing.T) {
+	fs := &tmpFileXAttrFS{RawFileSystem: NewDefaultRawFileSystem()}
+	ms := &Server{fileSystem: fs, opts: &MountOptions{}}
+	req := &request{
+		inHeader:   &InHeader{Opcode: _OP_TMPFILE, NodeId: FUSE_ROOT_ID},
+		inData:     unsafe.Pointer(&CreateIn{}),
+		extensions: securityContextExt("security.selinux", "label"),
+	}
+	doTmpFile(ms, req)
+	if !req.status.Ok() {
+		t.Fatalf("TmpFile: %v", req.status)
+	}
+	if fs.node != 7 || fs.attr != "security.selinux" || fs.value != "label" {
+		t.Errorf("got SetXAttr(n%d, %q, %q), want (n7, security.selinux, label)", fs.node, fs.attr, fs.value)
+	}
+}
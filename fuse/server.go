@@ -12,12 +12,15 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 	"unsafe"
+
+	"github.com/hanwen/go-fuse/v2/splice"
 )
 
 const (
@@ -28,6 +31,12 @@ const (
 	// Reads from /dev/fuse that are smaller fail with EINVAL.
 	_FUSE_MIN_READ_BUFFER = 8192
 
+	// Linux kernel constants from include/uapi/linux/limits.h. The
+	// kernel sends SETXATTR in one go, and fails it with E2BIG if
+	// it does not fit the read buffer.
+	_XATTR_SIZE_MAX = 64 * 1024
+	_XATTR_NAME_MAX = 255
+
 	minMaxReaders = 2
 	maxMaxReaders = 16
 )
@@ -37,6 +46,10 @@ const (
 type Server struct {
 	// Empty if unmounted.
 	mountPoint string
+
+	// mountDir is the mount point; unlike mountPoint, it is not
+	// cleared by Unmount, so WaitMount can read it concurrently.
+	mountDir   string
 	fileSystem RawFileSystem
 
 	// writeMu serializes close and notify writes
@@ -46,6 +59,13 @@ type Server struct {
 	mountFd int
 
 	latencies LatencyMap
+	stats     serverStats
+
+	// recorder is set if MountOptions.Record is set.
+	recorder *recorder
+
+	// middleware is the MountOptions.Middleware chain, or nil.
+	middleware Handler
 
 	opts *MountOptions
 
@@ -62,9 +82,37 @@ type Server struct {
 	readPool       sync.Pool
 	reqMu          sync.Mutex
 	reqReaders     int
+	readerDone     sync.Cond // signaled with reqMu when a read finishes
 	reqInflight    []*request
 	kernelSettings InitIn
 
+	// sched queues requests if PriorityWorkers is set.
+	sched *scheduler
+
+	// ring receives requests if MountOptions.EnableIoUring is set
+	// and the kernel supports it.
+	ring *ioRing
+
+	// autoUnmount is the fusermount process that unmounts when
+	// we exit, if MountOptions.AutoUnmount is set.
+	autoUnmount *autoUnmounter
+
+	// clones are the device descriptors opened for
+	// MountOptions.CloneDevice. Serve closes them under writeMu.
+	clones []int
+
+	// cuse is set if this server handles a CUSE device rather
+	// than a mount.
+	cuse *CuseOptions
+
+	// protoMinor is the negotiated minor protocol version, ie. the
+	// lower of the kernel's and ours. It is zero until INIT has
+	// completed.
+	protoMinor uint32
+
+	// maxReadAhead is the readahead size sent in the INIT reply.
+	maxReadAhead uint32
+
 	// in-flight notify-retrieve queries
 	retrieveMu   sync.Mutex
 	retrieveNext uint64
@@ -72,12 +120,35 @@ type Server struct {
 
 	singleReader bool
 	canSplice    bool
+	spliceWrite  bool
 	loops        sync.WaitGroup
 
 	ready chan error
 
+	// mounted is closed once WaitMount has completed, with the
+	// result in mountErr.
+	mountOnce sync.Once
+	mountErr  error
+	mounted   chan struct{}
+
 	// for implementing single threaded processing.
 	requestProcessingMu sync.Mutex
+
+	// If set, new requests are refused with ENOTCONN. Protected
+	// by reqMu.
+	draining bool
+
+	destroyOnce sync.Once
+}
+
+// logf writes debug output to MountOptions.Logger, or to the
+// standard logger if it is not set.
+func (ms *Server) logf(format string, args ...interface{}) {
+	if ms.opts.Logger != nil {
+		ms.opts.Logger.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
 }
 
 // SetDebug is deprecated. Use MountOptions.Debug instead.
@@ -97,6 +168,26 @@ func (ms *Server) KernelSettings() *InitIn {
 	return &s
 }
 
+// ProtocolVersion returns the FUSE protocol version negotiated
+// with the kernel. Before the mount is initialized, it returns 0.0.
+func (ms *Server) ProtocolVersion() (major, minor uint32) {
+	ms.reqMu.Lock()
+	defer ms.reqMu.Unlock()
+	if ms.protoMinor == 0 {
+		return 0, 0
+	}
+	return _FUSE_KERNEL_VERSION, ms.protoMinor
+}
+
+// MaxReadAhead returns the maximum number of bytes the kernel reads
+// ahead of sequential reads, as negotiated at mount time. File systems
+// serving streaming data can use it to size their own buffers.
+func (ms *Server) MaxReadAhead() int {
+	ms.reqMu.Lock()
+	defer ms.reqMu.Unlock()
+	return int(ms.maxReadAhead)
+}
+
 const _MAX_NAME_LEN = 20
 
 // This type may be provided for recording latencies of each FUSE
@@ -144,9 +235,14 @@ func (ms *Server) Unmount() (err error) {
 	if err != nil {
 		return
 	}
+	ms.cancelInflight()
 	// Wait for event loops to exit.
 	ms.loops.Wait()
 	ms.mountPoint = ""
+	ms.destroy()
+	if ms.autoUnmount != nil {
+		ms.autoUnmount.release()
+	}
 	return err
 }
 
@@ -156,6 +252,47 @@ func (ms *Server) Unmount() (err error) {
 // See the "Mount styles" section in the package documentation if you want to
 // know about the inner workings of the mount process. Usually you do not.
 func NewServer(fs RawFileSystem, mountPoint string, opts *MountOptions) (*Server, error) {
+	ms, err := newServer(fs, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	mountPoint = filepath.Clean(mountPoint)
+	if !filepath.IsAbs(mountPoint) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		mountPoint = filepath.Clean(filepath.Join(cwd, mountPoint))
+	}
+	fd, au, err := mount(mountPoint, ms.opts, ms.ready)
+	if err != nil {
+		return nil, err
+	}
+	ms.autoUnmount = au
+
+	ms.mountPoint = mountPoint
+	ms.mountDir = mountPoint
+	ms.mountFd = fd
+
+	if code := ms.handleInit(); !code.Ok() {
+		syscall.Close(fd)
+		// TODO - unmount as well?
+		return nil, fmt.Errorf("init: %s", code)
+	}
+	if ms.opts.CloneDevice {
+		ms.openClones()
+	}
+
+	// This prepares for Serve being called somewhere, either
+	// synchronously or asynchronously.
+	ms.loops.Add(1)
+	return ms, nil
+}
+
+// newServer validates opts and sets up a Server that is not yet
+// connected to the kernel.
+func newServer(fs RawFileSystem, opts *MountOptions) (*Server, error) {
 	if opts == nil {
 		opts = &MountOptions{
 			MaxBackground: _DEFAULT_BACKGROUND_TASKS,
@@ -181,6 +318,10 @@ func NewServer(fs RawFileSystem, mountPoint string, opts *MountOptions) (*Server
 		o.Name = strings.Replace(name[:l], ",", ";", -1)
 	}
 
+	if o.AllowOther && o.AllowRoot {
+		return nil, fmt.Errorf("AllowOther and AllowRoot are mutually exclusive")
+	}
+
 	for _, s := range o.optionsStrings() {
 		if strings.Contains(s, ",") {
 			return nil, fmt.Errorf("found ',' in option string %q", s)
@@ -193,6 +334,9 @@ func NewServer(fs RawFileSystem, mountPoint string, opts *MountOptions) (*Server
 	} else if maxReaders > maxMaxReaders {
 		maxReaders = maxMaxReaders
 	}
+	if o.MaxReaders > 0 {
+		maxReaders = o.MaxReaders
+	}
 
 	ms := &Server{
 		fileSystem:  fs,
@@ -204,14 +348,20 @@ func NewServer(fs RawFileSystem, mountPoint string, opts *MountOptions) (*Server
 		// error-out, meaning that unmount will hang.
 		singleReader: runtime.GOOS == "darwin",
 		ready:        make(chan error, 1),
+		mounted:      make(chan struct{}),
 	}
+	ms.readerDone.L = &ms.reqMu
 	ms.reqPool.New = func() interface{} {
 		return &request{
 			cancel: make(chan struct{}),
 		}
 	}
 	ms.readPool.New = func() interface{} {
-		targetSize := o.MaxWrite + int(maxInputSize)
+		targetSize := o.MaxWrite
+		if targetSize < _XATTR_SIZE_MAX+_XATTR_NAME_MAX+1 {
+			targetSize = _XATTR_SIZE_MAX + _XATTR_NAME_MAX + 1
+		}
+		targetSize += int(maxInputSize)
 		if targetSize < _FUSE_MIN_READ_BUFFER {
 			targetSize = _FUSE_MIN_READ_BUFFER
 		}
@@ -219,40 +369,19 @@ func NewServer(fs RawFileSystem, mountPoint string, opts *MountOptions) (*Server
 		buf = alignSlice(buf, unsafe.Sizeof(WriteIn{}), logicalBlockSize, uintptr(targetSize))
 		return buf
 	}
-	mountPoint = filepath.Clean(mountPoint)
-	if !filepath.IsAbs(mountPoint) {
-		cwd, err := os.Getwd()
-		if err != nil {
-			return nil, err
-		}
-		mountPoint = filepath.Clean(filepath.Join(cwd, mountPoint))
-	}
-	fd, err := mount(mountPoint, &o, ms.ready)
-	if err != nil {
-		return nil, err
+	if o.Record != nil {
+		ms.recorder = &recorder{w: o.Record}
 	}
-
-	ms.mountPoint = mountPoint
-	ms.mountFd = fd
-
-	if code := ms.handleInit(); !code.Ok() {
-		syscall.Close(fd)
-		// TODO - unmount as well?
-		return nil, fmt.Errorf("init: %s", code)
+	if len(o.Middleware) > 0 {
+		ms.middleware = ms.chainMiddleware(o.Middleware)
 	}
-
-	// This prepares for Serve being called somewhere, either
-	// synchronously or asynchronously.
-	ms.loops.Add(1)
 	return ms, nil
 }
 
 func (o *MountOptions) optionsStrings() []string {
-	var r []string
-	r = append(r, o.Options...)
-
-	if o.AllowOther {
-		r = append(r, "allow_other")
+	r := o.kernelOptionsStrings()
+	if o.ReadOnly {
+		r = append(r, "ro")
 	}
 
 	if o.FsName != "" {
@@ -272,6 +401,24 @@ func (o *MountOptions) optionsStrings() []string {
 	return r
 }
 
+// kernelOptionsStrings returns the options that are understood by
+// the kernel's FUSE mount code.
+func (o *MountOptions) kernelOptionsStrings() []string {
+	var r []string
+	r = append(r, o.Options...)
+
+	if o.AllowOther || o.AllowRoot {
+		r = append(r, "allow_other")
+	}
+	if o.DefaultPermissions {
+		r = append(r, "default_permissions")
+	}
+	if o.MaxRead > 0 {
+		r = append(r, fmt.Sprintf("max_read=%d", o.MaxRead))
+	}
+	return r
+}
+
 // DebugData returns internal status information for debugging
 // purposes.
 func (ms *Server) DebugData() string {
@@ -302,39 +449,136 @@ func handleEINTR(fn func() error) (err error) {
 	return
 }
 
+// UnmountTimeout unmounts the file system gracefully: it stops
+// serving new requests, waits up to timeout for the requests that
+// are being processed to complete, unmounts, and then calls Destroy
+// on the file system if it implements Destroyer. Requests that arrive
+// while waiting fail with ENOTCONN, except for RELEASE and FORGET,
+// which the kernel does not resend. If unmounting fails, the file
+// system is not destroyed, and continues serving requests.
+func (ms *Server) UnmountTimeout(timeout time.Duration) error {
+	ms.reqMu.Lock()
+	ms.draining = true
+	ms.reqMu.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	var busy int
+	for {
+		ms.reqMu.Lock()
+		// Requests that are refused while draining are
+		// in-flight too, but they complete quickly.
+		busy = 0
+		for _, req := range ms.reqInflight {
+			if !req.refused {
+				busy++
+			}
+		}
+		ms.reqMu.Unlock()
+		if busy == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Unmounting may need the kernel to talk to us.
+	ms.reqMu.Lock()
+	ms.draining = false
+	ms.reqMu.Unlock()
+
+	if busy > 0 {
+		return fmt.Errorf("timed out waiting for %d requests", busy)
+	}
+
+	return ms.Unmount()
+}
+
+// cancelInflight cancels the requests that are being served, as
+// their replies can no longer be delivered after unmounting.
+func (ms *Server) cancelInflight() {
+	ms.reqMu.Lock()
+	defer ms.reqMu.Unlock()
+	for _, req := range ms.reqInflight {
+		if !req.interrupted {
+			close(req.cancel)
+			req.interrupted = true
+		}
+	}
+}
+
+func (ms *Server) destroy() {
+	ms.destroyOnce.Do(func() {
+		if d, ok := ms.fileSystem.(Destroyer); ok {
+			d.Destroy()
+		}
+	})
+}
+
 // Returns a new request, or error. In case exitIdle is given, returns
-// nil, OK if we have too many readers already.
+// nil, OK if we have too many readers already; otherwise waits until
+// there is room for another reader.
 func (ms *Server) readRequest(exitIdle bool) (req *request, code Status) {
-	req = ms.reqPool.Get().(*request)
-	dest := ms.readPool.Get().([]byte)
-
 	ms.reqMu.Lock()
-	if ms.reqReaders > ms.maxReaders {
-		ms.reqMu.Unlock()
-		return nil, OK
+	for ms.reqReaders >= ms.maxReaders {
+		if exitIdle {
+			ms.reqMu.Unlock()
+			return nil, OK
+		}
+		ms.readerDone.Wait()
 	}
 	ms.reqReaders++
 	ms.reqMu.Unlock()
 
+	req, code = ms.readRequestFd(ms.mountFd)
+
+	ms.reqMu.Lock()
+	defer ms.reqMu.Unlock()
+	ms.reqReaders--
+	ms.readerDone.Signal()
+	// With a full scheduler queue, further readers would only
+	// add to the held requests.
+	if code.Ok() && !ms.singleReader && ms.reqReaders <= 0 &&
+		(ms.sched == nil || !ms.sched.full()) {
+		ms.loops.Add(1)
+		go ms.loop(true)
+	}
+	return req, code
+}
+
+// readRequestFd reads a request from the device descriptor fd, and
+// registers it as in flight.
+func (ms *Server) readRequestFd(fd int) (req *request, code Status) {
+	req = ms.reqPool.Get().(*request)
+	dest := ms.readPool.Get().([]byte)
+
 	var n int
-	err := handleEINTR(func() error {
-		var err error
-		n, err = syscall.Read(ms.mountFd, dest)
-		return err
-	})
+	var err error
+	if ms.spliceWrite {
+		n, err = ms.readSplice(fd, req, dest)
+	} else {
+		err = handleEINTR(func() error {
+			var err error
+			n, err = syscall.Read(fd, dest)
+			return err
+		})
+	}
+	if err == nil && n == 0 {
+		// The other end of a socket, as used by Replay, was
+		// closed.
+		err = syscall.ENODEV
+	}
 	if err != nil {
-		code = ToStatus(err)
 		ms.reqPool.Put(req)
-		ms.reqMu.Lock()
-		ms.reqReaders--
-		ms.reqMu.Unlock()
-		return nil, code
+		return nil, ToStatus(err)
 	}
 
-	if ms.latencies != nil {
+	if ms.latencies != nil || ms.opts.Debug || ms.opts.EnableStats || ms.opts.OpHook != nil {
 		req.startTime = time.Now()
 	}
+	if ms.recorder != nil {
+		ms.recorder.record(recordRequest, dest[:n])
+	}
 	gobbled := req.setInput(dest[:n])
+	req.fd = fd
 
 	ms.reqMu.Lock()
 	defer ms.reqMu.Unlock()
@@ -343,20 +587,25 @@ func (ms *Server) readRequest(exitIdle bool) (req *request, code Status) {
 		return nil, status
 	}
 	req.inflightIndex = len(ms.reqInflight)
+	req.refused = ms.draining && refusable(req.inHeader.Opcode)
 	ms.reqInflight = append(ms.reqInflight, req)
 	if !gobbled {
 		ms.readPool.Put(dest)
 		dest = nil
 	}
-	ms.reqReaders--
-	if !ms.singleReader && ms.reqReaders <= 0 {
-		ms.loops.Add(1)
-		go ms.loop(true)
-	}
-
 	return req, OK
 }
 
+// devFd returns the device descriptor that takes the reply to req:
+// the one it was read from, or the mount descriptor for
+// notifications.
+func (ms *Server) devFd(req *request) int {
+	if req.fd != 0 {
+		return req.fd
+	}
+	return ms.mountFd
+}
+
 // returnRequest returns a request to the pool of unused requests.
 func (ms *Server) returnRequest(req *request) {
 	ms.reqMu.Lock()
@@ -371,7 +620,15 @@ func (ms *Server) returnRequest(req *request) {
 	interrupted := req.interrupted
 	ms.reqMu.Unlock()
 
+	if req.splicePair != nil {
+		splice.Done(req.splicePair)
+		req.splicePair = nil
+	}
+
 	ms.recordStats(req)
+	if ms.opts.OpHook != nil {
+		ms.logOp(req)
+	}
 	if interrupted {
 		// Don't reposses data, because someone might still
 		// be looking at it
@@ -393,11 +650,17 @@ func (ms *Server) returnRequest(req *request) {
 }
 
 func (ms *Server) recordStats(req *request) {
+	if ms.latencies == nil && !ms.opts.EnableStats {
+		return
+	}
+	dt := time.Now().Sub(req.startTime)
 	if ms.latencies != nil {
-		dt := time.Now().Sub(req.startTime)
 		opname := operationName(req.inHeader.Opcode)
 		ms.latencies.Add(opname, dt)
 	}
+	if ms.opts.EnableStats {
+		ms.stats.add(req.inHeader.Opcode, req.status, dt)
+	}
 }
 
 // Serve initiates the FUSE loop. Normally, callers should run Serve()
@@ -406,11 +669,33 @@ func (ms *Server) recordStats(req *request) {
 //
 // Each filesystem operation executes in a separate goroutine.
 func (ms *Server) Serve() {
+	go ms.WaitMount()
+	if ms.opts.PriorityWorkers > 0 {
+		ms.sched = newScheduler(ms, ms.opts.PriorityWorkers, ms.opts.RequestPriority, ms.opts.MaxQueuedRequests)
+	}
+	if ms.KernelSettings().Flags2&CAP2_OVER_IO_URING != 0 {
+		ms.startRing()
+	}
+	if ms.opts.CloneDevice {
+		ms.startClones()
+	}
 	ms.loop(false)
+	ms.cancelInflight()
 	ms.loops.Wait()
+	ms.destroy()
+	if ms.autoUnmount != nil {
+		ms.autoUnmount.release()
+	}
+	if ms.sched != nil {
+		ms.sched.stop()
+	}
 
 	ms.writeMu.Lock()
 	syscall.Close(ms.mountFd)
+	for _, fd := range ms.clones {
+		syscall.Close(fd)
+	}
+	ms.clones = nil
 	ms.writeMu.Unlock()
 
 	// shutdown in-flight cache retrieves.
@@ -442,14 +727,18 @@ func (ms *Server) handleInit() Status {
 	// and don't spawn new readers.
 	orig := ms.singleReader
 	ms.singleReader = true
-	req, errNo := ms.readRequest(false)
-	ms.singleReader = orig
-
-	if errNo != OK || req == nil {
-		return errNo
-	}
-	if code := ms.handleRequest(req); !code.Ok() {
-		return code
+	defer func() { ms.singleReader = orig }()
+
+	// If the kernel speaks a newer major version, we answer with
+	// ours, and it retries INIT with our major version.
+	for ms.protoMinor == 0 {
+		req, errNo := ms.readRequest(false)
+		if errNo != OK || req == nil {
+			return errNo
+		}
+		if code := ms.handleRequest(req); !code.Ok() {
+			return code
+		}
 	}
 
 	// INIT is handled. Init the file system, but don't accept
@@ -473,7 +762,7 @@ exit:
 		case ENODEV:
 			// unmount
 			if ms.opts.Debug {
-				log.Printf("received ENODEV (unmount request), thread exiting")
+				ms.logf("received ENODEV (unmount request), thread exiting")
 			}
 			break exit
 		default: // some other error?
@@ -481,7 +770,9 @@ exit:
 			break exit
 		}
 
-		if ms.singleReader {
+		if ms.sched != nil {
+			ms.sched.push(req)
+		} else if ms.singleReader {
 			go ms.handleRequest(req)
 		} else {
 			ms.handleRequest(req)
@@ -489,6 +780,31 @@ exit:
 	}
 }
 
+// cloneLoop reads requests from a cloned device descriptor, and
+// handles each in its own goroutine.
+func (ms *Server) cloneLoop(fd int) {
+	defer ms.loops.Done()
+	for {
+		req, errNo := ms.readRequestFd(fd)
+		switch errNo {
+		case OK:
+		case ENOENT:
+			continue
+		case ENODEV:
+			return
+		default:
+			log.Printf("Failed to read from cloned fuse conn: %v", errNo)
+			return
+		}
+
+		if ms.sched != nil {
+			ms.sched.push(req)
+		} else {
+			go ms.handleRequest(req)
+		}
+	}
+}
+
 func (ms *Server) handleRequest(req *request) Status {
 	if ms.opts.SingleThreaded {
 		ms.requestProcessingMu.Lock()
@@ -501,7 +817,27 @@ func (ms *Server) handleRequest(req *request) Status {
 	}
 
 	if req.status.Ok() && ms.opts.Debug {
-		log.Println(req.InputDebug())
+		in := req.InputDebug()
+		if p, ok := ms.fileSystem.(NodePather); ok && req.inHeader.NodeId != 0 {
+			if path := p.NodePath(req.inHeader.NodeId); path != "" {
+				in += fmt.Sprintf(" [%s]", path)
+			}
+		}
+		ms.logf("%s", in)
+	}
+
+	if req.status.Ok() && req.handler.MinMinor > ms.protoMinor && req.inHeader.Opcode != _OP_INIT {
+		// Newer kernels send requests that older ones don't know
+		// about, with struct layouts we might not parse correctly.
+		log.Printf("Opcode %v requires protocol 7.%d, negotiated 7.%d",
+			operationName(req.inHeader.Opcode), req.handler.MinMinor, ms.protoMinor)
+		req.status = ENOSYS
+	}
+	if req.status.Ok() && ms.opts.AllowRoot && !allowedCaller(req) {
+		req.status = EACCES
+	}
+	if req.status.Ok() && req.refused {
+		req.status = ENOTCONN
 	}
 
 	if req.inHeader.NodeId == pollHackInode ||
@@ -511,7 +847,13 @@ func (ms *Server) handleRequest(req *request) Status {
 		log.Printf("Unimplemented opcode %v", operationName(req.inHeader.Opcode))
 		req.status = ENOSYS
 	} else if req.status.Ok() {
-		req.handler.Func(ms, req)
+		if timeout := ms.requestTimeout(req); timeout > 0 {
+			if done := ms.serveTimeout(req, timeout); done != nil {
+				return ms.abandon(req, done)
+			}
+		} else {
+			ms.serve(req)
+		}
 	}
 
 	errNo := ms.write(req)
@@ -530,6 +872,172 @@ func (ms *Server) handleRequest(req *request) Status {
 	return Status(errNo)
 }
 
+// serve runs the handler for req, through the middleware if there
+// is one. A panic in the handler fails the request with EIO, unless
+// MountOptions.RethrowPanics is set.
+func (ms *Server) serve(req *request) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic serving %s: %v\n%s",
+				operationName(req.inHeader.Opcode), r, debug.Stack())
+			if ms.opts.RethrowPanics {
+				panic(r)
+			}
+			req.status = EIO
+			req.cause = fmt.Errorf("panic: %v", r)
+			req.flatData = nil
+			req.fdData = nil
+		}
+	}()
+	if ms.opts.Debug || ms.opts.OpHook != nil {
+		req.startFailures()
+		defer req.endFailures()
+	}
+	if ms.middleware != nil && !bypassMiddleware(req.inHeader.Opcode) {
+		ms.dispatch(req)
+	} else {
+		req.handler.Func(ms, req)
+	}
+}
+
+// requestTimeout returns the MountOptions.RequestTimeout for req, or
+// 0 if it has none.
+func (ms *Server) requestTimeout(req *request) time.Duration {
+	if ms.opts.RequestTimeout == nil || ms.opts.SingleThreaded {
+		return 0
+	}
+	switch req.inHeader.Opcode {
+	case _OP_INIT, _OP_DESTROY, _OP_FORGET, _OP_BATCH_FORGET,
+		_OP_INTERRUPT, _OP_NOTIFY_REPLY:
+		return 0
+	case _OP_READDIRPLUS:
+		// Each entry takes a lookup reference, and we cannot
+		// tell which entries a late reply would have held.
+		return 0
+	}
+	return ms.opts.RequestTimeout(operationName(req.inHeader.Opcode))
+}
+
+// serveTimeout serves req in a separate goroutine. It returns nil if
+// the handler finished within timeout, or else a channel that is
+// closed when it does.
+func (ms *Server) serveTimeout(req *request, timeout time.Duration) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		ms.serve(req)
+		close(done)
+	}()
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-t.C:
+		return done
+	}
+}
+
+// abandon replies EIO to a request whose handler is still running.
+// The request is marked interrupted, so it is not reused, and the
+// handler's result is dropped once done is closed.
+func (ms *Server) abandon(req *request, done chan struct{}) Status {
+	ms.reqMu.Lock()
+	if !req.interrupted {
+		close(req.cancel)
+		req.interrupted = true
+	}
+	ms.reqMu.Unlock()
+
+	// The handler still writes to req, so reply from a copy.
+	reply := &request{
+		inHeader:  req.inHeader,
+		inData:    req.inData,
+		handler:   req.handler,
+		status:    EIO,
+		ringEntry: req.ringEntry,
+		fd:        req.fd,
+	}
+	if ms.opts.Debug {
+		ms.logf("request %d timed out", req.inHeader.Unique)
+	}
+	errNo := ms.write(reply)
+	if errNo != 0 {
+		log.Printf("writer: Write/Writev failed, err: %v. opcode: %v",
+			errNo, operationName(req.inHeader.Opcode))
+	}
+
+	go func() {
+		<-done
+		ms.undo(req)
+		ms.returnRequest(req)
+	}()
+	return errNo
+}
+
+// undo reverts the effect of a request that succeeded after the
+// kernel was told it failed: the kernel never learns about the node
+// IDs and file handles in the dropped reply, so it will never FORGET
+// or RELEASE them.
+func (ms *Server) undo(req *request) {
+	if !req.status.Ok() {
+		return
+	}
+	releaseIn := func(opcode uint32, fh uint64, flags uint32) *ReleaseIn {
+		in := &ReleaseIn{InHeader: *req.inHeader, Fh: fh, Flags: flags}
+		in.Opcode = opcode
+		return in
+	}
+	forget := func(out *EntryOut) {
+		if out.NodeId != 0 {
+			ms.fileSystem.Forget(out.NodeId, 1)
+		}
+	}
+
+	switch req.inHeader.Opcode {
+	case _OP_LOOKUP, _OP_MKNOD, _OP_MKDIR, _OP_SYMLINK, _OP_LINK:
+		forget((*EntryOut)(req.outData()))
+	case _OP_CREATE, _OP_TMPFILE:
+		out := (*CreateOut)(req.outData())
+		ms.fileSystem.Release(nil, releaseIn(_OP_RELEASE, out.Fh, (*CreateIn)(req.inData).Flags))
+		forget(&out.EntryOut)
+	case _OP_OPEN:
+		out := (*OpenOut)(req.outData())
+		ms.fileSystem.Release(nil, releaseIn(_OP_RELEASE, out.Fh, (*OpenIn)(req.inData).Flags))
+	case _OP_OPENDIR:
+		out := (*OpenOut)(req.outData())
+		ms.fileSystem.ReleaseDir(releaseIn(_OP_RELEASEDIR, out.Fh, (*OpenIn)(req.inData).Flags))
+	}
+}
+
+// refusable returns whether a request with the given opcode may be
+// refused while draining. The kernel does not resend RELEASE and
+// FORGET, so refusing them would leak the handle or node.
+func refusable(opcode uint32) bool {
+	switch opcode {
+	case _OP_RELEASE, _OP_RELEASEDIR, _OP_FORGET, _OP_BATCH_FORGET,
+		_OP_INTERRUPT, _OP_DESTROY, _OP_NOTIFY_REPLY:
+		return false
+	}
+	return true
+}
+
+// allowedCaller returns whether the request may be served if
+// AllowRoot is set. Requests on already opened files, and requests that
+// do not originate from a user are always allowed.
+func allowedCaller(req *request) bool {
+	switch req.inHeader.Opcode {
+	case _OP_INIT, _OP_CUSE_INIT, _OP_READ, _OP_WRITE, _OP_FLUSH, _OP_FSYNC,
+		_OP_RELEASE, _OP_GETLK, _OP_SETLK, _OP_SETLKW, _OP_FALLOCATE, _OP_LSEEK,
+		_OP_READDIR, _OP_READDIRPLUS, _OP_FSYNCDIR, _OP_RELEASEDIR,
+		_OP_FORGET, _OP_BATCH_FORGET, _OP_INTERRUPT, _OP_DESTROY,
+		_OP_NOTIFY_REPLY, _OP_IOCTL, _OP_POLL:
+		return true
+	}
+	uid := req.inHeader.Caller.Uid
+	return uid == 0 || uid == uint32(os.Getuid())
+}
+
 // alignSlice ensures that the byte at alignedByte is aligned with the
 // given logical block size.  The input slice should be at least (size
 // + blockSize)
@@ -558,6 +1066,10 @@ func (ms *Server) write(req *request) Status {
 	// Forget/NotifyReply do not wait for reply from filesystem server.
 	switch req.inHeader.Opcode {
 	case _OP_FORGET, _OP_BATCH_FORGET, _OP_NOTIFY_REPLY:
+		if req.ringEntry != nil {
+			// The kernel waits for the entry to come back.
+			return req.ringEntry.replyStatus(req.inHeader.Unique, OK)
+		}
 		return OK
 	case _OP_INTERRUPT:
 		if req.status.Ok() {
@@ -567,17 +1079,57 @@ func (ms *Server) write(req *request) Status {
 
 	header := req.serializeHeader(req.flatDataSize())
 	if ms.opts.Debug {
-		log.Println(req.OutputDebug())
+		ms.logf("%s", req.OutputDebug())
 	}
 
 	if header == nil {
 		return OK
 	}
 
+	if ms.recorder != nil {
+		if req.fdData != nil {
+			// Recording needs the reply data in memory.
+			buf := ms.allocOut(req, uint32(req.flatDataSize()))
+			req.flatData, req.status = req.fdData.Bytes(buf)
+			req.fdData = nil
+			header = req.serializeHeader(len(req.flatData))
+		}
+		ms.recorder.record(recordReply, header, req.flatData)
+	}
+
+	if req.ringEntry != nil {
+		return req.ringEntry.reply(req, header)
+	}
 	s := ms.systemWrite(req, header)
 	return s
 }
 
+// PollWakeupNotify wakes up poll(2) callers waiting on the file
+// handle that was registered with the given kernel handle. See
+// Poller.
+func (ms *Server) PollWakeupNotify(kh uint64) Status {
+	req := request{
+		inHeader: &InHeader{
+			Opcode: _OP_NOTIFY_POLL,
+		},
+		handler: operationHandlers[_OP_NOTIFY_POLL],
+		status:  NOTIFY_POLL,
+	}
+
+	entry := (*NotifyPollWakeupOut)(req.outData())
+	entry.Kh = kh
+
+	// Protect against concurrent close.
+	ms.writeMu.Lock()
+	result := ms.write(&req)
+	ms.writeMu.Unlock()
+
+	if ms.opts.Debug {
+		ms.logf("Response: POLL_NOTIFY %v", result)
+	}
+	return result
+}
+
 // InodeNotify invalidates the information associated with the inode
 // (ie. data cache, attributes, etc.)
 func (ms *Server) InodeNotify(node uint64, off int64, length int64) Status {
@@ -604,7 +1156,7 @@ func (ms *Server) InodeNotify(node uint64, off int64, length int64) Status {
 	ms.writeMu.Unlock()
 
 	if ms.opts.Debug {
-		log.Println("Response: INODE_NOTIFY", result)
+		ms.logf("Response: INODE_NOTIFY %v", result)
 	}
 	return result
 }
@@ -663,7 +1215,7 @@ func (ms *Server) inodeNotifyStoreCache32(node uint64, offset int64, data []byte
 	ms.writeMu.Unlock()
 
 	if ms.opts.Debug {
-		log.Printf("Response: INODE_NOTIFY_STORE_CACHE: %v", result)
+		ms.logf("Response: INODE_NOTIFY_STORE_CACHE: %v", result)
 	}
 	return result
 }
@@ -677,6 +1229,9 @@ func (ms *Server) inodeNotifyStoreCache32(node uint64, offset int64, data []byte
 //
 // The kernel returns ENOENT if it does not currently have entry for this inode
 // in its dentry cache.
+//
+// With EnableIoUring, the kernel does not deliver its reply, and ENOSYS is
+// returned.
 func (ms *Server) InodeRetrieveCache(node uint64, offset int64, dest []byte) (n int, st Status) {
 	// the kernel won't send us in one go more then what we negotiated as MaxWrite.
 	// retrieve the data in chunks.
@@ -708,7 +1263,8 @@ func (ms *Server) InodeRetrieveCache(node uint64, offset int64, dest []byte) (n
 // inodeRetrieveCache1 is internal worker for InodeRetrieveCache which
 // actually talks to kernel and retrieves chunks not larger than ms.opts.MaxWrite.
 func (ms *Server) inodeRetrieveCache1(node uint64, offset int64, dest []byte) (n int, st Status) {
-	if !ms.kernelSettings.SupportsNotify(NOTIFY_RETRIEVE_CACHE) {
+	if !ms.kernelSettings.SupportsNotify(NOTIFY_RETRIEVE_CACHE) ||
+		ms.kernelSettings.Flags2&CAP2_OVER_IO_URING != 0 {
 		return 0, ENOSYS
 	}
 
@@ -755,7 +1311,7 @@ func (ms *Server) inodeRetrieveCache1(node uint64, offset int64, dest []byte) (n
 	ms.writeMu.Unlock()
 
 	if ms.opts.Debug {
-		log.Printf("Response: NOTIFY_RETRIEVE_CACHE: %v", result)
+		ms.logf("Response: NOTIFY_RETRIEVE_CACHE: %v", result)
 	}
 	if result != OK {
 		ms.retrieveMu.Lock()
@@ -830,7 +1386,7 @@ func (ms *Server) DeleteNotify(parent uint64, child uint64, name string) Status
 	ms.writeMu.Unlock()
 
 	if ms.opts.Debug {
-		log.Printf("Response: DELETE_NOTIFY: %v", result)
+		ms.logf("Response: DELETE_NOTIFY: %v", result)
 	}
 	return result
 }
@@ -866,7 +1422,7 @@ func (ms *Server) EntryNotify(parent uint64, name string) Status {
 	ms.writeMu.Unlock()
 
 	if ms.opts.Debug {
-		log.Printf("Response: ENTRY_NOTIFY: %v", result)
+		ms.logf("Response: ENTRY_NOTIFY: %v", result)
 	}
 	return result
 }
@@ -896,17 +1452,39 @@ func (in *InitIn) SupportsNotify(notifyType int) bool {
 // WaitMount waits for the first request to be served. Use this to
 // avoid racing between accessing the (empty or not yet mounted)
 // mountpoint, and the OS trying to setup the user-space mount.
+// It may be called any number of times, and from several goroutines;
+// all calls return the same result. Serve must be running.
 func (ms *Server) WaitMount() error {
+	ms.mountOnce.Do(func() {
+		ms.mountErr = ms.waitMount()
+		close(ms.mounted)
+	})
+	return ms.mountErr
+}
+
+// Mounted returns a channel that is closed once the mount point is
+// usable, or mounting failed. Serve waits for the mount in the
+// background, so the channel is closed shortly after Serve starts.
+// Use WaitMount to retrieve the error, if any.
+func (ms *Server) Mounted() <-chan struct{} {
+	return ms.mounted
+}
+
+func (ms *Server) waitMount() error {
+	if ms.cuse != nil {
+		// The device is created when CUSE_INIT is answered.
+		return nil
+	}
 	err := <-ms.ready
 	if err != nil {
 		return err
 	}
-	if parseFuseFd(ms.mountPoint) >= 0 {
+	if parseFuseFd(ms.mountDir) >= 0 {
 		// Magic `/dev/fd/N` mountpoint. We don't know the real mountpoint, so
 		// we cannot run the poll hack.
 		return nil
 	}
-	return pollHack(ms.mountPoint)
+	return pollHack(ms.mountDir)
 }
 
 // parseFuseFd checks if `mountPoint` is the special form /dev/fd/N (with N >= 0),
@@ -30,3 +30,19 @@ func (ms *Server) systemWrite(req *request, header []byte) Status {
 	}
 	return ToStatus(err)
 }
+
+// RegisterBackingFd is not supported on Darwin.
+func (ms *Server) RegisterBackingFd(fd int) (int32, Status) {
+	return 0, ENOSYS
+}
+
+// UnregisterBackingFd is not supported on Darwin.
+func (ms *Server) UnregisterBackingFd(id int32) Status {
+	return ENOSYS
+}
+
+// openClones does nothing on Darwin, which cannot clone the device.
+func (ms *Server) openClones() {}
+
+// startClones does nothing on Darwin, which cannot clone the device.
+func (ms *Server) startClones() {}
@@ -6,13 +6,15 @@ package fuse
 
 import (
 	"log"
+	"runtime"
 	"syscall"
+	"unsafe"
 )
 
 func (ms *Server) systemWrite(req *request, header []byte) Status {
 	if req.flatDataSize() == 0 {
 		err := handleEINTR(func() error {
-			_, err := syscall.Write(ms.mountFd, header)
+			_, err := syscall.Write(ms.devFd(req), header)
 			return err
 		})
 		return ToStatus(err)
@@ -34,9 +36,99 @@ func (ms *Server) systemWrite(req *request, header []byte) Status {
 		header = req.serializeHeader(len(req.flatData))
 	}
 
-	_, err := writev(ms.mountFd, [][]byte{header, req.flatData})
+	_, err := writev(ms.devFd(req), [][]byte{header, req.flatData})
 	if req.readResult != nil {
 		req.readResult.Done()
 	}
 	return ToStatus(err)
 }
+
+// backingMap is struct fuse_backing_map from the kernel.
+type backingMap struct {
+	Fd      int32
+	Flags   uint32
+	Padding uint64
+}
+
+const (
+	_FUSE_DEV_IOC_CLONE         = 0x8004e500
+	_FUSE_DEV_IOC_BACKING_OPEN  = 0x4010e501
+	_FUSE_DEV_IOC_BACKING_CLOSE = 0x4004e502
+)
+
+// RegisterBackingFd registers the open file fd as a backing file for
+// passthrough, see MountOptions.EnablePassthrough. The returned ID
+// goes into OpenOut.BackingId, along with FOPEN_PASSTHROUGH. The
+// kernel keeps a reference to the file for each handle opened with
+// it, so fd may be closed after registering.
+func (ms *Server) RegisterBackingFd(fd int) (int32, Status) {
+	m := backingMap{Fd: int32(fd)}
+	id, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(ms.mountFd),
+		_FUSE_DEV_IOC_BACKING_OPEN, uintptr(unsafe.Pointer(&m)))
+	if errno != 0 {
+		return 0, ToStatus(errno)
+	}
+	return int32(id), OK
+}
+
+// UnregisterBackingFd drops a backing file ID. Handles that were
+// opened with it keep working.
+func (ms *Server) UnregisterBackingFd(id int32) Status {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(ms.mountFd),
+		_FUSE_DEV_IOC_BACKING_CLOSE, uintptr(unsafe.Pointer(&id)))
+	if errno != 0 {
+		return ToStatus(errno)
+	}
+	return OK
+}
+
+// cloneDevice opens /dev/fuse, and attaches it to the connection of
+// the mount descriptor.
+func (ms *Server) cloneDevice() (int, error) {
+	fd, err := syscall.Open("/dev/fuse", syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, err
+	}
+	src := uint32(ms.mountFd)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd),
+		_FUSE_DEV_IOC_CLONE, uintptr(unsafe.Pointer(&src))); errno != 0 {
+		syscall.Close(fd)
+		return -1, errno
+	}
+	return fd, nil
+}
+
+// openClones opens a cloned descriptor for each CPU beyond the
+// first, which the mount descriptor serves. If cloning fails, the
+// remaining requests go to the mount descriptor. It runs before
+// Serve, so the descriptors are in place before any reader starts.
+func (ms *Server) openClones() {
+	var clones []int
+	for i := 1; i < runtime.GOMAXPROCS(0); i++ {
+		fd, err := ms.cloneDevice()
+		if err != nil {
+			ms.logf("FUSE_DEV_IOC_CLONE: %v", err)
+			break
+		}
+		clones = append(clones, fd)
+	}
+	ms.writeMu.Lock()
+	ms.clones = clones
+	ms.writeMu.Unlock()
+}
+
+// cloneFds returns the descriptors opened by openClones that are
+// still open.
+func (ms *Server) cloneFds() []int {
+	ms.writeMu.Lock()
+	defer ms.writeMu.Unlock()
+	return append([]int(nil), ms.clones...)
+}
+
+// startClones starts a reader on each cloned descriptor.
+func (ms *Server) startClones() {
+	for _, fd := range ms.cloneFds() {
+		ms.loops.Add(1)
+		go ms.cloneLoop(fd)
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCloneDevice(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	dir, err := ioutil.TempDir("", "TestCloneDevice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv, err := NewServer(&uringFS{NewDefaultRawFileSystem()}, dir, &MountOptions{
+		CloneDevice: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Unmount()
+
+	if clones := srv.cloneFds(); len(clones) != 3 {
+		t.Fatalf("got %d cloned descriptors, want 3", len(clones))
+	}
+
+	// The kernel hands requests to any of the descriptors, and
+	// only accepts replies on the one the request was read from.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				content, err := ioutil.ReadFile(dir + "/file")
+				if err != nil || string(content) != "hello" {
+					t.Errorf("ReadFile: %q, %v", content, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type hangingReadFS struct {
+	uringFS
+	canceled chan struct{}
+	release  chan struct{}
+}
+
+func (fs *hangingReadFS) Read(cancel <-chan struct{}, in *ReadIn, buf []byte) (ReadResult, Status) {
+	<-cancel
+	select {
+	case fs.canceled <- struct{}{}:
+	default:
+	}
+	// Signals to the reading thread interrupt the request too, so
+	// only reply once the test is done.
+	<-fs.release
+	return ReadResultData([]byte("late")), OK
+}
+
+func TestRequestTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestRequestTimeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := &hangingReadFS{
+		uringFS:  uringFS{NewDefaultRawFileSystem()},
+		canceled: make(chan struct{}, 1),
+		release:  make(chan struct{}),
+	}
+	srv, err := NewServer(fs, dir, &MountOptions{
+		RequestTimeout: func(op string) time.Duration {
+			if op == "READ" {
+				return 100 * time.Millisecond
+			}
+			return 0
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Unmount()
+	defer close(fs.release)
+
+	f, err := os.Open(dir + "/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = f.Read(make([]byte, 5))
+	if pe, ok := err.(*os.PathError); !ok || pe.Err != syscall.EIO {
+		t.Fatalf("Read: got %v, want EIO", err)
+	}
+	select {
+	case <-fs.canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not canceled")
+	}
+}
+
+type lateSuccessFS struct {
+	uringFS
+	release  chan struct{}
+	forgets  chan uint64
+	releases chan uint64
+}
+
+func (fs *lateSuccessFS) Lookup(cancel <-chan struct{}, header *InHeader, name string, out *EntryOut) Status {
+	if name != "late" {
+		return fs.uringFS.Lookup(cancel, header, name, out)
+	}
+	<-fs.release
+	out.NodeId = 3
+	out.Mode = S_IFREG | 0644
+	return OK
+}
+
+func (fs *lateSuccessFS) Open(cancel <-chan struct{}, in *OpenIn, out *OpenOut) Status {
+	<-fs.release
+	out.Fh = 42
+	return OK
+}
+
+func (fs *lateSuccessFS) Forget(nodeid, nlookup uint64) {
+	fs.forgets <- nodeid
+}
+
+func (fs *lateSuccessFS) Release(cancel <-chan struct{}, in *ReleaseIn) {
+	fs.releases <- in.Fh
+}
+
+func TestRequestTimeoutLateSuccess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestRequestTimeoutLateSuccess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := &lateSuccessFS{
+		uringFS:  uringFS{NewDefaultRawFileSystem()},
+		release:  make(chan struct{}),
+		forgets:  make(chan uint64, 10),
+		releases: make(chan uint64, 10),
+	}
+	srv, err := NewServer(fs, dir, &MountOptions{
+		RequestTimeout: func(op string) time.Duration {
+			if op == "LOOKUP" || op == "OPEN" {
+				return 100 * time.Millisecond
+			}
+			return 0
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Unmount()
+
+	// Both requests time out while their handlers are blocked.
+	released := false
+	defer func() {
+		if !released {
+			close(fs.release)
+		}
+	}()
+	if _, err := os.Lstat(dir + "/late"); err == nil || err.(*os.PathError).Err != syscall.EIO {
+		t.Fatalf("Lstat: got %v, want EIO", err)
+	}
+	if _, err := os.Open(dir + "/file"); err == nil || err.(*os.PathError).Err != syscall.EIO {
+		t.Fatalf("Open: got %v, want EIO", err)
+	}
+
+	close(fs.release)
+	released = true
+	select {
+	case id := <-fs.forgets:
+		if id != 3 {
+			t.Errorf("got Forget(%d), want Forget(3)", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("late LOOKUP was not forgotten")
+	}
+	select {
+	case fh := <-fs.releases:
+		if fh != 42 {
+			t.Errorf("got Release of handle %d, want 42", fh)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("late OPEN was not released")
+	}
+}
+
+type interruptedReadFS struct {
+	uringFS
+	canceled chan uint64
+}
+
+func (fs *interruptedReadFS) Open(cancel <-chan struct{}, in *OpenIn, out *OpenOut) Status {
+	// Reads that go through the page cache are asynchronous, and
+	// the kernel does not interrupt them.
+	out.OpenFlags = FOPEN_DIRECT_IO
+	return OK
+}
+
+func (fs *interruptedReadFS) Read(cancel <-chan struct{}, in *ReadIn, buf []byte) (ReadResult, Status) {
+	<-cancel
+	fs.canceled <- in.Unique
+	return nil, EINTR
+}
+
+func TestMaxQueuedRequestsInterrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestMaxQueuedRequestsInterrupt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := &interruptedReadFS{
+		uringFS:  uringFS{NewDefaultRawFileSystem()},
+		canceled: make(chan uint64, 10),
+	}
+	srv, err := NewServer(fs, dir, &MountOptions{
+		PriorityWorkers:   1,
+		MaxQueuedRequests: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Unmount()
+
+	// The first process occupies the worker, the next four fill
+	// the queue, and the others are held, enough to occupy all
+	// reader goroutines if they stopped reading.
+	var cmds []*exec.Cmd
+	for i := 0; i < 8; i++ {
+		cmd := exec.Command("cat", dir+"/file")
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		defer cmd.Wait()
+		cmds = append(cmds, cmd)
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Killing the first process interrupts its READ, which the
+	// server must read, even though its queue is full.
+	cmds[0].Process.Kill()
+	select {
+	case <-fs.canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("READ was not interrupted")
+	}
+	for _, cmd := range cmds[1:] {
+		cmd.Process.Kill()
+	}
+}
+
+type panickingFS struct {
+	uringFS
+}
+
+func (fs *panickingFS) Read(cancel <-chan struct{}, in *ReadIn, buf []byte) (ReadResult, Status) {
+	panic("bug in Read")
+}
+
+func TestHandlerPanic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestHandlerPanic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv, err := NewServer(&panickingFS{uringFS{NewDefaultRawFileSystem()}}, dir, &MountOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Unmount()
+
+	_, err = ioutil.ReadFile(dir + "/file")
+	if pe, ok := err.(*os.PathError); !ok || pe.Err != syscall.EIO {
+		t.Fatalf("ReadFile: got %v, want EIO", err)
+	}
+
+	// The server survives the panic.
+	if _, err := os.Stat(dir + "/file"); err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+}
+
+type blockingReadFS struct {
+	uringFS
+	started chan struct{}
+	release chan struct{}
+}
+
+func (fs *blockingReadFS) Open(cancel <-chan struct{}, in *OpenIn, out *OpenOut) Status {
+	// Bypass the page cache, so concurrent reads all reach us.
+	out.OpenFlags = FOPEN_DIRECT_IO
+	return OK
+}
+
+func (fs *blockingReadFS) Read(cancel <-chan struct{}, in *ReadIn, buf []byte) (ReadResult, Status) {
+	fs.started <- struct{}{}
+	<-fs.release
+	return fs.uringFS.Read(cancel, in, buf)
+}
+
+func TestMaxReaders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestMaxReaders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const maxReaders, clients = 2, 8
+	fs := &blockingReadFS{
+		uringFS: uringFS{NewDefaultRawFileSystem()},
+		started: make(chan struct{}, clients),
+		release: make(chan struct{}),
+	}
+	srv, err := NewServer(fs, dir, &MountOptions{MaxReaders: maxReaders})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Unmount()
+
+	readers := func() int {
+		srv.reqMu.Lock()
+		defer srv.reqMu.Unlock()
+		return srv.reqReaders
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ioutil.ReadFile(dir + "/file"); err != nil {
+				t.Errorf("ReadFile: %v", err)
+			}
+		}()
+	}
+
+	// Each blocked read holds its goroutine, so new readers are
+	// started for the requests after it.
+	for i := 0; i < clients; i++ {
+		<-fs.started
+	}
+
+	// Once released, all goroutines go back to reading at the
+	// same time, and all but MaxReaders must exit.
+	close(fs.release)
+	max := 0
+	for deadline := time.Now().Add(200 * time.Millisecond); time.Now().Before(deadline); runtime.Gosched() {
+		if n := readers(); n > max {
+			max = n
+		}
+	}
+	wg.Wait()
+	if max > maxReaders {
+		t.Errorf("got %d concurrent readers, want at most %d", max, maxReaders)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionsStrings(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("options differ per OS")
+	}
+	opts := &MountOptions{
+		Options:            []string{"noatime"},
+		AllowRoot:          true,
+		ReadOnly:           true,
+		DefaultPermissions: true,
+		MaxRead:            4096,
+		FsName:             "src",
+		Name:               "test",
+	}
+	got := opts.optionsStrings()
+	want := []string{"noatime", "allow_other", "default_permissions",
+		"max_read=4096", "ro", "fsname=src", "subtype=test"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOutputDebugLatency(t *testing.T) {
+	req := &request{}
+	req.inHeader = &InHeader{Unique: 7, Opcode: _OP_GETATTR}
+	req.handler = getHandler(_OP_GETATTR)
+	req.startTime = time.Now().Add(-time.Millisecond)
+
+	got := req.OutputDebug()
+	if !strings.HasPrefix(got, "tx 7:") || !strings.HasSuffix(got, "ms)") {
+		t.Errorf("got %q, want reply with latency", got)
+	}
+}
+
+func TestAllowedCaller(t *testing.T) {
+	other := uint32(os.Getuid()) + 1
+	if other == 0 {
+		other++
+	}
+	for op, want := range map[uint32]bool{
+		_OP_LOOKUP: false,
+		_OP_OPEN:   false,
+		_OP_READ:   true,
+		_OP_FLUSH:  true,
+		_OP_GETLK:  true,
+		_OP_SETLKW: true,
+		_OP_LSEEK:  true,
+	} {
+		req := &request{inHeader: &InHeader{Opcode: op, Caller: Caller{Owner: Owner{Uid: other}}}}
+		if got := allowedCaller(req); got != want {
+			t.Errorf("%s: got %v, want %v", operationName(op), got, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// UnmountOnSignal installs a handler that unmounts the file system
+// through UnmountTimeout when one of the given signals arrives. If no
+// signals are given, SIGINT and SIGTERM are handled. Once the
+// unmount succeeds, Serve returns, so the program can exit normally
+// instead of leaving a dead mount point behind. If the unmount fails,
+// for example because files are still open, the error is logged, and
+// the next signal retries.
+//
+// The returned function removes the handler. It may be called more
+// than once.
+func (ms *Server) UnmountOnSignal(timeout time.Duration, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	// Unmount clears mountPoint, so log the path that does not
+	// change.
+	mountPoint := ms.mountDir
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case sig := <-ch:
+				if ms.opts.Debug {
+					ms.logf("received %v, unmounting %s", sig, mountPoint)
+				}
+				if err := ms.UnmountTimeout(timeout); err != nil {
+					ms.logf("unmount on %v failed: %v", sig, err)
+					continue
+				}
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
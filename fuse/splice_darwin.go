@@ -15,3 +15,11 @@ func (s *Server) setSplice() {
 func (ms *Server) trySplice(header []byte, req *request, fdData *readResultFd) error {
 	return fmt.Errorf("unimplemented")
 }
+
+func (ms *Server) readSplice(fd int, req *request, dest []byte) (int, error) {
+	return 0, fmt.Errorf("unimplemented")
+}
+
+func (ms *Server) spliceWriteRequest(req *request, in *WriteIn) (uint32, Status) {
+	return 0, ENOSYS
+}
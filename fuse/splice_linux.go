@@ -6,13 +6,111 @@ package fuse
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"syscall"
+	"unsafe"
 
 	"github.com/hanwen/go-fuse/v2/splice"
 )
 
+// spliceWriteMinSize is the smallest WRITE payload that is left in
+// the pipe. Smaller writes are cheaper to copy.
+const spliceWriteMinSize = 4096
+
 func (s *Server) setSplice() {
 	s.canSplice = splice.Resizable()
+	if _, ok := s.fileSystem.(SpliceWriter); ok && s.canSplice && s.opts.SpliceWrite && s.opts.Record == nil {
+		s.spliceWrite = true
+		s.kernelSettings.Flags |= CAP_SPLICE_READ
+	}
+}
+
+// readSplice reads a request from the FUSE device fd through a pipe,
+// and copies it into dest. For large WRITE requests, only the
+// headers are copied, and the payload is left in the pipe, which is
+// stored in req.
+func (ms *Server) readSplice(fd int, req *request, dest []byte) (int, error) {
+	pair, err := splice.Get()
+	if err != nil {
+		return 0, err
+	}
+	if err := pair.Grow(len(dest)); err != nil {
+		splice.Done(pair)
+		return 0, err
+	}
+
+	var total int
+	err = handleEINTR(func() error {
+		n, err := syscall.Splice(fd, nil, int(pair.WriteFd()), nil, len(dest), 0)
+		total = int(n)
+		return err
+	})
+	if err != nil {
+		splice.Done(pair)
+		return 0, err
+	}
+
+	hdrSize := int(unsafe.Sizeof(InHeader{}))
+	if total < hdrSize {
+		splice.Done(pair)
+		return 0, syscall.EIO
+	}
+	if _, err := io.ReadFull(pair, dest[:hdrSize]); err != nil {
+		splice.Done(pair)
+		return 0, err
+	}
+
+	n := total
+	hdr := (*InHeader)(unsafe.Pointer(&dest[0]))
+	writeInSize := int(unsafe.Sizeof(WriteIn{}))
+	if hdr.Opcode == _OP_WRITE && total-writeInSize >= spliceWriteMinSize {
+		n = writeInSize
+	}
+	if _, err := io.ReadFull(pair, dest[hdrSize:n]); err != nil {
+		splice.Done(pair)
+		return 0, err
+	}
+
+	if n < total {
+		req.splicePair = pair
+		req.spliceSize = total - n
+	} else {
+		splice.Done(pair)
+	}
+	return n, nil
+}
+
+// spliceWriteRequest handles a WRITE request whose payload is still in
+// req.splicePair.
+func (ms *Server) spliceWriteRequest(req *request, in *WriteIn) (uint32, Status) {
+	pair := req.splicePair
+	size := req.spliceSize
+
+	if sw, ok := ms.fileSystem.(SpliceWriter); ok {
+		if fd, done, ok := sw.WriteFd(req.cancel, in); ok {
+			defer done()
+			written := 0
+			for written < size {
+				n, err := pair.WriteToAt(fd, size-written, int64(in.Offset)+int64(written))
+				if err != nil {
+					return uint32(written), ToStatus(err)
+				}
+				if n == 0 {
+					break
+				}
+				written += n
+			}
+			return uint32(written), OK
+		}
+	}
+
+	buf := ms.buffers.AllocBuffer(uint32(size))
+	defer ms.buffers.FreeBuffer(buf)
+	if _, err := io.ReadFull(pair, buf[:size]); err != nil {
+		return 0, ToStatus(err)
+	}
+	return ms.fileSystem.Write(req.cancel, in, buf[:size])
 }
 
 // trySplice:  Zero-copy read from fdData.Fd into /dev/fuse
@@ -88,7 +186,7 @@ func (ms *Server) trySplice(header []byte, req *request, fdData *readResultFd) e
 	}
 
 	// Write header + data to /dev/fuse
-	_, err = pair2.WriteTo(uintptr(ms.mountFd), total)
+	_, err = pair2.WriteTo(uintptr(ms.devFd(req)), total)
 	if err != nil {
 		return err
 	}
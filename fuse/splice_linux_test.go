@@ -0,0 +1,83 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/splice"
+)
+
+type spliceWriteFS struct {
+	RawFileSystem
+	fd      uintptr
+	useFd   bool
+	done    bool
+	written []byte
+}
+
+func (fs *spliceWriteFS) WriteFd(cancel <-chan struct{}, input *WriteIn) (uintptr, func(), bool) {
+	return fs.fd, func() { fs.done = true }, fs.useFd
+}
+
+func (fs *spliceWriteFS) Write(cancel <-chan struct{}, input *WriteIn, data []byte) (uint32, Status) {
+	fs.written = append([]byte{}, data...)
+	return uint32(len(data)), OK
+}
+
+func TestSpliceWriteRequest(t *testing.T) {
+	f, err := ioutil.TempFile("", "TestSpliceWriteRequest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	payload := bytes.Repeat([]byte("abcdefgh"), 1024)
+	for _, useFd := range []bool{true, false} {
+		fs := &spliceWriteFS{
+			RawFileSystem: NewDefaultRawFileSystem(),
+			fd:            f.Fd(),
+			useFd:         useFd,
+		}
+		ms := &Server{fileSystem: fs}
+
+		pair, err := splice.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := pair.Grow(2 * len(payload)); err != nil {
+			t.Skipf("cannot grow pipe: %v", err)
+		}
+		if _, err := pair.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		req := &request{splicePair: pair, spliceSize: len(payload)}
+
+		n, code := ms.spliceWriteRequest(req, &WriteIn{Offset: 10})
+		splice.Done(pair)
+		if !code.Ok() || int(n) != len(payload) {
+			t.Fatalf("useFd=%v: got %d, %v", useFd, n, code)
+		}
+
+		if useFd {
+			if !fs.done {
+				t.Errorf("done was not called")
+			}
+			content, err := ioutil.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(content[10:], payload) {
+				t.Errorf("file content mismatch")
+			}
+		} else if !bytes.Equal(fs.written, payload) {
+			t.Errorf("fallback Write got %d bytes, want %d", len(fs.written), len(payload))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"sync"
+	"time"
+)
+
+// Latencies are recorded in buckets of powers of two microseconds;
+// the last bucket holds everything above 2^(statsBuckets-2) µs.
+const statsBuckets = 32
+
+// OpStats contains statistics for a single FUSE operation.
+type OpStats struct {
+	// Count is the number of requests handled.
+	Count uint64
+
+	// Errors is the number of requests that returned an error
+	// status.
+	Errors uint64
+
+	// ErrorsByStatus breaks down Errors by status.
+	ErrorsByStatus map[Status]uint64
+
+	// Total is the accumulated time spent handling requests.
+	Total time.Duration
+
+	// Latency percentiles. These are upper bounds, accurate to
+	// a factor of two.
+	P50, P90, P99 time.Duration
+}
+
+type opStats struct {
+	count   uint64
+	errors  uint64
+	errnos  map[Status]uint64
+	total   time.Duration
+	buckets [statsBuckets]uint64
+}
+
+type serverStats struct {
+	mu  sync.Mutex
+	ops [_OPCODE_COUNT]opStats
+}
+
+func latencyBucket(dt time.Duration) int {
+	us := uint64(dt / time.Microsecond)
+	b := 0
+	for us > 0 && b < statsBuckets-1 {
+		us >>= 1
+		b++
+	}
+	return b
+}
+
+func (s *serverStats) add(op uint32, status Status, dt time.Duration) {
+	if op >= _OPCODE_COUNT {
+		return
+	}
+	s.mu.Lock()
+	o := &s.ops[op]
+	o.count++
+	if !status.Ok() {
+		o.errors++
+		if o.errnos == nil {
+			o.errnos = map[Status]uint64{}
+		}
+		o.errnos[status]++
+	}
+	o.total += dt
+	o.buckets[latencyBucket(dt)]++
+	s.mu.Unlock()
+}
+
+func (o *opStats) percentile(p float64) time.Duration {
+	want := uint64(float64(o.count)*p + 0.5)
+	if want == 0 {
+		want = 1
+	}
+	var seen uint64
+	for i, n := range o.buckets {
+		seen += n
+		if seen >= want {
+			return time.Duration(uint64(1)<<uint(i)) * time.Microsecond
+		}
+	}
+	return 0
+}
+
+// Stats returns statistics for each FUSE operation, keyed by
+// operation name, eg. "LOOKUP". Operations that were never called
+// are omitted. Statistics are only collected if
+// MountOptions.EnableStats is set.
+func (ms *Server) Stats() map[string]OpStats {
+	r := map[string]OpStats{}
+	s := &ms.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for op := range s.ops {
+		o := &s.ops[op]
+		if o.count == 0 {
+			continue
+		}
+		st := OpStats{
+			Count:  o.count,
+			Errors: o.errors,
+			Total:  o.total,
+			P50:    o.percentile(0.5),
+			P90:    o.percentile(0.9),
+			P99:    o.percentile(0.99),
+		}
+		if len(o.errnos) > 0 {
+			st.ErrorsByStatus = make(map[Status]uint64, len(o.errnos))
+			for k, v := range o.errnos {
+				st.ErrorsByStatus[k] = v
+			}
+		}
+		r[operationName(uint32(op))] = st
+	}
+	return r
+}
+
+// ResetStats clears the statistics returned by Stats.
+func (ms *Server) ResetStats() {
+	s := &ms.stats
+	s.mu.Lock()
+	s.ops = [_OPCODE_COUNT]opStats{}
+	s.mu.Unlock()
+}
@@ -0,0 +1,46 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	ms := &Server{}
+	for i := 0; i < 98; i++ {
+		ms.stats.add(_OP_LOOKUP, OK, 10*time.Microsecond)
+	}
+	ms.stats.add(_OP_LOOKUP, ENOENT, 10*time.Millisecond)
+	ms.stats.add(_OP_LOOKUP, ENOENT, 10*time.Millisecond)
+	ms.stats.add(_OP_READ, OK, time.Millisecond)
+
+	st := ms.Stats()
+	if len(st) != 2 {
+		t.Fatalf("got %v, want LOOKUP and READ", st)
+	}
+	l := st["LOOKUP"]
+	if l.Count != 100 || l.Errors != 2 {
+		t.Errorf("LOOKUP: got count %d errors %d", l.Count, l.Errors)
+	}
+	if n := l.ErrorsByStatus[ENOENT]; n != 2 || len(l.ErrorsByStatus) != 1 {
+		t.Errorf("LOOKUP errors by status: got %v", l.ErrorsByStatus)
+	}
+	if l.P50 < 10*time.Microsecond || l.P50 > 20*time.Microsecond {
+		t.Errorf("LOOKUP P50: got %v", l.P50)
+	}
+	if l.P99 < 10*time.Millisecond || l.P99 > 20*time.Millisecond {
+		t.Errorf("LOOKUP P99: got %v", l.P99)
+	}
+	if want := 98*10*time.Microsecond + 20*time.Millisecond; l.Total != want {
+		t.Errorf("LOOKUP total: got %v, want %v", l.Total, want)
+	}
+
+	ms.ResetStats()
+	if st := ms.Stats(); len(st) != 0 {
+		t.Errorf("after reset: got %v", st)
+	}
+}
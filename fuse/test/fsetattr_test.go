@@ -5,6 +5,7 @@
 package test
 
 import (
+	"io/ioutil"
 	"os"
 	"syscall"
 	"testing"
@@ -143,6 +144,18 @@ func NewFile() *MutableDataFile {
 }
 
 func setupFAttrTest(t *testing.T, fs pathfs.FileSystem) (dir string, clean func()) {
+	dir, clean, state := mountFAttrTest(t, fs)
+	if state.KernelSettings().Flags&fuse.CAP_FILE_OPS == 0 {
+		clean()
+		t.Skip("Mount does not support file operations")
+	}
+
+	return dir, clean
+}
+
+// mountFAttrTest mounts fs, also if the kernel does not send file
+// handles with SETATTR.
+func mountFAttrTest(t *testing.T, fs pathfs.FileSystem) (dir string, clean func(), state *fuse.Server) {
 	dir = testutil.TempDir()
 	nfs := pathfs.NewPathNodeFs(fs, nil)
 	opts := nodefs.NewOptions()
@@ -165,13 +178,7 @@ func setupFAttrTest(t *testing.T, fs pathfs.FileSystem) (dir string, clean func(
 			os.RemoveAll(dir)
 		}
 	}
-
-	if state.KernelSettings().Flags&fuse.CAP_FILE_OPS == 0 {
-		clean()
-		t.Skip("Mount does not support file operations")
-	}
-
-	return dir, clean
+	return dir, clean, state
 }
 
 func TestFSetAttr(t *testing.T) {
@@ -259,3 +266,67 @@ func TestFSetAttr(t *testing.T) {
 		t.Error("Fsync was not called")
 	}
 }
+
+type setAttrHookFs struct {
+	FSetAttrFs
+	calls chan fuse.SetAttrIn
+}
+
+func (fs *setAttrHookFs) SetAttr(name string, input *fuse.SetAttrIn, context *fuse.Context) fuse.Status {
+	if mode, ok := input.GetMode(); ok {
+		fs.file.Chmod(mode)
+	}
+	if sz, ok := input.GetSize(); ok {
+		fs.file.Truncate(sz)
+	}
+	fs.calls <- *input
+	return fuse.OK
+}
+
+func TestSetAttrHook(t *testing.T) {
+	fs := &setAttrHookFs{
+		FSetAttrFs: FSetAttrFs{FileSystem: pathfs.NewDefaultFileSystem()},
+		calls:      make(chan fuse.SetAttrIn, 10),
+	}
+	dir, clean, _ := mountFAttrTest(t, fs)
+	defer clean()
+
+	fn := dir + "/file"
+	if err := ioutil.WriteFile(fn, []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	// Drain setattr calls from creation, if any.
+	for len(fs.calls) > 0 {
+		<-fs.calls
+	}
+
+	if err := os.Chmod(fn, 0600); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+	in := <-fs.calls
+	if mode, ok := in.GetMode(); !ok || mode&07777 != 0600 {
+		t.Errorf("chmod: got mode %o (valid %x), want 0600", mode, in.Valid)
+	}
+	if _, ok := in.GetSize(); ok {
+		t.Errorf("chmod: got size in valid mask %x", in.Valid)
+	}
+
+	if err := os.Truncate(fn, 2); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	in = <-fs.calls
+	if size, ok := in.GetSize(); !ok || size != 2 {
+		t.Errorf("truncate: got size %d (valid %x), want 2", size, in.Valid)
+	}
+	if _, ok := in.GetMode(); ok {
+		t.Errorf("truncate: got mode in valid mask %x", in.Valid)
+	}
+
+	fi, err := os.Stat(fn)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if fi.Mode().Perm() != 0600 || fi.Size() != 2 {
+		t.Errorf("Stat: got mode %v size %d, want 0600, 2", fi.Mode(), fi.Size())
+	}
+}
@@ -122,6 +122,35 @@ func TestUtimesNano(t *testing.T) {
 	}
 }
 
+func TestUtimesNow(t *testing.T) {
+	tc := NewTestCase(t)
+	defer tc.Cleanup()
+
+	if err := ioutil.WriteFile(tc.origFile, []byte("xyz"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tc.origFile, time.Unix(42, 0), time.Unix(43, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().Add(-time.Second)
+	ts := []unix.Timespec{{Nsec: unix.UTIME_NOW}, {Nsec: unix.UTIME_OMIT}}
+	if err := unix.UtimesNanoAt(unix.AT_FDCWD, tc.mountFile, ts, 0); err != nil {
+		t.Fatalf("UtimesNanoAt: %v", err)
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Stat(tc.origFile, &st); err != nil {
+		t.Fatal(err)
+	}
+	if atime := time.Unix(st.Atim.Unix()); atime.Before(before) {
+		t.Errorf("got atime %v, want after %v", atime, before)
+	}
+	if st.Mtim.Sec != 43 {
+		t.Errorf("got mtime.sec %d, want 43", st.Mtim.Sec)
+	}
+}
+
 func clearStatfs(s *syscall.Statfs_t) {
 	empty := syscall.Statfs_t{}
 	s.Type = 0
@@ -389,6 +389,7 @@ func TestPosix(t *testing.T) {
 		"ReadDir",
 		"ReadDirPicksUpCreate",
 		"AppendWrite",
+		"FtruncateDeleted",
 	}
 	for _, k := range tests {
 		f := posixtest.All[k]
@@ -477,6 +478,28 @@ func TestMknod(t *testing.T) {
 	}
 }
 
+func TestMknodSocket(t *testing.T) {
+	tc := NewTestCase(t)
+	defer tc.Cleanup()
+
+	if errNo := syscall.Mknod(tc.mountFile, syscall.S_IFSOCK|0755, 0); errNo != nil {
+		t.Fatalf("Mknod %v", errNo)
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Lstat(tc.mountFile, &st); err != nil {
+		t.Fatalf("Lstat(%q): %v", tc.mountFile, err)
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFSOCK || st.Nlink != 1 {
+		t.Errorf("got mode %o nlink %d, want socket with 1 link", st.Mode, st.Nlink)
+	}
+	if fi, err := os.Lstat(tc.origFile); err != nil {
+		t.Errorf("Lstat(%q): %v", tc.origFile, err)
+	} else if fi.Mode()&os.ModeSocket == 0 {
+		t.Errorf("Expected socket filetype, got %x", fi.Mode())
+	}
+}
+
 // Test that READDIR works even if the directory is renamed after the OPENDIR.
 // This checks that the fix for https://github.com/hanwen/go-fuse/issues/252
 // does not break this case.
@@ -0,0 +1,89 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/fuse/pathfs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	orig := testutil.TempDir()
+	defer os.RemoveAll(orig)
+	mnt := testutil.TempDir()
+	defer os.RemoveAll(mnt)
+
+	if err := ioutil.WriteFile(orig+"/secret", []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(orig+"/real", []byte("real"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	counts := map[string]int{}
+	count := func(next fuse.Handler) fuse.Handler {
+		return func(op *fuse.Op) fuse.Status {
+			mu.Lock()
+			counts[op.Name]++
+			mu.Unlock()
+			return next(op)
+		}
+	}
+	deny := func(next fuse.Handler) fuse.Handler {
+		return func(op *fuse.Op) fuse.Status {
+			if op.Name == "LOOKUP" && op.Names[0] == "secret" {
+				return fuse.EACCES
+			}
+			return next(op)
+		}
+	}
+	alias := func(next fuse.Handler) fuse.Handler {
+		return func(op *fuse.Op) fuse.Status {
+			if op.Name == "LOOKUP" && op.Names[0] == "alias" {
+				op.Names = []string{"real"}
+			}
+			return next(op)
+		}
+	}
+
+	opts := &fuse.MountOptions{
+		Debug:      testutil.VerboseTest(),
+		Middleware: []fuse.Middleware{count, deny, alias},
+	}
+	nfs := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(orig), nil)
+	state, _, err := nodefs.Mount(mnt, nfs.Root(), opts, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go state.Serve()
+	if err := state.WaitMount(); err != nil {
+		t.Fatalf("WaitMount: %v", err)
+	}
+	defer state.Unmount()
+
+	if _, err := ioutil.ReadFile(mnt + "/secret"); !os.IsPermission(err) {
+		t.Errorf("ReadFile secret: got %v, want EACCES", err)
+	}
+	if content, err := ioutil.ReadFile(mnt + "/alias"); err != nil || string(content) != "real" {
+		t.Errorf("ReadFile alias: got %q, %v", content, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if counts["LOOKUP"] < 2 || counts["READ"] == 0 {
+		t.Errorf("got counts %v, want LOOKUP and READ", counts)
+	}
+	if counts["INIT"] != 0 {
+		t.Errorf("INIT went through middleware")
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -47,6 +50,38 @@ func TestMountOnExisting(t *testing.T) {
 	}
 }
 
+func TestMountShadow(t *testing.T) {
+	ts := NewTestCase(t)
+	defer ts.Cleanup()
+
+	ts.Mkdir(ts.mnt+"/mnt", 0777)
+	ts.WriteFile(ts.mnt+"/mnt/shadowed", []byte("x"), 0644)
+
+	other := ts.tmpDir + "/other"
+	ts.Mkdir(other, 0777)
+	ts.WriteFile(other+"/file", []byte("y"), 0644)
+
+	fs := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(other), nil)
+	opts := nodefs.NewOptions()
+	opts.MountPolicy = nodefs.MountShadow
+	if code := ts.connector.Mount(ts.rootNode(), "mnt", fs.Root(), opts); !code.Ok() {
+		t.Fatalf("Mount: %v", code)
+	}
+	if _, err := os.Lstat(ts.mnt + "/mnt/file"); err != nil {
+		t.Errorf("Lstat mounted file: %v", err)
+	}
+	if _, err := os.Lstat(ts.mnt + "/mnt/shadowed"); !os.IsNotExist(err) {
+		t.Errorf("Lstat shadowed file: got %v, want ENOENT", err)
+	}
+
+	if code := ts.pathFs.Unmount("mnt"); !code.Ok() {
+		t.Fatalf("Unmount: %v", code)
+	}
+	if _, err := os.Lstat(ts.mnt + "/mnt/shadowed"); err != nil {
+		t.Errorf("Lstat after Unmount: %v", err)
+	}
+}
+
 func TestMountRename(t *testing.T) {
 	ts := NewTestCase(t)
 	defer ts.Cleanup()
@@ -170,6 +205,410 @@ func TestDeletedUnmount(t *testing.T) {
 	}
 }
 
+func TestMountReadOnly(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+
+	fs := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(orig), nil)
+	s, _, err := nodefs.Mount(mnt, fs.Root(), &fuse.MountOptions{
+		ReadOnly: true,
+		Debug:    testutil.VerboseTest(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	err = ioutil.WriteFile(mnt+"/file", []byte("hello"), 0644)
+	if fuse.ToStatus(err) != fuse.Status(syscall.EROFS) {
+		t.Errorf("WriteFile on read-only mount: got %v, want EROFS", err)
+	}
+}
+
+func TestMountReadOnlySubmount(t *testing.T) {
+	ts := NewTestCase(t)
+	defer ts.Cleanup()
+
+	loopback := pathfs.NewLoopbackFileSystem(ts.orig)
+	rw := pathfs.NewPathNodeFs(loopback, nil)
+	if code := ts.connector.Mount(ts.rootNode(), "rw", rw.Root(), nil); !code.Ok() {
+		t.Fatalf("Mount rw: %v", code)
+	}
+	opts := nodefs.NewOptions()
+	opts.ReadOnly = true
+	ro := pathfs.NewPathNodeFs(loopback, nil)
+	if code := ts.connector.Mount(ts.rootNode(), "ro", ro.Root(), opts); !code.Ok() {
+		t.Fatalf("Mount ro: %v", code)
+	}
+
+	if err := ioutil.WriteFile(ts.mnt+"/rw/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile rw: %v", err)
+	}
+	if content, err := ioutil.ReadFile(ts.mnt + "/ro/file"); err != nil || string(content) != "hello" {
+		t.Errorf("ReadFile ro: got %q, %v", content, err)
+	}
+
+	for _, f := range []func() error{
+		func() error { return ioutil.WriteFile(ts.mnt+"/ro/file", []byte("bye"), 0644) },
+		func() error { return ioutil.WriteFile(ts.mnt+"/ro/new", []byte("bye"), 0644) },
+		func() error { return os.Mkdir(ts.mnt+"/ro/dir", 0755) },
+		func() error { return os.Chmod(ts.mnt+"/ro/file", 0600) },
+		func() error { return os.Remove(ts.mnt + "/ro/file") },
+		func() error { return os.Rename(ts.mnt+"/ro/file", ts.mnt+"/ro/file2") },
+		func() error { return syscall.Access(ts.mnt+"/ro/file", 2) },
+	} {
+		if err := f(); fuse.ToStatus(err) != fuse.EROFS {
+			t.Errorf("got %v, want EROFS", err)
+		}
+	}
+	if content, err := ioutil.ReadFile(ts.orig + "/file"); err != nil || string(content) != "hello" {
+		t.Errorf("backing file changed: got %q, %v", content, err)
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+
+	fs := pathfs.NewPathNodeFs(pathfs.NewDefaultFileSystem(), nil)
+	s, _, err := nodefs.Mount(dir, fs.Root(), &fuse.MountOptions{
+		Debug: testutil.VerboseTest(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	major, minor := s.ProtocolVersion()
+	kernel := s.KernelSettings()
+	if major != 7 || minor < 12 || minor > kernel.Minor {
+		t.Errorf("got protocol %d.%d, kernel %d.%d", major, minor, kernel.Major, kernel.Minor)
+	}
+}
+
+func TestMountedChannel(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+	if err := ioutil.WriteFile(orig+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := pathfs.Mount(mnt, pathfs.NewLoopbackFileSystem(orig), &fuse.MountOptions{
+		Debug: testutil.VerboseTest(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	select {
+	case <-s.Mounted():
+	case <-time.After(10 * time.Second):
+		t.Fatal("mount did not become ready")
+	}
+	defer s.Unmount()
+
+	if _, err := os.Stat(mnt + "/file"); err != nil {
+		t.Errorf("Stat: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.WaitMount(); err != nil {
+			t.Errorf("WaitMount: %v", err)
+		}
+	}
+}
+
+func TestMaxReadAhead(t *testing.T) {
+	for _, c := range []struct{ opt, want int }{{-1, 0}, {8192, 8192}} {
+		dir := testutil.TempDir()
+		fs := pathfs.NewPathNodeFs(pathfs.NewDefaultFileSystem(), nil)
+		s, _, err := nodefs.Mount(dir, fs.Root(), &fuse.MountOptions{
+			MaxReadAhead: c.opt,
+			Debug:        testutil.VerboseTest(),
+		}, nil)
+		if err != nil {
+			t.Fatalf("Mount: %v", err)
+		}
+		go s.Serve()
+		if err := s.WaitMount(); err != nil {
+			t.Fatal("WaitMount", err)
+		}
+		if got := s.MaxReadAhead(); got != c.want {
+			t.Errorf("MaxReadAhead %d: got %d, want %d", c.opt, got, c.want)
+		}
+		s.Unmount()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPriorityWorkers(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+
+	var calls int32
+	fs := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(orig), nil)
+	s, _, err := nodefs.Mount(mnt, fs.Root(), &fuse.MountOptions{
+		PriorityWorkers: 2,
+		RequestPriority: func(op string, header *fuse.InHeader) int {
+			atomic.AddInt32(&calls, 1)
+			return fuse.DefaultRequestPriority(op, header)
+		},
+		Debug: testutil.VerboseTest(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	if err := ioutil.WriteFile(mnt+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if content, err := ioutil.ReadFile(mnt + "/file"); err != nil || string(content) != "hello" {
+		t.Errorf("ReadFile: %q, %v", content, err)
+	}
+	if atomic.LoadInt32(&calls) == 0 {
+		t.Errorf("RequestPriority was not called")
+	}
+}
+
+type slowGetAttrFs struct {
+	pathfs.FileSystem
+	done chan struct{}
+}
+
+func (fs *slowGetAttrFs) GetAttr(name string, context *fuse.Context) (*fuse.Attr, fuse.Status) {
+	if name == "slow" {
+		time.Sleep(100 * time.Millisecond)
+		close(fs.done)
+		return &fuse.Attr{Mode: fuse.S_IFREG | 0644}, fuse.OK
+	}
+	return fs.FileSystem.GetAttr(name, context)
+}
+
+func TestUnmountTimeout(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+
+	fs := &slowGetAttrFs{
+		FileSystem: pathfs.NewLoopbackFileSystem(dir),
+		done:       make(chan struct{}),
+	}
+	mnt := testutil.TempDir()
+	defer os.RemoveAll(mnt)
+	nfs := pathfs.NewPathNodeFs(fs, nil)
+	s, _, err := nodefs.MountRoot(mnt, nfs.Root(), nil)
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+
+	statErr := make(chan error, 1)
+	go func() {
+		_, err := os.Lstat(mnt + "/slow")
+		statErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	if err := s.UnmountTimeout(5 * time.Second); err != nil {
+		t.Fatalf("UnmountTimeout: %v", err)
+	}
+	select {
+	case <-fs.done:
+	default:
+		t.Errorf("unmounted before in-flight GetAttr completed")
+	}
+	if err := <-statErr; err != nil {
+		t.Errorf("Lstat: %v", err)
+	}
+}
+
+type releaseRecordingFs struct {
+	pathfs.FileSystem
+	released chan struct{}
+}
+
+func (fs *releaseRecordingFs) Open(name string, flags uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	f, code := fs.FileSystem.Open(name, flags, context)
+	if !code.Ok() {
+		return nil, code
+	}
+	return &releaseRecordingFile{f, fs.released}, fuse.OK
+}
+
+type releaseRecordingFile struct {
+	nodefs.File
+	released chan struct{}
+}
+
+func (f *releaseRecordingFile) Release() {
+	f.File.Release()
+	close(f.released)
+}
+
+func TestUnmountTimeoutRelease(t *testing.T) {
+	testUnmountTimeoutRelease(t, &fuse.MountOptions{})
+}
+
+func TestUnmountTimeoutReleaseIoUring(t *testing.T) {
+	param, err := ioutil.ReadFile("/sys/module/fuse/parameters/enable_uring")
+	if err != nil || strings.TrimSpace(string(param)) != "Y" {
+		t.Skip("kernel does not have FUSE over io_uring enabled")
+	}
+	testUnmountTimeoutRelease(t, &fuse.MountOptions{EnableIoUring: true})
+}
+
+func testUnmountTimeoutRelease(t *testing.T, opts *fuse.MountOptions) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(dir+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := &releaseRecordingFs{
+		FileSystem: &slowGetAttrFs{
+			FileSystem: pathfs.NewLoopbackFileSystem(dir),
+			done:       make(chan struct{}),
+		},
+		released: make(chan struct{}),
+	}
+	mnt := testutil.TempDir()
+	defer os.RemoveAll(mnt)
+	nfs := pathfs.NewPathNodeFs(fs, nil)
+	s, _, err := nodefs.Mount(mnt, nfs.Root(), opts, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	if opts.EnableIoUring && s.KernelSettings().Flags2&fuse.CAP2_OVER_IO_URING == 0 {
+		s.Unmount()
+		t.Skip("kernel does not support FUSE over io_uring")
+	}
+
+	f, err := os.Open(mnt + "/file")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	go os.Lstat(mnt + "/slow")
+	time.Sleep(20 * time.Millisecond)
+
+	unmounted := make(chan error, 1)
+	go func() {
+		unmounted <- s.UnmountTimeout(5 * time.Second)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	f.Close()
+
+	if err := <-unmounted; err != nil {
+		t.Fatalf("UnmountTimeout: %v", err)
+	}
+	select {
+	case <-fs.released:
+	default:
+		t.Errorf("RELEASE while draining did not reach the file system")
+	}
+}
+
+func TestUnmountTimeoutBusy(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	mnt := dir + "/mnt"
+	orig := dir + "/orig"
+	os.Mkdir(mnt, 0755)
+	os.Mkdir(orig, 0755)
+
+	log := make(chan string, 10)
+	root := pathfs.NewPathNodeFs(&unmountRecordingFs{pathfs.NewLoopbackFileSystem(orig), "root", log}, nil)
+	s, _, err := nodefs.MountRoot(mnt, root.Root(), nil)
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+
+	d, err := os.Open(mnt)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := s.UnmountTimeout(time.Second); err == nil {
+		t.Errorf("UnmountTimeout with open directory succeeded")
+	}
+	select {
+	case name := <-log:
+		t.Errorf("OnUnmount(%q) called while still mounted", name)
+	default:
+	}
+	if _, err := d.Readdirnames(-1); err != nil {
+		t.Errorf("Readdirnames after failed unmount: %v", err)
+	}
+
+	d.Close()
+	if err := s.UnmountTimeout(time.Second); err != nil {
+		t.Fatalf("UnmountTimeout: %v", err)
+	}
+	if name := <-log; name != "root" {
+		t.Errorf("got OnUnmount(%q), want root", name)
+	}
+}
+
+func TestUnmountOnSignal(t *testing.T) {
+	mnt := testutil.TempDir()
+	defer os.RemoveAll(mnt)
+	s, _, err := nodefs.MountRoot(mnt, nodefs.NewDefaultNode(), nil)
+	if err != nil {
+		t.Fatalf("MountRoot: %v", err)
+	}
+	served := make(chan struct{})
+	go func() {
+		s.Serve()
+		close(served)
+	}()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+
+	stop := s.UnmountOnSignal(time.Second, syscall.SIGUSR1)
+	defer stop()
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		s.Unmount()
+		t.Fatal("Serve did not return after signal")
+	}
+}
+
 func TestDefaultNodeMount(t *testing.T) {
 	dir := testutil.TempDir()
 	defer os.RemoveAll(dir)
@@ -223,3 +662,307 @@ func TestLiveness(t *testing.T) {
 		t.Fatalf("ReadDir: %v", err)
 	}
 }
+
+type unmountRecordingFs struct {
+	pathfs.FileSystem
+	name string
+	log  chan<- string
+}
+
+func (fs *unmountRecordingFs) OnUnmount() {
+	fs.log <- fs.name
+}
+
+func TestDestroyUnmountsAll(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	mnt := dir + "/mnt"
+	os.Mkdir(mnt, 0755)
+
+	log := make(chan string, 10)
+	root := pathfs.NewPathNodeFs(&unmountRecordingFs{pathfs.NewDefaultFileSystem(), "root", log}, nil)
+	s, conn, err := nodefs.Mount(mnt, root.Root(), &fuse.MountOptions{
+		Debug: testutil.VerboseTest(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+
+	sub := pathfs.NewPathNodeFs(&unmountRecordingFs{pathfs.NewDefaultFileSystem(), "sub", log}, nil)
+	if code := conn.Mount(root.Root().Inode(), "sub", sub.Root(), nil); !code.Ok() {
+		t.Fatalf("Mount sub: %v", code)
+	}
+
+	if err := s.UnmountTimeout(time.Second); err != nil {
+		t.Fatalf("UnmountTimeout: %v", err)
+	}
+	close(log)
+	var got []string
+	for name := range log {
+		got = append(got, name)
+	}
+	if len(got) != 2 || got[0] != "sub" || got[1] != "root" {
+		t.Errorf("got OnUnmount calls %v, want [sub root]", got)
+	}
+}
+
+func TestLazyUnmount(t *testing.T) {
+	ts := NewTestCase(t)
+	defer ts.Cleanup()
+
+	other := ts.tmpDir + "/other"
+	ts.Mkdir(other, 0777)
+	ts.WriteFile(other+"/file", []byte("hello"), 0644)
+
+	log := make(chan string, 10)
+	fs := pathfs.NewPathNodeFs(&unmountRecordingFs{pathfs.NewLoopbackFileSystem(other), "sub", log}, nil)
+	if code := ts.connector.Mount(ts.rootNode(), "sub", fs.Root(), nil); !code.Ok() {
+		t.Fatalf("Mount: %v", code)
+	}
+	f, err := os.OpenFile(ts.mnt+"/sub/file", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer f.Close()
+
+	if code := ts.pathFs.Unmount("sub"); code != fuse.EBUSY {
+		t.Errorf("Unmount with open file: got %v, want EBUSY", code)
+	}
+	if code := ts.connector.LazyUnmount(fs.Root().Inode()); !code.Ok() {
+		t.Fatalf("LazyUnmount: %v", code)
+	}
+	if _, err := os.Lstat(ts.mnt + "/sub"); !os.IsNotExist(err) {
+		t.Errorf("Lstat mount point: got %v, want ENOENT", err)
+	}
+	if _, err := f.Write([]byte("x")); err == nil || err.(*os.PathError).Err != syscall.ENOTCONN {
+		t.Errorf("Write after LazyUnmount: got %v, want ENOTCONN", err)
+	}
+	select {
+	case <-log:
+		t.Errorf("OnUnmount called with open file")
+	default:
+	}
+
+	f.Close()
+	select {
+	case <-log:
+	case <-time.After(5 * time.Second):
+		t.Errorf("OnUnmount not called after release")
+	}
+}
+
+func TestCopyOnCrossMountRename(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	other := dir + "/other"
+	mnt := dir + "/mnt"
+	for _, d := range []string{orig, other, mnt, orig + "/subdir"} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(orig+"/file", []byte("hello"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("target", orig+"/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := nodefs.NewOptions()
+	opts.CopyOnCrossMountRename = true
+	root := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(orig), nil)
+	s, conn, err := nodefs.Mount(mnt, root.Root(), &fuse.MountOptions{
+		Debug: testutil.VerboseTest(),
+	}, opts)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	sub := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(other), nil)
+	if code := conn.Mount(root.Root().Inode(), "sub", sub.Root(), opts); !code.Ok() {
+		t.Fatalf("Mount sub: %v", code)
+	}
+
+	if err := os.Rename(mnt+"/file", mnt+"/sub/file"); err != nil {
+		t.Fatalf("Rename file: %v", err)
+	}
+	if got, err := ioutil.ReadFile(other + "/file"); err != nil || string(got) != "hello" {
+		t.Errorf("copied file: got %q, %v", got, err)
+	}
+	if fi, err := os.Stat(mnt + "/sub/file"); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("Stat copy: got %v, %v", fi, err)
+	}
+	if _, err := os.Lstat(orig + "/file"); !os.IsNotExist(err) {
+		t.Errorf("source still exists: %v", err)
+	}
+
+	if err := os.Rename(mnt+"/link", mnt+"/sub/link"); err != nil {
+		t.Fatalf("Rename symlink: %v", err)
+	}
+	if got, err := os.Readlink(other + "/link"); err != nil || got != "target" {
+		t.Errorf("copied symlink: got %q, %v", got, err)
+	}
+
+	err = os.Rename(mnt+"/subdir", mnt+"/sub/subdir")
+	if le, ok := err.(*os.LinkError); !ok || le.Err != syscall.EXDEV {
+		t.Errorf("Rename directory: got %v, want EXDEV", err)
+	}
+}
+
+type failReadFs struct {
+	pathfs.FileSystem
+}
+
+func (fs *failReadFs) Open(name string, flags uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	f, code := fs.FileSystem.Open(name, flags, context)
+	if !code.Ok() {
+		return nil, code
+	}
+	return &failReadFile{f}, fuse.OK
+}
+
+type failReadFile struct {
+	nodefs.File
+}
+
+func (f *failReadFile) Read(dest []byte, off int64) (fuse.ReadResult, fuse.Status) {
+	return nil, fuse.EIO
+}
+
+func TestCopyOnCrossMountRenameFailure(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	other := dir + "/other"
+	mnt := dir + "/mnt"
+	for _, d := range []string{orig, other, mnt} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(orig+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(other+"/file", []byte("precious"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := nodefs.NewOptions()
+	opts.CopyOnCrossMountRename = true
+	root := pathfs.NewPathNodeFs(&failReadFs{pathfs.NewLoopbackFileSystem(orig)}, nil)
+	s, conn, err := nodefs.Mount(mnt, root.Root(), &fuse.MountOptions{
+		Debug: testutil.VerboseTest(),
+	}, opts)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	sub := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(other), nil)
+	if code := conn.Mount(root.Root().Inode(), "sub", sub.Root(), opts); !code.Ok() {
+		t.Fatalf("Mount sub: %v", code)
+	}
+
+	err = os.Rename(mnt+"/file", mnt+"/sub/file")
+	if le, ok := err.(*os.LinkError); !ok || le.Err != syscall.EIO {
+		t.Errorf("Rename: got %v, want EIO", err)
+	}
+	if got, err := ioutil.ReadFile(other + "/file"); err != nil || string(got) != "precious" {
+		t.Errorf("target after failed copy: got %q, %v", got, err)
+	}
+	if got, err := ioutil.ReadFile(orig + "/file"); err != nil || string(got) != "hello" {
+		t.Errorf("source after failed copy: got %q, %v", got, err)
+	}
+	if entries, err := ioutil.ReadDir(other); err != nil || len(entries) != 1 {
+		t.Errorf("ReadDir target directory: got %v, %v, want only the target", entries, err)
+	}
+}
+
+type failRenameFs struct {
+	pathfs.FileSystem
+}
+
+func (fs *failRenameFs) Rename(oldName string, newName string, context *fuse.Context) fuse.Status {
+	return fuse.EPERM
+}
+
+func TestCopyOnCrossMountRenameKeepsSource(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	other := dir + "/other"
+	mnt := dir + "/mnt"
+	for _, d := range []string{orig, other, mnt} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{orig + "/file", orig + "/open"} {
+		if err := ioutil.WriteFile(f, []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(other+"/file", []byte("precious"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := nodefs.NewOptions()
+	opts.CopyOnCrossMountRename = true
+	root := pathfs.NewPathNodeFs(&failRenameFs{pathfs.NewLoopbackFileSystem(orig)}, nil)
+	s, conn, err := nodefs.Mount(mnt, root.Root(), &fuse.MountOptions{
+		Debug: testutil.VerboseTest(),
+	}, opts)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	sub := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(other), nil)
+	if code := conn.Mount(root.Root().Inode(), "sub", sub.Root(), opts); !code.Ok() {
+		t.Fatalf("Mount sub: %v", code)
+	}
+
+	f, err := os.Open(mnt + "/open")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	err = os.Rename(mnt+"/open", mnt+"/sub/open")
+	if le, ok := err.(*os.LinkError); !ok || le.Err != syscall.EXDEV {
+		t.Errorf("Rename of open file: got %v, want EXDEV", err)
+	}
+
+	// The source cannot be moved aside, so the target must not
+	// be replaced.
+	err = os.Rename(mnt+"/file", mnt+"/sub/file")
+	if le, ok := err.(*os.LinkError); !ok || le.Err != syscall.EPERM {
+		t.Errorf("Rename: got %v, want EPERM", err)
+	}
+	if got, err := ioutil.ReadFile(other + "/file"); err != nil || string(got) != "precious" {
+		t.Errorf("target after failed rename: got %q, %v", got, err)
+	}
+	if got, err := ioutil.ReadFile(orig + "/file"); err != nil || string(got) != "hello" {
+		t.Errorf("source after failed rename: got %q, %v", got, err)
+	}
+	if entries, err := ioutil.ReadDir(other); err != nil || len(entries) != 1 {
+		t.Errorf("ReadDir target directory: got %v, %v, want only the target", entries, err)
+	}
+}
@@ -179,3 +179,46 @@ func TestEntryNotify(t *testing.T) {
 		t.Fatalf("Lstat failed: %v", err)
 	}
 }
+
+func TestInvalidateAttr(t *testing.T) {
+	test := NewNotifyTest(t)
+	defer test.Clean()
+
+	test.fs.sizeChan <- 42
+	if fi, err := os.Lstat(test.dir + "/file"); err != nil || fi.Size() != 42 {
+		t.Fatalf("Lstat: %v, %v", fi, err)
+	}
+	test.fs.sizeChan <- 666
+	if code := test.pathfs.InvalidateAttr("file"); !code.Ok() {
+		t.Errorf("InvalidateAttr: %v", code)
+	}
+	if fi, err := os.Lstat(test.dir + "/file"); err != nil || fi.Size() != 666 {
+		t.Errorf("Lstat after InvalidateAttr: %v, %v", fi, err)
+	}
+
+	if code := test.pathfs.InvalidateAttr("dir/unknown"); !code.Ok() {
+		t.Errorf("InvalidateAttr of unknown path: %v", code)
+	}
+}
+
+func TestInvalidateEntry(t *testing.T) {
+	test := NewNotifyTest(t)
+	defer test.Clean()
+
+	fn := test.dir + "/dir/file"
+	test.fs.existChan <- false
+	if _, err := os.Lstat(fn); err == nil {
+		t.Fatalf("file should not exist")
+	}
+	test.fs.existChan <- true
+	if code := test.pathfs.InvalidateEntry("dir", "file"); !code.Ok() {
+		t.Errorf("InvalidateEntry: %v", code)
+	}
+	if _, err := os.Lstat(fn); err != nil {
+		t.Errorf("Lstat after InvalidateEntry: %v", err)
+	}
+
+	if code := test.pathfs.InvalidateEntry("unknown", "file"); !code.Ok() {
+		t.Errorf("InvalidateEntry in unknown directory: %v", code)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/fuse/pathfs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestJSONOpHook(t *testing.T) {
+	orig := testutil.TempDir()
+	defer os.RemoveAll(orig)
+	mnt := testutil.TempDir()
+	defer os.RemoveAll(mnt)
+
+	var log bytes.Buffer
+	opts := &fuse.MountOptions{
+		Debug:  testutil.VerboseTest(),
+		OpHook: fuse.NewJSONOpHook(&log),
+	}
+	nfs := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(orig), nil)
+	state, _, err := nodefs.Mount(mnt, nfs.Root(), opts, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	served := make(chan struct{})
+	go func() {
+		state.Serve()
+		close(served)
+	}()
+	if err := state.WaitMount(); err != nil {
+		t.Fatalf("WaitMount: %v", err)
+	}
+	if _, err := os.Lstat(mnt + "/nonexistent"); !os.IsNotExist(err) {
+		t.Errorf("Lstat: got %v, want ENOENT", err)
+	}
+	if err := state.Unmount(); err != nil {
+		t.Fatalf("Unmount: %v", err)
+	}
+	<-served
+
+	found := false
+	scanner := bufio.NewScanner(&log)
+	for scanner.Scan() {
+		var rec struct {
+			Op    string
+			Names []string
+			Uid   uint32
+			Pid   uint32
+			Errno int32
+			Error string
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("Unmarshal(%q): %v", scanner.Text(), err)
+		}
+		if rec.Op != "LOOKUP" || len(rec.Names) != 1 || rec.Names[0] != "nonexistent" {
+			continue
+		}
+		found = true
+		if rec.Errno != int32(fuse.ENOENT) || rec.Error == "" {
+			t.Errorf("got errno %d (%q), want ENOENT", rec.Errno, rec.Error)
+		}
+		if rec.Uid != uint32(os.Getuid()) || rec.Pid == 0 {
+			t.Errorf("got uid %d pid %d, want uid %d and a pid", rec.Uid, rec.Pid, os.Getuid())
+		}
+	}
+	if !found {
+		t.Errorf("no LOOKUP of nonexistent in log:\n%s", log.String())
+	}
+}
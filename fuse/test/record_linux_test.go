@@ -0,0 +1,82 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/fuse/pathfs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestRecordReplay(t *testing.T) {
+	orig := testutil.TempDir()
+	defer os.RemoveAll(orig)
+	mnt := testutil.TempDir()
+	defer os.RemoveAll(mnt)
+
+	var recording bytes.Buffer
+	opts := &fuse.MountOptions{
+		Debug:  testutil.VerboseTest(),
+		Record: &recording,
+	}
+	nfs := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(orig), nil)
+	state, _, err := nodefs.Mount(mnt, nfs.Root(), opts, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	served := make(chan struct{})
+	go func() {
+		state.Serve()
+		close(served)
+	}()
+	if err := state.WaitMount(); err != nil {
+		t.Fatalf("WaitMount: %v", err)
+	}
+
+	if err := os.Mkdir(mnt+"/dir", 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := ioutil.WriteFile(mnt+"/dir/file", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if content, err := ioutil.ReadFile(mnt + "/dir/file"); err != nil || string(content) != "hello" {
+		t.Fatalf("ReadFile: got %q, %v", content, err)
+	}
+	if err := state.Unmount(); err != nil {
+		t.Fatalf("Unmount: %v", err)
+	}
+	<-served
+
+	// Replaying onto an empty directory recreates the files.
+	replayDir := testutil.TempDir()
+	defer os.RemoveAll(replayDir)
+	rfs := pathfs.NewPathNodeFs(pathfs.NewLoopbackFileSystem(replayDir), nil)
+	conn := nodefs.NewFileSystemConnector(rfs.Root(), nil)
+	res, err := fuse.Replay(bytes.NewReader(recording.Bytes()), conn.RawFS(), nil)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if res.Requests == 0 {
+		t.Errorf("Replay sent no requests")
+	}
+	if content, err := ioutil.ReadFile(replayDir + "/dir/file"); err != nil || string(content) != "hello" {
+		t.Errorf("replayed file: got %q, %v", content, err)
+	}
+
+	// A file system that implements nothing answers differently.
+	res, err = fuse.Replay(bytes.NewReader(recording.Bytes()), fuse.NewDefaultRawFileSystem(), nil)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(res.Mismatches) == 0 {
+		t.Errorf("Replay onto default file system: no mismatches")
+	}
+}
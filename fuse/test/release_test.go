@@ -0,0 +1,106 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/fuse/pathfs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+type releaseRecord struct {
+	name string
+	info nodefs.ReleaseInfo
+}
+
+type releaseFile struct {
+	nodefs.File
+	name string
+	log  chan<- releaseRecord
+}
+
+func (f *releaseFile) ReleaseWithInfo(info *nodefs.ReleaseInfo) {
+	f.File.Release()
+	f.log <- releaseRecord{f.name, *info}
+}
+
+type releaseFs struct {
+	pathfs.FileSystem
+	log chan<- releaseRecord
+}
+
+func (fs *releaseFs) Open(name string, flags uint32, context *fuse.Context) (nodefs.File, fuse.Status) {
+	f, code := fs.FileSystem.Open(name, flags, context)
+	if !code.Ok() {
+		return nil, code
+	}
+	return &releaseFile{f, name, fs.log}, fuse.OK
+}
+
+func (fs *releaseFs) ReleaseDir(name string, info *nodefs.ReleaseInfo) {
+	fs.log <- releaseRecord{name + "/", *info}
+}
+
+func TestReleaseInfo(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(orig+"/sub", 0755)
+	os.Mkdir(mnt, 0755)
+	if err := ioutil.WriteFile(orig+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log := make(chan releaseRecord, 10)
+	s, err := pathfs.Mount(mnt, &releaseFs{pathfs.NewLoopbackFileSystem(orig), log},
+		&fuse.MountOptions{Debug: testutil.VerboseTest()}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	next := func() releaseRecord {
+		select {
+		case r := <-log:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for release")
+		}
+		return releaseRecord{}
+	}
+
+	f, err := os.OpenFile(mnt+"/file", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	f.Close()
+	if r := next(); r.name != "file" || !r.info.Flushed || r.info.OpenFlags&0x3 != uint32(os.O_WRONLY) {
+		t.Errorf("got file release %+v", r)
+	}
+
+	d, err := os.Open(mnt + "/sub")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := d.Readdirnames(-1); err != nil {
+		t.Fatalf("Readdirnames: %v", err)
+	}
+	d.Close()
+	if r := next(); r.name != "sub/" {
+		t.Errorf("got dir release %+v", r)
+	}
+}
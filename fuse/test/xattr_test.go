@@ -7,13 +7,19 @@
 package test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"testing"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	"github.com/hanwen/go-fuse/v2/fuse/pathfs"
 	"github.com/hanwen/go-fuse/v2/internal/testutil"
 )
 
@@ -97,3 +103,264 @@ func TestEmptyXAttr(t *testing.T) {
 		t.Fatalf("got %q, want %q", got, want)
 	}
 }
+
+// posixACL encodes an access ACL granting read access to uid, in the
+// format of the system.posix_acl_access attribute.
+func posixACL(uid uint32) []byte {
+	const (
+		userObj  = 0x01
+		user     = 0x02
+		groupObj = 0x04
+		mask     = 0x10
+		other    = 0x20
+		noID     = ^uint32(0)
+	)
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(2))
+	for _, e := range []struct {
+		tag, perm uint16
+		id        uint32
+	}{
+		{userObj, 6, noID},
+		{user, 4, uid},
+		{groupObj, 4, noID},
+		{mask, 4, noID},
+		{other, 0, noID},
+	} {
+		binary.Write(&buf, binary.LittleEndian, e)
+	}
+	return buf.Bytes()
+}
+
+func TestPosixACL(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+	if err := ioutil.WriteFile(orig+"/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	acl := posixACL(1234)
+	if err := syscall.Setxattr(orig+"/file", "system.posix_acl_access", acl, 0); err != nil {
+		t.Skipf("backing file system does not support ACLs: %v", err)
+	}
+
+	s, err := pathfs.Mount(mnt, pathfs.NewLoopbackFileSystem(orig), &fuse.MountOptions{
+		EnableAcl:            true,
+		IgnoreSecurityLabels: true,
+		Debug:                testutil.VerboseTest(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	var data [1024]byte
+	sz, err := syscall.Getxattr(mnt+"/file", "system.posix_acl_access", data[:])
+	if err != nil {
+		t.Fatalf("Getxattr: %v", err)
+	} else if !bytes.Equal(data[:sz], acl) {
+		t.Errorf("got ACL %x, want %x", data[:sz], acl)
+	}
+
+	// Setting an ACL through the mount reaches the backing file.
+	acl = posixACL(5678)
+	if err := ioutil.WriteFile(mnt+"/file2", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Setxattr(mnt+"/file2", "system.posix_acl_access", acl, 0); err != nil {
+		t.Fatalf("Setxattr: %v", err)
+	}
+	sz, err = syscall.Getxattr(orig+"/file2", "system.posix_acl_access", data[:])
+	if err != nil {
+		t.Fatalf("Getxattr: %v", err)
+	} else if !bytes.Equal(data[:sz], acl) {
+		t.Errorf("got backing ACL %x, want %x", data[:sz], acl)
+	}
+}
+
+type setXAttrRecorder struct {
+	pathfs.FileSystem
+	mu    sync.Mutex
+	attrs map[string][]byte
+}
+
+func (fs *setXAttrRecorder) SetXAttr(name string, attr string, data []byte, flags int, context *fuse.Context) fuse.Status {
+	fs.mu.Lock()
+	fs.attrs[name+" "+attr] = append([]byte{}, data...)
+	fs.mu.Unlock()
+	return fs.FileSystem.SetXAttr(name, attr, data, flags, context)
+}
+
+func TestSecurityContext(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+	orig := dir + "/orig"
+	mnt := dir + "/mnt"
+	os.Mkdir(orig, 0755)
+	os.Mkdir(mnt, 0755)
+
+	recorder := &setXAttrRecorder{
+		FileSystem: pathfs.NewLoopbackFileSystem(orig),
+		attrs:      map[string][]byte{},
+	}
+	s, err := pathfs.Mount(mnt, recorder, &fuse.MountOptions{
+		EnableSecurityContext: true,
+		Debug:                 testutil.VerboseTest(),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Mount: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	if s.KernelSettings().Flags2&fuse.CAP2_SECURITY_CTX == 0 {
+		t.Skip("kernel does not support security contexts")
+	}
+
+	// The kernel now sends a security context header with each
+	// request below, which must not end up in the names.
+	if err := ioutil.WriteFile(mnt+"/file", nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.Mkdir(mnt+"/dir", 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := syscall.Mkfifo(mnt+"/fifo", 0644); err != nil {
+		t.Fatalf("Mkfifo: %v", err)
+	}
+	if err := os.Symlink("target", mnt+"/link"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	for _, name := range []string{"file", "dir", "fifo", "link"} {
+		if _, err := os.Lstat(filepath.Join(orig, name)); err != nil {
+			t.Errorf("Lstat: %v", err)
+		}
+	}
+	if target, err := os.Readlink(orig + "/link"); err != nil || target != "target" {
+		t.Errorf("Readlink: got %q, %v", target, err)
+	}
+
+	// If a security module labeled the new file, the label was
+	// passed on.
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	for k, v := range recorder.attrs {
+		t.Logf("%s = %q", k, v)
+		if !strings.HasPrefix(k, "file security.") {
+			continue
+		}
+		var data [1024]byte
+		sz, err := syscall.Getxattr(orig+"/file", strings.TrimPrefix(k, "file "), data[:])
+		if err != nil {
+			t.Errorf("Getxattr: %v", err)
+		} else if !bytes.Equal(data[:sz], v) {
+			t.Errorf("got label %q, want %q", data[:sz], v)
+		}
+	}
+}
+
+type memXAttrNode struct {
+	nodefs.Node
+	mu    sync.Mutex
+	attrs map[string][]byte
+}
+
+func (n *memXAttrNode) GetXAttr(attr string, context *fuse.Context) ([]byte, fuse.Status) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	v, ok := n.attrs[attr]
+	if !ok {
+		return nil, fuse.ENOATTR
+	}
+	return v, fuse.OK
+}
+
+func (n *memXAttrNode) SetXAttr(attr string, data []byte, flags int, context *fuse.Context) fuse.Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.attrs[attr] = append([]byte{}, data...)
+	return fuse.OK
+}
+
+func (n *memXAttrNode) ListXAttr(context *fuse.Context) ([]string, fuse.Status) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var names []string
+	for k := range n.attrs {
+		names = append(names, k)
+	}
+	return names, fuse.OK
+}
+
+func TestLargeXAttr(t *testing.T) {
+	dir := testutil.TempDir()
+	defer os.RemoveAll(dir)
+
+	root := &memXAttrNode{
+		Node:  nodefs.NewDefaultNode(),
+		attrs: map[string][]byte{},
+	}
+	conn := nodefs.NewFileSystemConnector(root, nodefs.NewOptions())
+
+	// A small MaxWrite must not limit the size of xattrs.
+	s, err := fuse.NewServer(conn.RawFS(), dir, &fuse.MountOptions{
+		MaxWrite: 4096,
+		Debug:    testutil.VerboseTest(),
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	go s.Serve()
+	if err := s.WaitMount(); err != nil {
+		t.Fatal("WaitMount", err)
+	}
+	defer s.Unmount()
+
+	want := bytes.Repeat([]byte("0123456789"), 6000)
+	if err := syscall.Setxattr(dir, "user.large", want, 0); err != nil {
+		t.Fatalf("Setxattr: %v", err)
+	}
+
+	sz, err := syscall.Getxattr(dir, "user.large", nil)
+	if err != nil {
+		t.Fatalf("Getxattr size: %v", err)
+	} else if sz != len(want) {
+		t.Errorf("got size %d, want %d", sz, len(want))
+	}
+
+	if _, err := syscall.Getxattr(dir, "user.large", make([]byte, 100)); err != syscall.ERANGE {
+		t.Errorf("Getxattr with small buffer: got %v, want ERANGE", err)
+	}
+
+	data := make([]byte, sz)
+	sz, err = syscall.Getxattr(dir, "user.large", data)
+	if err != nil {
+		t.Fatalf("Getxattr: %v", err)
+	} else if !bytes.Equal(data[:sz], want) {
+		t.Errorf("got %d bytes, want %d bytes", sz, len(want))
+	}
+
+	if _, err := syscall.Getxattr(dir, "user.missing", nil); err != syscall.ENODATA {
+		t.Errorf("Getxattr missing: got %v, want ENODATA", err)
+	}
+
+	sz, err = syscall.Listxattr(dir, nil)
+	if err != nil {
+		t.Fatalf("Listxattr size: %v", err)
+	} else if wantSz := len("user.large") + 1; sz != wantSz {
+		t.Errorf("got list size %d, want %d", sz, wantSz)
+	}
+	if _, err := syscall.Listxattr(dir, make([]byte, 2)); err != syscall.ERANGE {
+		t.Errorf("Listxattr with small buffer: got %v, want ERANGE", err)
+	}
+}
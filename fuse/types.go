@@ -73,6 +73,12 @@ const (
 
 	// EROFS Read-only file system
 	EROFS = Status(syscall.EROFS)
+
+	// ENOTCONN Transport endpoint is not connected
+	ENOTCONN = Status(syscall.ENOTCONN)
+
+	// ESTALE Stale file handle
+	ESTALE = Status(syscall.ESTALE)
 )
 
 type ForgetIn struct {
@@ -199,7 +205,7 @@ func (s *SetAttrInCommon) GetMTime() (time.Time, bool) {
 	var t time.Time
 	if s.Valid&FATTR_MTIME != 0 {
 		if s.Valid&FATTR_MTIME_NOW != 0 {
-			t = time.Now()
+			t = UtimeNow()
 		} else {
 			t = time.Unix(int64(s.Mtime), int64(s.Mtimensec))
 		}
@@ -213,7 +219,7 @@ func (s *SetAttrInCommon) GetATime() (time.Time, bool) {
 	var t time.Time
 	if s.Valid&FATTR_ATIME != 0 {
 		if s.Valid&FATTR_ATIME_NOW != 0 {
-			t = time.Now()
+			t = UtimeNow()
 		} else {
 			t = time.Unix(int64(s.Atime), int64(s.Atimensec))
 		}
@@ -223,6 +229,19 @@ func (s *SetAttrInCommon) GetATime() (time.Time, bool) {
 	return t, false
 }
 
+// GetTimes returns the access and modification times to set, in the
+// form that Utimens takes: a time that is not set is nil, so it is
+// left unchanged (UTIME_OMIT).
+func (s *SetAttrInCommon) GetTimes() (atime *time.Time, mtime *time.Time) {
+	if a, ok := s.GetATime(); ok {
+		atime = &a
+	}
+	if m, ok := s.GetMTime(); ok {
+		mtime = &m
+	}
+	return atime, mtime
+}
+
 func (s *SetAttrInCommon) GetCTime() (time.Time, bool) {
 	var t time.Time
 	if s.Valid&FATTR_CTIME != 0 {
@@ -256,12 +275,16 @@ const (
 	FOPEN_NONSEEKABLE = (1 << 2)
 	FOPEN_CACHE_DIR   = (1 << 3)
 	FOPEN_STREAM      = (1 << 4)
+	FOPEN_PASSTHROUGH = (1 << 7)
 )
 
 type OpenOut struct {
 	Fh        uint64
 	OpenFlags uint32
-	Padding   uint32
+
+	// BackingId is the backing file used for FOPEN_PASSTHROUGH,
+	// as returned by Server.RegisterBackingFd.
+	BackingId int32
 }
 
 // To be set in InitIn/InitOut.Flags.
@@ -299,6 +322,15 @@ const (
 	CAP_CACHE_SYMLINKS      = (1 << 23)
 	CAP_NO_OPENDIR_SUPPORT  = (1 << 24)
 	CAP_EXPLICIT_INVAL_DATA = (1 << 25)
+	CAP_INIT_EXT            = (1 << 30)
+)
+
+// Capabilities in InitIn.Flags2 and InitOut.Flags2, which are only
+// used if CAP_INIT_EXT is set.
+const (
+	CAP2_SECURITY_CTX  = (1 << 0)
+	CAP2_PASSTHROUGH   = (1 << 5)
+	CAP2_OVER_IO_URING = (1 << 9)
 )
 
 type InitIn struct {
@@ -308,6 +340,10 @@ type InitIn struct {
 	Minor        uint32
 	MaxReadAhead uint32
 	Flags        uint32
+
+	// Since 7.36. Zero for older kernels.
+	Flags2 uint32
+	Unused [11]uint32
 }
 
 type InitOut struct {
@@ -321,10 +357,12 @@ type InitOut struct {
 	TimeGran            uint32
 	MaxPages            uint16
 	Padding             uint16
-	Unused              [8]uint32
+	Flags2              uint32
+	MaxStackDepth       uint32
+	Unused              [6]uint32
 }
 
-type _CuseInitIn struct {
+type CuseInitIn struct {
 	InHeader
 	Major  uint32
 	Minor  uint32
@@ -332,7 +370,7 @@ type _CuseInitIn struct {
 	Flags  uint32
 }
 
-type _CuseInitOut struct {
+type CuseInitOut struct {
 	Major    uint32
 	Minor    uint32
 	Unused   uint32
@@ -366,7 +404,7 @@ const (
 	FUSE_IOCTL_RETRY        = (1 << 2)
 )
 
-type _IoctlIn struct {
+type IoctlIn struct {
 	InHeader
 	Fh      uint64
 	Flags   uint32
@@ -376,14 +414,14 @@ type _IoctlIn struct {
 	OutSize uint32
 }
 
-type _IoctlOut struct {
+type IoctlOut struct {
 	Result  int32
 	Flags   uint32
 	InIovs  uint32
 	OutIovs uint32
 }
 
-type _PollIn struct {
+type PollIn struct {
 	InHeader
 	Fh      uint64
 	Kh      uint64
@@ -391,7 +429,7 @@ type _PollIn struct {
 	Padding uint32
 }
 
-type _PollOut struct {
+type PollOut struct {
 	Revents uint32
 	Padding uint32
 }
@@ -457,6 +495,10 @@ type OutHeader struct {
 	Unique uint64
 }
 
+type NotifyPollWakeupOut struct {
+	Kh uint64
+}
+
 type NotifyInvalInodeOut struct {
 	Ino    uint64
 	Off    int64
@@ -502,7 +544,7 @@ type NotifyRetrieveIn struct {
 }
 
 const (
-	//	NOTIFY_POLL         = -1 // notify kernel that a poll waiting for IO on a file handle should wake up
+	NOTIFY_POLL           = -1 // notify kernel that a poll waiting for IO on a file handle should wake up
 	NOTIFY_INVAL_INODE    = -2 // notify kernel that an inode should be invalidated
 	NOTIFY_INVAL_ENTRY    = -3 // notify kernel that a directory entry should be invalidated
 	NOTIFY_STORE_CACHE    = -4 // store data into kernel cache of an inode
@@ -532,6 +574,23 @@ type LseekOut struct {
 	Offset uint64
 }
 
+// ExchangeIn is the input for the OSXFUSE EXCHANGE opcode, which
+// implements exchangedata(2).
+type ExchangeIn struct {
+	InHeader
+	Olddir  uint64
+	Newdir  uint64
+	Options uint64
+}
+
+// GetxtimesOut is the output for the OSXFUSE GETXTIMES opcode.
+type GetxtimesOut struct {
+	Bkuptime     uint64
+	Crtime       uint64
+	Bkuptimensec uint32
+	Crtimensec   uint32
+}
+
 type CopyFileRangeIn struct {
 	InHeader
 	FhIn      uint64
@@ -600,6 +659,83 @@ type CreateOut struct {
 	OpenOut
 }
 
+// SxTime is a timestamp in a Statx.
+type SxTime struct {
+	Sec      int64
+	Nsec     uint32
+	Reserved int32
+}
+
+// Time returns the timestamp as a time.Time.
+func (t *SxTime) Time() time.Time {
+	return time.Unix(t.Sec, int64(t.Nsec))
+}
+
+// FromTime sets the timestamp from a time.Time.
+func (t *SxTime) FromTime(tm time.Time) {
+	t.Sec = tm.Unix()
+	t.Nsec = uint32(tm.Nanosecond())
+}
+
+// Statx holds the attributes returned by statx(2), which include the
+// birth time (Btime) that Attr has no room for. Mask says which of
+// the fields are set, using the STATX_* constants of statx(2).
+type Statx struct {
+	Mask           uint32
+	Blksize        uint32
+	Attributes     uint64
+	Nlink          uint32
+	Uid            uint32
+	Gid            uint32
+	Mode           uint16
+	Spare0         uint16
+	Ino            uint64
+	Size           uint64
+	Blocks         uint64
+	AttributesMask uint64
+	Atime          SxTime
+	Btime          SxTime
+	Ctime          SxTime
+	Mtime          SxTime
+	RdevMajor      uint32
+	RdevMinor      uint32
+	DevMajor       uint32
+	DevMinor       uint32
+	Spare2         [14]uint64
+}
+
+type StatxIn struct {
+	InHeader
+
+	// GetattrFlags has FUSE_GETATTR_FH if Fh is set.
+	GetattrFlags uint32
+	Reserved     uint32
+	Fh           uint64
+
+	// SxFlags and SxMask are the flags and mask arguments of
+	// statx(2).
+	SxFlags uint32
+	SxMask  uint32
+}
+
+type StatxOut struct {
+	AttrValid     uint64
+	AttrValidNsec uint32
+	Flags         uint32
+	Spare         [2]uint64
+	Statx
+}
+
+func (o *StatxOut) Timeout() time.Duration {
+	return time.Duration(uint64(o.AttrValidNsec) + o.AttrValid*1e9)
+}
+
+func (o *StatxOut) SetTimeout(dt time.Duration) {
+	ns := int64(dt)
+	o.AttrValidNsec = uint32(ns % 1e9)
+	o.AttrValid = uint64(ns / 1e9)
+}
+
 // Caller has data on the process making the FS call.
 //
 // The UID and GID are effective UID/GID, except for the ACCESS
@@ -615,7 +751,11 @@ type InHeader struct {
 	Unique uint64
 	NodeId uint64
 	Caller
-	Padding uint32
+
+	// TotalExtlen is the size of the extensions at the end of
+	// the request, in units of 8 bytes.
+	TotalExtlen uint16
+	Padding     uint16
 }
 
 type StatfsOut struct {
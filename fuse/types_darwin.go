@@ -150,20 +150,6 @@ const (
 	CAP_XTIMES           = (1 << 31)
 )
 
-type GetxtimesOut struct {
-	Bkuptime     uint64
-	Crtime       uint64
-	Bkuptimensec uint32
-	Crtimensec   uint32
-}
-
-type ExchangeIn struct {
-	InHeader
-	Olddir  uint64
-	Newdir  uint64
-	Options uint64
-}
-
 func (s *StatfsOut) FromStatfsT(statfs *syscall.Statfs_t) {
 	s.Blocks = statfs.Blocks
 	s.Bfree = statfs.Bfree
@@ -0,0 +1,20 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+// io_uring is Linux only.
+type ioRing struct{}
+
+type ringEntry struct{}
+
+func (e *ringEntry) reply(req *request, header []byte) Status {
+	return ENOSYS
+}
+
+func (e *ringEntry) replyStatus(unique uint64, code Status) Status {
+	return ENOSYS
+}
+
+func (ms *Server) startRing() {}
@@ -0,0 +1,605 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Constants from include/uapi/linux/io_uring.h.
+const (
+	_SYS_IO_URING_SETUP = 425
+	_SYS_IO_URING_ENTER = 426
+
+	_IORING_SETUP_SQE128     = 1 << 10
+	_IORING_FEAT_SINGLE_MMAP = 1 << 0
+	_IORING_ENTER_GETEVENTS  = 1 << 0
+	_IORING_OP_READ          = 22
+	_IORING_OP_URING_CMD     = 46
+
+	_IORING_OFF_SQ_RING = 0
+	_IORING_OFF_CQ_RING = 0x8000000
+	_IORING_OFF_SQES    = 0x10000000
+)
+
+// Constants from include/uapi/linux/fuse.h.
+const (
+	_FUSE_IO_URING_CMD_REGISTER         = 1
+	_FUSE_IO_URING_CMD_COMMIT_AND_FETCH = 2
+
+	// ringDepth is the number of requests each queue can have
+	// outstanding in the file system.
+	ringDepth = 8
+
+	// wakeupData is the user data of the eventfd read, which
+	// wakes the ring thread to submit replies.
+	wakeupData = ^uint64(0)
+)
+
+type ioSqringOffsets struct {
+	Head        uint32
+	Tail        uint32
+	RingMask    uint32
+	RingEntries uint32
+	Flags       uint32
+	Dropped     uint32
+	Array       uint32
+	Resv1       uint32
+	UserAddr    uint64
+}
+
+type ioCqringOffsets struct {
+	Head        uint32
+	Tail        uint32
+	RingMask    uint32
+	RingEntries uint32
+	Overflow    uint32
+	Cqes        uint32
+	Flags       uint32
+	Resv1       uint32
+	UserAddr    uint64
+}
+
+type ioUringParams struct {
+	SqEntries    uint32
+	CqEntries    uint32
+	Flags        uint32
+	SqThreadCpu  uint32
+	SqThreadIdle uint32
+	Features     uint32
+	WqFd         uint32
+	Resv         [3]uint32
+	SqOff        ioSqringOffsets
+	CqOff        ioCqringOffsets
+}
+
+// ioUringSqe is a submission queue entry for a ring set up with
+// IORING_SETUP_SQE128.
+type ioUringSqe struct {
+	Opcode      uint8
+	Flags       uint8
+	Ioprio      uint16
+	Fd          int32
+	Off         uint32 // cmd_op for IORING_OP_URING_CMD
+	Pad1        uint32
+	Addr        uint64
+	Len         uint32
+	OpFlags     uint32
+	UserData    uint64
+	BufIndex    uint16
+	Personality uint16
+	SpliceFdIn  int32
+	Cmd         [80]byte
+}
+
+type ioUringCqe struct {
+	UserData uint64
+	Res      int32
+	Flags    uint32
+}
+
+// uringCmdReq is struct fuse_uring_cmd_req, which goes in the
+// command area of the submission.
+type uringCmdReq struct {
+	Flags    uint64
+	CommitId uint64
+	Qid      uint16
+	Padding  [6]uint8
+}
+
+// uringReqHeader is struct fuse_uring_req_header. The kernel puts
+// the InHeader in InOut, and the opcode specific input in OpIn; the
+// rest of the request is in the payload buffer. The reply has the
+// OutHeader in InOut, and everything else in the payload buffer.
+type uringReqHeader struct {
+	InOut     [128]byte
+	OpIn      [128]byte
+	Flags     uint64
+	CommitId  uint64
+	PayloadSz uint32
+	Padding   uint32
+	Reserved  uint64
+}
+
+// ioRing receives requests from the kernel through io_uring. It
+// registers ringDepth entries for each of the kernel's per-CPU
+// queues. The kernel completes an entry's command when it has put a
+// request in the entry's buffers, and the reply is submitted with the
+// command that fetches the next request.
+//
+// The kernel delivers requests in the thread that submitted the
+// command, so all submissions are done by a single locked thread,
+// which only waits on the ring. Replies are handed to it through
+// pending, and an eventfd wakes it up.
+type ioRing struct {
+	server *Server
+	fd     int
+
+	sqHead  *uint32
+	sqTail  *uint32
+	sqMask  uint32
+	sqArray []uint32
+	sqes    []ioUringSqe
+
+	// tail is the tail of the submission queue, including
+	// entries not yet published to the kernel.
+	tail uint32
+
+	cqHead *uint32
+	cqTail *uint32
+	cqMask uint32
+	cqes   []ioUringCqe
+
+	maps    [][]byte
+	entries []*ringEntry
+
+	wakeFd  int
+	wakeBuf uint64
+
+	// state protects the buffers against being unmapped while
+	// replies are written into them.
+	state  sync.RWMutex
+	closed bool
+
+	mu      sync.Mutex
+	pending []*ringEntry
+
+	// served counts the requests received through the ring.
+	served uint64
+}
+
+// ringEntry is a buffer registered with the kernel, which carries one
+// request and its reply at a time.
+type ringEntry struct {
+	ring     *ioRing
+	index    uint64
+	qid      uint16
+	header   *uringReqHeader
+	payload  []byte
+	commitId uint64
+	iov      [2]syscall.Iovec
+}
+
+// possibleCPUs returns the number of CPU queues the kernel uses.
+func possibleCPUs() (int, error) {
+	data, err := ioutil.ReadFile("/sys/devices/system/cpu/possible")
+	if err != nil {
+		return 0, err
+	}
+	max := 0
+	for _, r := range strings.Split(strings.TrimSpace(string(data)), ",") {
+		bounds := strings.Split(r, "-")
+		n, err := strconv.Atoi(bounds[len(bounds)-1])
+		if err != nil {
+			return 0, err
+		}
+		if n > max {
+			max = n
+		}
+	}
+	return max + 1, nil
+}
+
+// startRing sets up the io_uring. If this fails, requests keep
+// coming through /dev/fuse.
+func (ms *Server) startRing() {
+	ready := make(chan error, 1)
+	ms.loops.Add(1)
+	go func() {
+		defer ms.loops.Done()
+
+		// The thread is not unlocked, so it exits with the
+		// goroutine.
+		runtime.LockOSThread()
+		r, err := newIoRing(ms)
+		if err == nil {
+			ms.ring = r
+		}
+		ready <- err
+		if err == nil {
+			r.loop()
+		}
+	}()
+	if err := <-ready; err != nil {
+		ms.logf("io_uring unavailable, reading /dev/fuse: %v", err)
+	}
+}
+
+func newIoRing(ms *Server) (*ioRing, error) {
+	queues, err := possibleCPUs()
+	if err != nil {
+		return nil, err
+	}
+
+	// The kernel rejects buffers smaller than its largest
+	// request: a write of MaxWrite, or the default of 32 pages.
+	payloadSize := ms.opts.MaxWrite
+	if n := 32 * os.Getpagesize(); payloadSize < n {
+		payloadSize = n
+	}
+	if payloadSize < _FUSE_MIN_READ_BUFFER {
+		payloadSize = _FUSE_MIN_READ_BUFFER
+	}
+
+	n := queues * ringDepth
+	params := ioUringParams{Flags: _IORING_SETUP_SQE128}
+	fd, _, errno := syscall.Syscall(_SYS_IO_URING_SETUP, uintptr(n+1), uintptr(unsafe.Pointer(&params)), 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("io_uring_setup: %v", errno)
+	}
+	r := &ioRing{server: ms, fd: int(fd), wakeFd: -1}
+	if err := r.mapRings(&params); err != nil {
+		r.close()
+		return nil, err
+	}
+	wakeFd, _, errno := syscall.Syscall(syscall.SYS_EVENTFD2, 0, syscall.O_CLOEXEC, 0)
+	if errno != 0 {
+		r.close()
+		return nil, fmt.Errorf("eventfd: %v", errno)
+	}
+	r.wakeFd = int(wakeFd)
+
+	for q := 0; q < queues; q++ {
+		for i := 0; i < ringDepth; i++ {
+			e, err := r.newEntry(uint16(q), payloadSize)
+			if err != nil {
+				r.close()
+				return nil, err
+			}
+			r.prepCmd(e, _FUSE_IO_URING_CMD_REGISTER)
+		}
+	}
+	r.prepWakeup()
+	if err := r.enter(0); err != nil {
+		r.close()
+		return nil, fmt.Errorf("register: %v", err)
+	}
+	return r, nil
+}
+
+func (r *ioRing) mmap(off int64, size int) ([]byte, error) {
+	m, err := syscall.Mmap(r.fd, off, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	if err != nil {
+		return nil, err
+	}
+	r.maps = append(r.maps, m)
+	return m, nil
+}
+
+func (r *ioRing) mapRings(p *ioUringParams) error {
+	sqSize := int(p.SqOff.Array) + int(p.SqEntries)*4
+	cqSize := int(p.CqOff.Cqes) + int(p.CqEntries)*int(unsafe.Sizeof(ioUringCqe{}))
+	if p.Features&_IORING_FEAT_SINGLE_MMAP == 0 {
+		return fmt.Errorf("io_uring lacks IORING_FEAT_SINGLE_MMAP")
+	}
+	if cqSize > sqSize {
+		sqSize = cqSize
+	}
+	rings, err := r.mmap(_IORING_OFF_SQ_RING, sqSize)
+	if err != nil {
+		return err
+	}
+	sqes, err := r.mmap(_IORING_OFF_SQES, int(p.SqEntries)*int(unsafe.Sizeof(ioUringSqe{})))
+	if err != nil {
+		return err
+	}
+
+	r.sqHead = (*uint32)(unsafe.Pointer(&rings[p.SqOff.Head]))
+	r.sqTail = (*uint32)(unsafe.Pointer(&rings[p.SqOff.Tail]))
+	r.sqMask = *(*uint32)(unsafe.Pointer(&rings[p.SqOff.RingMask]))
+	r.tail = *r.sqTail
+	r.sqArray = (*[1 << 20]uint32)(unsafe.Pointer(&rings[p.SqOff.Array]))[:p.SqEntries:p.SqEntries]
+	r.sqes = (*[1 << 16]ioUringSqe)(unsafe.Pointer(&sqes[0]))[:p.SqEntries:p.SqEntries]
+
+	r.cqHead = (*uint32)(unsafe.Pointer(&rings[p.CqOff.Head]))
+	r.cqTail = (*uint32)(unsafe.Pointer(&rings[p.CqOff.Tail]))
+	r.cqMask = *(*uint32)(unsafe.Pointer(&rings[p.CqOff.RingMask]))
+	r.cqes = (*[1 << 20]ioUringCqe)(unsafe.Pointer(&rings[p.CqOff.Cqes]))[:p.CqEntries:p.CqEntries]
+	return nil
+}
+
+// newEntry allocates the buffers of an entry outside the Go heap, as
+// the kernel writes them while we are not looking. The payload starts
+// on a page boundary, so files opened with O_DIRECT can be read into
+// it.
+func (r *ioRing) newEntry(qid uint16, payloadSize int) (*ringEntry, error) {
+	headerSize := int(unsafe.Sizeof(uringReqHeader{}))
+	pageSize := os.Getpagesize()
+	buf, err := syscall.Mmap(-1, 0, pageSize+payloadSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	r.maps = append(r.maps, buf)
+
+	e := &ringEntry{
+		ring:    r,
+		index:   uint64(len(r.entries)),
+		qid:     qid,
+		header:  (*uringReqHeader)(unsafe.Pointer(&buf[0])),
+		payload: buf[pageSize:],
+	}
+	e.iov[0].Base = &buf[0]
+	e.iov[0].SetLen(headerSize)
+	e.iov[1].Base = &e.payload[0]
+	e.iov[1].SetLen(payloadSize)
+	r.entries = append(r.entries, e)
+	return e, nil
+}
+
+// prep returns the next free submission. It must only be called
+// from the ring thread.
+func (r *ioRing) prep() *ioUringSqe {
+	idx := r.tail & r.sqMask
+	r.sqArray[idx] = idx
+	r.tail++
+	sqe := &r.sqes[idx]
+	*sqe = ioUringSqe{}
+	return sqe
+}
+
+// prepCmd queues a FUSE command for entry e.
+func (r *ioRing) prepCmd(e *ringEntry, cmdOp uint32) {
+	sqe := r.prep()
+	sqe.Opcode = _IORING_OP_URING_CMD
+	sqe.Fd = int32(r.server.mountFd)
+	sqe.Off = cmdOp
+	sqe.UserData = e.index
+	if cmdOp == _FUSE_IO_URING_CMD_REGISTER {
+		sqe.Addr = uint64(uintptr(unsafe.Pointer(&e.iov[0])))
+		sqe.Len = uint32(len(e.iov))
+	}
+	cmd := (*uringCmdReq)(unsafe.Pointer(&sqe.Cmd[0]))
+	cmd.CommitId = e.commitId
+	cmd.Qid = e.qid
+}
+
+// prepWakeup queues a read of the eventfd.
+func (r *ioRing) prepWakeup() {
+	sqe := r.prep()
+	sqe.Opcode = _IORING_OP_READ
+	sqe.Fd = int32(r.wakeFd)
+	sqe.Addr = uint64(uintptr(unsafe.Pointer(&r.wakeBuf)))
+	sqe.Len = uint32(unsafe.Sizeof(r.wakeBuf))
+	sqe.UserData = wakeupData
+}
+
+// enter submits the queued submissions, and waits for minComplete
+// completions.
+func (r *ioRing) enter(minComplete uint32) error {
+	atomic.StoreUint32(r.sqTail, r.tail)
+	toSubmit := r.tail - atomic.LoadUint32(r.sqHead)
+	var flags uintptr
+	if minComplete > 0 {
+		flags = _IORING_ENTER_GETEVENTS
+	}
+	_, _, errno := syscall.Syscall6(_SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(toSubmit), uintptr(minComplete), flags, 0, 0)
+	if errno == syscall.EINTR || errno == syscall.EAGAIN || errno == syscall.EBUSY {
+		return nil
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// loop waits for completed commands, and dispatches the requests
+// they carry. It exits when the kernel has cancelled all entries,
+// which happens when the file system is unmounted.
+func (r *ioRing) loop() {
+	defer r.close()
+
+	live := len(r.entries)
+	for live > 0 {
+		r.mu.Lock()
+		pending := r.pending
+		r.pending = nil
+		r.mu.Unlock()
+		for _, e := range pending {
+			r.prepCmd(e, _FUSE_IO_URING_CMD_COMMIT_AND_FETCH)
+		}
+
+		if err := r.enter(1); err != nil {
+			r.server.logf("io_uring_enter: %v", err)
+			return
+		}
+
+		head := *r.cqHead
+		tail := atomic.LoadUint32(r.cqTail)
+		for ; head != tail; head++ {
+			cqe := r.cqes[head&r.cqMask]
+			if cqe.UserData == wakeupData {
+				r.prepWakeup()
+				continue
+			}
+			e := r.entries[cqe.UserData]
+			if cqe.Res < 0 {
+				errno := syscall.Errno(-cqe.Res)
+				if errno != syscall.ENOTCONN && errno != syscall.ECANCELED && r.server.opts.Debug {
+					r.server.logf("io_uring entry %d: %v", e.index, errno)
+				}
+				live--
+				continue
+			}
+			r.dispatch(e)
+		}
+		atomic.StoreUint32(r.cqHead, head)
+	}
+}
+
+func (r *ioRing) close() {
+	r.state.Lock()
+	defer r.state.Unlock()
+	r.closed = true
+	for _, m := range r.maps {
+		syscall.Munmap(m)
+	}
+	r.maps = nil
+	if r.wakeFd >= 0 {
+		syscall.Close(r.wakeFd)
+	}
+	syscall.Close(r.fd)
+}
+
+// dispatch turns the request in e into a request struct, and hands
+// it to the server.
+func (r *ioRing) dispatch(e *ringEntry) {
+	ms := r.server
+	atomic.AddUint64(&r.served, 1)
+	e.commitId = e.header.CommitId
+
+	in := (*InHeader)(unsafe.Pointer(&e.header.InOut[0]))
+	hdrSize := int(unsafe.Sizeof(InHeader{}))
+	payloadSize := int(e.header.PayloadSz)
+	opSize := int(in.Length) - hdrSize - payloadSize
+	if opSize < 0 || opSize > len(e.header.OpIn) || payloadSize > len(e.payload) {
+		e.replyStatus(in.Unique, EIO)
+		return
+	}
+
+	req := ms.reqPool.Get().(*request)
+	buf := ms.readPool.Get().([]byte)
+	n := int(in.Length)
+	if n > len(buf) {
+		buf = make([]byte, n)
+	}
+	dest := buf[:n]
+	copy(dest, e.header.InOut[:hdrSize])
+	copy(dest[hdrSize:], e.header.OpIn[:opSize])
+	copy(dest[hdrSize+opSize:], e.payload[:payloadSize])
+
+	if ms.latencies != nil || ms.opts.Debug || ms.opts.EnableStats || ms.opts.OpHook != nil {
+		req.startTime = time.Now()
+	}
+	if ms.recorder != nil {
+		ms.recorder.record(recordRequest, dest)
+	}
+	gobbled := req.setInput(dest)
+	req.ringEntry = e
+
+	ms.reqMu.Lock()
+	if status := req.parseHeader(); !status.Ok() {
+		ms.reqMu.Unlock()
+		e.replyStatus(in.Unique, status)
+		return
+	}
+	req.inflightIndex = len(ms.reqInflight)
+	req.refused = ms.draining && refusable(req.inHeader.Opcode)
+	ms.reqInflight = append(ms.reqInflight, req)
+	ms.reqMu.Unlock()
+	if !gobbled {
+		ms.readPool.Put(buf)
+	}
+
+	if ms.sched != nil {
+		ms.sched.push(req)
+	} else {
+		go ms.handleRequest(req)
+	}
+}
+
+// commit hands e to the ring thread, which submits the reply in its
+// buffers while fetching the next request. The caller must hold
+// state.RLock.
+func (e *ringEntry) commit() Status {
+	r := e.ring
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = append(r.pending, e)
+	if len(r.pending) > 1 {
+		return OK
+	}
+	one := uint64(1)
+	_, err := syscall.Write(r.wakeFd, (*[8]byte)(unsafe.Pointer(&one))[:])
+	return ToStatus(err)
+}
+
+// reply puts the reply in the entry's buffers, and queues it for
+// submission.
+func (e *ringEntry) reply(req *request, header []byte) Status {
+	e.ring.state.RLock()
+	defer e.ring.state.RUnlock()
+	if e.ring.closed {
+		return ENODEV
+	}
+
+	hdrSize := int(sizeOfOutHeader)
+	copy(e.header.InOut[:], header[:hdrSize])
+
+	n := copy(e.payload, header[hdrSize:])
+	if req.fdData != nil {
+		data, code := req.fdData.Bytes(e.payload[n:])
+		if !code.Ok() {
+			return e.replyStatusLocked(req.inHeader.Unique, code)
+		}
+		if len(data) > 0 && &data[0] != &e.payload[n] {
+			copy(e.payload[n:], data)
+		}
+		n += len(data)
+	} else {
+		if len(req.flatData) > len(e.payload)-n {
+			return e.replyStatusLocked(req.inHeader.Unique, EIO)
+		}
+		n += copy(e.payload[n:], req.flatData)
+	}
+	if req.readResult != nil {
+		req.readResult.Done()
+	}
+
+	o := (*OutHeader)(unsafe.Pointer(&e.header.InOut[0]))
+	o.Length = uint32(hdrSize + n)
+	e.header.PayloadSz = uint32(n)
+	return e.commit()
+}
+
+// replyStatus replies with an error.
+func (e *ringEntry) replyStatus(unique uint64, code Status) Status {
+	e.ring.state.RLock()
+	defer e.ring.state.RUnlock()
+	if e.ring.closed {
+		return ENODEV
+	}
+	return e.replyStatusLocked(unique, code)
+}
+
+func (e *ringEntry) replyStatusLocked(unique uint64, code Status) Status {
+	o := (*OutHeader)(unsafe.Pointer(&e.header.InOut[0]))
+	*o = OutHeader{
+		Length: uint32(sizeOfOutHeader),
+		Status: int32(-code),
+		Unique: unique,
+	}
+	e.header.PayloadSz = 0
+	return e.commit()
+}
@@ -0,0 +1,94 @@
+// Copyright 2026 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type uringFS struct {
+	RawFileSystem
+}
+
+func (fs *uringFS) GetAttr(cancel <-chan struct{}, in *GetAttrIn, out *AttrOut) Status {
+	switch in.NodeId {
+	case FUSE_ROOT_ID:
+		out.Mode = S_IFDIR | 0755
+	case 2:
+		out.Mode = S_IFREG | 0644
+		out.Size = 5
+	default:
+		return ENOENT
+	}
+	out.Ino = in.NodeId
+	return OK
+}
+
+func (fs *uringFS) Lookup(cancel <-chan struct{}, header *InHeader, name string, out *EntryOut) Status {
+	if header.NodeId != FUSE_ROOT_ID || name != "file" {
+		return ENOENT
+	}
+	out.NodeId = 2
+	out.Ino = 2
+	out.Mode = S_IFREG | 0644
+	out.Size = 5
+	return OK
+}
+
+func (fs *uringFS) Open(cancel <-chan struct{}, in *OpenIn, out *OpenOut) Status {
+	return OK
+}
+
+func (fs *uringFS) Read(cancel <-chan struct{}, in *ReadIn, buf []byte) (ReadResult, Status) {
+	data := []byte("hello")
+	if in.Offset >= uint64(len(data)) {
+		return ReadResultData(nil), OK
+	}
+	return ReadResultData(data[in.Offset:]), OK
+}
+
+func TestIoUring(t *testing.T) {
+	param, err := ioutil.ReadFile("/sys/module/fuse/parameters/enable_uring")
+	if err != nil || strings.TrimSpace(string(param)) != "Y" {
+		t.Skip("kernel does not have FUSE over io_uring enabled")
+	}
+
+	dir, err := ioutil.TempDir("", "TestIoUring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv, err := NewServer(&uringFS{NewDefaultRawFileSystem()}, dir, &MountOptions{
+		EnableIoUring: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Unmount()
+
+	if srv.KernelSettings().Flags2&CAP2_OVER_IO_URING == 0 {
+		t.Skip("kernel does not support FUSE over io_uring")
+	}
+	if srv.ring == nil {
+		t.Fatal("io_uring was not set up")
+	}
+
+	content, err := ioutil.ReadFile(dir + "/file")
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("ReadFile: %q, %v", content, err)
+	}
+	if served := atomic.LoadUint64(&srv.ring.served); served == 0 {
+		t.Errorf("no requests were served through io_uring")
+	}
+}
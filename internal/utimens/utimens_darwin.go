@@ -26,11 +26,11 @@ func timeToTimeval(t *time.Time) syscall.Timeval {
 // to syscall.Utimes. Missing values (if any) are taken from attr
 func Fill(a *time.Time, m *time.Time, attr *fuse.Attr) []syscall.Timeval {
 	if a == nil {
-		a2 := time.Unix(int64(attr.Atime), int64(attr.Atimensec))
+		a2 := attr.AccessTime()
 		a = &a2
 	}
 	if m == nil {
-		m2 := time.Unix(int64(attr.Mtime), int64(attr.Mtimensec))
+		m2 := attr.ModTime()
 		m = &m2
 	}
 	tv := make([]syscall.Timeval, 2)
@@ -140,19 +140,7 @@ func (n *unionFSNode) Setattr(ctx context.Context, fh fs.FileHandle, in *fuse.Se
 			}
 		}
 
-		mtime, mok := in.GetMTime()
-		atime, aok := in.GetATime()
-
-		if mok || aok {
-
-			ap := &atime
-			mp := &mtime
-			if !aok {
-				ap = nil
-			}
-			if !mok {
-				mp = nil
-			}
+		if ap, mp := in.GetTimes(); ap != nil || mp != nil {
 			var ts [2]syscall.Timespec
 			ts[0] = fuse.UtimeToTimespec(ap)
 			ts[1] = fuse.UtimeToTimespec(mp)
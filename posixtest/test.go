@@ -29,6 +29,7 @@ var All = map[string]func(*testing.T, string){
 	"MkdirRmdir":                 MkdirRmdir,
 	"NlinkZero":                  NlinkZero,
 	"FstatDeleted":               FstatDeleted,
+	"FtruncateDeleted":           FtruncateDeleted,
 	"ParallelFileOpen":           ParallelFileOpen,
 	"Link":                       Link,
 	"LinkUnlinkRename":           LinkUnlinkRename,
@@ -258,6 +259,33 @@ func NlinkZero(t *testing.T, mnt string) {
 
 }
 
+// FtruncateDeleted checks that ftruncate works on an open file that
+// was unlinked, as is common for temporary files.
+func FtruncateDeleted(t *testing.T, mnt string) {
+	path := mnt + "/file"
+	if err := ioutil.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	fd, err := syscall.Open(path, syscall.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer syscall.Close(fd)
+	if err := syscall.Unlink(path); err != nil {
+		t.Fatalf("Unlink: %v", err)
+	}
+	if err := syscall.Ftruncate(fd, 3); err != nil {
+		t.Fatalf("Ftruncate: %v", err)
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		t.Fatalf("Fstat: %v", err)
+	}
+	if st.Size != 3 {
+		t.Errorf("got size %d, want 3", st.Size)
+	}
+}
+
 // FstatDeleted is similar to NlinkZero, but Fstat()s multiple deleted files
 // in random order and checks that the results match an earlier Stat().
 //
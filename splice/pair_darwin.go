@@ -4,19 +4,41 @@
 
 package splice
 
-import ()
+import "syscall"
 
 func (p *Pair) LoadFromAt(fd uintptr, sz int, off int64) (int, error) {
 	panic("not implemented")
-	return 0, nil
 }
 
 func (p *Pair) LoadFrom(fd uintptr, sz int) (int, error) {
 	panic("not implemented")
-	return 0, nil
 }
 
 func (p *Pair) WriteTo(fd uintptr, n int) (int, error) {
 	panic("not implemented")
-	return 0, nil
+}
+
+func (p *Pair) WriteToAt(fd uintptr, n int, off int64) (int, error) {
+	panic("not implemented")
+}
+
+func (p *Pair) discard() {
+	panic("not implemented")
+}
+
+// osPipe emulates pipe2(O_NONBLOCK), which Darwin lacks.
+func osPipe() (int, int, error) {
+	var fds [2]int
+	if err := syscall.Pipe(fds[:]); err != nil {
+		return 0, 0, err
+	}
+	for _, fd := range fds {
+		syscall.CloseOnExec(fd)
+		if err := syscall.SetNonblock(fd, true); err != nil {
+			syscall.Close(fds[0])
+			syscall.Close(fds[1])
+			return 0, 0, err
+		}
+	}
+	return fds[0], fds[1], nil
 }
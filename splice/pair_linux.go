@@ -11,7 +11,12 @@ import (
 	"syscall"
 )
 
+// LoadFromAt splices sz bytes at offset off of fd into the pipe. A
+// negative offset reads from the current file offset.
 func (p *Pair) LoadFromAt(fd uintptr, sz int, off int64) (int, error) {
+	if off < 0 {
+		return p.LoadFrom(fd, sz)
+	}
 	n, err := syscall.Splice(int(fd), &off, p.w, nil, sz, 0)
 	return int(n), err
 }
@@ -37,6 +42,15 @@ func (p *Pair) WriteTo(fd uintptr, n int) (int, error) {
 	return int(m), err
 }
 
+// WriteToAt splices n bytes from the pipe into fd at offset off.
+func (p *Pair) WriteToAt(fd uintptr, n int, off int64) (int, error) {
+	m, err := syscall.Splice(p.r, nil, int(fd), &off, int(n), 0)
+	if err != nil {
+		err = os.NewSyscallError("Splice write", err)
+	}
+	return int(m), err
+}
+
 const _SPLICE_F_NONBLOCK = 0x2
 
 func (p *Pair) discard() {
@@ -52,3 +66,9 @@ func (p *Pair) discard() {
 		log.Panicf("splicing into /dev/null: %v (close R %d '%v', close W %d '%v')", err, p.r, errR, p.w, errW)
 	}
 }
+
+func osPipe() (int, int, error) {
+	var fds [2]int
+	err := syscall.Pipe2(fds[:], syscall.O_NONBLOCK)
+	return fds[0], fds[1], err
+}